@@ -6,10 +6,12 @@ go 1.18
 
 require (
 	github.com/MicahParks/keyfunc/v2 v2.1.0
+	github.com/beevik/etree v1.1.0
 	github.com/derekstavis/go-qs v0.0.0-20180720192143-9eef69e6c4e7
 	github.com/golang-jwt/jwt/v5 v5.0.0
 	github.com/joho/godotenv v1.3.0
 	github.com/nyaruka/phonenumbers v1.0.73
+	github.com/russellhaering/goxmldsig v1.4.0
 	github.com/stretchr/testify v1.7.0
 	github.com/twilio/twilio-go v0.26.0
 	golang.org/x/crypto v0.2.0
@@ -23,6 +25,7 @@ require (
 	github.com/golang/mock v1.6.0 // indirect
 	github.com/golang/protobuf v1.3.2 // indirect
 	github.com/h2non/parth v0.0.0-20190131123155-b4df798d6542 // indirect
+	github.com/jonboulle/clockwork v0.2.2 // indirect
 	github.com/pkg/errors v0.9.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	golang.org/x/text v0.4.0 // indirect
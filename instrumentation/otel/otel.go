@@ -0,0 +1,117 @@
+/* Copyright (c) 2021, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+// Package otel implements supertokens.Instrumentation on top of OpenTelemetry. It lives in its
+// own module so that the SDK does not depend on OpenTelemetry.
+package otel
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/supertokens/supertokens-golang/supertokens"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/supertokens/supertokens-golang"
+
+type instrumentation struct {
+	tracer     trace.Tracer
+	meter      metric.Meter
+	counters   sync.Map
+	histograms sync.Map
+}
+
+// New returns an Instrumentation that creates spans using the tracerProvider and records metrics
+// using the meterProvider. Pass it in supertokens.TypeInput.Instrumentation.
+func New(tracerProvider trace.TracerProvider, meterProvider metric.MeterProvider) supertokens.Instrumentation {
+	return &instrumentation{
+		tracer: tracerProvider.Tracer(instrumentationName),
+		meter:  meterProvider.Meter(instrumentationName),
+	}
+}
+
+type span struct {
+	span trace.Span
+}
+
+func (s span) SetAttributes(attributes map[string]interface{}) {
+	s.span.SetAttributes(toAttributes(attributes)...)
+}
+
+func (s span) RecordError(err error) {
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+func (s span) End() {
+	s.span.End()
+}
+
+func (i *instrumentation) StartSpan(ctx context.Context, name string, attributes map[string]interface{}) (context.Context, supertokens.Span) {
+	ctx, otelSpan := i.tracer.Start(ctx, name, trace.WithAttributes(toAttributes(attributes)...))
+	return ctx, span{span: otelSpan}
+}
+
+func (i *instrumentation) AddToCounter(ctx context.Context, name string, value int64, attributes map[string]interface{}) {
+	counter, ok := i.counters.Load(name)
+	if !ok {
+		newCounter, err := i.meter.Int64Counter(name)
+		if err != nil {
+			supertokens.LogWarn("otel: could not create counter", "name", name, supertokens.LogFieldError, err.Error())
+			return
+		}
+		counter, _ = i.counters.LoadOrStore(name, newCounter)
+	}
+	counter.(metric.Int64Counter).Add(ctx, value, metric.WithAttributes(toAttributes(attributes)...))
+}
+
+func (i *instrumentation) RecordDuration(ctx context.Context, name string, durationMS float64, attributes map[string]interface{}) {
+	histogram, ok := i.histograms.Load(name)
+	if !ok {
+		newHistogram, err := i.meter.Float64Histogram(name, metric.WithUnit("ms"))
+		if err != nil {
+			supertokens.LogWarn("otel: could not create histogram", "name", name, supertokens.LogFieldError, err.Error())
+			return
+		}
+		histogram, _ = i.histograms.LoadOrStore(name, newHistogram)
+	}
+	histogram.(metric.Float64Histogram).Record(ctx, durationMS, metric.WithAttributes(toAttributes(attributes)...))
+}
+
+func toAttributes(attributes map[string]interface{}) []attribute.KeyValue {
+	result := make([]attribute.KeyValue, 0, len(attributes))
+	for key, value := range attributes {
+		switch v := value.(type) {
+		case string:
+			result = append(result, attribute.String(key, v))
+		case int:
+			result = append(result, attribute.Int(key, v))
+		case int64:
+			result = append(result, attribute.Int64(key, v))
+		case float64:
+			result = append(result, attribute.Float64(key, v))
+		case bool:
+			result = append(result, attribute.Bool(key, v))
+		default:
+			result = append(result, attribute.String(key, fmt.Sprint(v)))
+		}
+	}
+	return result
+}
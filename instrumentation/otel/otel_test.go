@@ -0,0 +1,64 @@
+package otel
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/supertokens/supertokens-golang/supertokens"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSpansAndMetricsAreExported(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	reader := sdkmetric.NewManualReader()
+	meterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	instrumentation := New(tracerProvider, meterProvider)
+
+	ctx, apiSpan := instrumentation.StartSpan(context.Background(), supertokens.SpanNameAPI, map[string]interface{}{
+		supertokens.AttributeAPIID: "/signin",
+	})
+	_, coreSpan := instrumentation.StartSpan(ctx, supertokens.SpanNameCoreRequest, nil)
+	coreSpan.SetAttributes(map[string]interface{}{supertokens.AttributeHTTPStatusCode: 500})
+	coreSpan.RecordError(errors.New("core error"))
+	coreSpan.End()
+	apiSpan.End()
+
+	instrumentation.AddToCounter(ctx, supertokens.MetricSignIns, 1, map[string]interface{}{supertokens.AttributeRecipeID: "emailpassword"})
+	instrumentation.AddToCounter(ctx, supertokens.MetricSignIns, 2, map[string]interface{}{supertokens.AttributeRecipeID: "emailpassword"})
+	instrumentation.RecordDuration(ctx, supertokens.MetricAPIDuration, 12.5, nil)
+
+	spans := exporter.GetSpans()
+	assert.Equal(t, 2, len(spans))
+	assert.Equal(t, supertokens.SpanNameCoreRequest, spans[0].Name)
+	assert.Equal(t, codes.Error, spans[0].Status.Code)
+	assert.Contains(t, spans[0].Attributes, attribute.Int(supertokens.AttributeHTTPStatusCode, 500))
+	assert.Equal(t, supertokens.SpanNameAPI, spans[1].Name)
+	assert.Equal(t, spans[1].SpanContext.SpanID(), spans[0].Parent.SpanID())
+	assert.Contains(t, spans[1].Attributes, attribute.String(supertokens.AttributeAPIID, "/signin"))
+
+	data := metricdata.ResourceMetrics{}
+	assert.NoError(t, reader.Collect(context.Background(), &data))
+	assert.Equal(t, 1, len(data.ScopeMetrics))
+
+	metrics := map[string]metricdata.Metrics{}
+	for _, m := range data.ScopeMetrics[0].Metrics {
+		metrics[m.Name] = m
+	}
+
+	signIns := metrics[supertokens.MetricSignIns].Data.(metricdata.Sum[int64])
+	assert.Equal(t, 1, len(signIns.DataPoints))
+	assert.Equal(t, int64(3), signIns.DataPoints[0].Value)
+
+	apiDuration := metrics[supertokens.MetricAPIDuration].Data.(metricdata.Histogram[float64])
+	assert.Equal(t, 1, len(apiDuration.DataPoints))
+	assert.Equal(t, 12.5, apiDuration.DataPoints[0].Sum)
+}
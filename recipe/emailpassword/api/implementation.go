@@ -198,11 +198,51 @@ func MakeAPIImplementation() epmodels.APIInterface {
 			},
 		}, nil
 	}
+	reauthenticatePOST := func(formFields []epmodels.TypeFormField, sessionContainer sessmodels.SessionContainer, tenantId string, options epmodels.APIOptions, userContext supertokens.UserContext) (epmodels.ReauthenticatePOSTResponse, error) {
+		var password string
+		for _, formField := range formFields {
+			if formField.ID == "password" {
+				password = formField.Value
+			}
+		}
+
+		user, err := (*options.RecipeImplementation.GetUserByID)(sessionContainer.GetUserIDWithContext(userContext), userContext)
+		if err != nil {
+			return epmodels.ReauthenticatePOSTResponse{}, err
+		}
+		if user == nil {
+			// The user of the session did not sign in with emailpassword
+			return epmodels.ReauthenticatePOSTResponse{
+				WrongCredentialsError: &struct{}{},
+			}, nil
+		}
+
+		response, err := (*options.RecipeImplementation.SignIn)(user.Email, password, sessionContainer.GetTenantIdWithContext(userContext), userContext)
+		if err != nil {
+			return epmodels.ReauthenticatePOSTResponse{}, err
+		}
+		if response.WrongCredentialsError != nil || response.OK.User.ID != user.ID {
+			return epmodels.ReauthenticatePOSTResponse{
+				WrongCredentialsError: &struct{}{},
+			}, nil
+		}
+
+		err = session.UpdateLastAuthenticatedAt(sessionContainer, userContext)
+		if err != nil {
+			return epmodels.ReauthenticatePOSTResponse{}, err
+		}
+
+		return epmodels.ReauthenticatePOSTResponse{
+			OK: &struct{}{},
+		}, nil
+	}
+
 	return epmodels.APIInterface{
 		EmailExistsGET:                 &emailExistsGET,
 		GeneratePasswordResetTokenPOST: &generatePasswordResetTokenPOST,
 		PasswordResetPOST:              &passwordResetPOST,
 		SignInPOST:                     &signInPOST,
 		SignUpPOST:                     &signUpPOST,
+		ReauthenticatePOST:             &reauthenticatePOST,
 	}
 }
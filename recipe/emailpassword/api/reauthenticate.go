@@ -0,0 +1,93 @@
+/* Copyright (c) 2023, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/supertokens/supertokens-golang/recipe/emailpassword/epmodels"
+	"github.com/supertokens/supertokens-golang/recipe/session"
+	"github.com/supertokens/supertokens-golang/recipe/session/claims"
+	"github.com/supertokens/supertokens-golang/recipe/session/sessmodels"
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+func ReauthenticateAPI(apiImplementation epmodels.APIInterface, tenantId string, options epmodels.APIOptions, userContext supertokens.UserContext) error {
+	if apiImplementation.ReauthenticatePOST == nil || (*apiImplementation.ReauthenticatePOST) == nil {
+		options.OtherHandler(options.Res, options.Req)
+		return nil
+	}
+
+	sessionContainer, err := getSessionForReauthentication(options.Req, options.Res, userContext)
+	if err != nil {
+		return err
+	}
+
+	body, err := supertokens.ReadFromRequest(options.Req)
+	if err != nil {
+		return err
+	}
+	var formFieldsRaw map[string]interface{}
+	err = json.Unmarshal(body, &formFieldsRaw)
+	if err != nil {
+		return err
+	}
+
+	// The email is taken from the user of the session, so only the password is sent
+	passwordFormFields := []epmodels.NormalisedFormField{}
+	for _, formField := range options.Config.SignInFeature.FormFields {
+		if formField.ID == "password" {
+			passwordFormFields = append(passwordFormFields, formField)
+		}
+	}
+
+	formFields, err := validateFormFieldsOrThrowError(passwordFormFields, formFieldsRaw["formFields"], tenantId)
+	if err != nil {
+		return err
+	}
+
+	result, err := (*apiImplementation.ReauthenticatePOST)(formFields, sessionContainer, tenantId, options, userContext)
+	if err != nil {
+		return err
+	}
+	if result.WrongCredentialsError != nil {
+		return supertokens.Send200Response(options.Res, map[string]interface{}{
+			"status": "WRONG_CREDENTIALS_ERROR",
+		})
+	} else if result.OK != nil {
+		return supertokens.Send200Response(options.Res, map[string]interface{}{
+			"status": "OK",
+		})
+	} else if result.GeneralError != nil {
+		return supertokens.Send200Response(options.Res, supertokens.ConvertGeneralErrorToJsonResponse(*result.GeneralError))
+	}
+	return supertokens.ErrorIfNoResponse(options.Res)
+}
+
+// getSessionForReauthentication gets the session without the validators of the
+// LastAuthenticatedAtClaim, since users call this API when their sign in is too old for those
+func getSessionForReauthentication(req *http.Request, res http.ResponseWriter, userContext supertokens.UserContext) (sessmodels.SessionContainer, error) {
+	return session.GetSession(
+		req, res,
+		&sessmodels.VerifySessionOptions{
+			OverrideGlobalClaimValidators: func(globalClaimValidators []claims.SessionClaimValidator, sessionContainer sessmodels.SessionContainer, userContext supertokens.UserContext) ([]claims.SessionClaimValidator, error) {
+				return session.RemoveLastAuthenticatedAtClaimValidators(globalClaimValidators), nil
+			},
+		},
+		userContext,
+	)
+}
@@ -40,6 +40,7 @@ type APIInterface struct {
 	PasswordResetPOST              *func(formFields []TypeFormField, token string, tenantId string, options APIOptions, userContext supertokens.UserContext) (ResetPasswordPOSTResponse, error)
 	SignInPOST                     *func(formFields []TypeFormField, tenantId string, options APIOptions, userContext supertokens.UserContext) (SignInPOSTResponse, error)
 	SignUpPOST                     *func(formFields []TypeFormField, tenantId string, options APIOptions, userContext supertokens.UserContext) (SignUpPOSTResponse, error)
+	ReauthenticatePOST             *func(formFields []TypeFormField, sessionContainer sessmodels.SessionContainer, tenantId string, options APIOptions, userContext supertokens.UserContext) (ReauthenticatePOSTResponse, error)
 }
 
 type ResetPasswordPOSTResponse struct {
@@ -68,6 +69,12 @@ type SignInPOSTResponse struct {
 	GeneralError          *supertokens.GeneralErrorResponse
 }
 
+type ReauthenticatePOSTResponse struct {
+	OK                    *struct{}
+	WrongCredentialsError *struct{}
+	GeneralError          *supertokens.GeneralErrorResponse
+}
+
 type EmailExistsGETResponse struct {
 	OK           *struct{ Exists bool }
 	GeneralError *supertokens.GeneralErrorResponse
@@ -0,0 +1,150 @@
+/* Copyright (c) 2023, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package emailpassword
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/supertokens/supertokens-golang/recipe/emailpassword/epmodels"
+	"github.com/supertokens/supertokens-golang/recipe/session"
+	"github.com/supertokens/supertokens-golang/recipe/session/claims"
+	"github.com/supertokens/supertokens-golang/recipe/session/sessionclaims"
+	"github.com/supertokens/supertokens-golang/recipe/session/sessmodels"
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+func TestReauthenticateAPIDoesNotRequireARecentSignIn(t *testing.T) {
+	resetAll()
+	defer resetAll()
+
+	coreServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request to the core: %s %s", r.Method, r.URL.Path)
+		rw.WriteHeader(500)
+	}))
+	defer coreServer.Close()
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	jwks, err := json.Marshal(map[string]interface{}{"keys": []map[string]interface{}{{
+		"kty": "RSA",
+		"kid": "s-key",
+		"alg": "RS256",
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(privateKey.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(privateKey.E)).Bytes()),
+	}}})
+	assert.NoError(t, err)
+
+	reauthenticated := false
+	False := false
+	err = supertokens.Init(supertokens.TypeInput{
+		Supertokens: &supertokens.ConnectionInfo{
+			ConnectionURI: coreServer.URL,
+		},
+		AppInfo: supertokens.AppInfo{
+			AppName:       "SuperTokens",
+			APIDomain:     "api.supertokens.io",
+			WebsiteDomain: "supertokens.io",
+		},
+		RecipeList: []supertokens.Recipe{
+			Init(&epmodels.TypeInput{
+				Override: &epmodels.OverrideStruct{
+					APIs: func(originalImplementation epmodels.APIInterface) epmodels.APIInterface {
+						*originalImplementation.ReauthenticatePOST = func(formFields []epmodels.TypeFormField, sessionContainer sessmodels.SessionContainer, tenantId string, options epmodels.APIOptions, userContext supertokens.UserContext) (epmodels.ReauthenticatePOSTResponse, error) {
+							reauthenticated = true
+							return epmodels.ReauthenticatePOSTResponse{OK: &struct{}{}}, nil
+						}
+						return originalImplementation
+					},
+				},
+			}),
+			session.Init(&sessmodels.TypeInput{
+				UseDynamicAccessTokenSigningKey: &False,
+				JWKSSource:                      &sessmodels.JWKSSource{JSON: jwks},
+				GetTokenTransferMethod: func(req *http.Request, forCreateNewSession bool, userContext supertokens.UserContext) sessmodels.TokenTransferMethod {
+					return sessmodels.HeaderTransferMethod
+				},
+				Override: &sessmodels.OverrideStruct{
+					Functions: func(originalImplementation sessmodels.RecipeInterface) sessmodels.RecipeInterface {
+						*originalImplementation.GetGlobalClaimValidators = func(userId string, claimValidatorsAddedByOtherRecipes []claims.SessionClaimValidator, tenantId string, userContext supertokens.UserContext) ([]claims.SessionClaimValidator, error) {
+							return append(claimValidatorsAddedByOtherRecipes, sessionclaims.LastAuthenticatedAtClaimValidators.AuthenticatedWithin(5*60, nil)), nil
+						}
+						return originalImplementation
+					},
+				},
+			}),
+		},
+	})
+	assert.NoError(t, err)
+	supertokens.SetQuerierApiVersionForTests("3.0")
+	defer supertokens.SetQuerierApiVersionForTests("")
+
+	// The user signed in an hour ago, which is too long ago for the global validators
+	now := time.Now()
+	signedInAt := now.Add(-time.Hour).UnixNano() / 1000000
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"sub":                     "user-1",
+		"tId":                     "public",
+		"sessionHandle":           "test-session-handle",
+		"refreshTokenHash1":       "test-refresh-token-hash",
+		"iat":                     now.Unix(),
+		"exp":                     now.Add(time.Hour).Unix(),
+		"st-last-auth":            map[string]interface{}{"v": signedInAt, "t": signedInAt},
+		"antiCsrfToken":           nil,
+		"parentRefreshTokenHash1": nil,
+	})
+	token.Header["kid"] = "s-key"
+	token.Header["version"] = "4"
+	accessToken, err := token.SignedString(privateKey)
+	assert.NoError(t, err)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/protected", session.VerifySession(nil, func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(200)
+	}))
+	testServer := httptest.NewServer(supertokens.Middleware(mux))
+	defer testServer.Close()
+
+	request := func(path string, body string) *http.Response {
+		req, err := http.NewRequest(http.MethodPost, testServer.URL+path, strings.NewReader(body))
+		assert.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+		res, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		return res
+	}
+
+	res := request("/protected", "")
+	assert.Equal(t, 403, res.StatusCode)
+
+	res = request("/auth/reauthenticate/password", `{"formFields":[{"id":"password","value":"validpass123"}]}`)
+	assert.Equal(t, 200, res.StatusCode)
+	var result map[string]interface{}
+	assert.NoError(t, json.NewDecoder(res.Body).Decode(&result))
+	assert.Equal(t, "OK", result["status"])
+	assert.True(t, reauthenticated)
+}
@@ -130,6 +130,10 @@ func (r *Recipe) getAPIsHandled() ([]supertokens.APIHandled, error) {
 	if err != nil {
 		return nil, err
 	}
+	reauthenticateAPI, err := supertokens.NewNormalisedURLPath(constants.ReauthenticateAPI)
+	if err != nil {
+		return nil, err
+	}
 	return []supertokens.APIHandled{{
 		Method:                 http.MethodPost,
 		PathWithoutAPIBasePath: signUpAPI,
@@ -160,6 +164,11 @@ func (r *Recipe) getAPIsHandled() ([]supertokens.APIHandled, error) {
 		PathWithoutAPIBasePath: signupEmailExistsAPI,
 		ID:                     constants.SignupEmailExistsAPI,
 		Disabled:               r.APIImpl.EmailExistsGET == nil,
+	}, {
+		Method:                 http.MethodPost,
+		PathWithoutAPIBasePath: reauthenticateAPI,
+		ID:                     constants.ReauthenticateAPI,
+		Disabled:               r.APIImpl.ReauthenticatePOST == nil,
 	}}, nil
 }
 
@@ -184,6 +193,8 @@ func (r *Recipe) handleAPIRequest(id string, tenantId string, req *http.Request,
 		return api.PasswordReset(r.APIImpl, tenantId, options, userContext)
 	} else if id == constants.SignupEmailExistsAPIOld || id == constants.SignupEmailExistsAPI {
 		return api.EmailExists(r.APIImpl, tenantId, options, userContext)
+	} else if id == constants.ReauthenticateAPI {
+		return api.ReauthenticateAPI(r.APIImpl, tenantId, options, userContext)
 	}
 	return defaultErrors.New("should never come here")
 }
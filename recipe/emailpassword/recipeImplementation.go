@@ -35,10 +35,13 @@ func MakeRecipeImplementation(querier supertokens.Querier, getEmailPasswordConfi
 			if err != nil {
 				return epmodels.SignUpResponse{}, err
 			}
+			supertokens.LogInfo("emailpassword: user signed up", supertokens.RecipeLogFields(RECIPE_ID, tenantId, userContext, supertokens.LogFieldUserID, user.ID)...)
+			supertokens.AddToCounter(userContext, supertokens.MetricSignUps, 1, supertokens.RecipeMetricAttributes(RECIPE_ID, tenantId))
 			return epmodels.SignUpResponse{
 				OK: &struct{ User epmodels.User }{User: *user},
 			}, nil
 		}
+		supertokens.LogInfo("emailpassword: sign up failed because the email already exists", supertokens.RecipeLogFields(RECIPE_ID, tenantId, userContext)...)
 		return epmodels.SignUpResponse{
 			EmailAlreadyExistsError: &struct{}{},
 		}, nil
@@ -58,10 +61,13 @@ func MakeRecipeImplementation(querier supertokens.Querier, getEmailPasswordConfi
 			if err != nil {
 				return epmodels.SignInResponse{}, err
 			}
+			supertokens.LogInfo("emailpassword: user signed in", supertokens.RecipeLogFields(RECIPE_ID, tenantId, userContext, supertokens.LogFieldUserID, user.ID)...)
+			supertokens.AddToCounter(userContext, supertokens.MetricSignIns, 1, supertokens.RecipeMetricAttributes(RECIPE_ID, tenantId))
 			return epmodels.SignInResponse{
 				OK: &struct{ User epmodels.User }{User: *user},
 			}, nil
 		}
+		supertokens.LogInfo("emailpassword: sign in failed because of wrong credentials", supertokens.RecipeLogFields(RECIPE_ID, tenantId, userContext)...)
 		return epmodels.SignInResponse{
 			WrongCredentialsError: &struct{}{},
 		}, nil
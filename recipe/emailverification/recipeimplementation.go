@@ -51,6 +51,7 @@ func makeRecipeImplementation(querier supertokens.Querier) evmodels.RecipeInterf
 		}
 		status, ok := response["status"]
 		if ok && status == "OK" {
+			supertokens.LogInfo("emailverification: email verified", supertokens.RecipeLogFields(RECIPE_ID, tenantId, userContext, supertokens.LogFieldUserID, response["userId"])...)
 			return evmodels.VerifyEmailUsingTokenResponse{
 				OK: &struct{ User evmodels.User }{User: evmodels.User{
 					ID:    response["userId"].(string),
@@ -0,0 +1,211 @@
+/* Copyright (c) 2023, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package api
+
+import (
+	"github.com/supertokens/supertokens-golang/recipe/multifactorauth/mfaclaims"
+	"github.com/supertokens/supertokens-golang/recipe/multifactorauth/mfamodels"
+	"github.com/supertokens/supertokens-golang/recipe/session/claims"
+	"github.com/supertokens/supertokens-golang/recipe/session/sessmodels"
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+func MakeAPIImplementation() mfamodels.APIInterface {
+	// assertSecondFactorCompletedIfSetUp makes sure that once a user has a verified TOTP device, the
+	// devices and recovery codes can only be changed from a session in which TOTP or a recovery code
+	// was completed. A recovery code is enough so that users who lost their device can replace it.
+	assertSecondFactorCompletedIfSetUp := func(sessionContainer sessmodels.SessionContainer, options mfamodels.APIOptions, userContext supertokens.UserContext) error {
+		devices, err := (*options.RecipeImplementation.ListTOTPDevices)(sessionContainer.GetUserIDWithContext(userContext), userContext)
+		if err != nil {
+			return err
+		}
+		for _, device := range devices {
+			if device.Verified {
+				return sessionContainer.AssertClaimsWithContext([]claims.SessionClaimValidator{
+					claims.AnyOf(
+						mfaclaims.MultiFactorAuthClaimValidators.HasCompletedFactors([]string{mfamodels.FactorIdTOTP}, nil),
+						mfaclaims.MultiFactorAuthClaimValidators.HasCompletedFactors([]string{mfamodels.FactorIdRecoveryCode}, nil),
+					),
+				}, userContext)
+			}
+		}
+		return nil
+	}
+
+	mfaInfoGET := func(sessionContainer sessmodels.SessionContainer, options mfamodels.APIOptions, userContext supertokens.UserContext) (mfamodels.MFAInfoGETResponse, error) {
+		value, _ := mfaclaims.MultiFactorAuthClaim.GetValueFromPayload(sessionContainer.GetAccessTokenPayloadWithContext(userContext), userContext).(map[string]interface{})
+		completedFactors := mfaclaims.GetCompletedFactors(value)
+
+		requirements, err := (*options.RecipeImplementation.GetRequirements)(sessionContainer.GetUserIDWithContext(userContext), sessionContainer.GetTenantIdWithContext(userContext), userContext)
+		if err != nil {
+			return mfamodels.MFAInfoGETResponse{}, err
+		}
+
+		return mfamodels.MFAInfoGETResponse{
+			OK: &struct {
+				CompletedFactors []string
+				Requirements     mfamodels.MFARequirements
+				IsSatisfied      bool
+			}{
+				CompletedFactors: completedFactors,
+				Requirements:     requirements,
+				IsSatisfied:      requirements.IsSatisfiedBy(completedFactors),
+			},
+		}, nil
+	}
+
+	createTOTPDevicePOST := func(deviceName *string, sessionContainer sessmodels.SessionContainer, options mfamodels.APIOptions, userContext supertokens.UserContext) (mfamodels.CreateTOTPDevicePOSTResponse, error) {
+		err := assertSecondFactorCompletedIfSetUp(sessionContainer, options, userContext)
+		if err != nil {
+			return mfamodels.CreateTOTPDevicePOSTResponse{}, err
+		}
+
+		response, err := (*options.RecipeImplementation.CreateTOTPDevice)(sessionContainer.GetUserIDWithContext(userContext), deviceName, userContext)
+		if err != nil {
+			return mfamodels.CreateTOTPDevicePOSTResponse{}, err
+		}
+		return mfamodels.CreateTOTPDevicePOSTResponse{
+			OK:                       response.OK,
+			DeviceAlreadyExistsError: response.DeviceAlreadyExistsError,
+		}, nil
+	}
+
+	listTOTPDevicesGET := func(sessionContainer sessmodels.SessionContainer, options mfamodels.APIOptions, userContext supertokens.UserContext) (mfamodels.ListTOTPDevicesGETResponse, error) {
+		devices, err := (*options.RecipeImplementation.ListTOTPDevices)(sessionContainer.GetUserIDWithContext(userContext), userContext)
+		if err != nil {
+			return mfamodels.ListTOTPDevicesGETResponse{}, err
+		}
+		return mfamodels.ListTOTPDevicesGETResponse{
+			OK: &struct{ Devices []mfamodels.TOTPDeviceInfo }{
+				Devices: devices,
+			},
+		}, nil
+	}
+
+	removeTOTPDevicePOST := func(deviceName string, sessionContainer sessmodels.SessionContainer, options mfamodels.APIOptions, userContext supertokens.UserContext) (mfamodels.RemoveTOTPDevicePOSTResponse, error) {
+		err := assertSecondFactorCompletedIfSetUp(sessionContainer, options, userContext)
+		if err != nil {
+			return mfamodels.RemoveTOTPDevicePOSTResponse{}, err
+		}
+
+		didDeviceExist, err := (*options.RecipeImplementation.RemoveTOTPDevice)(sessionContainer.GetUserIDWithContext(userContext), deviceName, userContext)
+		if err != nil {
+			return mfamodels.RemoveTOTPDevicePOSTResponse{}, err
+		}
+		return mfamodels.RemoveTOTPDevicePOSTResponse{
+			OK: &struct{ DidDeviceExist bool }{
+				DidDeviceExist: didDeviceExist,
+			},
+		}, nil
+	}
+
+	verifyTOTPDevicePOST := func(deviceName string, totp string, sessionContainer sessmodels.SessionContainer, options mfamodels.APIOptions, userContext supertokens.UserContext) (mfamodels.VerifyTOTPDevicePOSTResponse, error) {
+		response, err := (*options.RecipeImplementation.VerifyTOTPDevice)(sessionContainer.GetUserIDWithContext(userContext), deviceName, totp, userContext)
+		if err != nil {
+			return mfamodels.VerifyTOTPDevicePOSTResponse{}, err
+		}
+		if response.OK != nil {
+			err = (*options.RecipeImplementation.MarkFactorAsCompleteInSession)(sessionContainer, mfamodels.FactorIdTOTP, userContext)
+			if err != nil {
+				return mfamodels.VerifyTOTPDevicePOSTResponse{}, err
+			}
+		}
+		return mfamodels.VerifyTOTPDevicePOSTResponse{
+			OK:                 response.OK,
+			UnknownDeviceError: response.UnknownDeviceError,
+			InvalidTOTPError:   response.InvalidTOTPError,
+			LimitReachedError:  response.LimitReachedError,
+		}, nil
+	}
+
+	verifyTOTPPOST := func(totp string, sessionContainer sessmodels.SessionContainer, options mfamodels.APIOptions, userContext supertokens.UserContext) (mfamodels.VerifyTOTPPOSTResponse, error) {
+		response, err := (*options.RecipeImplementation.VerifyTOTP)(sessionContainer.GetUserIDWithContext(userContext), totp, userContext)
+		if err != nil {
+			return mfamodels.VerifyTOTPPOSTResponse{}, err
+		}
+		if response.OK != nil {
+			err = (*options.RecipeImplementation.MarkFactorAsCompleteInSession)(sessionContainer, mfamodels.FactorIdTOTP, userContext)
+			if err != nil {
+				return mfamodels.VerifyTOTPPOSTResponse{}, err
+			}
+		}
+		return mfamodels.VerifyTOTPPOSTResponse{
+			OK:                 response.OK,
+			UnknownUserIdError: response.UnknownUserIdError,
+			InvalidTOTPError:   response.InvalidTOTPError,
+			LimitReachedError:  response.LimitReachedError,
+		}, nil
+	}
+
+	generateRecoveryCodesPOST := func(sessionContainer sessmodels.SessionContainer, options mfamodels.APIOptions, userContext supertokens.UserContext) (mfamodels.GenerateRecoveryCodesPOSTResponse, error) {
+		err := assertSecondFactorCompletedIfSetUp(sessionContainer, options, userContext)
+		if err != nil {
+			return mfamodels.GenerateRecoveryCodesPOSTResponse{}, err
+		}
+
+		codes, err := (*options.RecipeImplementation.GenerateRecoveryCodes)(sessionContainer.GetUserIDWithContext(userContext), userContext)
+		if err != nil {
+			return mfamodels.GenerateRecoveryCodesPOSTResponse{}, err
+		}
+		return mfamodels.GenerateRecoveryCodesPOSTResponse{
+			OK: &struct{ Codes []string }{
+				Codes: codes,
+			},
+		}, nil
+	}
+
+	recoveryCodesGET := func(sessionContainer sessmodels.SessionContainer, options mfamodels.APIOptions, userContext supertokens.UserContext) (mfamodels.RecoveryCodesGETResponse, error) {
+		remainingCodes, err := (*options.RecipeImplementation.GetRecoveryCodesCount)(sessionContainer.GetUserIDWithContext(userContext), userContext)
+		if err != nil {
+			return mfamodels.RecoveryCodesGETResponse{}, err
+		}
+		return mfamodels.RecoveryCodesGETResponse{
+			OK: &struct{ RemainingCodes int }{
+				RemainingCodes: remainingCodes,
+			},
+		}, nil
+	}
+
+	verifyRecoveryCodePOST := func(code string, sessionContainer sessmodels.SessionContainer, options mfamodels.APIOptions, userContext supertokens.UserContext) (mfamodels.VerifyRecoveryCodePOSTResponse, error) {
+		response, err := (*options.RecipeImplementation.VerifyRecoveryCode)(sessionContainer.GetUserIDWithContext(userContext), code, userContext)
+		if err != nil {
+			return mfamodels.VerifyRecoveryCodePOSTResponse{}, err
+		}
+		if response.OK != nil {
+			err = (*options.RecipeImplementation.MarkFactorAsCompleteInSession)(sessionContainer, mfamodels.FactorIdRecoveryCode, userContext)
+			if err != nil {
+				return mfamodels.VerifyRecoveryCodePOSTResponse{}, err
+			}
+		}
+		return mfamodels.VerifyRecoveryCodePOSTResponse{
+			OK:                       response.OK,
+			InvalidRecoveryCodeError: response.InvalidRecoveryCodeError,
+		}, nil
+	}
+
+	return mfamodels.APIInterface{
+		MFAInfoGET:           &mfaInfoGET,
+		CreateTOTPDevicePOST: &createTOTPDevicePOST,
+		ListTOTPDevicesGET:   &listTOTPDevicesGET,
+		RemoveTOTPDevicePOST: &removeTOTPDevicePOST,
+		VerifyTOTPDevicePOST: &verifyTOTPDevicePOST,
+		VerifyTOTPPOST:       &verifyTOTPPOST,
+
+		GenerateRecoveryCodesPOST: &generateRecoveryCodesPOST,
+		RecoveryCodesGET:          &recoveryCodesGET,
+		VerifyRecoveryCodePOST:    &verifyRecoveryCodePOST,
+	}
+}
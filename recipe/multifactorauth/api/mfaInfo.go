@@ -0,0 +1,50 @@
+/* Copyright (c) 2023, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package api
+
+import (
+	"github.com/supertokens/supertokens-golang/recipe/multifactorauth/mfamodels"
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+func MFAInfo(apiImplementation mfamodels.APIInterface, options mfamodels.APIOptions, userContext supertokens.UserContext) error {
+	if apiImplementation.MFAInfoGET == nil || (*apiImplementation.MFAInfoGET) == nil {
+		options.OtherHandler(options.Res, options.Req)
+		return nil
+	}
+
+	sessionContainer, err := getSessionWithoutClaimValidators(options, userContext)
+	if err != nil {
+		return err
+	}
+
+	response, err := (*apiImplementation.MFAInfoGET)(sessionContainer, options, userContext)
+	if err != nil {
+		return err
+	}
+
+	if response.OK != nil {
+		return supertokens.Send200Response(options.Res, map[string]interface{}{
+			"status":           "OK",
+			"completedFactors": response.OK.CompletedFactors,
+			"requirements":     response.OK.Requirements,
+			"isSatisfied":      response.OK.IsSatisfied,
+		})
+	} else if response.GeneralError != nil {
+		return supertokens.Send200Response(options.Res, supertokens.ConvertGeneralErrorToJsonResponse(*response.GeneralError))
+	}
+	return supertokens.ErrorIfNoResponse(options.Res)
+}
@@ -0,0 +1,115 @@
+/* Copyright (c) 2023, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package api
+
+import (
+	"github.com/supertokens/supertokens-golang/recipe/multifactorauth/mfamodels"
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+func RecoveryCodes(apiImplementation mfamodels.APIInterface, options mfamodels.APIOptions, userContext supertokens.UserContext) error {
+	if apiImplementation.RecoveryCodesGET == nil || (*apiImplementation.RecoveryCodesGET) == nil {
+		options.OtherHandler(options.Res, options.Req)
+		return nil
+	}
+
+	sessionContainer, err := getSessionWithoutClaimValidators(options, userContext)
+	if err != nil {
+		return err
+	}
+
+	response, err := (*apiImplementation.RecoveryCodesGET)(sessionContainer, options, userContext)
+	if err != nil {
+		return err
+	}
+
+	if response.OK != nil {
+		return supertokens.Send200Response(options.Res, map[string]interface{}{
+			"status":         "OK",
+			"remainingCodes": response.OK.RemainingCodes,
+		})
+	} else if response.GeneralError != nil {
+		return supertokens.Send200Response(options.Res, supertokens.ConvertGeneralErrorToJsonResponse(*response.GeneralError))
+	}
+	return supertokens.ErrorIfNoResponse(options.Res)
+}
+
+func GenerateRecoveryCodes(apiImplementation mfamodels.APIInterface, options mfamodels.APIOptions, userContext supertokens.UserContext) error {
+	if apiImplementation.GenerateRecoveryCodesPOST == nil || (*apiImplementation.GenerateRecoveryCodesPOST) == nil {
+		options.OtherHandler(options.Res, options.Req)
+		return nil
+	}
+
+	sessionContainer, err := getSessionWithoutClaimValidators(options, userContext)
+	if err != nil {
+		return err
+	}
+
+	response, err := (*apiImplementation.GenerateRecoveryCodesPOST)(sessionContainer, options, userContext)
+	if err != nil {
+		return err
+	}
+
+	if response.OK != nil {
+		return supertokens.Send200Response(options.Res, map[string]interface{}{
+			"status": "OK",
+			"codes":  response.OK.Codes,
+		})
+	} else if response.GeneralError != nil {
+		return supertokens.Send200Response(options.Res, supertokens.ConvertGeneralErrorToJsonResponse(*response.GeneralError))
+	}
+	return supertokens.ErrorIfNoResponse(options.Res)
+}
+
+func VerifyRecoveryCode(apiImplementation mfamodels.APIInterface, options mfamodels.APIOptions, userContext supertokens.UserContext) error {
+	if apiImplementation.VerifyRecoveryCodePOST == nil || (*apiImplementation.VerifyRecoveryCodePOST) == nil {
+		options.OtherHandler(options.Res, options.Req)
+		return nil
+	}
+
+	sessionContainer, err := getSessionWithoutClaimValidators(options, userContext)
+	if err != nil {
+		return err
+	}
+
+	body, err := readBody(options)
+	if err != nil {
+		return err
+	}
+	code, err := getStringFromBody(body, "code")
+	if err != nil {
+		return err
+	}
+
+	response, err := (*apiImplementation.VerifyRecoveryCodePOST)(code, sessionContainer, options, userContext)
+	if err != nil {
+		return err
+	}
+
+	if response.OK != nil {
+		return supertokens.Send200Response(options.Res, map[string]interface{}{
+			"status":         "OK",
+			"remainingCodes": response.OK.RemainingCodes,
+		})
+	} else if response.InvalidRecoveryCodeError != nil {
+		return supertokens.Send200Response(options.Res, map[string]interface{}{
+			"status": "INVALID_RECOVERY_CODE_ERROR",
+		})
+	} else if response.GeneralError != nil {
+		return supertokens.Send200Response(options.Res, supertokens.ConvertGeneralErrorToJsonResponse(*response.GeneralError))
+	}
+	return supertokens.ErrorIfNoResponse(options.Res)
+}
@@ -0,0 +1,231 @@
+/* Copyright (c) 2023, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package api
+
+import (
+	"github.com/supertokens/supertokens-golang/recipe/multifactorauth/mfamodels"
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+func CreateTOTPDevice(apiImplementation mfamodels.APIInterface, options mfamodels.APIOptions, userContext supertokens.UserContext) error {
+	if apiImplementation.CreateTOTPDevicePOST == nil || (*apiImplementation.CreateTOTPDevicePOST) == nil {
+		options.OtherHandler(options.Res, options.Req)
+		return nil
+	}
+
+	sessionContainer, err := getSessionWithoutClaimValidators(options, userContext)
+	if err != nil {
+		return err
+	}
+
+	body, err := readBody(options)
+	if err != nil {
+		return err
+	}
+	var deviceName *string
+	if body["deviceName"] != nil {
+		name, err := getStringFromBody(body, "deviceName")
+		if err != nil {
+			return err
+		}
+		deviceName = &name
+	}
+
+	response, err := (*apiImplementation.CreateTOTPDevicePOST)(deviceName, sessionContainer, options, userContext)
+	if err != nil {
+		return err
+	}
+
+	if response.OK != nil {
+		return supertokens.Send200Response(options.Res, map[string]interface{}{
+			"status":       "OK",
+			"deviceName":   response.OK.DeviceName,
+			"secret":       response.OK.Secret,
+			"qrCodeString": response.OK.QRCodeString,
+		})
+	} else if response.DeviceAlreadyExistsError != nil {
+		return supertokens.Send200Response(options.Res, map[string]interface{}{
+			"status": "DEVICE_ALREADY_EXISTS_ERROR",
+		})
+	} else if response.GeneralError != nil {
+		return supertokens.Send200Response(options.Res, supertokens.ConvertGeneralErrorToJsonResponse(*response.GeneralError))
+	}
+	return supertokens.ErrorIfNoResponse(options.Res)
+}
+
+func ListTOTPDevices(apiImplementation mfamodels.APIInterface, options mfamodels.APIOptions, userContext supertokens.UserContext) error {
+	if apiImplementation.ListTOTPDevicesGET == nil || (*apiImplementation.ListTOTPDevicesGET) == nil {
+		options.OtherHandler(options.Res, options.Req)
+		return nil
+	}
+
+	sessionContainer, err := getSessionWithoutClaimValidators(options, userContext)
+	if err != nil {
+		return err
+	}
+
+	response, err := (*apiImplementation.ListTOTPDevicesGET)(sessionContainer, options, userContext)
+	if err != nil {
+		return err
+	}
+
+	if response.OK != nil {
+		return supertokens.Send200Response(options.Res, map[string]interface{}{
+			"status":  "OK",
+			"devices": response.OK.Devices,
+		})
+	} else if response.GeneralError != nil {
+		return supertokens.Send200Response(options.Res, supertokens.ConvertGeneralErrorToJsonResponse(*response.GeneralError))
+	}
+	return supertokens.ErrorIfNoResponse(options.Res)
+}
+
+func RemoveTOTPDevice(apiImplementation mfamodels.APIInterface, options mfamodels.APIOptions, userContext supertokens.UserContext) error {
+	if apiImplementation.RemoveTOTPDevicePOST == nil || (*apiImplementation.RemoveTOTPDevicePOST) == nil {
+		options.OtherHandler(options.Res, options.Req)
+		return nil
+	}
+
+	sessionContainer, err := getSessionWithoutClaimValidators(options, userContext)
+	if err != nil {
+		return err
+	}
+
+	body, err := readBody(options)
+	if err != nil {
+		return err
+	}
+	deviceName, err := getStringFromBody(body, "deviceName")
+	if err != nil {
+		return err
+	}
+
+	response, err := (*apiImplementation.RemoveTOTPDevicePOST)(deviceName, sessionContainer, options, userContext)
+	if err != nil {
+		return err
+	}
+
+	if response.OK != nil {
+		return supertokens.Send200Response(options.Res, map[string]interface{}{
+			"status":         "OK",
+			"didDeviceExist": response.OK.DidDeviceExist,
+		})
+	} else if response.GeneralError != nil {
+		return supertokens.Send200Response(options.Res, supertokens.ConvertGeneralErrorToJsonResponse(*response.GeneralError))
+	}
+	return supertokens.ErrorIfNoResponse(options.Res)
+}
+
+func VerifyTOTPDevice(apiImplementation mfamodels.APIInterface, options mfamodels.APIOptions, userContext supertokens.UserContext) error {
+	if apiImplementation.VerifyTOTPDevicePOST == nil || (*apiImplementation.VerifyTOTPDevicePOST) == nil {
+		options.OtherHandler(options.Res, options.Req)
+		return nil
+	}
+
+	sessionContainer, err := getSessionWithoutClaimValidators(options, userContext)
+	if err != nil {
+		return err
+	}
+
+	body, err := readBody(options)
+	if err != nil {
+		return err
+	}
+	deviceName, err := getStringFromBody(body, "deviceName")
+	if err != nil {
+		return err
+	}
+	totp, err := getStringFromBody(body, "totp")
+	if err != nil {
+		return err
+	}
+
+	response, err := (*apiImplementation.VerifyTOTPDevicePOST)(deviceName, totp, sessionContainer, options, userContext)
+	if err != nil {
+		return err
+	}
+
+	if response.OK != nil {
+		return supertokens.Send200Response(options.Res, map[string]interface{}{
+			"status":             "OK",
+			"wasAlreadyVerified": response.OK.WasAlreadyVerified,
+		})
+	} else if response.UnknownDeviceError != nil {
+		return supertokens.Send200Response(options.Res, map[string]interface{}{
+			"status": "UNKNOWN_DEVICE_ERROR",
+		})
+	} else if response.InvalidTOTPError != nil {
+		return supertokens.Send200Response(options.Res, map[string]interface{}{
+			"status": "INVALID_TOTP_ERROR",
+		})
+	} else if response.LimitReachedError != nil {
+		return supertokens.Send200Response(options.Res, map[string]interface{}{
+			"status":       "LIMIT_REACHED_ERROR",
+			"retryAfterMs": response.LimitReachedError.RetryAfterMs,
+		})
+	} else if response.GeneralError != nil {
+		return supertokens.Send200Response(options.Res, supertokens.ConvertGeneralErrorToJsonResponse(*response.GeneralError))
+	}
+	return supertokens.ErrorIfNoResponse(options.Res)
+}
+
+func VerifyTOTP(apiImplementation mfamodels.APIInterface, options mfamodels.APIOptions, userContext supertokens.UserContext) error {
+	if apiImplementation.VerifyTOTPPOST == nil || (*apiImplementation.VerifyTOTPPOST) == nil {
+		options.OtherHandler(options.Res, options.Req)
+		return nil
+	}
+
+	sessionContainer, err := getSessionWithoutClaimValidators(options, userContext)
+	if err != nil {
+		return err
+	}
+
+	body, err := readBody(options)
+	if err != nil {
+		return err
+	}
+	totp, err := getStringFromBody(body, "totp")
+	if err != nil {
+		return err
+	}
+
+	response, err := (*apiImplementation.VerifyTOTPPOST)(totp, sessionContainer, options, userContext)
+	if err != nil {
+		return err
+	}
+
+	if response.OK != nil {
+		return supertokens.Send200Response(options.Res, map[string]interface{}{
+			"status": "OK",
+		})
+	} else if response.UnknownUserIdError != nil {
+		return supertokens.Send200Response(options.Res, map[string]interface{}{
+			"status": "UNKNOWN_USER_ID_ERROR",
+		})
+	} else if response.InvalidTOTPError != nil {
+		return supertokens.Send200Response(options.Res, map[string]interface{}{
+			"status": "INVALID_TOTP_ERROR",
+		})
+	} else if response.LimitReachedError != nil {
+		return supertokens.Send200Response(options.Res, map[string]interface{}{
+			"status":       "LIMIT_REACHED_ERROR",
+			"retryAfterMs": response.LimitReachedError.RetryAfterMs,
+		})
+	} else if response.GeneralError != nil {
+		return supertokens.Send200Response(options.Res, supertokens.ConvertGeneralErrorToJsonResponse(*response.GeneralError))
+	}
+	return supertokens.ErrorIfNoResponse(options.Res)
+}
@@ -0,0 +1,63 @@
+/* Copyright (c) 2023, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package api
+
+import (
+	"encoding/json"
+
+	"github.com/supertokens/supertokens-golang/recipe/multifactorauth/mfamodels"
+	"github.com/supertokens/supertokens-golang/recipe/session"
+	"github.com/supertokens/supertokens-golang/recipe/session/claims"
+	"github.com/supertokens/supertokens-golang/recipe/session/sessmodels"
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+// getSessionWithoutClaimValidators gets the session without checking the global claim validators,
+// since the factors are completed using these APIs.
+func getSessionWithoutClaimValidators(options mfamodels.APIOptions, userContext supertokens.UserContext) (sessmodels.SessionContainer, error) {
+	return session.GetSession(
+		options.Req, options.Res,
+		&sessmodels.VerifySessionOptions{
+			OverrideGlobalClaimValidators: func(globalClaimValidators []claims.SessionClaimValidator, sessionContainer sessmodels.SessionContainer, userContext supertokens.UserContext) ([]claims.SessionClaimValidator, error) {
+				return []claims.SessionClaimValidator{}, nil
+			},
+		},
+		userContext,
+	)
+}
+
+func readBody(options mfamodels.APIOptions) (map[string]interface{}, error) {
+	body, err := supertokens.ReadFromRequest(options.Req)
+	if err != nil {
+		return nil, err
+	}
+	var readBody map[string]interface{}
+	if len(body) > 0 {
+		err = json.Unmarshal(body, &readBody)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return readBody, nil
+}
+
+func getStringFromBody(body map[string]interface{}, key string) (string, error) {
+	value, ok := body[key].(string)
+	if !ok || value == "" {
+		return "", supertokens.BadInputError{Msg: "Please provide the " + key}
+	}
+	return value, nil
+}
@@ -0,0 +1,145 @@
+/* Copyright (c) 2023, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package multifactorauth
+
+import (
+	"github.com/supertokens/supertokens-golang/recipe/multifactorauth/mfamodels"
+	"github.com/supertokens/supertokens-golang/recipe/session/sessmodels"
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+func Init(config *mfamodels.TypeInput) supertokens.Recipe {
+	return recipeInit(config)
+}
+
+// GetRequirements returns the factors that must be completed in the sessions of the user.
+func GetRequirements(userId string, tenantId string, userContext ...supertokens.UserContext) (mfamodels.MFARequirements, error) {
+	instance, err := getRecipeInstanceOrThrowError()
+	if err != nil {
+		return mfamodels.MFARequirements{}, err
+	}
+	if len(userContext) == 0 {
+		userContext = append(userContext, &map[string]interface{}{})
+	}
+	return (*instance.RecipeImpl.GetRequirements)(userId, tenantId, userContext[0])
+}
+
+// MarkFactorAsCompleteInSession adds the factor to the MultiFactorAuthClaim of the session. It can be
+// used for factors checked outside of this recipe, like an OTP sent with the passwordless recipe.
+func MarkFactorAsCompleteInSession(sessionContainer sessmodels.SessionContainer, factorId string, userContext ...supertokens.UserContext) error {
+	instance, err := getRecipeInstanceOrThrowError()
+	if err != nil {
+		return err
+	}
+	if len(userContext) == 0 {
+		userContext = append(userContext, &map[string]interface{}{})
+	}
+	return (*instance.RecipeImpl.MarkFactorAsCompleteInSession)(sessionContainer, factorId, userContext[0])
+}
+
+func CreateTOTPDevice(userId string, deviceName *string, userContext ...supertokens.UserContext) (mfamodels.CreateTOTPDeviceResponse, error) {
+	instance, err := getRecipeInstanceOrThrowError()
+	if err != nil {
+		return mfamodels.CreateTOTPDeviceResponse{}, err
+	}
+	if len(userContext) == 0 {
+		userContext = append(userContext, &map[string]interface{}{})
+	}
+	return (*instance.RecipeImpl.CreateTOTPDevice)(userId, deviceName, userContext[0])
+}
+
+func VerifyTOTPDevice(userId string, deviceName string, totp string, userContext ...supertokens.UserContext) (mfamodels.VerifyTOTPDeviceResponse, error) {
+	instance, err := getRecipeInstanceOrThrowError()
+	if err != nil {
+		return mfamodels.VerifyTOTPDeviceResponse{}, err
+	}
+	if len(userContext) == 0 {
+		userContext = append(userContext, &map[string]interface{}{})
+	}
+	return (*instance.RecipeImpl.VerifyTOTPDevice)(userId, deviceName, totp, userContext[0])
+}
+
+func VerifyTOTP(userId string, totp string, userContext ...supertokens.UserContext) (mfamodels.VerifyTOTPResponse, error) {
+	instance, err := getRecipeInstanceOrThrowError()
+	if err != nil {
+		return mfamodels.VerifyTOTPResponse{}, err
+	}
+	if len(userContext) == 0 {
+		userContext = append(userContext, &map[string]interface{}{})
+	}
+	return (*instance.RecipeImpl.VerifyTOTP)(userId, totp, userContext[0])
+}
+
+func ListTOTPDevices(userId string, userContext ...supertokens.UserContext) ([]mfamodels.TOTPDeviceInfo, error) {
+	instance, err := getRecipeInstanceOrThrowError()
+	if err != nil {
+		return nil, err
+	}
+	if len(userContext) == 0 {
+		userContext = append(userContext, &map[string]interface{}{})
+	}
+	return (*instance.RecipeImpl.ListTOTPDevices)(userId, userContext[0])
+}
+
+// RemoveTOTPDevice returns false if the user does not have a device with the name.
+func RemoveTOTPDevice(userId string, deviceName string, userContext ...supertokens.UserContext) (bool, error) {
+	instance, err := getRecipeInstanceOrThrowError()
+	if err != nil {
+		return false, err
+	}
+	if len(userContext) == 0 {
+		userContext = append(userContext, &map[string]interface{}{})
+	}
+	return (*instance.RecipeImpl.RemoveTOTPDevice)(userId, deviceName, userContext[0])
+}
+
+// GenerateRecoveryCodes replaces the recovery codes of the user with new ones. Only the hashes of the
+// codes are saved, so they must be shown to the user now.
+func GenerateRecoveryCodes(userId string, userContext ...supertokens.UserContext) ([]string, error) {
+	instance, err := getRecipeInstanceOrThrowError()
+	if err != nil {
+		return nil, err
+	}
+	if len(userContext) == 0 {
+		userContext = append(userContext, &map[string]interface{}{})
+	}
+	return (*instance.RecipeImpl.GenerateRecoveryCodes)(userId, userContext[0])
+}
+
+// VerifyRecoveryCode uses up the recovery code if it is valid. It does not mark the factor as
+// completed in any session, use MarkFactorAsCompleteInSession for that.
+func VerifyRecoveryCode(userId string, code string, userContext ...supertokens.UserContext) (mfamodels.VerifyRecoveryCodeResponse, error) {
+	instance, err := getRecipeInstanceOrThrowError()
+	if err != nil {
+		return mfamodels.VerifyRecoveryCodeResponse{}, err
+	}
+	if len(userContext) == 0 {
+		userContext = append(userContext, &map[string]interface{}{})
+	}
+	return (*instance.RecipeImpl.VerifyRecoveryCode)(userId, code, userContext[0])
+}
+
+// GetRecoveryCodesCount returns how many recovery codes the user has left.
+func GetRecoveryCodesCount(userId string, userContext ...supertokens.UserContext) (int, error) {
+	instance, err := getRecipeInstanceOrThrowError()
+	if err != nil {
+		return 0, err
+	}
+	if len(userContext) == 0 {
+		userContext = append(userContext, &map[string]interface{}{})
+	}
+	return (*instance.RecipeImpl.GetRecoveryCodesCount)(userId, userContext[0])
+}
@@ -0,0 +1,36 @@
+package mfaclaims
+
+import (
+	"sort"
+
+	"github.com/supertokens/supertokens-golang/recipe/session/claims"
+)
+
+type TypeMultiFactorAuthClaimValidators struct {
+	// HasCompletedRequirements checks the requirements of the user, from the GetRequirements config,
+	// as they were when the session was created or a factor was last completed in it.
+	HasCompletedRequirements func(id *string) claims.SessionClaimValidator
+	// HasCompletedFactors checks that all the factors were completed in the session.
+	HasCompletedFactors func(factorIds []string, id *string) claims.SessionClaimValidator
+	// HasCompletedNumberOfFactors checks that at least numberOfFactors different factors were
+	// completed in the session, besides signing in.
+	HasCompletedNumberOfFactors func(numberOfFactors int, id *string) claims.SessionClaimValidator
+}
+
+// MultiFactorAuthClaim has the factors completed in the session, with the time they were completed,
+// and whether they satisfy the requirements of the user.
+var MultiFactorAuthClaim *claims.TypeSessionClaim
+
+var MultiFactorAuthClaimValidators TypeMultiFactorAuthClaimValidators
+
+// GetCompletedFactors returns the ids of the factors completed in the session, from the value of
+// the MultiFactorAuthClaim.
+func GetCompletedFactors(value map[string]interface{}) []string {
+	factorIds := []string{}
+	completedFactors, _ := value["c"].(map[string]interface{})
+	for factorId := range completedFactors {
+		factorIds = append(factorIds, factorId)
+	}
+	sort.Strings(factorIds)
+	return factorIds
+}
@@ -0,0 +1,123 @@
+/* Copyright (c) 2023, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package mfamodels
+
+import (
+	"net/http"
+
+	"github.com/supertokens/supertokens-golang/recipe/session/sessmodels"
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+type APIOptions struct {
+	RecipeImplementation RecipeInterface
+	AppInfo              supertokens.NormalisedAppinfo
+	Config               TypeNormalisedInput
+	RecipeID             string
+	Req                  *http.Request
+	Res                  http.ResponseWriter
+	OtherHandler         http.HandlerFunc
+}
+
+type APIInterface struct {
+	MFAInfoGET           *func(sessionContainer sessmodels.SessionContainer, options APIOptions, userContext supertokens.UserContext) (MFAInfoGETResponse, error)
+	CreateTOTPDevicePOST *func(deviceName *string, sessionContainer sessmodels.SessionContainer, options APIOptions, userContext supertokens.UserContext) (CreateTOTPDevicePOSTResponse, error)
+	ListTOTPDevicesGET   *func(sessionContainer sessmodels.SessionContainer, options APIOptions, userContext supertokens.UserContext) (ListTOTPDevicesGETResponse, error)
+	RemoveTOTPDevicePOST *func(deviceName string, sessionContainer sessmodels.SessionContainer, options APIOptions, userContext supertokens.UserContext) (RemoveTOTPDevicePOSTResponse, error)
+	VerifyTOTPDevicePOST *func(deviceName string, totp string, sessionContainer sessmodels.SessionContainer, options APIOptions, userContext supertokens.UserContext) (VerifyTOTPDevicePOSTResponse, error)
+	VerifyTOTPPOST       *func(totp string, sessionContainer sessmodels.SessionContainer, options APIOptions, userContext supertokens.UserContext) (VerifyTOTPPOSTResponse, error)
+
+	GenerateRecoveryCodesPOST *func(sessionContainer sessmodels.SessionContainer, options APIOptions, userContext supertokens.UserContext) (GenerateRecoveryCodesPOSTResponse, error)
+	RecoveryCodesGET          *func(sessionContainer sessmodels.SessionContainer, options APIOptions, userContext supertokens.UserContext) (RecoveryCodesGETResponse, error)
+	VerifyRecoveryCodePOST    *func(code string, sessionContainer sessmodels.SessionContainer, options APIOptions, userContext supertokens.UserContext) (VerifyRecoveryCodePOSTResponse, error)
+}
+
+type MFAInfoGETResponse struct {
+	OK *struct {
+		CompletedFactors []string
+		Requirements     MFARequirements
+		IsSatisfied      bool
+	}
+	GeneralError *supertokens.GeneralErrorResponse
+}
+
+type CreateTOTPDevicePOSTResponse struct {
+	OK *struct {
+		DeviceName   string
+		Secret       string
+		QRCodeString string
+	}
+	DeviceAlreadyExistsError *struct{}
+	GeneralError             *supertokens.GeneralErrorResponse
+}
+
+type ListTOTPDevicesGETResponse struct {
+	OK *struct {
+		Devices []TOTPDeviceInfo
+	}
+	GeneralError *supertokens.GeneralErrorResponse
+}
+
+type RemoveTOTPDevicePOSTResponse struct {
+	OK *struct {
+		DidDeviceExist bool
+	}
+	GeneralError *supertokens.GeneralErrorResponse
+}
+
+type VerifyTOTPDevicePOSTResponse struct {
+	OK *struct {
+		WasAlreadyVerified bool
+	}
+	UnknownDeviceError *struct{}
+	InvalidTOTPError   *struct{}
+	LimitReachedError  *struct {
+		RetryAfterMs int64
+	}
+	GeneralError *supertokens.GeneralErrorResponse
+}
+
+type VerifyTOTPPOSTResponse struct {
+	OK                 *struct{}
+	UnknownUserIdError *struct{}
+	InvalidTOTPError   *struct{}
+	LimitReachedError  *struct {
+		RetryAfterMs int64
+	}
+	GeneralError *supertokens.GeneralErrorResponse
+}
+
+type GenerateRecoveryCodesPOSTResponse struct {
+	OK *struct {
+		Codes []string
+	}
+	GeneralError *supertokens.GeneralErrorResponse
+}
+
+type RecoveryCodesGETResponse struct {
+	OK *struct {
+		RemainingCodes int
+	}
+	GeneralError *supertokens.GeneralErrorResponse
+}
+
+type VerifyRecoveryCodePOSTResponse struct {
+	OK *struct {
+		RemainingCodes int
+	}
+	InvalidRecoveryCodeError *struct{}
+	GeneralError             *supertokens.GeneralErrorResponse
+}
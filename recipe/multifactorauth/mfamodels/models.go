@@ -0,0 +1,186 @@
+/* Copyright (c) 2023, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package mfamodels
+
+import (
+	"time"
+
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+// FactorIdTOTP is the factor completed by verifying a code from a TOTP device.
+const FactorIdTOTP = "totp"
+
+// FactorIdRecoveryCode is the factor completed by using one of the recovery codes of the user, in
+// place of their other second factors.
+const FactorIdRecoveryCode = "recovery-code"
+
+type TypeInput struct {
+	// TOTP configures the TOTP devices. Its Store is required.
+	TOTP TOTPConfig
+	// RecoveryCodes configures the recovery codes. By default they are saved in the user metadata,
+	// which needs the usermetadata recipe.
+	RecoveryCodes *RecoveryCodesConfig
+	// RequirementsPerTenant are the factors required in the sessions of each tenant, by tenant id.
+	RequirementsPerTenant map[string]MFARequirements
+	// GetRequirements returns the factors required in the sessions of a user. By default it returns
+	// the requirements of the tenant from RequirementsPerTenant, or requires TOTP or a recovery code
+	// if the user has a verified TOTP device.
+	GetRequirements func(userId string, tenantId string, userContext supertokens.UserContext) (MFARequirements, error)
+	Override        *OverrideStruct
+}
+
+type TypeNormalisedInput struct {
+	TOTP                  NormalisedTOTPConfig
+	RecoveryCodes         NormalisedRecoveryCodesConfig
+	RequirementsPerTenant map[string]MFARequirements
+	GetRequirements       func(userId string, tenantId string, userContext supertokens.UserContext) (MFARequirements, error)
+	Override              OverrideStruct
+}
+
+type OverrideStruct struct {
+	Functions func(originalImplementation RecipeInterface) RecipeInterface
+	APIs      func(originalImplementation APIInterface) APIInterface
+}
+
+// MFARequirements are the factors that must be completed in a session, besides signing in, before
+// it passes the MultiFactorAuthClaim validator.
+type MFARequirements struct {
+	// AllOf are the factors that must all be completed.
+	AllOf []string `json:"allOf,omitempty"`
+	// OneOf are the factors of which at least one must be completed.
+	OneOf []string `json:"oneOf,omitempty"`
+	// NumberOfFactors is the number of different factors that must be completed.
+	NumberOfFactors int `json:"numberOfFactors,omitempty"`
+}
+
+// IsSatisfiedBy returns whether the completed factors satisfy the requirements.
+func (r MFARequirements) IsSatisfiedBy(completedFactors []string) bool {
+	completed := map[string]bool{}
+	for _, factorId := range completedFactors {
+		completed[factorId] = true
+	}
+
+	for _, factorId := range r.AllOf {
+		if !completed[factorId] {
+			return false
+		}
+	}
+
+	if len(r.OneOf) > 0 {
+		completedOne := false
+		for _, factorId := range r.OneOf {
+			completedOne = completedOne || completed[factorId]
+		}
+		if !completedOne {
+			return false
+		}
+	}
+
+	return len(completed) >= r.NumberOfFactors
+}
+
+type TOTPConfig struct {
+	Store TOTPDeviceStore
+	// Issuer is shown in the authenticator apps. Defaults to the app name.
+	Issuer *string
+	// Period is how many seconds each code is valid for. Defaults to 30.
+	Period int
+	// Skew is how many periods before and after the current one are accepted, to allow for clock
+	// drift. Defaults to 1.
+	Skew int
+	// GetAccountName returns the name of the account shown in the authenticator apps, like the
+	// email of the user. Defaults to the user id.
+	GetAccountName func(userId string, userContext supertokens.UserContext) (string, error)
+	// MaxAttempts is how many wrong codes in a row a user can enter before they have to wait
+	// CooldownSec to try again. Defaults to 5.
+	MaxAttempts int
+	// CooldownSec is how many seconds the user has to wait after MaxAttempts wrong codes. Defaults
+	// to 900.
+	CooldownSec int
+}
+
+type NormalisedTOTPConfig struct {
+	Store          TOTPDeviceStore
+	Issuer         string
+	Period         int
+	Skew           int
+	GetAccountName func(userId string, userContext supertokens.UserContext) (string, error)
+	MaxAttempts    int
+	CooldownSec    int
+}
+
+// TOTPDeviceStore saves the TOTP devices of the users. The secrets of the devices are saved as they
+// are, so the store must keep them safe.
+type TOTPDeviceStore interface {
+	// Create returns false if the user already has a device with the same name.
+	Create(userId string, device TOTPDevice, userContext supertokens.UserContext) (bool, error)
+	// Update saves the device, which the user already has, if the LastUsedCounter saved for it is
+	// still previousLastUsedCounter, and returns false otherwise. This has to be atomic, so that a
+	// code sent in two requests at the same time is only accepted once.
+	Update(userId string, device TOTPDevice, previousLastUsedCounter int64, userContext supertokens.UserContext) (bool, error)
+	ListForUser(userId string, userContext supertokens.UserContext) ([]TOTPDevice, error)
+	// Delete returns false if the user does not have a device with the name.
+	Delete(userId string, deviceName string, userContext supertokens.UserContext) (bool, error)
+	// RecordFailedAttempt counts a wrong code entered by the user, and returns the attempts that
+	// failed since the last code of the user that was accepted.
+	RecordFailedAttempt(userId string, failedAt time.Time, userContext supertokens.UserContext) (TOTPFailedAttempts, error)
+	GetFailedAttempts(userId string, userContext supertokens.UserContext) (TOTPFailedAttempts, error)
+	// ResetFailedAttempts is called when a code of the user is accepted.
+	ResetFailedAttempts(userId string, userContext supertokens.UserContext) error
+}
+
+// TOTPFailedAttempts are the wrong codes entered by a user since their last code that was accepted.
+type TOTPFailedAttempts struct {
+	Count        int       `json:"count"`
+	LastFailedAt time.Time `json:"lastFailedAt"`
+}
+
+type TOTPDevice struct {
+	Name string `json:"name"`
+	// Secret is the base32 encoded secret shared with the authenticator app.
+	Secret   string `json:"secret"`
+	Period   int    `json:"period"`
+	Skew     int    `json:"skew"`
+	Verified bool   `json:"verified"`
+	// LastUsedCounter is the time step of the last code accepted for the device, so that a code
+	// cannot be used twice.
+	LastUsedCounter int64 `json:"lastUsedCounter"`
+}
+
+type RecoveryCodesConfig struct {
+	// Store defaults to saving the codes in the user metadata.
+	Store RecoveryCodeStore
+	// NumberOfCodes is how many codes are generated at a time. Defaults to 10.
+	NumberOfCodes int
+}
+
+type NormalisedRecoveryCodesConfig struct {
+	Store         RecoveryCodeStore
+	NumberOfCodes int
+}
+
+// RecoveryCodeStore saves the recovery codes of the users. Only the hashes of the codes are given
+// to the store.
+type RecoveryCodeStore interface {
+	// Set replaces the recovery codes of the user.
+	Set(userId string, hashedCodes []string, userContext supertokens.UserContext) error
+	Get(userId string, userContext supertokens.UserContext) ([]string, error)
+	// Remove returns false if the user does not have the code, like when it was already used, and
+	// how many codes the user has left. It has to be atomic, so that a code sent in two requests at
+	// the same time is only accepted once.
+	Remove(userId string, hashedCode string, userContext supertokens.UserContext) (bool, int, error)
+}
@@ -0,0 +1,84 @@
+/* Copyright (c) 2023, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package mfamodels
+
+import (
+	"github.com/supertokens/supertokens-golang/recipe/session/sessmodels"
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+type RecipeInterface struct {
+	GetRequirements               *func(userId string, tenantId string, userContext supertokens.UserContext) (MFARequirements, error)
+	MarkFactorAsCompleteInSession *func(sessionContainer sessmodels.SessionContainer, factorId string, userContext supertokens.UserContext) error
+	CreateTOTPDevice              *func(userId string, deviceName *string, userContext supertokens.UserContext) (CreateTOTPDeviceResponse, error)
+	VerifyTOTPDevice              *func(userId string, deviceName string, totp string, userContext supertokens.UserContext) (VerifyTOTPDeviceResponse, error)
+	VerifyTOTP                    *func(userId string, totp string, userContext supertokens.UserContext) (VerifyTOTPResponse, error)
+	ListTOTPDevices               *func(userId string, userContext supertokens.UserContext) ([]TOTPDeviceInfo, error)
+	RemoveTOTPDevice              *func(userId string, deviceName string, userContext supertokens.UserContext) (bool, error)
+	// GenerateRecoveryCodes replaces the recovery codes of the user with new ones, which are only
+	// returned here.
+	GenerateRecoveryCodes *func(userId string, userContext supertokens.UserContext) ([]string, error)
+	// VerifyRecoveryCode uses up the recovery code if it is valid.
+	VerifyRecoveryCode    *func(userId string, code string, userContext supertokens.UserContext) (VerifyRecoveryCodeResponse, error)
+	GetRecoveryCodesCount *func(userId string, userContext supertokens.UserContext) (int, error)
+}
+
+type CreateTOTPDeviceResponse struct {
+	OK *struct {
+		DeviceName string
+		Secret     string
+		// QRCodeString is the otpauth:// URI of the device, to be shown as a QR code.
+		QRCodeString string
+	}
+	DeviceAlreadyExistsError *struct{}
+}
+
+type VerifyTOTPDeviceResponse struct {
+	OK *struct {
+		WasAlreadyVerified bool
+	}
+	UnknownDeviceError *struct{}
+	InvalidTOTPError   *struct{}
+	// LimitReachedError is returned when the user entered too many wrong codes, until the cooldown
+	// is over.
+	LimitReachedError *struct {
+		RetryAfterMs int64
+	}
+}
+
+type VerifyTOTPResponse struct {
+	OK *struct{}
+	// UnknownUserIdError is returned if the user has no verified TOTP device.
+	UnknownUserIdError *struct{}
+	InvalidTOTPError   *struct{}
+	LimitReachedError  *struct {
+		RetryAfterMs int64
+	}
+}
+
+type TOTPDeviceInfo struct {
+	Name     string `json:"name"`
+	Period   int    `json:"period"`
+	Skew     int    `json:"skew"`
+	Verified bool   `json:"verified"`
+}
+
+type VerifyRecoveryCodeResponse struct {
+	OK *struct {
+		RemainingCodes int
+	}
+	InvalidRecoveryCodeError *struct{}
+}
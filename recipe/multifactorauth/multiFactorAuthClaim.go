@@ -0,0 +1,109 @@
+/* Copyright (c) 2023, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package multifactorauth
+
+import (
+	"github.com/supertokens/supertokens-golang/recipe/multifactorauth/mfaclaims"
+	"github.com/supertokens/supertokens-golang/recipe/multifactorauth/mfamodels"
+	"github.com/supertokens/supertokens-golang/recipe/session/claims"
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+func init() {
+	// automatically called when this package is imported
+	mfaclaims.MultiFactorAuthClaim, mfaclaims.MultiFactorAuthClaimValidators = NewMultiFactorAuthClaim()
+}
+
+func NewMultiFactorAuthClaim() (*claims.TypeSessionClaim, mfaclaims.TypeMultiFactorAuthClaimValidators) {
+	// The value is only fetched for sessions without it, like new sessions. The factors completed in
+	// the session are added to it by MarkFactorAsCompleteInSession.
+	fetchValue := func(userId string, tenantId string, userContext supertokens.UserContext) (interface{}, error) {
+		instance, err := getRecipeInstanceOrThrowError()
+		if err != nil {
+			return nil, err
+		}
+		requirements, err := (*instance.RecipeImpl.GetRequirements)(userId, tenantId, userContext)
+		if err != nil {
+			return nil, err
+		}
+		return getMFAClaimValue(map[string]interface{}{}, requirements), nil
+	}
+
+	mfaClaim, _ := claims.PrimitiveClaim("st-mfa", fetchValue, nil)
+
+	getValidator := func(id *string, isValid func(value map[string]interface{}) bool) claims.SessionClaimValidator {
+		validatorId := mfaClaim.Key
+		if id != nil {
+			validatorId = *id
+		}
+		return claims.SessionClaimValidator{
+			ID:    validatorId,
+			Claim: mfaClaim,
+			ShouldRefetch: func(payload map[string]interface{}, userContext supertokens.UserContext) bool {
+				return mfaClaim.GetValueFromPayload(payload, userContext) == nil
+			},
+			Validate: func(payload map[string]interface{}, userContext supertokens.UserContext) claims.ClaimValidationResult {
+				value, _ := mfaClaim.GetValueFromPayload(payload, userContext).(map[string]interface{})
+				if value != nil && isValid(value) {
+					return claims.ClaimValidationResult{
+						IsValid: true,
+					}
+				}
+				return claims.ClaimValidationResult{
+					IsValid: false,
+					Reason: map[string]interface{}{
+						"message":          "MFA_REQUIRED",
+						"completedFactors": mfaclaims.GetCompletedFactors(value),
+					},
+				}
+			},
+		}
+	}
+
+	validators := mfaclaims.TypeMultiFactorAuthClaimValidators{
+		HasCompletedRequirements: func(id *string) claims.SessionClaimValidator {
+			return getValidator(id, func(value map[string]interface{}) bool {
+				isSatisfied, _ := value["v"].(bool)
+				return isSatisfied
+			})
+		},
+		HasCompletedFactors: func(factorIds []string, id *string) claims.SessionClaimValidator {
+			return getValidator(id, func(value map[string]interface{}) bool {
+				return mfamodels.MFARequirements{AllOf: factorIds}.IsSatisfiedBy(mfaclaims.GetCompletedFactors(value))
+			})
+		},
+		HasCompletedNumberOfFactors: func(numberOfFactors int, id *string) claims.SessionClaimValidator {
+			return getValidator(id, func(value map[string]interface{}) bool {
+				return mfamodels.MFARequirements{NumberOfFactors: numberOfFactors}.IsSatisfiedBy(mfaclaims.GetCompletedFactors(value))
+			})
+		},
+	}
+
+	return mfaClaim, validators
+}
+
+// getMFAClaimValue returns the value of the claim, with the time each factor was completed in c, and
+// whether they satisfy the requirements in v.
+func getMFAClaimValue(completedFactors map[string]interface{}, requirements mfamodels.MFARequirements) map[string]interface{} {
+	factorIds := []string{}
+	for factorId := range completedFactors {
+		factorIds = append(factorIds, factorId)
+	}
+	return map[string]interface{}{
+		"c": completedFactors,
+		"v": requirements.IsSatisfiedBy(factorIds),
+	}
+}
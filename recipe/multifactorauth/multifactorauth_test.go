@@ -0,0 +1,417 @@
+/* Copyright (c) 2023, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package multifactorauth
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/supertokens/supertokens-golang/recipe/multifactorauth/mfaclaims"
+	"github.com/supertokens/supertokens-golang/recipe/multifactorauth/mfamodels"
+	"github.com/supertokens/supertokens-golang/recipe/session"
+	"github.com/supertokens/supertokens-golang/recipe/session/claims"
+	"github.com/supertokens/supertokens-golang/recipe/session/sessmodels"
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+type memoryTOTPDeviceStore struct {
+	lock           sync.Mutex
+	devices        map[string][]mfamodels.TOTPDevice
+	failedAttempts map[string]mfamodels.TOTPFailedAttempts
+}
+
+func (s *memoryTOTPDeviceStore) Create(userId string, device mfamodels.TOTPDevice, userContext supertokens.UserContext) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, existing := range s.devices[userId] {
+		if existing.Name == device.Name {
+			return false, nil
+		}
+	}
+	s.devices[userId] = append(s.devices[userId], device)
+	return true, nil
+}
+
+func (s *memoryTOTPDeviceStore) Update(userId string, device mfamodels.TOTPDevice, previousLastUsedCounter int64, userContext supertokens.UserContext) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for i, existing := range s.devices[userId] {
+		if existing.Name == device.Name && existing.LastUsedCounter == previousLastUsedCounter {
+			s.devices[userId][i] = device
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *memoryTOTPDeviceStore) ListForUser(userId string, userContext supertokens.UserContext) ([]mfamodels.TOTPDevice, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]mfamodels.TOTPDevice{}, s.devices[userId]...), nil
+}
+
+func (s *memoryTOTPDeviceStore) Delete(userId string, deviceName string, userContext supertokens.UserContext) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for i, existing := range s.devices[userId] {
+		if existing.Name == deviceName {
+			s.devices[userId] = append(s.devices[userId][:i], s.devices[userId][i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *memoryTOTPDeviceStore) RecordFailedAttempt(userId string, failedAt time.Time, userContext supertokens.UserContext) (mfamodels.TOTPFailedAttempts, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.failedAttempts == nil {
+		s.failedAttempts = map[string]mfamodels.TOTPFailedAttempts{}
+	}
+	s.failedAttempts[userId] = mfamodels.TOTPFailedAttempts{
+		Count:        s.failedAttempts[userId].Count + 1,
+		LastFailedAt: failedAt,
+	}
+	return s.failedAttempts[userId], nil
+}
+
+func (s *memoryTOTPDeviceStore) GetFailedAttempts(userId string, userContext supertokens.UserContext) (mfamodels.TOTPFailedAttempts, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.failedAttempts[userId], nil
+}
+
+func (s *memoryTOTPDeviceStore) ResetFailedAttempts(userId string, userContext supertokens.UserContext) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.failedAttempts, userId)
+	return nil
+}
+
+// resetAll also resets the recipes directly, since supertokens.ResetForTest skips them when the init failed
+func resetAll() {
+	supertokens.ResetForTest()
+	session.ResetForTest()
+	resetForTest()
+}
+
+type memoryRecoveryCodeStore struct {
+	lock        sync.Mutex
+	hashedCodes map[string][]string
+}
+
+func (s *memoryRecoveryCodeStore) Set(userId string, hashedCodes []string, userContext supertokens.UserContext) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.hashedCodes[userId] = hashedCodes
+	return nil
+}
+
+func (s *memoryRecoveryCodeStore) Get(userId string, userContext supertokens.UserContext) ([]string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]string{}, s.hashedCodes[userId]...), nil
+}
+
+func (s *memoryRecoveryCodeStore) Remove(userId string, hashedCode string, userContext supertokens.UserContext) (bool, int, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for i, existing := range s.hashedCodes[userId] {
+		if existing == hashedCode {
+			s.hashedCodes[userId] = append(s.hashedCodes[userId][:i], s.hashedCodes[userId][i+1:]...)
+			return true, len(s.hashedCodes[userId]), nil
+		}
+	}
+	return false, len(s.hashedCodes[userId]), nil
+}
+
+func initForTest(t *testing.T, config *mfamodels.TypeInput) {
+	resetAll()
+	err := supertokens.Init(supertokens.TypeInput{
+		Supertokens: &supertokens.ConnectionInfo{
+			ConnectionURI: "http://localhost:8080",
+		},
+		AppInfo: supertokens.AppInfo{
+			APIDomain:     "api.supertokens.io",
+			AppName:       "SuperTokens",
+			WebsiteDomain: "supertokens.io",
+		},
+		RecipeList: []supertokens.Recipe{
+			session.Init(nil),
+			Init(config),
+		},
+	})
+	assert.NoError(t, err)
+}
+
+// makeTestSession returns a session that keeps its access token payload in memory
+func makeTestSession(userId string) sessmodels.SessionContainer {
+	payload := map[string]interface{}{}
+	return &sessmodels.TypeSessionContainer{
+		GetUserIDWithContext: func(userContext supertokens.UserContext) string {
+			return userId
+		},
+		GetTenantIdWithContext: func(userContext supertokens.UserContext) string {
+			return "public"
+		},
+		GetAccessTokenPayloadWithContext: func(userContext supertokens.UserContext) map[string]interface{} {
+			return payload
+		},
+		SetClaimValueWithContext: func(claim *claims.TypeSessionClaim, value interface{}, userContext supertokens.UserContext) error {
+			payload = claim.AddToPayload_internal(payload, value, userContext)
+			return nil
+		},
+	}
+}
+
+func getCurrentTOTP(t *testing.T, secret string, period int, offset int64) string {
+	decodedSecret, err := totpSecretEncoding.DecodeString(secret)
+	assert.NoError(t, err)
+	return getTOTPCode(decodedSecret, time.Now().Unix()/int64(period)+offset)
+}
+
+func TestTOTPStoreIsRequired(t *testing.T) {
+	resetAll()
+	defer resetAll()
+	err := supertokens.Init(supertokens.TypeInput{
+		Supertokens: &supertokens.ConnectionInfo{
+			ConnectionURI: "http://localhost:8080",
+		},
+		AppInfo: supertokens.AppInfo{
+			APIDomain:     "api.supertokens.io",
+			AppName:       "SuperTokens",
+			WebsiteDomain: "supertokens.io",
+		},
+		RecipeList: []supertokens.Recipe{
+			session.Init(nil),
+			Init(&mfamodels.TypeInput{}),
+		},
+	})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "TOTP.Store is required")
+}
+
+func TestTOTPDeviceFlow(t *testing.T) {
+	store := &memoryTOTPDeviceStore{devices: map[string][]mfamodels.TOTPDevice{}}
+	initForTest(t, &mfamodels.TypeInput{
+		TOTP: mfamodels.TOTPConfig{Store: store},
+	})
+	defer resetAll()
+
+	requirements, err := GetRequirements("user-1", "public")
+	assert.NoError(t, err)
+	assert.True(t, requirements.IsSatisfiedBy([]string{}))
+
+	created, err := CreateTOTPDevice("user-1", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "TOTP Device 1", created.OK.DeviceName)
+	assert.Contains(t, created.OK.QRCodeString, "otpauth://totp/SuperTokens:user-1?")
+
+	duplicate, err := CreateTOTPDevice("user-1", &created.OK.DeviceName)
+	assert.NoError(t, err)
+	assert.NotNil(t, duplicate.DeviceAlreadyExistsError)
+
+	// the codes are computed once, so that the test works across time steps
+	currentTOTP := getCurrentTOTP(t, created.OK.Secret, 30, 0)
+	nextTOTP := getCurrentTOTP(t, created.OK.Secret, 30, 1)
+
+	// the device is not used until it is verified
+	verifyResponse, err := VerifyTOTP("user-1", currentTOTP)
+	assert.NoError(t, err)
+	assert.NotNil(t, verifyResponse.UnknownUserIdError)
+
+	verifyDeviceResponse, err := VerifyTOTPDevice("user-1", created.OK.DeviceName, "000000")
+	assert.NoError(t, err)
+	assert.NotNil(t, verifyDeviceResponse.InvalidTOTPError)
+
+	verifyDeviceResponse, err = VerifyTOTPDevice("user-1", created.OK.DeviceName, currentTOTP)
+	assert.NoError(t, err)
+	assert.False(t, verifyDeviceResponse.OK.WasAlreadyVerified)
+
+	requirements, err = GetRequirements("user-1", "public")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{mfamodels.FactorIdTOTP, mfamodels.FactorIdRecoveryCode}, requirements.OneOf)
+	assert.False(t, requirements.IsSatisfiedBy([]string{}))
+
+	// the code that was used to verify the device cannot be used again
+	verifyResponse, err = VerifyTOTP("user-1", currentTOTP)
+	assert.NoError(t, err)
+	assert.NotNil(t, verifyResponse.InvalidTOTPError)
+
+	verifyResponse, err = VerifyTOTP("user-1", nextTOTP)
+	assert.NoError(t, err)
+	assert.NotNil(t, verifyResponse.OK)
+
+	devices, err := ListTOTPDevices("user-1")
+	assert.NoError(t, err)
+	assert.Equal(t, []mfamodels.TOTPDeviceInfo{{Name: "TOTP Device 1", Period: 30, Skew: 1, Verified: true}}, devices)
+
+	didExist, err := RemoveTOTPDevice("user-1", created.OK.DeviceName)
+	assert.NoError(t, err)
+	assert.True(t, didExist)
+	didExist, err = RemoveTOTPDevice("user-1", created.OK.DeviceName)
+	assert.NoError(t, err)
+	assert.False(t, didExist)
+}
+
+func TestTOTPLimitReached(t *testing.T) {
+	store := &memoryTOTPDeviceStore{devices: map[string][]mfamodels.TOTPDevice{}}
+	initForTest(t, &mfamodels.TypeInput{
+		TOTP: mfamodels.TOTPConfig{Store: store, MaxAttempts: 3},
+	})
+	defer resetAll()
+
+	created, err := CreateTOTPDevice("user-1", nil)
+	assert.NoError(t, err)
+	currentTOTP := getCurrentTOTP(t, created.OK.Secret, 30, 0)
+	nextTOTP := getCurrentTOTP(t, created.OK.Secret, 30, 1)
+	verifyDeviceResponse, err := VerifyTOTPDevice("user-1", created.OK.DeviceName, currentTOTP)
+	assert.NoError(t, err)
+	assert.NotNil(t, verifyDeviceResponse.OK)
+
+	for i := 0; i < 3; i++ {
+		verifyResponse, err := VerifyTOTP("user-1", "000000")
+		assert.NoError(t, err)
+		assert.NotNil(t, verifyResponse.InvalidTOTPError)
+	}
+
+	// even the right code is rejected until the cooldown is over
+	verifyResponse, err := VerifyTOTP("user-1", nextTOTP)
+	assert.NoError(t, err)
+	assert.Nil(t, verifyResponse.OK)
+	assert.NotNil(t, verifyResponse.LimitReachedError)
+	assert.InDelta(t, 900*1000, verifyResponse.LimitReachedError.RetryAfterMs, 5000)
+	verifyDeviceResponse, err = VerifyTOTPDevice("user-1", created.OK.DeviceName, nextTOTP)
+	assert.NoError(t, err)
+	assert.NotNil(t, verifyDeviceResponse.LimitReachedError)
+
+	store.lock.Lock()
+	store.failedAttempts["user-1"] = mfamodels.TOTPFailedAttempts{Count: 3, LastFailedAt: time.Now().Add(-901 * time.Second)}
+	store.lock.Unlock()
+
+	verifyResponse, err = VerifyTOTP("user-1", nextTOTP)
+	assert.NoError(t, err)
+	assert.NotNil(t, verifyResponse.OK)
+	failedAttempts, err := store.GetFailedAttempts("user-1", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, failedAttempts.Count)
+}
+
+// raceTOTPDeviceStore makes the requests that list the devices wait for each other, so that they
+// all check the code before any of them saves that it was used
+type raceTOTPDeviceStore struct {
+	*memoryTOTPDeviceStore
+	// listed is only waited for when it is set
+	listed *sync.WaitGroup
+}
+
+func (s *raceTOTPDeviceStore) ListForUser(userId string, userContext supertokens.UserContext) ([]mfamodels.TOTPDevice, error) {
+	devices, err := s.memoryTOTPDeviceStore.ListForUser(userId, userContext)
+	if s.listed != nil {
+		s.listed.Done()
+		s.listed.Wait()
+	}
+	return devices, err
+}
+
+func TestTOTPIsOnlyAcceptedOnceWhenUsedConcurrently(t *testing.T) {
+	store := &raceTOTPDeviceStore{memoryTOTPDeviceStore: &memoryTOTPDeviceStore{devices: map[string][]mfamodels.TOTPDevice{}}}
+	initForTest(t, &mfamodels.TypeInput{
+		TOTP: mfamodels.TOTPConfig{Store: store},
+	})
+	defer resetAll()
+
+	created, err := CreateTOTPDevice("user-1", nil)
+	assert.NoError(t, err)
+	verifyDeviceResponse, err := VerifyTOTPDevice("user-1", created.OK.DeviceName, getCurrentTOTP(t, created.OK.Secret, 30, 0))
+	assert.NoError(t, err)
+	assert.NotNil(t, verifyDeviceResponse.OK)
+	nextTOTP := getCurrentTOTP(t, created.OK.Secret, 30, 1)
+
+	const requests = 5
+	store.listed = &sync.WaitGroup{}
+	store.listed.Add(requests)
+	results := make(chan mfamodels.VerifyTOTPResponse, requests)
+	for i := 0; i < requests; i++ {
+		go func() {
+			verifyResponse, err := VerifyTOTP("user-1", nextTOTP)
+			assert.NoError(t, err)
+			results <- verifyResponse
+		}()
+	}
+
+	accepted := 0
+	for i := 0; i < requests; i++ {
+		if verifyResponse := <-results; verifyResponse.OK != nil {
+			accepted++
+		} else {
+			assert.NotNil(t, verifyResponse.InvalidTOTPError)
+		}
+	}
+	assert.Equal(t, 1, accepted)
+}
+
+func TestMarkFactorAsCompleteInSession(t *testing.T) {
+	initForTest(t, &mfamodels.TypeInput{
+		TOTP: mfamodels.TOTPConfig{Store: &memoryTOTPDeviceStore{devices: map[string][]mfamodels.TOTPDevice{}}},
+		RequirementsPerTenant: map[string]mfamodels.MFARequirements{
+			"public": {AllOf: []string{"otp-email", mfamodels.FactorIdTOTP}},
+		},
+	})
+	defer resetAll()
+
+	sessionContainer := makeTestSession("user-1")
+	payload := sessionContainer.GetAccessTokenPayloadWithContext(nil)
+
+	// sessions without the claim are refetched
+	validator := mfaclaims.MultiFactorAuthClaimValidators.HasCompletedRequirements(nil)
+	assert.True(t, validator.ShouldRefetch(payload, nil))
+	result := validator.Validate(payload, nil)
+	assert.False(t, result.IsValid)
+	assert.Equal(t, "MFA_REQUIRED", result.Reason.(map[string]interface{})["message"])
+
+	err := MarkFactorAsCompleteInSession(sessionContainer, "otp-email")
+	assert.NoError(t, err)
+	payload = sessionContainer.GetAccessTokenPayloadWithContext(nil)
+	assert.False(t, validator.ShouldRefetch(payload, nil))
+	assert.False(t, validator.Validate(payload, nil).IsValid)
+	assert.True(t, mfaclaims.MultiFactorAuthClaimValidators.HasCompletedFactors([]string{"otp-email"}, nil).Validate(payload, nil).IsValid)
+	assert.False(t, mfaclaims.MultiFactorAuthClaimValidators.HasCompletedNumberOfFactors(2, nil).Validate(payload, nil).IsValid)
+
+	err = MarkFactorAsCompleteInSession(sessionContainer, mfamodels.FactorIdTOTP)
+	assert.NoError(t, err)
+	payload = sessionContainer.GetAccessTokenPayloadWithContext(nil)
+	assert.True(t, validator.Validate(payload, nil).IsValid)
+	assert.True(t, mfaclaims.MultiFactorAuthClaimValidators.HasCompletedNumberOfFactors(2, nil).Validate(payload, nil).IsValid)
+	value := mfaclaims.MultiFactorAuthClaim.GetValueFromPayload(payload, nil).(map[string]interface{})
+	assert.Equal(t, []string{"otp-email", mfamodels.FactorIdTOTP}, mfaclaims.GetCompletedFactors(value))
+}
+
+func TestMFARequirementsIsSatisfiedBy(t *testing.T) {
+	requirements := mfamodels.MFARequirements{
+		AllOf: []string{"a"},
+		OneOf: []string{"b", "c"},
+	}
+	assert.False(t, requirements.IsSatisfiedBy([]string{"a"}))
+	assert.False(t, requirements.IsSatisfiedBy([]string{"b", "c"}))
+	assert.True(t, requirements.IsSatisfiedBy([]string{"a", "c"}))
+
+	requirements = mfamodels.MFARequirements{NumberOfFactors: 2}
+	assert.False(t, requirements.IsSatisfiedBy([]string{"a"}))
+	assert.True(t, requirements.IsSatisfiedBy([]string{"a", "b"}))
+}
@@ -0,0 +1,227 @@
+/* Copyright (c) 2023, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package multifactorauth
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/supertokens/supertokens-golang/recipe/multifactorauth/api"
+	"github.com/supertokens/supertokens-golang/recipe/multifactorauth/mfaclaims"
+	"github.com/supertokens/supertokens-golang/recipe/multifactorauth/mfamodels"
+	"github.com/supertokens/supertokens-golang/recipe/session"
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+const RECIPE_ID = "multifactorauth"
+
+type Recipe struct {
+	RecipeModule supertokens.RecipeModule
+	Config       mfamodels.TypeNormalisedInput
+	RecipeImpl   mfamodels.RecipeInterface
+	APIImpl      mfamodels.APIInterface
+}
+
+var singletonInstance *Recipe
+
+func MakeRecipe(recipeId string, appInfo supertokens.NormalisedAppinfo, config *mfamodels.TypeInput, onSuperTokensAPIError func(err error, req *http.Request, res http.ResponseWriter)) (Recipe, error) {
+	r := &Recipe{}
+	verifiedConfig, err := validateAndNormaliseUserInput(appInfo, config)
+	if err != nil {
+		return Recipe{}, err
+	}
+	r.Config = verifiedConfig
+	r.RecipeImpl = verifiedConfig.Override.Functions(makeRecipeImplementation(verifiedConfig))
+	r.APIImpl = verifiedConfig.Override.APIs(api.MakeAPIImplementation())
+
+	recipeModuleInstance := supertokens.MakeRecipeModule(recipeId, appInfo, r.handleAPIRequest, r.getAllCORSHeaders, r.getAPIsHandled, nil, r.handleError, onSuperTokensAPIError)
+	r.RecipeModule = recipeModuleInstance
+
+	r.RecipeModule.ResetForTest = resetForTest
+
+	return *r, nil
+}
+
+func getRecipeInstanceOrThrowError() (*Recipe, error) {
+	if singletonInstance != nil {
+		return singletonInstance, nil
+	}
+	return nil, errors.New("Initialisation not done. Did you forget to call the init function?")
+}
+
+func GetRecipeInstance() *Recipe {
+	return singletonInstance
+}
+
+func recipeInit(config *mfamodels.TypeInput) supertokens.Recipe {
+	return func(appInfo supertokens.NormalisedAppinfo, onSuperTokensAPIError func(err error, req *http.Request, res http.ResponseWriter)) (*supertokens.RecipeModule, error) {
+		if singletonInstance == nil {
+			recipe, err := MakeRecipe(RECIPE_ID, appInfo, config, onSuperTokensAPIError)
+			if err != nil {
+				return nil, err
+			}
+			singletonInstance = &recipe
+
+			supertokens.AddPostInitCallback(func() error {
+				sessionRecipe, err := session.GetRecipeInstanceOrThrowError()
+				if err != nil {
+					return err
+				}
+
+				sessionRecipe.AddClaimFromOtherRecipe(mfaclaims.MultiFactorAuthClaim)
+				sessionRecipe.AddClaimValidatorFromOtherRecipe(
+					mfaclaims.MultiFactorAuthClaimValidators.HasCompletedRequirements(nil),
+				)
+				return nil
+			})
+
+			return &singletonInstance.RecipeModule, nil
+		}
+		return nil, errors.New("Multi factor auth recipe has already been initialised. Please check your code for bugs.")
+	}
+}
+
+// implement RecipeModule
+
+func (r *Recipe) getAPIsHandled() ([]supertokens.APIHandled, error) {
+	mfaInfoAPINormalised, err := supertokens.NewNormalisedURLPath(mfaInfoAPI)
+	if err != nil {
+		return nil, err
+	}
+	createTOTPDeviceAPINormalised, err := supertokens.NewNormalisedURLPath(createTOTPDeviceAPI)
+	if err != nil {
+		return nil, err
+	}
+	listTOTPDevicesAPINormalised, err := supertokens.NewNormalisedURLPath(listTOTPDevicesAPI)
+	if err != nil {
+		return nil, err
+	}
+	removeTOTPDeviceAPINormalised, err := supertokens.NewNormalisedURLPath(removeTOTPDeviceAPI)
+	if err != nil {
+		return nil, err
+	}
+	verifyTOTPDeviceAPINormalised, err := supertokens.NewNormalisedURLPath(verifyTOTPDeviceAPI)
+	if err != nil {
+		return nil, err
+	}
+	verifyTOTPAPINormalised, err := supertokens.NewNormalisedURLPath(verifyTOTPAPI)
+	if err != nil {
+		return nil, err
+	}
+	recoveryCodesAPINormalised, err := supertokens.NewNormalisedURLPath(recoveryCodesAPI)
+	if err != nil {
+		return nil, err
+	}
+	generateRecoveryCodesAPINormalised, err := supertokens.NewNormalisedURLPath(generateRecoveryCodesAPI)
+	if err != nil {
+		return nil, err
+	}
+	verifyRecoveryCodeAPINormalised, err := supertokens.NewNormalisedURLPath(verifyRecoveryCodeAPI)
+	if err != nil {
+		return nil, err
+	}
+
+	return []supertokens.APIHandled{{
+		Method:                 http.MethodGet,
+		PathWithoutAPIBasePath: mfaInfoAPINormalised,
+		ID:                     mfaInfoAPI,
+		Disabled:               r.APIImpl.MFAInfoGET == nil,
+	}, {
+		Method:                 http.MethodPost,
+		PathWithoutAPIBasePath: createTOTPDeviceAPINormalised,
+		ID:                     createTOTPDeviceAPI,
+		Disabled:               r.APIImpl.CreateTOTPDevicePOST == nil,
+	}, {
+		Method:                 http.MethodGet,
+		PathWithoutAPIBasePath: listTOTPDevicesAPINormalised,
+		ID:                     listTOTPDevicesAPI,
+		Disabled:               r.APIImpl.ListTOTPDevicesGET == nil,
+	}, {
+		Method:                 http.MethodPost,
+		PathWithoutAPIBasePath: removeTOTPDeviceAPINormalised,
+		ID:                     removeTOTPDeviceAPI,
+		Disabled:               r.APIImpl.RemoveTOTPDevicePOST == nil,
+	}, {
+		Method:                 http.MethodPost,
+		PathWithoutAPIBasePath: verifyTOTPDeviceAPINormalised,
+		ID:                     verifyTOTPDeviceAPI,
+		Disabled:               r.APIImpl.VerifyTOTPDevicePOST == nil,
+	}, {
+		Method:                 http.MethodPost,
+		PathWithoutAPIBasePath: verifyTOTPAPINormalised,
+		ID:                     verifyTOTPAPI,
+		Disabled:               r.APIImpl.VerifyTOTPPOST == nil,
+	}, {
+		Method:                 http.MethodGet,
+		PathWithoutAPIBasePath: recoveryCodesAPINormalised,
+		ID:                     recoveryCodesAPI,
+		Disabled:               r.APIImpl.RecoveryCodesGET == nil,
+	}, {
+		Method:                 http.MethodPost,
+		PathWithoutAPIBasePath: generateRecoveryCodesAPINormalised,
+		ID:                     generateRecoveryCodesAPI,
+		Disabled:               r.APIImpl.GenerateRecoveryCodesPOST == nil,
+	}, {
+		Method:                 http.MethodPost,
+		PathWithoutAPIBasePath: verifyRecoveryCodeAPINormalised,
+		ID:                     verifyRecoveryCodeAPI,
+		Disabled:               r.APIImpl.VerifyRecoveryCodePOST == nil,
+	}}, nil
+}
+
+func (r *Recipe) handleAPIRequest(id string, tenantId string, req *http.Request, res http.ResponseWriter, theirHandler http.HandlerFunc, _ supertokens.NormalisedURLPath, _ string, userContext supertokens.UserContext) error {
+	options := mfamodels.APIOptions{
+		Config:               r.Config,
+		RecipeID:             r.RecipeModule.GetRecipeID(),
+		RecipeImplementation: r.RecipeImpl,
+		AppInfo:              r.RecipeModule.GetAppInfo(),
+		Req:                  req,
+		Res:                  res,
+		OtherHandler:         theirHandler,
+	}
+	if id == mfaInfoAPI {
+		return api.MFAInfo(r.APIImpl, options, userContext)
+	} else if id == createTOTPDeviceAPI {
+		return api.CreateTOTPDevice(r.APIImpl, options, userContext)
+	} else if id == listTOTPDevicesAPI {
+		return api.ListTOTPDevices(r.APIImpl, options, userContext)
+	} else if id == removeTOTPDeviceAPI {
+		return api.RemoveTOTPDevice(r.APIImpl, options, userContext)
+	} else if id == verifyTOTPDeviceAPI {
+		return api.VerifyTOTPDevice(r.APIImpl, options, userContext)
+	} else if id == verifyTOTPAPI {
+		return api.VerifyTOTP(r.APIImpl, options, userContext)
+	} else if id == recoveryCodesAPI {
+		return api.RecoveryCodes(r.APIImpl, options, userContext)
+	} else if id == generateRecoveryCodesAPI {
+		return api.GenerateRecoveryCodes(r.APIImpl, options, userContext)
+	} else if id == verifyRecoveryCodeAPI {
+		return api.VerifyRecoveryCode(r.APIImpl, options, userContext)
+	}
+	return errors.New("should never come here")
+}
+
+func (r *Recipe) getAllCORSHeaders() []string {
+	return []string{}
+}
+
+func (r *Recipe) handleError(err error, req *http.Request, res http.ResponseWriter, userContext supertokens.UserContext) (bool, error) {
+	return false, nil
+}
+
+func resetForTest() {
+	singletonInstance = nil
+}
@@ -0,0 +1,364 @@
+/* Copyright (c) 2023, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package multifactorauth
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/supertokens/supertokens-golang/recipe/multifactorauth/mfaclaims"
+	"github.com/supertokens/supertokens-golang/recipe/multifactorauth/mfamodels"
+	"github.com/supertokens/supertokens-golang/recipe/session/sessmodels"
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+func makeRecipeImplementation(config mfamodels.TypeNormalisedInput) mfamodels.RecipeInterface {
+	store := config.TOTP.Store
+
+	getDevice := func(userId string, deviceName string, userContext supertokens.UserContext) (*mfamodels.TOTPDevice, error) {
+		devices, err := store.ListForUser(userId, userContext)
+		if err != nil {
+			return nil, err
+		}
+		for _, device := range devices {
+			if device.Name == deviceName {
+				return &device, nil
+			}
+		}
+		return nil, nil
+	}
+
+	// getLimitReachedError returns the error to send if the user entered MaxAttempts wrong codes in
+	// a row, and the cooldown after the last one is not over
+	getLimitReachedError := func(userId string, now time.Time, userContext supertokens.UserContext) (*struct{ RetryAfterMs int64 }, error) {
+		failedAttempts, err := store.GetFailedAttempts(userId, userContext)
+		if err != nil {
+			return nil, err
+		}
+		if failedAttempts.Count < config.TOTP.MaxAttempts {
+			return nil, nil
+		}
+		retryAfter := failedAttempts.LastFailedAt.Add(time.Duration(config.TOTP.CooldownSec) * time.Second).Sub(now)
+		if retryAfter <= 0 {
+			return nil, nil
+		}
+		return &struct{ RetryAfterMs int64 }{
+			RetryAfterMs: retryAfter.Milliseconds(),
+		}, nil
+	}
+
+	getRequirements := func(userId string, tenantId string, userContext supertokens.UserContext) (mfamodels.MFARequirements, error) {
+		if config.GetRequirements != nil {
+			return config.GetRequirements(userId, tenantId, userContext)
+		}
+		if requirements, ok := config.RequirementsPerTenant[tenantId]; ok {
+			return requirements, nil
+		}
+
+		devices, err := store.ListForUser(userId, userContext)
+		if err != nil {
+			return mfamodels.MFARequirements{}, err
+		}
+		for _, device := range devices {
+			if device.Verified {
+				return mfamodels.MFARequirements{
+					OneOf: []string{mfamodels.FactorIdTOTP, mfamodels.FactorIdRecoveryCode},
+				}, nil
+			}
+		}
+		return mfamodels.MFARequirements{}, nil
+	}
+
+	markFactorAsCompleteInSession := func(sessionContainer sessmodels.SessionContainer, factorId string, userContext supertokens.UserContext) error {
+		payload := sessionContainer.GetAccessTokenPayloadWithContext(userContext)
+		completedFactors := map[string]interface{}{}
+		if value, ok := mfaclaims.MultiFactorAuthClaim.GetValueFromPayload(payload, userContext).(map[string]interface{}); ok {
+			if c, ok := value["c"].(map[string]interface{}); ok {
+				for k, v := range c {
+					completedFactors[k] = v
+				}
+			}
+		}
+		completedFactors[factorId] = time.Now().UnixNano() / 1000000
+
+		// The requirements are read from the instance, so that they can be overridden
+		instance, err := getRecipeInstanceOrThrowError()
+		if err != nil {
+			return err
+		}
+		requirements, err := (*instance.RecipeImpl.GetRequirements)(sessionContainer.GetUserIDWithContext(userContext), sessionContainer.GetTenantIdWithContext(userContext), userContext)
+		if err != nil {
+			return err
+		}
+
+		return sessionContainer.SetClaimValueWithContext(mfaclaims.MultiFactorAuthClaim, getMFAClaimValue(completedFactors, requirements), userContext)
+	}
+
+	createTOTPDevice := func(userId string, deviceName *string, userContext supertokens.UserContext) (mfamodels.CreateTOTPDeviceResponse, error) {
+		devices, err := store.ListForUser(userId, userContext)
+		if err != nil {
+			return mfamodels.CreateTOTPDeviceResponse{}, err
+		}
+
+		name := ""
+		if deviceName != nil {
+			name = *deviceName
+		} else {
+			// The default names are numbered, skipping the ones that are taken
+			names := map[string]bool{}
+			for _, device := range devices {
+				names[device.Name] = true
+			}
+			for i := len(devices) + 1; name == "" || names[name]; i++ {
+				name = fmt.Sprintf("TOTP Device %d", i)
+			}
+		}
+
+		secret, err := generateTOTPSecret()
+		if err != nil {
+			return mfamodels.CreateTOTPDeviceResponse{}, err
+		}
+		device := mfamodels.TOTPDevice{
+			Name:   name,
+			Secret: secret,
+			Period: config.TOTP.Period,
+			Skew:   config.TOTP.Skew,
+		}
+
+		created, err := store.Create(userId, device, userContext)
+		if err != nil {
+			return mfamodels.CreateTOTPDeviceResponse{}, err
+		}
+		if !created {
+			return mfamodels.CreateTOTPDeviceResponse{
+				DeviceAlreadyExistsError: &struct{}{},
+			}, nil
+		}
+
+		accountName, err := config.TOTP.GetAccountName(userId, userContext)
+		if err != nil {
+			return mfamodels.CreateTOTPDeviceResponse{}, err
+		}
+
+		return mfamodels.CreateTOTPDeviceResponse{
+			OK: &struct {
+				DeviceName   string
+				Secret       string
+				QRCodeString string
+			}{
+				DeviceName:   name,
+				Secret:       secret,
+				QRCodeString: getTOTPURI(config.TOTP.Issuer, accountName, device),
+			},
+		}, nil
+	}
+
+	verifyTOTPDevice := func(userId string, deviceName string, totp string, userContext supertokens.UserContext) (mfamodels.VerifyTOTPDeviceResponse, error) {
+		device, err := getDevice(userId, deviceName, userContext)
+		if err != nil {
+			return mfamodels.VerifyTOTPDeviceResponse{}, err
+		}
+		if device == nil {
+			return mfamodels.VerifyTOTPDeviceResponse{
+				UnknownDeviceError: &struct{}{},
+			}, nil
+		}
+
+		now := time.Now()
+		limitReachedError, err := getLimitReachedError(userId, now, userContext)
+		if err != nil {
+			return mfamodels.VerifyTOTPDeviceResponse{}, err
+		}
+		if limitReachedError != nil {
+			return mfamodels.VerifyTOTPDeviceResponse{
+				LimitReachedError: limitReachedError,
+			}, nil
+		}
+
+		wasAlreadyVerified := device.Verified
+		counter, ok := checkTOTP(*device, totp, now)
+		if ok {
+			previousLastUsedCounter := device.LastUsedCounter
+			device.Verified = true
+			device.LastUsedCounter = counter
+			ok, err = store.Update(userId, *device, previousLastUsedCounter, userContext)
+			if err != nil {
+				return mfamodels.VerifyTOTPDeviceResponse{}, err
+			}
+		}
+		if !ok {
+			_, err = store.RecordFailedAttempt(userId, now, userContext)
+			if err != nil {
+				return mfamodels.VerifyTOTPDeviceResponse{}, err
+			}
+			return mfamodels.VerifyTOTPDeviceResponse{
+				InvalidTOTPError: &struct{}{},
+			}, nil
+		}
+
+		err = store.ResetFailedAttempts(userId, userContext)
+		if err != nil {
+			return mfamodels.VerifyTOTPDeviceResponse{}, err
+		}
+
+		return mfamodels.VerifyTOTPDeviceResponse{
+			OK: &struct{ WasAlreadyVerified bool }{
+				WasAlreadyVerified: wasAlreadyVerified,
+			},
+		}, nil
+	}
+
+	verifyTOTP := func(userId string, totp string, userContext supertokens.UserContext) (mfamodels.VerifyTOTPResponse, error) {
+		devices, err := store.ListForUser(userId, userContext)
+		if err != nil {
+			return mfamodels.VerifyTOTPResponse{}, err
+		}
+
+		verifiedDevices := []mfamodels.TOTPDevice{}
+		for _, device := range devices {
+			if device.Verified {
+				verifiedDevices = append(verifiedDevices, device)
+			}
+		}
+		if len(verifiedDevices) == 0 {
+			return mfamodels.VerifyTOTPResponse{
+				UnknownUserIdError: &struct{}{},
+			}, nil
+		}
+
+		now := time.Now()
+		limitReachedError, err := getLimitReachedError(userId, now, userContext)
+		if err != nil {
+			return mfamodels.VerifyTOTPResponse{}, err
+		}
+		if limitReachedError != nil {
+			return mfamodels.VerifyTOTPResponse{
+				LimitReachedError: limitReachedError,
+			}, nil
+		}
+
+		for _, device := range verifiedDevices {
+			counter, ok := checkTOTP(device, totp, now)
+			if !ok {
+				continue
+			}
+			previousLastUsedCounter := device.LastUsedCounter
+			device.LastUsedCounter = counter
+			// The update fails if another request used a code of the device in the meantime
+			updated, err := store.Update(userId, device, previousLastUsedCounter, userContext)
+			if err != nil {
+				return mfamodels.VerifyTOTPResponse{}, err
+			}
+			if updated {
+				err = store.ResetFailedAttempts(userId, userContext)
+				if err != nil {
+					return mfamodels.VerifyTOTPResponse{}, err
+				}
+				return mfamodels.VerifyTOTPResponse{
+					OK: &struct{}{},
+				}, nil
+			}
+		}
+
+		_, err = store.RecordFailedAttempt(userId, now, userContext)
+		if err != nil {
+			return mfamodels.VerifyTOTPResponse{}, err
+		}
+		return mfamodels.VerifyTOTPResponse{
+			InvalidTOTPError: &struct{}{},
+		}, nil
+	}
+
+	listTOTPDevices := func(userId string, userContext supertokens.UserContext) ([]mfamodels.TOTPDeviceInfo, error) {
+		devices, err := store.ListForUser(userId, userContext)
+		if err != nil {
+			return nil, err
+		}
+		result := []mfamodels.TOTPDeviceInfo{}
+		for _, device := range devices {
+			result = append(result, mfamodels.TOTPDeviceInfo{
+				Name:     device.Name,
+				Period:   device.Period,
+				Skew:     device.Skew,
+				Verified: device.Verified,
+			})
+		}
+		return result, nil
+	}
+
+	removeTOTPDevice := func(userId string, deviceName string, userContext supertokens.UserContext) (bool, error) {
+		return store.Delete(userId, deviceName, userContext)
+	}
+
+	recoveryCodeStore := config.RecoveryCodes.Store
+
+	generateRecoveryCodes := func(userId string, userContext supertokens.UserContext) ([]string, error) {
+		codes := []string{}
+		hashedCodes := []string{}
+		for len(codes) < config.RecoveryCodes.NumberOfCodes {
+			code, err := generateRecoveryCode()
+			if err != nil {
+				return nil, err
+			}
+			codes = append(codes, code)
+			hashedCodes = append(hashedCodes, hashRecoveryCode(code))
+		}
+
+		err := recoveryCodeStore.Set(userId, hashedCodes, userContext)
+		if err != nil {
+			return nil, err
+		}
+		return codes, nil
+	}
+
+	verifyRecoveryCode := func(userId string, code string, userContext supertokens.UserContext) (mfamodels.VerifyRecoveryCodeResponse, error) {
+		removed, remainingCodes, err := recoveryCodeStore.Remove(userId, hashRecoveryCode(code), userContext)
+		if err != nil {
+			return mfamodels.VerifyRecoveryCodeResponse{}, err
+		}
+		if !removed {
+			return mfamodels.VerifyRecoveryCodeResponse{
+				InvalidRecoveryCodeError: &struct{}{},
+			}, nil
+		}
+		return mfamodels.VerifyRecoveryCodeResponse{
+			OK: &struct{ RemainingCodes int }{
+				RemainingCodes: remainingCodes,
+			},
+		}, nil
+	}
+
+	getRecoveryCodesCount := func(userId string, userContext supertokens.UserContext) (int, error) {
+		hashedCodes, err := recoveryCodeStore.Get(userId, userContext)
+		if err != nil {
+			return 0, err
+		}
+		return len(hashedCodes), nil
+	}
+
+	return mfamodels.RecipeInterface{
+		GetRequirements:               &getRequirements,
+		MarkFactorAsCompleteInSession: &markFactorAsCompleteInSession,
+		CreateTOTPDevice:              &createTOTPDevice,
+		VerifyTOTPDevice:              &verifyTOTPDevice,
+		VerifyTOTP:                    &verifyTOTP,
+		ListTOTPDevices:               &listTOTPDevices,
+		RemoveTOTPDevice:              &removeTOTPDevice,
+		GenerateRecoveryCodes:         &generateRecoveryCodes,
+		VerifyRecoveryCode:            &verifyRecoveryCode,
+		GetRecoveryCodesCount:         &getRecoveryCodesCount,
+	}
+}
@@ -0,0 +1,125 @@
+/* Copyright (c) 2023, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package multifactorauth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"hash/fnv"
+	"strings"
+	"sync"
+
+	"github.com/supertokens/supertokens-golang/recipe/usermetadata"
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+// recoveryCodeEncoding leaves out the letters and digits that are easy to mix up, like O and 0
+var recoveryCodeEncoding = base32.NewEncoding("ABCDEFGHJKLMNPQRSTUVWXYZ23456789").WithPadding(base32.NoPadding)
+
+// generateRecoveryCode returns a code like ABCDE-FGHJK, which has 50 random bits
+func generateRecoveryCode() (string, error) {
+	randomBytes := make([]byte, 10)
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return "", err
+	}
+	code := recoveryCodeEncoding.EncodeToString(randomBytes)[:10]
+	return code[:5] + "-" + code[5:], nil
+}
+
+// hashRecoveryCode hashes the code ignoring its case, spaces and dashes. A salt is not needed since
+// the codes are random.
+func hashRecoveryCode(code string) string {
+	code = strings.ToUpper(code)
+	code = strings.ReplaceAll(code, "-", "")
+	code = strings.ReplaceAll(code, " ", "")
+	hash := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(hash[:])
+}
+
+const recoveryCodesMetadataKey = "_supertokens_mfa_recovery_codes"
+
+// recoveryCodeLocks makes the changes to the recovery codes of a user in the user metadata happen
+// one at a time, since the metadata can only be read and then updated.
+var recoveryCodeLocks [64]sync.Mutex
+
+func getRecoveryCodeLock(userId string) *sync.Mutex {
+	h := fnv.New32a()
+	h.Write([]byte(userId))
+	return &recoveryCodeLocks[h.Sum32()%uint32(len(recoveryCodeLocks))]
+}
+
+// userMetadataRecoveryCodeStore saves the hashed recovery codes in the user metadata. The locks are
+// only held in this process, so apps that run more than one instance of the backend should use a
+// store that removes the codes atomically.
+type userMetadataRecoveryCodeStore struct{}
+
+func (s userMetadataRecoveryCodeStore) Set(userId string, hashedCodes []string, userContext supertokens.UserContext) error {
+	lock := getRecoveryCodeLock(userId)
+	lock.Lock()
+	defer lock.Unlock()
+	return s.set(userId, hashedCodes, userContext)
+}
+
+func (s userMetadataRecoveryCodeStore) set(userId string, hashedCodes []string, userContext supertokens.UserContext) error {
+	_, err := usermetadata.UpdateUserMetadata(userId, map[string]interface{}{
+		recoveryCodesMetadataKey: hashedCodes,
+	}, userContext)
+	return err
+}
+
+func (s userMetadataRecoveryCodeStore) Get(userId string, userContext supertokens.UserContext) ([]string, error) {
+	metadata, err := usermetadata.GetUserMetadata(userId, userContext)
+	if err != nil {
+		return nil, err
+	}
+	hashedCodes := []string{}
+	if values, ok := metadata[recoveryCodesMetadataKey].([]interface{}); ok {
+		for _, value := range values {
+			if hashedCode, ok := value.(string); ok {
+				hashedCodes = append(hashedCodes, hashedCode)
+			}
+		}
+	}
+	return hashedCodes, nil
+}
+
+func (s userMetadataRecoveryCodeStore) Remove(userId string, hashedCode string, userContext supertokens.UserContext) (bool, int, error) {
+	lock := getRecoveryCodeLock(userId)
+	lock.Lock()
+	defer lock.Unlock()
+
+	hashedCodes, err := s.Get(userId, userContext)
+	if err != nil {
+		return false, 0, err
+	}
+	remainingCodes := []string{}
+	for _, existing := range hashedCodes {
+		if existing != hashedCode {
+			remainingCodes = append(remainingCodes, existing)
+		}
+	}
+	if len(remainingCodes) == len(hashedCodes) {
+		return false, len(hashedCodes), nil
+	}
+	err = s.set(userId, remainingCodes, userContext)
+	if err != nil {
+		return false, 0, err
+	}
+	return true, len(remainingCodes), nil
+}
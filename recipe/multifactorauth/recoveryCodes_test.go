@@ -0,0 +1,213 @@
+/* Copyright (c) 2023, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package multifactorauth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/supertokens/supertokens-golang/recipe/multifactorauth/mfaclaims"
+	"github.com/supertokens/supertokens-golang/recipe/multifactorauth/mfamodels"
+	"github.com/supertokens/supertokens-golang/recipe/session"
+	"github.com/supertokens/supertokens-golang/recipe/usermetadata"
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+func TestGenerateRecoveryCode(t *testing.T) {
+	code, err := generateRecoveryCode()
+	assert.NoError(t, err)
+	assert.Regexp(t, "^[A-HJ-NP-Z2-9]{5}-[A-HJ-NP-Z2-9]{5}$", code)
+
+	otherCode, err := generateRecoveryCode()
+	assert.NoError(t, err)
+	assert.NotEqual(t, code, otherCode)
+
+	// codes are accepted in lower case and without the dash
+	assert.Equal(t, hashRecoveryCode(code), hashRecoveryCode(strings.ToLower(strings.ReplaceAll(code, "-", ""))))
+	assert.NotEqual(t, hashRecoveryCode(code), hashRecoveryCode(otherCode))
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	store := &memoryRecoveryCodeStore{hashedCodes: map[string][]string{}}
+	initForTest(t, &mfamodels.TypeInput{
+		TOTP: mfamodels.TOTPConfig{Store: &memoryTOTPDeviceStore{devices: map[string][]mfamodels.TOTPDevice{}}},
+		RecoveryCodes: &mfamodels.RecoveryCodesConfig{
+			Store:         store,
+			NumberOfCodes: 5,
+		},
+	})
+	defer resetAll()
+
+	count, err := GetRecoveryCodesCount("user-1")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	codes, err := GenerateRecoveryCodes("user-1")
+	assert.NoError(t, err)
+	assert.Equal(t, 5, len(codes))
+	// only the hashes are saved
+	for _, hashedCode := range store.hashedCodes["user-1"] {
+		assert.NotContains(t, codes, hashedCode)
+	}
+
+	response, err := VerifyRecoveryCode("user-1", "AAAAA-AAAAA")
+	assert.NoError(t, err)
+	assert.NotNil(t, response.InvalidRecoveryCodeError)
+
+	response, err = VerifyRecoveryCode("user-1", strings.ToLower(codes[0]))
+	assert.NoError(t, err)
+	assert.Equal(t, 4, response.OK.RemainingCodes)
+
+	// each code can be used once, and only by its user
+	response, err = VerifyRecoveryCode("user-1", codes[0])
+	assert.NoError(t, err)
+	assert.NotNil(t, response.InvalidRecoveryCodeError)
+	response, err = VerifyRecoveryCode("user-2", codes[1])
+	assert.NoError(t, err)
+	assert.NotNil(t, response.InvalidRecoveryCodeError)
+
+	count, err = GetRecoveryCodesCount("user-1")
+	assert.NoError(t, err)
+	assert.Equal(t, 4, count)
+
+	// regenerating the codes replaces the old ones
+	newCodes, err := GenerateRecoveryCodes("user-1")
+	assert.NoError(t, err)
+	response, err = VerifyRecoveryCode("user-1", codes[1])
+	assert.NoError(t, err)
+	assert.NotNil(t, response.InvalidRecoveryCodeError)
+	response, err = VerifyRecoveryCode("user-1", newCodes[1])
+	assert.NoError(t, err)
+	assert.Equal(t, 4, response.OK.RemainingCodes)
+}
+
+func TestRecoveryCodeCompletesTheDefaultRequirements(t *testing.T) {
+	devices := &memoryTOTPDeviceStore{devices: map[string][]mfamodels.TOTPDevice{
+		"user-1": {{Name: "device", Secret: "ABCDEF", Period: 30, Skew: 1, Verified: true}},
+	}}
+	initForTest(t, &mfamodels.TypeInput{
+		TOTP: mfamodels.TOTPConfig{Store: devices},
+		RecoveryCodes: &mfamodels.RecoveryCodesConfig{
+			Store: &memoryRecoveryCodeStore{hashedCodes: map[string][]string{}},
+		},
+	})
+	defer resetAll()
+
+	sessionContainer := makeTestSession("user-1")
+	err := MarkFactorAsCompleteInSession(sessionContainer, mfamodels.FactorIdRecoveryCode)
+	assert.NoError(t, err)
+	payload := sessionContainer.GetAccessTokenPayloadWithContext(nil)
+	assert.True(t, mfaclaims.MultiFactorAuthClaimValidators.HasCompletedRequirements(nil).Validate(payload, nil).IsValid)
+}
+
+func TestDefaultRecoveryCodesConfig(t *testing.T) {
+	initForTest(t, &mfamodels.TypeInput{
+		TOTP: mfamodels.TOTPConfig{Store: &memoryTOTPDeviceStore{devices: map[string][]mfamodels.TOTPDevice{}}},
+	})
+	defer resetAll()
+
+	recipe, err := getRecipeInstanceOrThrowError()
+	assert.NoError(t, err)
+	assert.Equal(t, 10, recipe.Config.RecoveryCodes.NumberOfCodes)
+	assert.IsType(t, userMetadataRecoveryCodeStore{}, recipe.Config.RecoveryCodes.Store)
+}
+
+func TestRecoveryCodesInUserMetadataAreOnlyAcceptedOnce(t *testing.T) {
+	resetAll()
+	defer resetAll()
+
+	var lock sync.Mutex
+	metadata := map[string]interface{}{}
+	coreServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/recipe/user/metadata" {
+			t.Errorf("unexpected request to the core: %s %s", r.Method, r.URL.Path)
+			rw.WriteHeader(404)
+			return
+		}
+		if r.Method == http.MethodGet {
+			// the requests read the metadata at about the same time, as they would if the core was slow
+			time.Sleep(10 * time.Millisecond)
+		} else {
+			var body map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			lock.Lock()
+			for key, value := range body["metadataUpdate"].(map[string]interface{}) {
+				metadata[key] = value
+			}
+			lock.Unlock()
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		json.NewEncoder(rw).Encode(map[string]interface{}{"status": "OK", "metadata": metadata})
+	}))
+	defer coreServer.Close()
+
+	err := supertokens.Init(supertokens.TypeInput{
+		Supertokens: &supertokens.ConnectionInfo{
+			ConnectionURI: coreServer.URL,
+		},
+		AppInfo: supertokens.AppInfo{
+			APIDomain:     "api.supertokens.io",
+			AppName:       "SuperTokens",
+			WebsiteDomain: "supertokens.io",
+		},
+		RecipeList: []supertokens.Recipe{
+			session.Init(nil),
+			usermetadata.Init(nil),
+			Init(&mfamodels.TypeInput{
+				TOTP:          mfamodels.TOTPConfig{Store: &memoryTOTPDeviceStore{devices: map[string][]mfamodels.TOTPDevice{}}},
+				RecoveryCodes: &mfamodels.RecoveryCodesConfig{NumberOfCodes: 3},
+			}),
+		},
+	})
+	assert.NoError(t, err)
+	supertokens.SetQuerierApiVersionForTests("3.0")
+	defer supertokens.SetQuerierApiVersionForTests("")
+
+	codes, err := GenerateRecoveryCodes("user-1")
+	assert.NoError(t, err)
+
+	const requests = 5
+	results := make(chan mfamodels.VerifyRecoveryCodeResponse, requests)
+	for i := 0; i < requests; i++ {
+		go func() {
+			response, err := VerifyRecoveryCode("user-1", codes[0])
+			assert.NoError(t, err)
+			results <- response
+		}()
+	}
+
+	accepted := 0
+	for i := 0; i < requests; i++ {
+		if response := <-results; response.OK != nil {
+			accepted++
+			assert.Equal(t, 2, response.OK.RemainingCodes)
+		} else {
+			assert.NotNil(t, response.InvalidRecoveryCodeError)
+		}
+	}
+	assert.Equal(t, 1, accepted)
+
+	count, err := GetRecoveryCodesCount("user-1")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
@@ -0,0 +1,103 @@
+/* Copyright (c) 2023, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package multifactorauth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/supertokens/supertokens-golang/recipe/multifactorauth/mfamodels"
+)
+
+const totpDigits = 6
+
+var totpSecretEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func generateTOTPSecret() (string, error) {
+	// RFC 4226 recommends a secret of 160 bits
+	secret := make([]byte, 20)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", err
+	}
+	return totpSecretEncoding.EncodeToString(secret), nil
+}
+
+// getTOTPCode returns the code for a time step, as described in RFC 6238 using HMAC-SHA1
+func getTOTPCode(secret []byte, counter int64) string {
+	counterBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(counterBytes, uint64(counter))
+
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(counterBytes)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0xf
+	code := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	modulo := uint32(1)
+	for i := 0; i < totpDigits; i++ {
+		modulo *= 10
+	}
+	return fmt.Sprintf("%0*d", totpDigits, code%modulo)
+}
+
+// checkTOTP returns the time step of the code if it is valid for the device at the given time. Codes
+// of time steps that were already used are rejected.
+func checkTOTP(device mfamodels.TOTPDevice, totp string, now time.Time) (int64, bool) {
+	secret, err := totpSecretEncoding.DecodeString(strings.ToUpper(strings.TrimRight(device.Secret, "=")))
+	if err != nil {
+		return 0, false
+	}
+	totp = strings.ReplaceAll(totp, " ", "")
+	if len(totp) != totpDigits {
+		return 0, false
+	}
+
+	currentCounter := now.Unix() / int64(device.Period)
+	for i := -device.Skew; i <= device.Skew; i++ {
+		counter := currentCounter + int64(i)
+		if counter <= device.LastUsedCounter {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(getTOTPCode(secret, counter)), []byte(totp)) == 1 {
+			return counter, true
+		}
+	}
+	return 0, false
+}
+
+// getTOTPURI returns the otpauth:// URI that authenticator apps read from QR codes
+func getTOTPURI(issuer string, accountName string, device mfamodels.TOTPDevice) string {
+	query := url.Values{}
+	query.Set("secret", device.Secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", strconv.Itoa(totpDigits))
+	query.Set("period", strconv.Itoa(device.Period))
+
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(accountName)
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
@@ -0,0 +1,86 @@
+/* Copyright (c) 2023, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package multifactorauth
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/supertokens/supertokens-golang/recipe/multifactorauth/mfamodels"
+)
+
+func TestTOTPCodesMatchRFC6238(t *testing.T) {
+	// test vectors from RFC 6238 for SHA1, truncated to 6 digits
+	secret := []byte("12345678901234567890")
+	testCases := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for unixTime, expected := range testCases {
+		assert.Equal(t, expected, getTOTPCode(secret, unixTime/30), unixTime)
+	}
+}
+
+func TestCheckTOTP(t *testing.T) {
+	device := mfamodels.TOTPDevice{
+		Name:   "device",
+		Secret: totpSecretEncoding.EncodeToString([]byte("12345678901234567890")),
+		Period: 30,
+		Skew:   1,
+	}
+	now := time.Unix(1111111109, 0)
+
+	counter, ok := checkTOTP(device, "081804", now)
+	assert.True(t, ok)
+	assert.Equal(t, int64(1111111109/30), counter)
+
+	// codes of the previous and the next time steps are accepted, but not older ones
+	_, ok = checkTOTP(device, "081804", now.Add(30*time.Second))
+	assert.True(t, ok)
+	_, ok = checkTOTP(device, "081804", now.Add(-30*time.Second))
+	assert.True(t, ok)
+	_, ok = checkTOTP(device, "081804", now.Add(90*time.Second))
+	assert.False(t, ok)
+
+	_, ok = checkTOTP(device, "000000", now)
+	assert.False(t, ok)
+	_, ok = checkTOTP(device, "08180", now)
+	assert.False(t, ok)
+
+	// a code cannot be used twice
+	device.LastUsedCounter = counter
+	_, ok = checkTOTP(device, "081804", now)
+	assert.False(t, ok)
+}
+
+func TestGetTOTPURI(t *testing.T) {
+	device := mfamodels.TOTPDevice{Secret: "ABCDEF", Period: 30}
+	uri, err := url.Parse(getTOTPURI("My App", "user@example.com", device))
+	assert.NoError(t, err)
+	assert.Equal(t, "otpauth", uri.Scheme)
+	assert.Equal(t, "totp", uri.Host)
+	assert.Equal(t, "/My App:user@example.com", uri.Path)
+	assert.Equal(t, "ABCDEF", uri.Query().Get("secret"))
+	assert.Equal(t, "My App", uri.Query().Get("issuer"))
+	assert.Equal(t, "30", uri.Query().Get("period"))
+	assert.Equal(t, "6", uri.Query().Get("digits"))
+}
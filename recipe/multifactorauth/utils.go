@@ -0,0 +1,113 @@
+/* Copyright (c) 2023, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package multifactorauth
+
+import (
+	"errors"
+
+	"github.com/supertokens/supertokens-golang/recipe/multifactorauth/mfamodels"
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+func validateAndNormaliseUserInput(appInfo supertokens.NormalisedAppinfo, config *mfamodels.TypeInput) (mfamodels.TypeNormalisedInput, error) {
+	typeNormalisedInput := makeTypeNormalisedInput(appInfo)
+
+	if config == nil || config.TOTP.Store == nil {
+		return mfamodels.TypeNormalisedInput{}, errors.New("TOTP.Store is required in the config of the multifactorauth recipe")
+	}
+
+	typeNormalisedInput.TOTP.Store = config.TOTP.Store
+	if config.TOTP.Issuer != nil {
+		typeNormalisedInput.TOTP.Issuer = *config.TOTP.Issuer
+	}
+	if config.TOTP.Period < 0 || config.TOTP.Skew < 0 {
+		return mfamodels.TypeNormalisedInput{}, errors.New("TOTP.Period and TOTP.Skew cannot be negative")
+	}
+	if config.TOTP.Period != 0 {
+		typeNormalisedInput.TOTP.Period = config.TOTP.Period
+	}
+	if config.TOTP.Skew != 0 {
+		typeNormalisedInput.TOTP.Skew = config.TOTP.Skew
+	}
+	if config.TOTP.MaxAttempts < 0 || config.TOTP.CooldownSec < 0 {
+		return mfamodels.TypeNormalisedInput{}, errors.New("TOTP.MaxAttempts and TOTP.CooldownSec cannot be negative")
+	}
+	if config.TOTP.MaxAttempts != 0 {
+		typeNormalisedInput.TOTP.MaxAttempts = config.TOTP.MaxAttempts
+	}
+	if config.TOTP.CooldownSec != 0 {
+		typeNormalisedInput.TOTP.CooldownSec = config.TOTP.CooldownSec
+	}
+	if config.TOTP.GetAccountName != nil {
+		typeNormalisedInput.TOTP.GetAccountName = config.TOTP.GetAccountName
+	}
+
+	if config.RecoveryCodes != nil {
+		if config.RecoveryCodes.Store != nil {
+			typeNormalisedInput.RecoveryCodes.Store = config.RecoveryCodes.Store
+		}
+		if config.RecoveryCodes.NumberOfCodes < 0 {
+			return mfamodels.TypeNormalisedInput{}, errors.New("RecoveryCodes.NumberOfCodes cannot be negative")
+		}
+		if config.RecoveryCodes.NumberOfCodes != 0 {
+			typeNormalisedInput.RecoveryCodes.NumberOfCodes = config.RecoveryCodes.NumberOfCodes
+		}
+	}
+
+	if config.RequirementsPerTenant != nil {
+		typeNormalisedInput.RequirementsPerTenant = config.RequirementsPerTenant
+	}
+	typeNormalisedInput.GetRequirements = config.GetRequirements
+
+	if config.Override != nil {
+		if config.Override.Functions != nil {
+			typeNormalisedInput.Override.Functions = config.Override.Functions
+		}
+		if config.Override.APIs != nil {
+			typeNormalisedInput.Override.APIs = config.Override.APIs
+		}
+	}
+
+	return typeNormalisedInput, nil
+}
+
+func makeTypeNormalisedInput(appInfo supertokens.NormalisedAppinfo) mfamodels.TypeNormalisedInput {
+	return mfamodels.TypeNormalisedInput{
+		TOTP: mfamodels.NormalisedTOTPConfig{
+			Issuer: appInfo.AppName,
+			Period: 30,
+			Skew:   1,
+			GetAccountName: func(userId string, userContext supertokens.UserContext) (string, error) {
+				return userId, nil
+			},
+			MaxAttempts: 5,
+			CooldownSec: 900,
+		},
+		RecoveryCodes: mfamodels.NormalisedRecoveryCodesConfig{
+			Store:         userMetadataRecoveryCodeStore{},
+			NumberOfCodes: 10,
+		},
+		RequirementsPerTenant: map[string]mfamodels.MFARequirements{},
+		Override: mfamodels.OverrideStruct{
+			Functions: func(originalImplementation mfamodels.RecipeInterface) mfamodels.RecipeInterface {
+				return originalImplementation
+			},
+			APIs: func(originalImplementation mfamodels.APIInterface) mfamodels.APIInterface {
+				return originalImplementation
+			},
+		},
+	}
+}
@@ -390,11 +390,60 @@ func MakeAPIImplementation() plessmodels.APIInterface {
 		}, nil
 	}
 
+	reauthenticatePOST := func(userInput *plessmodels.UserInputCodeWithDeviceID, linkCode *string, preAuthSessionID string, sessionContainer sessmodels.SessionContainer, tenantId string, options plessmodels.APIOptions, userContext supertokens.UserContext) (plessmodels.ReauthenticatePOSTResponse, error) {
+		user, err := (*options.RecipeImplementation.GetUserByID)(sessionContainer.GetUserIDWithContext(userContext), userContext)
+		if err != nil {
+			return plessmodels.ReauthenticatePOSTResponse{}, err
+		}
+		sessionTenantId := sessionContainer.GetTenantIdWithContext(userContext)
+
+		// The code must have been sent to the user of the session. Otherwise consuming it would sign
+		// in, or sign up, someone else.
+		device, err := (*options.RecipeImplementation.ListCodesByPreAuthSessionID)(preAuthSessionID, sessionTenantId, userContext)
+		if err != nil {
+			return plessmodels.ReauthenticatePOSTResponse{}, err
+		}
+		if user == nil || device == nil ||
+			!((device.Email != nil && user.Email != nil && *device.Email == *user.Email) ||
+				(device.PhoneNumber != nil && user.PhoneNumber != nil && *device.PhoneNumber == *user.PhoneNumber)) {
+			return plessmodels.ReauthenticatePOSTResponse{
+				RestartFlowError: &struct{}{},
+			}, nil
+		}
+
+		response, err := (*options.RecipeImplementation.ConsumeCode)(userInput, linkCode, preAuthSessionID, sessionTenantId, userContext)
+		if err != nil {
+			return plessmodels.ReauthenticatePOSTResponse{}, err
+		}
+		if response.OK == nil {
+			return plessmodels.ReauthenticatePOSTResponse{
+				IncorrectUserInputCodeError: response.IncorrectUserInputCodeError,
+				ExpiredUserInputCodeError:   response.ExpiredUserInputCodeError,
+				RestartFlowError:            response.RestartFlowError,
+			}, nil
+		}
+		if response.OK.User.ID != user.ID {
+			return plessmodels.ReauthenticatePOSTResponse{
+				RestartFlowError: &struct{}{},
+			}, nil
+		}
+
+		err = session.UpdateLastAuthenticatedAt(sessionContainer, userContext)
+		if err != nil {
+			return plessmodels.ReauthenticatePOSTResponse{}, err
+		}
+
+		return plessmodels.ReauthenticatePOSTResponse{
+			OK: &struct{}{},
+		}, nil
+	}
+
 	return plessmodels.APIInterface{
 		ConsumeCodePOST:      &consumeCodePOST,
 		CreateCodePOST:       &createCodePOST,
 		EmailExistsGET:       &emailExistsGET,
 		PhoneNumberExistsGET: &phoneNumberExistsGET,
 		ResendCodePOST:       &resendCodePOST,
+		ReauthenticatePOST:   &reauthenticatePOST,
 	}
 }
@@ -23,4 +23,5 @@ const (
 	doesPhoneNumberExistAPIOld = "/signup/phonenumber/exists"
 	doesEmailExistAPI          = "/passwordless/email/exists"
 	doesPhoneNumberExistAPI    = "/passwordless/phonenumber/exists"
+	reauthenticateAPI          = "/reauthenticate/code/consume"
 )
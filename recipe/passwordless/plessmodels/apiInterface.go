@@ -42,6 +42,7 @@ type APIInterface struct {
 	ConsumeCodePOST      *func(userInput *UserInputCodeWithDeviceID, linkCode *string, preAuthSessionID string, tenantId string, options APIOptions, userContext supertokens.UserContext) (ConsumeCodePOSTResponse, error)
 	EmailExistsGET       *func(email string, tenantId string, options APIOptions, userContext supertokens.UserContext) (EmailExistsGETResponse, error)
 	PhoneNumberExistsGET *func(phoneNumber string, tenantId string, options APIOptions, userContext supertokens.UserContext) (PhoneNumberExistsGETResponse, error)
+	ReauthenticatePOST   *func(userInput *UserInputCodeWithDeviceID, linkCode *string, preAuthSessionID string, sessionContainer sessmodels.SessionContainer, tenantId string, options APIOptions, userContext supertokens.UserContext) (ReauthenticatePOSTResponse, error)
 }
 
 type ConsumeCodePOSTResponse struct {
@@ -62,6 +63,20 @@ type ConsumeCodePOSTResponse struct {
 	GeneralError     *supertokens.GeneralErrorResponse
 }
 
+type ReauthenticatePOSTResponse struct {
+	OK                          *struct{}
+	IncorrectUserInputCodeError *struct {
+		FailedCodeInputAttemptCount int
+		MaximumCodeInputAttempts    int
+	}
+	ExpiredUserInputCodeError *struct {
+		FailedCodeInputAttemptCount int
+		MaximumCodeInputAttempts    int
+	}
+	RestartFlowError *struct{}
+	GeneralError     *supertokens.GeneralErrorResponse
+}
+
 type ResendCodePOSTResponse struct {
 	OK             *struct{}
 	ResetFlowError *struct{}
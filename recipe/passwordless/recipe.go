@@ -160,6 +160,10 @@ func (r *Recipe) getAPIsHandled() ([]supertokens.APIHandled, error) {
 	if err != nil {
 		return nil, err
 	}
+	reauthenticateAPINormalised, err := supertokens.NewNormalisedURLPath(reauthenticateAPI)
+	if err != nil {
+		return nil, err
+	}
 
 	return []supertokens.APIHandled{{
 		Method:                 http.MethodPost,
@@ -196,6 +200,11 @@ func (r *Recipe) getAPIsHandled() ([]supertokens.APIHandled, error) {
 		PathWithoutAPIBasePath: resendCodeAPINormalised,
 		ID:                     resendCodeAPI,
 		Disabled:               r.APIImpl.ResendCodePOST == nil,
+	}, {
+		Method:                 http.MethodPost,
+		PathWithoutAPIBasePath: reauthenticateAPINormalised,
+		ID:                     reauthenticateAPI,
+		Disabled:               r.APIImpl.ReauthenticatePOST == nil,
 	}}, nil
 }
 
@@ -219,6 +228,8 @@ func (r *Recipe) handleAPIRequest(id string, tenantId string, req *http.Request,
 		return api.DoesEmailExist(r.APIImpl, tenantId, options, userContext)
 	} else if id == doesPhoneNumberExistAPIOld || id == doesPhoneNumberExistAPI {
 		return api.DoesPhoneNumberExist(r.APIImpl, tenantId, options, userContext)
+	} else if id == reauthenticateAPI {
+		return api.Reauthenticate(r.APIImpl, tenantId, options, userContext)
 	} else {
 		return api.ResendCode(r.APIImpl, tenantId, options, userContext)
 	}
@@ -66,16 +66,21 @@ func MakeRecipeImplementation(querier supertokens.Querier) plessmodels.RecipeInt
 		}
 		status := response["status"].(string)
 		if status == "OK" {
+			user := getUserFromJSONResponse(response["user"].(map[string]interface{}))
+			supertokens.LogInfo("passwordless: code consumed", supertokens.RecipeLogFields(RECIPE_ID, tenantId, userContext, supertokens.LogFieldUserID, user.ID, "createdNewUser", response["createdNewUser"])...)
+			supertokens.AddToCounter(userContext, supertokens.SignInUpMetricName(response["createdNewUser"].(bool)), 1, supertokens.RecipeMetricAttributes(RECIPE_ID, tenantId))
 			return plessmodels.ConsumeCodeResponse{
 				OK: &struct {
 					CreatedNewUser bool
 					User           plessmodels.User
 				}{
 					CreatedNewUser: response["createdNewUser"].(bool),
-					User:           getUserFromJSONResponse(response["user"].(map[string]interface{})),
+					User:           user,
 				},
 			}, nil
-		} else if status == "INCORRECT_USER_INPUT_CODE_ERROR" {
+		}
+		supertokens.LogInfo("passwordless: consuming code failed", supertokens.RecipeLogFields(RECIPE_ID, tenantId, userContext, "status", status)...)
+		if status == "INCORRECT_USER_INPUT_CODE_ERROR" {
 			return plessmodels.ConsumeCodeResponse{
 				IncorrectUserInputCodeError: &struct {
 					FailedCodeInputAttemptCount int
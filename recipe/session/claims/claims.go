@@ -49,6 +49,9 @@ type SessionClaimValidator struct {
 	Claim         *TypeSessionClaim
 	ShouldRefetch func(payload map[string]interface{}, userContext supertokens.UserContext) bool
 	Validate      func(payload map[string]interface{}, userContext supertokens.UserContext) ClaimValidationResult
+	// Validators are the validators combined by AllOf, AnyOf and Not. Their claims are refetched
+	// when needed, since a combined validator has no Claim of its own.
+	Validators []SessionClaimValidator
 }
 
 type ClaimValidationResult struct {
@@ -0,0 +1,126 @@
+package claims
+
+import (
+	"strings"
+
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+// AllOf returns a validator that passes if all the validators pass. The reason of a failure lists
+// the validators that did not pass, with their reasons.
+func AllOf(validators ...SessionClaimValidator) SessionClaimValidator {
+	return SessionClaimValidator{
+		ID:            getCombinedValidatorId("allOf", validators),
+		ShouldRefetch: shouldRefetchAnyOf(validators),
+		Validators:    validators,
+		Validate: func(payload map[string]interface{}, userContext supertokens.UserContext) ClaimValidationResult {
+			failures := getValidationFailures(validators, payload, userContext)
+			if len(failures) > 0 {
+				return ClaimValidationResult{
+					IsValid: false,
+					Reason: map[string]interface{}{
+						"message":  "not all validators passed",
+						"failures": failures,
+					},
+				}
+			}
+			return ClaimValidationResult{
+				IsValid: true,
+			}
+		},
+	}
+}
+
+// AnyOf returns a validator that passes if at least one of the validators passes. The reason of a
+// failure lists the reasons of all the validators.
+func AnyOf(validators ...SessionClaimValidator) SessionClaimValidator {
+	return SessionClaimValidator{
+		ID:            getCombinedValidatorId("anyOf", validators),
+		ShouldRefetch: shouldRefetchAnyOf(validators),
+		Validators:    validators,
+		Validate: func(payload map[string]interface{}, userContext supertokens.UserContext) ClaimValidationResult {
+			failures := getValidationFailures(validators, payload, userContext)
+			if len(failures) == len(validators) {
+				return ClaimValidationResult{
+					IsValid: false,
+					Reason: map[string]interface{}{
+						"message":  "none of the validators passed",
+						"failures": failures,
+					},
+				}
+			}
+			return ClaimValidationResult{
+				IsValid: true,
+			}
+		},
+	}
+}
+
+// Not returns a validator that passes if the validator does not pass.
+func Not(validator SessionClaimValidator) SessionClaimValidator {
+	return SessionClaimValidator{
+		ID:            "not(" + validator.ID + ")",
+		ShouldRefetch: shouldRefetchAnyOf([]SessionClaimValidator{validator}),
+		Validators:    []SessionClaimValidator{validator},
+		Validate: func(payload map[string]interface{}, userContext supertokens.UserContext) ClaimValidationResult {
+			if validator.Validate(payload, userContext).IsValid {
+				return ClaimValidationResult{
+					IsValid: false,
+					Reason: map[string]interface{}{
+						"message":     "validator passed",
+						"validatorId": validator.ID,
+					},
+				}
+			}
+			return ClaimValidationResult{
+				IsValid: true,
+			}
+		},
+	}
+}
+
+// GetValidatorsWithClaims returns the validators with a Claim, including the ones combined by AllOf,
+// AnyOf and Not, so that their claims can be refetched.
+func GetValidatorsWithClaims(validators []SessionClaimValidator) []SessionClaimValidator {
+	result := []SessionClaimValidator{}
+	for _, validator := range validators {
+		if validator.Claim != nil {
+			result = append(result, validator)
+		}
+		result = append(result, GetValidatorsWithClaims(validator.Validators)...)
+	}
+	return result
+}
+
+func getCombinedValidatorId(combinator string, validators []SessionClaimValidator) string {
+	ids := []string{}
+	for _, validator := range validators {
+		ids = append(ids, validator.ID)
+	}
+	return combinator + "(" + strings.Join(ids, ",") + ")"
+}
+
+func shouldRefetchAnyOf(validators []SessionClaimValidator) func(payload map[string]interface{}, userContext supertokens.UserContext) bool {
+	return func(payload map[string]interface{}, userContext supertokens.UserContext) bool {
+		for _, validator := range validators {
+			if validator.ShouldRefetch != nil && validator.ShouldRefetch(payload, userContext) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func getValidationFailures(validators []SessionClaimValidator, payload map[string]interface{}, userContext supertokens.UserContext) []ClaimValidationError {
+	failures := []ClaimValidationError{}
+	for _, validator := range validators {
+		result := validator.Validate(payload, userContext)
+		if !result.IsValid {
+			failures = append(failures, ClaimValidationError{
+				ID:     validator.ID,
+				Reason: result.Reason,
+			})
+		}
+	}
+	return failures
+}
@@ -0,0 +1,87 @@
+package claims
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+func TestClaimValidatorCombinators(t *testing.T) {
+	roleClaim, roleValidators := PrimitiveArrayClaim(
+		"role",
+		func(userId string, tenantId string, userContext supertokens.UserContext) (interface{}, error) {
+			return []interface{}{"user"}, nil
+		},
+		nil,
+	)
+	verifiedClaim, verifiedValidators := BooleanClaim(
+		"verified",
+		func(userId string, tenantId string, userContext supertokens.UserContext) (interface{}, error) {
+			return true, nil
+		},
+		nil,
+	)
+
+	payload := map[string]interface{}{}
+	payload = roleClaim.AddToPayload_internal(payload, []interface{}{"user"}, nil)
+	payload = verifiedClaim.AddToPayload_internal(payload, true, nil)
+
+	isAdmin := roleValidators.Includes("admin", nil, nil)
+	isUser := roleValidators.Includes("user", nil, nil)
+	isVerified := verifiedValidators.IsTrue(nil, nil)
+
+	assert.True(t, AllOf(isUser, isVerified).Validate(payload, nil).IsValid)
+	assert.False(t, AllOf(isAdmin, isVerified).Validate(payload, nil).IsValid)
+	assert.True(t, AnyOf(isAdmin, isVerified).Validate(payload, nil).IsValid)
+	assert.False(t, AnyOf(isAdmin, Not(isVerified)).Validate(payload, nil).IsValid)
+	assert.True(t, Not(isAdmin).Validate(payload, nil).IsValid)
+	assert.False(t, Not(isUser).Validate(payload, nil).IsValid)
+
+	validator := AllOf(isVerified, AnyOf(isAdmin, Not(isUser)))
+	assert.Equal(t, "allOf(verified,anyOf(role,not(role)))", validator.ID)
+
+	result := validator.Validate(payload, nil)
+	assert.False(t, result.IsValid)
+	reason := result.Reason.(map[string]interface{})
+	assert.Equal(t, "not all validators passed", reason["message"])
+	failures := reason["failures"].([]ClaimValidationError)
+	assert.Equal(t, 1, len(failures))
+	assert.Equal(t, "anyOf(role,not(role))", failures[0].ID)
+	nestedFailures := failures[0].Reason.(map[string]interface{})["failures"].([]ClaimValidationError)
+	assert.Equal(t, 2, len(nestedFailures))
+	assert.Equal(t, "role", nestedFailures[0].ID)
+	assert.Equal(t, "not(role)", nestedFailures[1].ID)
+}
+
+func TestClaimValidatorCombinatorsRefetch(t *testing.T) {
+	roleClaim, roleValidators := PrimitiveArrayClaim(
+		"role",
+		func(userId string, tenantId string, userContext supertokens.UserContext) (interface{}, error) {
+			return []interface{}{"user"}, nil
+		},
+		nil,
+	)
+	_, verifiedValidators := BooleanClaim(
+		"verified",
+		func(userId string, tenantId string, userContext supertokens.UserContext) (interface{}, error) {
+			return true, nil
+		},
+		nil,
+	)
+
+	payload := map[string]interface{}{}
+	payload = roleClaim.AddToPayload_internal(payload, []interface{}{"user"}, nil)
+
+	isUser := roleValidators.Includes("user", nil, nil)
+	isVerified := verifiedValidators.IsTrue(nil, nil)
+
+	assert.False(t, AllOf(isUser).ShouldRefetch(payload, nil))
+	assert.True(t, AllOf(isUser, isVerified).ShouldRefetch(payload, nil))
+	assert.True(t, AnyOf(isUser, Not(isVerified)).ShouldRefetch(payload, nil))
+
+	validators := GetValidatorsWithClaims([]SessionClaimValidator{AllOf(isUser, AnyOf(Not(isVerified)))})
+	assert.Equal(t, 2, len(validators))
+	assert.Equal(t, "role", validators[0].Claim.Key)
+	assert.Equal(t, "verified", validators[1].Claim.Key)
+}
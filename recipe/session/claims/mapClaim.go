@@ -0,0 +1,93 @@
+package claims
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// MapClaim is a claim with an object as its value, like {"plan": {"name": "pro", "seats": 10}}.
+func MapClaim(key string, fetchValue FetchValueFunc, defaultMaxAgeInSeconds *int64) (*TypeSessionClaim, MapClaimValidators) {
+	// Claim functions are identical to primitive claim, only validators are different
+	sessionClaim, primitiveClaimValidators := PrimitiveClaim(key, fetchValue, defaultMaxAgeInSeconds)
+
+	validators := MapClaimValidators{
+		PrimitiveClaimValidators: primitiveClaimValidators,
+
+		HasValueAtPath: func(path string, val interface{}, maxAgeInSeconds *int64, id *string) SessionClaimValidator {
+			if maxAgeInSeconds == nil {
+				maxAgeInSeconds = defaultMaxAgeInSeconds
+			}
+			return getClaimValidator(sessionClaim, maxAgeInSeconds, id, map[string]interface{}{
+				"path":          path,
+				"expectedValue": val,
+			}, func(claimVal interface{}) bool {
+				valAtPath, ok := getValueAtPath(claimVal, path)
+				return ok && isEqualClaimValue(valAtPath, val)
+			})
+		},
+	}
+
+	return sessionClaim, validators
+}
+
+type MapClaimValidators struct {
+	PrimitiveClaimValidators
+	// HasValueAtPath checks the value at a path like plan.name or $.teams[0].role in the object
+	HasValueAtPath func(path string, val interface{}, maxAgeInSeconds *int64, id *string) SessionClaimValidator
+}
+
+// getValueAtPath returns the value at a path made of keys separated by dots and array indexes in
+// brackets. The path can start with $ for the root, like in JSONPath.
+func getValueAtPath(value interface{}, path string) (interface{}, bool) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	if path == "" {
+		return value, true
+	}
+	for _, part := range strings.Split(path, ".") {
+		key := part
+		indexes := ""
+		if i := strings.Index(part, "["); i != -1 {
+			key = part[:i]
+			indexes = part[i:]
+		}
+		if key != "" {
+			object, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			value, ok = object[key]
+			if !ok {
+				return nil, false
+			}
+		}
+		for indexes != "" {
+			end := strings.Index(indexes, "]")
+			if !strings.HasPrefix(indexes, "[") || end == -1 {
+				return nil, false
+			}
+			index, err := strconv.Atoi(indexes[1:end])
+			if err != nil {
+				return nil, false
+			}
+			array, ok := value.([]interface{})
+			if !ok || index < 0 || index >= len(array) {
+				return nil, false
+			}
+			value = array[index]
+			indexes = indexes[end+1:]
+		}
+	}
+	return value, true
+}
+
+// isEqualClaimValue compares numbers by their value, since numbers in a payload read from a token
+// are float64.
+func isEqualClaimValue(a interface{}, b interface{}) bool {
+	aNumber, aIsNumber := toFloat64(a)
+	bNumber, bIsNumber := toFloat64(b)
+	if aIsNumber && bIsNumber {
+		return aNumber == bNumber
+	}
+	return reflect.DeepEqual(a, b)
+}
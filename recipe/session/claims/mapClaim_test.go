@@ -0,0 +1,50 @@
+package claims
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+func TestMapClaimValidators(t *testing.T) {
+	mapClaim, validators := MapClaim(
+		"test",
+		func(userId string, tenantId string, userContext supertokens.UserContext) (interface{}, error) {
+			return map[string]interface{}{}, nil
+		},
+		nil,
+	)
+
+	payload := map[string]interface{}{}
+	payload = mapClaim.AddToPayload_internal(payload, map[string]interface{}{
+		"plan": map[string]interface{}{
+			"name":  "pro",
+			"seats": float64(10),
+		},
+		"teams": []interface{}{
+			map[string]interface{}{"id": "t1", "role": "admin"},
+			map[string]interface{}{"id": "t2", "role": "member"},
+		},
+		"beta": true,
+	}, nil)
+
+	assert.True(t, validators.HasValueAtPath("plan.name", "pro", nil, nil).Validate(payload, nil).IsValid)
+	assert.True(t, validators.HasValueAtPath("$.plan.seats", 10, nil, nil).Validate(payload, nil).IsValid)
+	assert.True(t, validators.HasValueAtPath("teams[1].role", "member", nil, nil).Validate(payload, nil).IsValid)
+	assert.True(t, validators.HasValueAtPath("beta", true, nil, nil).Validate(payload, nil).IsValid)
+	assert.True(t, validators.HasValueAtPath("plan", map[string]interface{}{"name": "pro", "seats": float64(10)}, nil, nil).Validate(payload, nil).IsValid)
+
+	assert.False(t, validators.HasValueAtPath("plan.name", "free", nil, nil).Validate(payload, nil).IsValid)
+	assert.False(t, validators.HasValueAtPath("plan.missing", nil, nil, nil).Validate(payload, nil).IsValid)
+	assert.False(t, validators.HasValueAtPath("teams[2].role", "admin", nil, nil).Validate(payload, nil).IsValid)
+	assert.False(t, validators.HasValueAtPath("teams.role", "admin", nil, nil).Validate(payload, nil).IsValid)
+	assert.False(t, validators.HasValueAtPath("beta[0]", true, nil, nil).Validate(payload, nil).IsValid)
+
+	result := validators.HasValueAtPath("teams[0].role", "member", nil, nil).Validate(payload, nil)
+	assert.False(t, result.IsValid)
+	reason := result.Reason.(map[string]interface{})
+	assert.Equal(t, "wrong value", reason["message"])
+	assert.Equal(t, "teams[0].role", reason["path"])
+	assert.Equal(t, "member", reason["expectedValue"])
+}
@@ -0,0 +1,54 @@
+package claims
+
+func NumberClaim(key string, fetchValue FetchValueFunc, defaultMaxAgeInSeconds *int64) (*TypeSessionClaim, NumberClaimValidators) {
+	// Claim functions are identical to primitive claim, only validators are different
+	sessionClaim, primitiveClaimValidators := PrimitiveClaim(key, fetchValue, defaultMaxAgeInSeconds)
+
+	getValidator := func(maxAgeInSeconds *int64, id *string, expected map[string]interface{}, isValid func(claimVal float64) bool) SessionClaimValidator {
+		if maxAgeInSeconds == nil {
+			maxAgeInSeconds = defaultMaxAgeInSeconds
+		}
+		return getClaimValidator(sessionClaim, maxAgeInSeconds, id, expected, func(claimVal interface{}) bool {
+			number, ok := toFloat64(claimVal)
+			return ok && isValid(number)
+		})
+	}
+
+	validators := NumberClaimValidators{
+		PrimitiveClaimValidators: primitiveClaimValidators,
+
+		GreaterThan: func(val float64, maxAgeInSeconds *int64, id *string) SessionClaimValidator {
+			return getValidator(maxAgeInSeconds, id, map[string]interface{}{
+				"expectedToBeGreaterThan": val,
+			}, func(claimVal float64) bool {
+				return claimVal > val
+			})
+		},
+
+		LessThan: func(val float64, maxAgeInSeconds *int64, id *string) SessionClaimValidator {
+			return getValidator(maxAgeInSeconds, id, map[string]interface{}{
+				"expectedToBeLessThan": val,
+			}, func(claimVal float64) bool {
+				return claimVal < val
+			})
+		},
+
+		InRange: func(min float64, max float64, maxAgeInSeconds *int64, id *string) SessionClaimValidator {
+			return getValidator(maxAgeInSeconds, id, map[string]interface{}{
+				"expectedToBeInRange": []float64{min, max},
+			}, func(claimVal float64) bool {
+				return claimVal >= min && claimVal <= max
+			})
+		},
+	}
+
+	return sessionClaim, validators
+}
+
+type NumberClaimValidators struct {
+	PrimitiveClaimValidators
+	GreaterThan func(val float64, maxAgeInSeconds *int64, id *string) SessionClaimValidator
+	LessThan    func(val float64, maxAgeInSeconds *int64, id *string) SessionClaimValidator
+	// InRange checks that the value is between min and max, including both
+	InRange func(min float64, max float64, maxAgeInSeconds *int64, id *string) SessionClaimValidator
+}
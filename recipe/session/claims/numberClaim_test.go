@@ -0,0 +1,75 @@
+package claims
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+func TestNumberClaimValidators(t *testing.T) {
+	numberClaim, validators := NumberClaim(
+		"test",
+		func(userId string, tenantId string, userContext supertokens.UserContext) (interface{}, error) {
+			return 5, nil
+		},
+		nil,
+	)
+
+	payload := map[string]interface{}{}
+	payload = numberClaim.AddToPayload_internal(payload, 5, nil)
+
+	assert.True(t, validators.GreaterThan(4, nil, nil).Validate(payload, nil).IsValid)
+	assert.False(t, validators.GreaterThan(5, nil, nil).Validate(payload, nil).IsValid)
+	assert.True(t, validators.LessThan(5.5, nil, nil).Validate(payload, nil).IsValid)
+	assert.False(t, validators.LessThan(5, nil, nil).Validate(payload, nil).IsValid)
+	assert.True(t, validators.InRange(5, 10, nil, nil).Validate(payload, nil).IsValid)
+	assert.False(t, validators.InRange(6, 10, nil, nil).Validate(payload, nil).IsValid)
+
+	// Numbers in a payload read from a token are float64
+	payload = numberClaim.AddToPayload_internal(payload, float64(5), nil)
+	assert.True(t, validators.InRange(0, 5, nil, nil).Validate(payload, nil).IsValid)
+
+	result := validators.GreaterThan(10, nil, nil).Validate(payload, nil)
+	assert.False(t, result.IsValid)
+	assert.Equal(t, map[string]interface{}{
+		"message":                 "wrong value",
+		"expectedToBeGreaterThan": float64(10),
+		"actualValue":             float64(5),
+	}, result.Reason)
+
+	payload = numberClaim.AddToPayload_internal(payload, "5", nil)
+	assert.False(t, validators.GreaterThan(4, nil, nil).Validate(payload, nil).IsValid)
+}
+
+func TestNumberClaimMaxAge(t *testing.T) {
+	numberClaim, validators := NumberClaim(
+		"test",
+		func(userId string, tenantId string, userContext supertokens.UserContext) (interface{}, error) {
+			return 5, nil
+		},
+		nil,
+	)
+
+	validator := validators.GreaterThan(1, nil, nil)
+	assert.Equal(t, "test", validator.ID)
+	assert.True(t, validator.ShouldRefetch(map[string]interface{}{}, nil))
+	result := validator.Validate(map[string]interface{}{}, nil)
+	assert.False(t, result.IsValid)
+	assert.Equal(t, "value does not exist", result.Reason.(map[string]interface{})["message"])
+
+	payload := numberClaim.AddToPayload_internal(map[string]interface{}{}, 5, nil)
+	maxAge := int64(1)
+	id := "custom"
+	validator = validators.GreaterThan(1, &maxAge, &id)
+	assert.Equal(t, "custom", validator.ID)
+	assert.False(t, validator.ShouldRefetch(payload, nil))
+	assert.True(t, validator.Validate(payload, nil).IsValid)
+
+	time.Sleep(2 * time.Second)
+	assert.True(t, validator.ShouldRefetch(payload, nil))
+	result = validator.Validate(payload, nil)
+	assert.False(t, result.IsValid)
+	assert.Equal(t, "expired", result.Reason.(map[string]interface{})["message"])
+}
@@ -0,0 +1,177 @@
+package claims
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ClaimPolicyAtoms maps the names used in a claim policy to the validators they stand for. The value
+// is the part after the colon, like admin in role:admin, or nil if there is no colon.
+type ClaimPolicyAtoms map[string]func(value *string) (SessionClaimValidator, error)
+
+// CompileClaimPolicy compiles an expression like
+//
+//	role:admin || (perm:billing.read && emailVerified)
+//
+// into a validator made with AllOf, AnyOf and Not. The expression can use &&, ||, ! and
+// parentheses, with the usual precedence. Values with spaces or operators can be quoted, like
+// role:"billing admin".
+func CompileClaimPolicy(expression string, atoms ClaimPolicyAtoms) (SessionClaimValidator, error) {
+	parser := &claimPolicyParser{expression: expression, atoms: atoms}
+	validator, err := parser.parseOr()
+	if err != nil {
+		return SessionClaimValidator{}, err
+	}
+	parser.skipSpaces()
+	if parser.pos < len(parser.expression) {
+		return SessionClaimValidator{}, parser.errorf("unexpected %q", parser.expression[parser.pos:])
+	}
+	return validator, nil
+}
+
+type claimPolicyParser struct {
+	expression string
+	pos        int
+	atoms      ClaimPolicyAtoms
+}
+
+func (p *claimPolicyParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("invalid claim policy at position %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *claimPolicyParser) skipSpaces() {
+	for p.pos < len(p.expression) && unicode.IsSpace(rune(p.expression[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *claimPolicyParser) consume(token string) bool {
+	p.skipSpaces()
+	if strings.HasPrefix(p.expression[p.pos:], token) {
+		p.pos += len(token)
+		return true
+	}
+	return false
+}
+
+func (p *claimPolicyParser) parseOr() (SessionClaimValidator, error) {
+	validator, err := p.parseAnd()
+	if err != nil {
+		return SessionClaimValidator{}, err
+	}
+	validators := []SessionClaimValidator{validator}
+	for p.consume("||") {
+		validator, err := p.parseAnd()
+		if err != nil {
+			return SessionClaimValidator{}, err
+		}
+		validators = append(validators, validator)
+	}
+	if len(validators) == 1 {
+		return validators[0], nil
+	}
+	return AnyOf(validators...), nil
+}
+
+func (p *claimPolicyParser) parseAnd() (SessionClaimValidator, error) {
+	validator, err := p.parseUnary()
+	if err != nil {
+		return SessionClaimValidator{}, err
+	}
+	validators := []SessionClaimValidator{validator}
+	for p.consume("&&") {
+		validator, err := p.parseUnary()
+		if err != nil {
+			return SessionClaimValidator{}, err
+		}
+		validators = append(validators, validator)
+	}
+	if len(validators) == 1 {
+		return validators[0], nil
+	}
+	return AllOf(validators...), nil
+}
+
+func (p *claimPolicyParser) parseUnary() (SessionClaimValidator, error) {
+	if p.consume("!") {
+		validator, err := p.parseUnary()
+		if err != nil {
+			return SessionClaimValidator{}, err
+		}
+		return Not(validator), nil
+	}
+	if p.consume("(") {
+		validator, err := p.parseOr()
+		if err != nil {
+			return SessionClaimValidator{}, err
+		}
+		if !p.consume(")") {
+			return SessionClaimValidator{}, p.errorf("expected )")
+		}
+		return validator, nil
+	}
+	return p.parseAtom()
+}
+
+func isClaimPolicyNameChar(c byte) bool {
+	return c == '_' || c == '-' || c == '.' || c == '/' || c == '*' || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func (p *claimPolicyParser) parseAtom() (SessionClaimValidator, error) {
+	p.skipSpaces()
+	start := p.pos
+	for p.pos < len(p.expression) && isClaimPolicyNameChar(p.expression[p.pos]) {
+		p.pos++
+	}
+	name := p.expression[start:p.pos]
+	if name == "" {
+		if p.pos == len(p.expression) {
+			return SessionClaimValidator{}, p.errorf("unexpected end of the expression")
+		}
+		return SessionClaimValidator{}, p.errorf("unexpected %q", p.expression[p.pos:p.pos+1])
+	}
+
+	var value *string
+	if p.pos < len(p.expression) && p.expression[p.pos] == ':' {
+		p.pos++
+		parsedValue, err := p.parseValue()
+		if err != nil {
+			return SessionClaimValidator{}, err
+		}
+		value = &parsedValue
+	}
+
+	atom, ok := p.atoms[name]
+	if !ok {
+		p.pos = start
+		return SessionClaimValidator{}, p.errorf("unknown name %s", name)
+	}
+	validator, err := atom(value)
+	if err != nil {
+		p.pos = start
+		return SessionClaimValidator{}, p.errorf("%s: %s", name, err.Error())
+	}
+	return validator, nil
+}
+
+func (p *claimPolicyParser) parseValue() (string, error) {
+	if p.pos < len(p.expression) && p.expression[p.pos] == '"' {
+		end := strings.IndexByte(p.expression[p.pos+1:], '"')
+		if end == -1 {
+			return "", p.errorf("the quoted value is not closed")
+		}
+		value := p.expression[p.pos+1 : p.pos+1+end]
+		p.pos += end + 2
+		return value, nil
+	}
+
+	start := p.pos
+	for p.pos < len(p.expression) && (isClaimPolicyNameChar(p.expression[p.pos]) || p.expression[p.pos] == ':') {
+		p.pos++
+	}
+	if p.pos == start {
+		return "", p.errorf("expected a value after the colon")
+	}
+	return p.expression[start:p.pos], nil
+}
@@ -0,0 +1,102 @@
+package claims
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+func TestClaimPolicy(t *testing.T) {
+	roleClaim, roleValidators := PrimitiveArrayClaim(
+		"role",
+		func(userId string, tenantId string, userContext supertokens.UserContext) (interface{}, error) {
+			return []interface{}{}, nil
+		},
+		nil,
+	)
+	permClaim, permValidators := PrimitiveArrayClaim(
+		"perm",
+		func(userId string, tenantId string, userContext supertokens.UserContext) (interface{}, error) {
+			return []interface{}{}, nil
+		},
+		nil,
+	)
+	verifiedClaim, verifiedValidators := BooleanClaim(
+		"emailVerified",
+		func(userId string, tenantId string, userContext supertokens.UserContext) (interface{}, error) {
+			return true, nil
+		},
+		nil,
+	)
+
+	atoms := ClaimPolicyAtoms{
+		"role": func(value *string) (SessionClaimValidator, error) {
+			if value == nil {
+				return SessionClaimValidator{}, errors.New("a role is required")
+			}
+			return roleValidators.Includes(*value, nil, nil), nil
+		},
+		"perm": func(value *string) (SessionClaimValidator, error) {
+			if value == nil {
+				return SessionClaimValidator{}, errors.New("a permission is required")
+			}
+			return permValidators.Includes(*value, nil, nil), nil
+		},
+		"emailVerified": func(value *string) (SessionClaimValidator, error) {
+			return verifiedValidators.IsTrue(nil, nil), nil
+		},
+	}
+
+	validator, err := CompileClaimPolicy(`role:admin || (perm:billing.read && emailVerified)`, atoms)
+	assert.NoError(t, err)
+	assert.Equal(t, "anyOf(role,allOf(perm,emailVerified))", validator.ID)
+
+	getPayload := func(roles []interface{}, perms []interface{}, verified bool) map[string]interface{} {
+		payload := map[string]interface{}{}
+		payload = roleClaim.AddToPayload_internal(payload, roles, nil)
+		payload = permClaim.AddToPayload_internal(payload, perms, nil)
+		return verifiedClaim.AddToPayload_internal(payload, verified, nil)
+	}
+
+	assert.True(t, validator.Validate(getPayload([]interface{}{"admin"}, []interface{}{}, false), nil).IsValid)
+	assert.True(t, validator.Validate(getPayload([]interface{}{}, []interface{}{"billing.read"}, true), nil).IsValid)
+	assert.False(t, validator.Validate(getPayload([]interface{}{}, []interface{}{"billing.read"}, false), nil).IsValid)
+	assert.False(t, validator.Validate(getPayload([]interface{}{"user"}, []interface{}{}, true), nil).IsValid)
+
+	validator, err = CompileClaimPolicy(`!role:"billing admin" && !(perm:a || perm:b)`, atoms)
+	assert.NoError(t, err)
+	assert.True(t, validator.Validate(getPayload([]interface{}{"admin"}, []interface{}{"c"}, true), nil).IsValid)
+	assert.False(t, validator.Validate(getPayload([]interface{}{"billing admin"}, []interface{}{}, true), nil).IsValid)
+	assert.False(t, validator.Validate(getPayload([]interface{}{}, []interface{}{"b"}, true), nil).IsValid)
+}
+
+func TestClaimPolicyErrors(t *testing.T) {
+	atoms := ClaimPolicyAtoms{
+		"role": func(value *string) (SessionClaimValidator, error) {
+			if value == nil {
+				return SessionClaimValidator{}, errors.New("a role is required")
+			}
+			return SessionClaimValidator{ID: "role"}, nil
+		},
+	}
+
+	_, err := CompileClaimPolicy(`role:admin || group:x`, atoms)
+	assert.EqualError(t, err, "invalid claim policy at position 14: unknown name group")
+
+	_, err = CompileClaimPolicy(`role`, atoms)
+	assert.EqualError(t, err, "invalid claim policy at position 0: role: a role is required")
+
+	_, err = CompileClaimPolicy(`(role:admin`, atoms)
+	assert.EqualError(t, err, "invalid claim policy at position 11: expected )")
+
+	_, err = CompileClaimPolicy(`role:admin &&`, atoms)
+	assert.EqualError(t, err, "invalid claim policy at position 13: unexpected end of the expression")
+
+	_, err = CompileClaimPolicy(`role:admin role:user`, atoms)
+	assert.EqualError(t, err, `invalid claim policy at position 11: unexpected "role:user"`)
+
+	_, err = CompileClaimPolicy(`role:"admin`, atoms)
+	assert.EqualError(t, err, "invalid claim policy at position 5: the quoted value is not closed")
+}
@@ -0,0 +1,52 @@
+package claims
+
+import (
+	"time"
+)
+
+// TimestampClaim is a claim with a time in milliseconds since the epoch as its value, like the time
+// the user last entered their password.
+func TimestampClaim(key string, fetchValue FetchValueFunc, defaultMaxAgeInSeconds *int64) (*TypeSessionClaim, TimestampClaimValidators) {
+	// Claim functions are identical to primitive claim, only validators are different
+	sessionClaim, primitiveClaimValidators := PrimitiveClaim(key, fetchValue, defaultMaxAgeInSeconds)
+
+	getValidator := func(maxAgeInSeconds *int64, id *string, expected map[string]interface{}, isValid func(claimVal int64) bool) SessionClaimValidator {
+		if maxAgeInSeconds == nil {
+			maxAgeInSeconds = defaultMaxAgeInSeconds
+		}
+		return getClaimValidator(sessionClaim, maxAgeInSeconds, id, expected, func(claimVal interface{}) bool {
+			timestamp, ok := toFloat64(claimVal)
+			return ok && isValid(int64(timestamp))
+		})
+	}
+
+	validators := TimestampClaimValidators{
+		PrimitiveClaimValidators: primitiveClaimValidators,
+
+		NotOlderThan: func(seconds int64, maxAgeInSeconds *int64, id *string) SessionClaimValidator {
+			return getValidator(maxAgeInSeconds, id, map[string]interface{}{
+				"expectedToBeNotOlderThanSeconds": seconds,
+			}, func(claimVal int64) bool {
+				return claimVal >= time.Now().UnixNano()/1000000-seconds*1000
+			})
+		},
+
+		After: func(timestamp int64, maxAgeInSeconds *int64, id *string) SessionClaimValidator {
+			return getValidator(maxAgeInSeconds, id, map[string]interface{}{
+				"expectedToBeAfter": timestamp,
+			}, func(claimVal int64) bool {
+				return claimVal > timestamp
+			})
+		},
+	}
+
+	return sessionClaim, validators
+}
+
+type TimestampClaimValidators struct {
+	PrimitiveClaimValidators
+	// NotOlderThan checks that the value is at most seconds before now
+	NotOlderThan func(seconds int64, maxAgeInSeconds *int64, id *string) SessionClaimValidator
+	// After checks that the value is after timestamp, in milliseconds since the epoch
+	After func(timestamp int64, maxAgeInSeconds *int64, id *string) SessionClaimValidator
+}
@@ -0,0 +1,40 @@
+package claims
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+func TestTimestampClaimValidators(t *testing.T) {
+	timestampClaim, validators := TimestampClaim(
+		"test",
+		func(userId string, tenantId string, userContext supertokens.UserContext) (interface{}, error) {
+			return time.Now().UnixNano() / 1000000, nil
+		},
+		nil,
+	)
+
+	now := time.Now().UnixNano() / 1000000
+	payload := map[string]interface{}{}
+	payload = timestampClaim.AddToPayload_internal(payload, now-10*60*1000, nil)
+
+	assert.True(t, validators.NotOlderThan(15*60, nil, nil).Validate(payload, nil).IsValid)
+	assert.False(t, validators.NotOlderThan(5*60, nil, nil).Validate(payload, nil).IsValid)
+	assert.True(t, validators.After(now-11*60*1000, nil, nil).Validate(payload, nil).IsValid)
+	assert.False(t, validators.After(now-9*60*1000, nil, nil).Validate(payload, nil).IsValid)
+
+	// Numbers in a payload read from a token are float64
+	payload = timestampClaim.AddToPayload_internal(payload, float64(now), nil)
+	assert.True(t, validators.NotOlderThan(60, nil, nil).Validate(payload, nil).IsValid)
+
+	result := validators.After(now, nil, nil).Validate(payload, nil)
+	assert.False(t, result.IsValid)
+	assert.Equal(t, map[string]interface{}{
+		"message":           "wrong value",
+		"expectedToBeAfter": now,
+		"actualValue":       float64(now),
+	}, result.Reason)
+}
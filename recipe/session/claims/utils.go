@@ -1,5 +1,12 @@
 package claims
 
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
 func includes(s []interface{}, e interface{}) bool {
 	for _, a := range s {
 		if a == e {
@@ -34,3 +41,103 @@ func excludesAll(s []interface{}, e []interface{}) bool {
 	}
 	return true
 }
+
+func toFloat64(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// getClaimValidator makes a validator with the refetch and maxAgeInSeconds checks of the primitive
+// claims. expected is added to the reason of a failure, along with the actual value.
+func getClaimValidator(sessionClaim *TypeSessionClaim, maxAgeInSeconds *int64, id *string, expected map[string]interface{}, isValid func(claimVal interface{}) bool) SessionClaimValidator {
+	validatorId := sessionClaim.Key
+	if id != nil {
+		validatorId = *id
+	}
+
+	getReason := func(message string, claimVal interface{}) map[string]interface{} {
+		reason := map[string]interface{}{
+			"message":     message,
+			"actualValue": claimVal,
+		}
+		for k, v := range expected {
+			reason[k] = v
+		}
+		return reason
+	}
+
+	return SessionClaimValidator{
+		ID:    validatorId,
+		Claim: sessionClaim,
+		ShouldRefetch: func(payload map[string]interface{}, userContext supertokens.UserContext) bool {
+			if sessionClaim.GetValueFromPayload(payload, userContext) == nil {
+				return true
+			}
+			lastRefetchTime := sessionClaim.GetLastRefetchTime(payload, userContext)
+			return maxAgeInSeconds != nil && (lastRefetchTime == nil || *lastRefetchTime < time.Now().UnixNano()/1000000-*maxAgeInSeconds*1000)
+		},
+		Validate: func(payload map[string]interface{}, userContext supertokens.UserContext) ClaimValidationResult {
+			claimVal := sessionClaim.GetValueFromPayload(payload, userContext)
+			if claimVal == nil {
+				return ClaimValidationResult{
+					IsValid: false,
+					Reason:  getReason("value does not exist", claimVal),
+				}
+			}
+			if maxAgeInSeconds != nil {
+				lastRefetchTime := sessionClaim.GetLastRefetchTime(payload, userContext)
+				ageInSeconds := int64(0)
+				if lastRefetchTime != nil {
+					ageInSeconds = (time.Now().UnixNano()/1000000 - *lastRefetchTime) / 1000
+				}
+				if lastRefetchTime == nil || ageInSeconds > *maxAgeInSeconds {
+					return ClaimValidationResult{
+						IsValid: false,
+						Reason: map[string]interface{}{
+							"message":         "expired",
+							"ageInSeconds":    ageInSeconds,
+							"maxAgeInSeconds": *maxAgeInSeconds,
+						},
+					}
+				}
+			}
+			if !isValid(claimVal) {
+				return ClaimValidationResult{
+					IsValid: false,
+					Reason:  getReason("wrong value", claimVal),
+				}
+			}
+			return ClaimValidationResult{
+				IsValid: true,
+			}
+		},
+	}
+}
@@ -0,0 +1,181 @@
+package session
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/supertokens/supertokens-golang/recipe/session/claims"
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+type testSpanKey struct{}
+
+type testSpan struct {
+	name       string
+	parent     *testSpan
+	attributes map[string]interface{}
+	errors     []error
+	ended      bool
+}
+
+func (s *testSpan) SetAttributes(attributes map[string]interface{}) {
+	for k, v := range attributes {
+		s.attributes[k] = v
+	}
+}
+
+func (s *testSpan) RecordError(err error) {
+	s.errors = append(s.errors, err)
+}
+
+func (s *testSpan) End() {
+	s.ended = true
+}
+
+type testMeasurement struct {
+	name       string
+	value      float64
+	attributes map[string]interface{}
+}
+
+type testInstrumentation struct {
+	lock      sync.Mutex
+	spans     []*testSpan
+	counters  []testMeasurement
+	durations []testMeasurement
+}
+
+func (i *testInstrumentation) StartSpan(ctx context.Context, name string, attributes map[string]interface{}) (context.Context, supertokens.Span) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	parent, _ := ctx.Value(testSpanKey{}).(*testSpan)
+	span := &testSpan{name: name, parent: parent, attributes: map[string]interface{}{}}
+	span.SetAttributes(attributes)
+	i.spans = append(i.spans, span)
+	return context.WithValue(ctx, testSpanKey{}, span), span
+}
+
+func (i *testInstrumentation) AddToCounter(ctx context.Context, name string, value int64, attributes map[string]interface{}) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	i.counters = append(i.counters, testMeasurement{name: name, value: float64(value), attributes: attributes})
+}
+
+func (i *testInstrumentation) RecordDuration(ctx context.Context, name string, durationMS float64, attributes map[string]interface{}) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	i.durations = append(i.durations, testMeasurement{name: name, value: durationMS, attributes: attributes})
+}
+
+func (i *testInstrumentation) findSpan(name string) *testSpan {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	for _, span := range i.spans {
+		if span.name == name {
+			return span
+		}
+	}
+	return nil
+}
+
+func (i *testInstrumentation) findMeasurement(measurements []testMeasurement, name string) *testMeasurement {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	for _, measurement := range measurements {
+		if measurement.name == name {
+			return &measurement
+		}
+	}
+	return nil
+}
+
+func TestInstrumentationReceivesSpansAndMetrics(t *testing.T) {
+	resetAll()
+	defer resetAll()
+
+	coreMux := http.NewServeMux()
+	coreMux.HandleFunc("/testing", func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(200)
+	})
+	coreServer := httptest.NewServer(coreMux)
+	defer coreServer.Close()
+
+	instrumentation := &testInstrumentation{}
+	configValue := supertokens.TypeInput{
+		Supertokens: &supertokens.ConnectionInfo{
+			// We need the querier to call the test server and not the core
+			ConnectionURI: coreServer.URL,
+		},
+		AppInfo: supertokens.AppInfo{
+			AppName:       "SuperTokens",
+			APIDomain:     "api.supertokens.io",
+			WebsiteDomain: "supertokens.io",
+		},
+		RecipeList: []supertokens.Recipe{
+			Init(nil),
+		},
+		Instrumentation: instrumentation,
+	}
+
+	err := supertokens.Init(configValue)
+	assert.NoError(t, err)
+
+	q, err := supertokens.GetNewQuerierInstanceOrThrowError("")
+	assert.NoError(t, err)
+	supertokens.SetQuerierApiVersionForTests("3.0")
+	defer resetQuerier()
+
+	userContext := supertokens.MakeDefaultUserContextFromAPI(nil)
+	parent, end := supertokens.StartSpan(userContext, "parent", nil)
+	_, err = q.SendGetRequest("/testing", map[string]string{}, userContext)
+	assert.NoError(t, err)
+	end()
+
+	coreSpan := instrumentation.findSpan(supertokens.SpanNameCoreRequest)
+	assert.NotNil(t, coreSpan)
+	assert.True(t, coreSpan.ended)
+	assert.Equal(t, parent, coreSpan.parent)
+	assert.Equal(t, "/testing", coreSpan.attributes[supertokens.AttributeCorePath])
+	assert.Equal(t, 200, coreSpan.attributes[supertokens.AttributeHTTPStatusCode])
+	assert.NotNil(t, instrumentation.findMeasurement(instrumentation.durations, supertokens.MetricCoreRequestDuration))
+
+	// the span is no longer the parent of the spans started with the userContext once it has ended
+	assert.Equal(t, context.Background(), supertokens.GetContextFromUserContext(userContext))
+
+	apiServer := httptest.NewServer(supertokens.Middleware(http.NewServeMux()))
+	defer apiServer.Close()
+
+	res, err := http.Post(apiServer.URL+"/auth/session/refresh", "application/json", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 401, res.StatusCode)
+
+	apiSpan := instrumentation.findSpan(supertokens.SpanNameAPI)
+	assert.NotNil(t, apiSpan)
+	assert.True(t, apiSpan.ended)
+	assert.Nil(t, apiSpan.parent)
+	assert.Equal(t, RefreshAPIPath, apiSpan.attributes[supertokens.AttributeAPIID])
+	assert.Equal(t, "session", apiSpan.attributes[supertokens.AttributeRecipeID])
+	assert.Equal(t, "public", apiSpan.attributes[supertokens.AttributeTenantID])
+	assert.Equal(t, 1, len(apiSpan.errors))
+	assert.NotNil(t, instrumentation.findMeasurement(instrumentation.durations, supertokens.MetricAPIDuration))
+
+	validationErrors := ValidateClaimsInPayload([]claims.SessionClaimValidator{
+		{
+			ID: "test-claim",
+			Validate: func(payload map[string]interface{}, userContext supertokens.UserContext) claims.ClaimValidationResult {
+				return claims.ClaimValidationResult{IsValid: false}
+			},
+		},
+	}, map[string]interface{}{}, userContext)
+	assert.Equal(t, 1, len(validationErrors))
+
+	counter := instrumentation.findMeasurement(instrumentation.counters, supertokens.MetricClaimValidationFailures)
+	assert.NotNil(t, counter)
+	assert.Equal(t, float64(1), counter.value)
+	assert.Equal(t, "test-claim", counter.attributes[supertokens.AttributeClaimID])
+	assert.Equal(t, "session", counter.attributes[supertokens.AttributeRecipeID])
+}
@@ -0,0 +1,164 @@
+/* Copyright (c) 2021, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package session
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/MicahParks/keyfunc/v2"
+	"github.com/supertokens/supertokens-golang/recipe/session/sessmodels"
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+const (
+	defaultJWKSSourceReloadIntervalSec = 60
+	unknownKIDReloadIntervalMS         = 1000
+)
+
+// LocalJWKS holds the keys loaded from a sessmodels.JWKSSource, and reloads them when the source
+// changes. It is safe for concurrent use.
+type LocalJWKS struct {
+	source           sessmodels.JWKSSource
+	reloadIntervalMS int64
+
+	lock         sync.Mutex
+	jwks         *keyfunc.JWKS
+	raw          []byte
+	fileModTime  time.Time
+	lastChecked  int64
+	lastKIDCheck int64
+}
+
+func validateJWKSSource(source *sessmodels.JWKSSource) error {
+	sourcesSet := 0
+	if source.FilePath != "" {
+		sourcesSet++
+	}
+	if source.JSON != nil {
+		sourcesSet++
+	}
+	if source.GetJWKS != nil {
+		sourcesSet++
+	}
+	if sourcesSet != 1 {
+		return errors.New("exactly one of FilePath, JSON or GetJWKS must be set in JWKSSource")
+	}
+	if source.JSON != nil {
+		if _, err := keyfunc.NewJSON(source.JSON); err != nil {
+			return errors.New("JWKSSource.JSON is not a valid JWKS: " + err.Error())
+		}
+	}
+	return nil
+}
+
+// NewLocalJWKS validates the source and returns the LocalJWKS for it. The keys are loaded the first
+// time they are needed.
+func NewLocalJWKS(source sessmodels.JWKSSource) (*LocalJWKS, error) {
+	if err := validateJWKSSource(&source); err != nil {
+		return nil, err
+	}
+	var reloadIntervalSec uint64 = defaultJWKSSourceReloadIntervalSec
+	if source.ReloadIntervalSec != nil {
+		reloadIntervalSec = *source.ReloadIntervalSec
+	}
+	return &LocalJWKS{
+		source:           source,
+		reloadIntervalMS: int64(reloadIntervalSec * 1000),
+	}, nil
+}
+
+// GetJWKS returns the current keys, reloading them first if the reload interval has passed, or if
+// kid is not one of the known keys. If reloading fails, the previously loaded keys are returned.
+func (l *LocalJWKS) GetJWKS(kid *string) (*keyfunc.JWKS, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	shouldReload := l.jwks == nil || now-l.lastChecked >= l.reloadIntervalMS
+	if !shouldReload && kid != nil && !l.hasKID(*kid) && now-l.lastKIDCheck >= unknownKIDReloadIntervalMS {
+		l.lastKIDCheck = now
+		shouldReload = true
+	}
+
+	if shouldReload {
+		err := l.reload()
+		l.lastChecked = now
+		if err != nil {
+			if l.jwks == nil {
+				return nil, err
+			}
+			supertokens.LogWarn("session: could not reload the JWKS, using the previously loaded keys", supertokens.LogFieldError, err.Error())
+		}
+	}
+
+	return l.jwks, nil
+}
+
+func (l *LocalJWKS) hasKID(kid string) bool {
+	for _, knownKID := range l.jwks.KIDs() {
+		if knownKID == kid {
+			return true
+		}
+	}
+	return false
+}
+
+// reload must be called with the lock held
+func (l *LocalJWKS) reload() error {
+	var raw []byte
+	if l.source.JSON != nil {
+		if l.jwks != nil {
+			return nil
+		}
+		raw = l.source.JSON
+	} else if l.source.FilePath != "" {
+		info, err := os.Stat(l.source.FilePath)
+		if err != nil {
+			return err
+		}
+		if l.jwks != nil && info.ModTime().Equal(l.fileModTime) {
+			return nil
+		}
+		raw, err = os.ReadFile(l.source.FilePath)
+		if err != nil {
+			return err
+		}
+		l.fileModTime = info.ModTime()
+	} else {
+		var err error
+		raw, err = l.source.GetJWKS()
+		if err != nil {
+			return err
+		}
+	}
+
+	if l.jwks != nil && bytes.Equal(raw, l.raw) {
+		return nil
+	}
+
+	jwks, err := keyfunc.NewJSON(raw)
+	if err != nil {
+		return err
+	}
+	l.jwks = jwks
+	l.raw = raw
+	supertokens.LogDebug("session: loaded JWKS from the configured source", "kids", jwks.KIDs())
+	return nil
+}
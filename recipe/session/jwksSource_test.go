@@ -0,0 +1,256 @@
+package session
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/supertokens/supertokens-golang/recipe/session/errors"
+	"github.com/supertokens/supertokens-golang/recipe/session/sessmodels"
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+type testSigningKey struct {
+	kid        string
+	privateKey *rsa.PrivateKey
+}
+
+func newTestSigningKey(t *testing.T, kid string) testSigningKey {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	return testSigningKey{kid: kid, privateKey: privateKey}
+}
+
+func makeTestJWKS(t *testing.T, keys ...testSigningKey) []byte {
+	jwks := []map[string]interface{}{}
+	for _, key := range keys {
+		jwks = append(jwks, map[string]interface{}{
+			"kty": "RSA",
+			"kid": key.kid,
+			"alg": "RS256",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.privateKey.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.privateKey.E)).Bytes()),
+		})
+	}
+	result, err := json.Marshal(map[string]interface{}{"keys": jwks})
+	assert.NoError(t, err)
+	return result
+}
+
+func makeTestAccessToken(t *testing.T, key testSigningKey, userId string) string {
+	return makeTestAccessTokenWithPayload(t, key, userId, map[string]interface{}{"custom": "value"})
+}
+
+func makeTestAccessTokenWithPayload(t *testing.T, key testSigningKey, userId string, payload map[string]interface{}) string {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"sub":               userId,
+		"tId":               "public",
+		"sessionHandle":     "test-session-handle",
+		"refreshTokenHash1": "test-refresh-token-hash",
+		// set in the tokens created by a refresh
+		"parentRefreshTokenHash1": "test-parent-refresh-token-hash",
+		"iat":                     now.Unix(),
+		"exp":                     now.Add(time.Hour).Unix(),
+	}
+	for k, v := range payload {
+		claims[k] = v
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = key.kid
+	token.Header["version"] = "4"
+	signed, err := token.SignedString(key.privateKey)
+	assert.NoError(t, err)
+	return signed
+}
+
+func initWithJWKSSourceForTest(t *testing.T, source *sessmodels.JWKSSource) func() {
+	resetAll()
+
+	coreServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request to the core: %s", r.URL.Path)
+		rw.WriteHeader(500)
+	}))
+
+	False := false
+	err := supertokens.Init(supertokens.TypeInput{
+		Supertokens: &supertokens.ConnectionInfo{
+			ConnectionURI: coreServer.URL,
+		},
+		AppInfo: supertokens.AppInfo{
+			AppName:       "SuperTokens",
+			APIDomain:     "api.supertokens.io",
+			WebsiteDomain: "supertokens.io",
+		},
+		RecipeList: []supertokens.Recipe{
+			Init(&sessmodels.TypeInput{
+				UseDynamicAccessTokenSigningKey: &False,
+				JWKSSource:                      source,
+			}),
+		},
+	})
+	assert.NoError(t, err)
+	supertokens.SetQuerierApiVersionForTests("3.0")
+
+	return func() {
+		coreServer.Close()
+		resetQuerier()
+		resetAll()
+	}
+}
+
+func TestGetSessionWithJWKSFromBytesDoesNotCallCore(t *testing.T) {
+	key := newTestSigningKey(t, "s-key-1")
+	cleanup := initWithJWKSSourceForTest(t, &sessmodels.JWKSSource{JSON: makeTestJWKS(t, key), VerifyOnly: true})
+	defer cleanup()
+
+	False := false
+	sessionContainer, err := GetSessionWithoutRequestResponse(makeTestAccessToken(t, key, "user-1"), nil, &sessmodels.VerifySessionOptions{AntiCsrfCheck: &False})
+	assert.NoError(t, err)
+	assert.Equal(t, "user-1", sessionContainer.GetUserID())
+	assert.Equal(t, "public", sessionContainer.GetTenantId())
+	assert.Equal(t, "test-session-handle", sessionContainer.GetHandle())
+	assert.Equal(t, "value", sessionContainer.GetAccessTokenPayload()["custom"])
+	assert.False(t, didGetSessionCallCore)
+
+	otherKey := newTestSigningKey(t, "s-key-2")
+	_, err = GetSessionWithoutRequestResponse(makeTestAccessToken(t, otherKey, "user-1"), nil, &sessmodels.VerifySessionOptions{AntiCsrfCheck: &False})
+	assert.ErrorAs(t, err, &errors.TryRefreshTokenError{})
+}
+
+func TestGetSessionWithJWKSChecksRefreshedTokensWithTheCoreUnlessVerifyOnly(t *testing.T) {
+	resetAll()
+	defer resetAll()
+
+	key := newTestSigningKey(t, "s-key-1")
+	verifyRequests := 0
+	coreServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/recipe/session/verify" {
+			t.Errorf("unexpected request to the core: %s", r.URL.Path)
+			rw.WriteHeader(500)
+			return
+		}
+		verifyRequests++
+		json.NewEncoder(rw).Encode(map[string]interface{}{
+			"status": "OK",
+			"session": map[string]interface{}{
+				"handle":        "test-session-handle",
+				"userId":        "user-1",
+				"userDataInJWT": map[string]interface{}{},
+				"tenantId":      "public",
+			},
+		})
+	}))
+	defer coreServer.Close()
+
+	False := false
+	err := supertokens.Init(supertokens.TypeInput{
+		Supertokens: &supertokens.ConnectionInfo{
+			ConnectionURI: coreServer.URL,
+		},
+		AppInfo: supertokens.AppInfo{
+			AppName:       "SuperTokens",
+			APIDomain:     "api.supertokens.io",
+			WebsiteDomain: "supertokens.io",
+		},
+		RecipeList: []supertokens.Recipe{
+			Init(&sessmodels.TypeInput{
+				UseDynamicAccessTokenSigningKey: &False,
+				JWKSSource:                      &sessmodels.JWKSSource{JSON: makeTestJWKS(t, key)},
+			}),
+		},
+	})
+	assert.NoError(t, err)
+	supertokens.SetQuerierApiVersionForTests("3.0")
+	defer resetQuerier()
+
+	// the token has a parent refresh token, which the core removes when it first sees the token
+	sessionContainer, err := GetSessionWithoutRequestResponse(makeTestAccessToken(t, key, "user-1"), nil, &sessmodels.VerifySessionOptions{AntiCsrfCheck: &False})
+	assert.NoError(t, err)
+	assert.Equal(t, "user-1", sessionContainer.GetUserID())
+	assert.Equal(t, 1, verifyRequests)
+}
+
+func TestGetSessionWithJWKSFileReloadsWhenFileChanges(t *testing.T) {
+	key1 := newTestSigningKey(t, "s-key-1")
+	key2 := newTestSigningKey(t, "s-key-2")
+
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	assert.NoError(t, os.WriteFile(jwksFile, makeTestJWKS(t, key1), 0600))
+
+	cleanup := initWithJWKSSourceForTest(t, &sessmodels.JWKSSource{FilePath: jwksFile, VerifyOnly: true})
+	defer cleanup()
+
+	False := false
+	options := &sessmodels.VerifySessionOptions{AntiCsrfCheck: &False}
+	_, err := GetSessionWithoutRequestResponse(makeTestAccessToken(t, key1, "user-1"), nil, options)
+	assert.NoError(t, err)
+
+	// key2 is rotated in, and key1 is removed
+	assert.NoError(t, os.WriteFile(jwksFile, makeTestJWKS(t, key2), 0600))
+	assert.NoError(t, os.Chtimes(jwksFile, time.Now(), time.Now().Add(time.Minute)))
+
+	// tokens signed with an unknown kid trigger a reload
+	sessionContainer, err := GetSessionWithoutRequestResponse(makeTestAccessToken(t, key2, "user-2"), nil, options)
+	assert.NoError(t, err)
+	assert.Equal(t, "user-2", sessionContainer.GetUserID())
+
+	// but tokens signed with a removed key are only rejected after the reload interval, since
+	// they do not trigger a reload
+	instance, err := getRecipeInstanceOrThrowError()
+	assert.NoError(t, err)
+	instance.localJWKS.lastChecked = 0
+	_, err = GetSessionWithoutRequestResponse(makeTestAccessToken(t, key1, "user-1"), nil, options)
+	assert.ErrorAs(t, err, &errors.TryRefreshTokenError{})
+}
+
+func TestGetSessionWithJWKSCallbackKeepsOldKeysIfReloadFails(t *testing.T) {
+	key := newTestSigningKey(t, "s-key-1")
+	jwks := makeTestJWKS(t, key)
+	calls := 0
+	var reloadIntervalSec uint64 = 0
+
+	cleanup := initWithJWKSSourceForTest(t, &sessmodels.JWKSSource{
+		GetJWKS: func() ([]byte, error) {
+			calls++
+			if calls > 1 {
+				return []byte("not a jwks"), nil
+			}
+			return jwks, nil
+		},
+		ReloadIntervalSec: &reloadIntervalSec,
+		VerifyOnly:        true,
+	})
+	defer cleanup()
+
+	False := false
+	options := &sessmodels.VerifySessionOptions{AntiCsrfCheck: &False}
+	_, err := GetSessionWithoutRequestResponse(makeTestAccessToken(t, key, "user-1"), nil, options)
+	assert.NoError(t, err)
+	_, err = GetSessionWithoutRequestResponse(makeTestAccessToken(t, key, "user-1"), nil, options)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+}
+
+func TestJWKSSourceValidation(t *testing.T) {
+	_, err := ValidateAndNormaliseUserInput(supertokens.NormalisedAppinfo{}, &sessmodels.TypeInput{
+		JWKSSource: &sessmodels.JWKSSource{},
+	})
+	assert.EqualError(t, err, "exactly one of FilePath, JSON or GetJWKS must be set in JWKSSource")
+
+	_, err = ValidateAndNormaliseUserInput(supertokens.NormalisedAppinfo{}, &sessmodels.TypeInput{
+		JWKSSource: &sessmodels.JWKSSource{JSON: []byte("{")},
+	})
+	assert.Error(t, err)
+}
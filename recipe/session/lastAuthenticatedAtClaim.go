@@ -0,0 +1,105 @@
+/* Copyright (c) 2023, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package session
+
+import (
+	"time"
+
+	"github.com/supertokens/supertokens-golang/recipe/session/claims"
+	"github.com/supertokens/supertokens-golang/recipe/session/sessionclaims"
+	"github.com/supertokens/supertokens-golang/recipe/session/sessmodels"
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+func init() {
+	// automatically called when this package is imported
+	sessionclaims.LastAuthenticatedAtClaim, sessionclaims.LastAuthenticatedAtClaimValidators = NewLastAuthenticatedAtClaim()
+}
+
+func NewLastAuthenticatedAtClaim() (*claims.TypeSessionClaim, sessionclaims.TypeLastAuthenticatedAtClaimValidators) {
+	// The value is only fetched when a session is created, which is when the user signs in
+	fetchValue := func(userId string, tenantId string, userContext supertokens.UserContext) (interface{}, error) {
+		return time.Now().UnixNano() / 1000000, nil
+	}
+
+	lastAuthenticatedAtClaim, timestampClaimValidators := claims.TimestampClaim("st-last-auth", fetchValue, nil)
+
+	validators := sessionclaims.TypeLastAuthenticatedAtClaimValidators{
+		AuthenticatedWithin: func(maxAgeInSeconds int64, id *string) claims.SessionClaimValidator {
+			validator := timestampClaimValidators.NotOlderThan(maxAgeInSeconds, nil, id)
+			return claims.SessionClaimValidator{
+				ID:    validator.ID,
+				Claim: validator.Claim,
+				ShouldRefetch: func(payload map[string]interface{}, userContext supertokens.UserContext) bool {
+					// Refetching would set the value to now, so the user has to re-authenticate instead
+					return false
+				},
+				Validate: func(payload map[string]interface{}, userContext supertokens.UserContext) claims.ClaimValidationResult {
+					if validator.Validate(payload, userContext).IsValid {
+						return claims.ClaimValidationResult{
+							IsValid: true,
+						}
+					}
+					return claims.ClaimValidationResult{
+						IsValid: false,
+						Reason: map[string]interface{}{
+							"message":             "REAUTHENTICATION_REQUIRED",
+							"lastAuthenticatedAt": lastAuthenticatedAtClaim.GetValueFromPayload(payload, userContext),
+							"maxAgeInSeconds":     maxAgeInSeconds,
+						},
+					}
+				},
+			}
+		},
+	}
+
+	return lastAuthenticatedAtClaim, validators
+}
+
+// UpdateLastAuthenticatedAt sets the LastAuthenticatedAtClaim of the session to now. It should only
+// be called after the user re-entered their credentials, like in the reauthenticate APIs of the
+// emailpassword and passwordless recipes.
+func UpdateLastAuthenticatedAt(sessionContainer sessmodels.SessionContainer, userContext ...supertokens.UserContext) error {
+	if len(userContext) == 0 {
+		userContext = append(userContext, &map[string]interface{}{})
+	}
+	return sessionContainer.SetClaimValueWithContext(sessionclaims.LastAuthenticatedAtClaim, time.Now().UnixNano()/1000000, userContext[0])
+}
+
+// RemoveLastAuthenticatedAtClaimValidators returns the validators without the ones that check the
+// LastAuthenticatedAtClaim, including those combined with other validators. The reauthenticate APIs
+// use it, since they are called by users whose session failed that check.
+func RemoveLastAuthenticatedAtClaimValidators(validators []claims.SessionClaimValidator) []claims.SessionClaimValidator {
+	result := []claims.SessionClaimValidator{}
+	for _, validator := range validators {
+		if !checksLastAuthenticatedAt(validator) {
+			result = append(result, validator)
+		}
+	}
+	return result
+}
+
+func checksLastAuthenticatedAt(validator claims.SessionClaimValidator) bool {
+	if validator.Claim != nil && validator.Claim.Key == sessionclaims.LastAuthenticatedAtClaim.Key {
+		return true
+	}
+	for _, combined := range validator.Validators {
+		if checksLastAuthenticatedAt(combined) {
+			return true
+		}
+	}
+	return false
+}
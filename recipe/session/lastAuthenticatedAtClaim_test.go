@@ -0,0 +1,152 @@
+package session
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/supertokens/supertokens-golang/recipe/session/claims"
+	"github.com/supertokens/supertokens-golang/recipe/session/sessionclaims"
+	"github.com/supertokens/supertokens-golang/recipe/session/sessmodels"
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+func TestLastAuthenticatedAtClaimValidator(t *testing.T) {
+	claim, validators := NewLastAuthenticatedAtClaim()
+
+	payload := claim.AddToPayload_internal(map[string]interface{}{}, time.Now().UnixNano()/1000000-10*60*1000, nil)
+
+	assert.True(t, validators.AuthenticatedWithin(15*60, nil).Validate(payload, nil).IsValid)
+
+	validator := validators.AuthenticatedWithin(5*60, nil)
+	assert.Equal(t, "st-last-auth", validator.ID)
+	assert.False(t, validator.ShouldRefetch(payload, nil))
+	result := validator.Validate(payload, nil)
+	assert.False(t, result.IsValid)
+	reason := result.Reason.(map[string]interface{})
+	assert.Equal(t, "REAUTHENTICATION_REQUIRED", reason["message"])
+	assert.Equal(t, int64(5*60), reason["maxAgeInSeconds"])
+
+	// Sessions created without the claim are never refetched, so the user has to re-authenticate
+	assert.False(t, validator.ShouldRefetch(map[string]interface{}{}, nil))
+	result = validator.Validate(map[string]interface{}{}, nil)
+	assert.False(t, result.IsValid)
+	assert.Equal(t, "REAUTHENTICATION_REQUIRED", result.Reason.(map[string]interface{})["message"])
+}
+
+func TestRemoveLastAuthenticatedAtClaimValidators(t *testing.T) {
+	_, otherValidators := claims.BooleanClaim("other", nil, nil)
+	other := otherValidators.IsTrue(nil, nil)
+	recent := sessionclaims.LastAuthenticatedAtClaimValidators.AuthenticatedWithin(5*60, nil)
+
+	validators := RemoveLastAuthenticatedAtClaimValidators([]claims.SessionClaimValidator{other, recent, claims.AnyOf(other, recent)})
+	assert.Equal(t, 1, len(validators))
+	assert.Equal(t, "other", validators[0].ID)
+}
+
+func TestLastAuthenticatedAtClaimIsAddedWhenSessionIsCreated(t *testing.T) {
+	for _, skip := range []bool{false, true} {
+		resetAll()
+
+		key := newTestSigningKey(t, "s-key")
+		core := &fakeSessionCore{t: t, key: key, sessionData: map[string]map[string]interface{}{}}
+		var accessTokenPayload map[string]interface{}
+		coreServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPost && r.URL.Path == "/public/recipe/session" {
+				body, _ := io.ReadAll(r.Body)
+				var input map[string]interface{}
+				json.Unmarshal(body, &input)
+				accessTokenPayload = input["userDataInJWT"].(map[string]interface{})
+				r.Body = io.NopCloser(bytes.NewReader(body))
+			}
+			core.ServeHTTP(rw, r)
+		}))
+
+		False := false
+		err := supertokens.Init(supertokens.TypeInput{
+			Supertokens: &supertokens.ConnectionInfo{
+				ConnectionURI: coreServer.URL,
+			},
+			AppInfo: supertokens.AppInfo{
+				AppName:       "SuperTokens",
+				APIDomain:     "api.supertokens.io",
+				WebsiteDomain: "supertokens.io",
+			},
+			RecipeList: []supertokens.Recipe{
+				Init(&sessmodels.TypeInput{
+					UseDynamicAccessTokenSigningKey:            &False,
+					JWKSSource:                                 &sessmodels.JWKSSource{JSON: makeTestJWKS(t, key)},
+					SkipAddingLastAuthenticatedAtToAccessToken: skip,
+				}),
+			},
+		})
+		assert.NoError(t, err)
+		supertokens.SetQuerierApiVersionForTests("3.0")
+
+		_, err = CreateNewSession(httptest.NewRequest(http.MethodPost, "/signin", nil), httptest.NewRecorder(), "public", "user-1", nil, nil)
+		assert.NoError(t, err)
+
+		value := sessionclaims.LastAuthenticatedAtClaim.GetValueFromPayload(accessTokenPayload, nil)
+		if skip {
+			assert.Nil(t, value)
+		} else {
+			assert.InDelta(t, time.Now().UnixNano()/1000000, value, 5000)
+		}
+
+		coreServer.Close()
+		resetQuerier()
+	}
+	resetAll()
+}
+
+func TestLastAuthenticatedAtClaimIsAddedWithoutRequestResponse(t *testing.T) {
+	resetAll()
+	defer resetAll()
+
+	key := newTestSigningKey(t, "s-key")
+	core := &fakeSessionCore{t: t, key: key, sessionData: map[string]map[string]interface{}{}}
+	var accessTokenPayload map[string]interface{}
+	coreServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && r.URL.Path == "/public/recipe/session" {
+			body, _ := io.ReadAll(r.Body)
+			var input map[string]interface{}
+			json.Unmarshal(body, &input)
+			accessTokenPayload = input["userDataInJWT"].(map[string]interface{})
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		core.ServeHTTP(rw, r)
+	}))
+	defer coreServer.Close()
+
+	False := false
+	err := supertokens.Init(supertokens.TypeInput{
+		Supertokens: &supertokens.ConnectionInfo{
+			ConnectionURI: coreServer.URL,
+		},
+		AppInfo: supertokens.AppInfo{
+			AppName:       "SuperTokens",
+			APIDomain:     "api.supertokens.io",
+			WebsiteDomain: "supertokens.io",
+		},
+		RecipeList: []supertokens.Recipe{
+			Init(&sessmodels.TypeInput{
+				UseDynamicAccessTokenSigningKey: &False,
+				JWKSSource:                      &sessmodels.JWKSSource{JSON: makeTestJWKS(t, key)},
+			}),
+		},
+	})
+	assert.NoError(t, err)
+	supertokens.SetQuerierApiVersionForTests("3.0")
+	defer resetQuerier()
+
+	// the claims are added even if no payload is passed
+	_, err = CreateNewSessionWithoutRequestResponse("public", "user-1", nil, nil, nil)
+	assert.NoError(t, err)
+	value := sessionclaims.LastAuthenticatedAtClaim.GetValueFromPayload(accessTokenPayload, nil)
+	assert.InDelta(t, time.Now().UnixNano()/1000000, value, 5000)
+}
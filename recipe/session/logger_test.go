@@ -3,6 +3,8 @@ package session
 import (
 	"bytes"
 	"log"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
 
@@ -165,3 +167,100 @@ func TestLogDebugMessageWithEnvVar(t *testing.T) {
 	supertokens.LogDebugMessage(logMessage)
 	assert.Contains(t, buf.String(), logMessage, "checking log message in logs")
 }
+
+type testLogEntry struct {
+	level     supertokens.LogLevel
+	message   string
+	keyValues map[string]interface{}
+}
+
+type testLogger struct {
+	entries []testLogEntry
+}
+
+func (l *testLogger) Enabled(level supertokens.LogLevel) bool {
+	return true
+}
+
+func (l *testLogger) Log(level supertokens.LogLevel, message string, keyValues ...interface{}) {
+	fields := map[string]interface{}{}
+	for i := 0; i+1 < len(keyValues); i += 2 {
+		fields[keyValues[i].(string)] = keyValues[i+1]
+	}
+	l.entries = append(l.entries, testLogEntry{level: level, message: message, keyValues: fields})
+}
+
+func (l *testLogger) find(message string) *testLogEntry {
+	for _, entry := range l.entries {
+		if entry.message == message {
+			return &entry
+		}
+	}
+	return nil
+}
+
+func TestStructuredLoggerReceivesCoreRequestAndAPILogs(t *testing.T) {
+	resetAll()
+	defer resetAll()
+
+	coreMux := http.NewServeMux()
+	coreMux.HandleFunc("/testing", func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(200)
+	})
+	coreServer := httptest.NewServer(coreMux)
+	defer coreServer.Close()
+
+	logger := &testLogger{}
+	configValue := supertokens.TypeInput{
+		Supertokens: &supertokens.ConnectionInfo{
+			// We need the querier to call the test server and not the core
+			ConnectionURI: coreServer.URL,
+		},
+		AppInfo: supertokens.AppInfo{
+			AppName:       "SuperTokens",
+			APIDomain:     "api.supertokens.io",
+			WebsiteDomain: "supertokens.io",
+		},
+		RecipeList: []supertokens.Recipe{
+			Init(nil),
+		},
+		Logger: logger,
+	}
+
+	err := supertokens.Init(configValue)
+	assert.NoError(t, err)
+
+	q, err := supertokens.GetNewQuerierInstanceOrThrowError("")
+	assert.NoError(t, err)
+	supertokens.SetQuerierApiVersionForTests("3.0")
+	defer resetQuerier()
+
+	_, err = q.SendGetRequest("/testing", map[string]string{}, nil)
+	assert.NoError(t, err)
+
+	entry := logger.find("querier: request to core completed")
+	assert.NotNil(t, entry)
+	assert.Equal(t, supertokens.LogLevelDebug, entry.level)
+	assert.Equal(t, "/testing", entry.keyValues[supertokens.LogFieldCorePath])
+	assert.Equal(t, "GET", entry.keyValues[supertokens.LogFieldMethod])
+	assert.Equal(t, 200, entry.keyValues[supertokens.LogFieldStatusCode])
+	assert.Contains(t, entry.keyValues, supertokens.LogFieldLatencyMS)
+
+	apiServer := httptest.NewServer(supertokens.Middleware(http.NewServeMux()))
+	defer apiServer.Close()
+
+	req, err := http.NewRequest(http.MethodPost, apiServer.URL+"/auth/session/refresh", nil)
+	assert.NoError(t, err)
+	req.Header.Set("X-Request-Id", "test-request-id")
+	res, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	assert.Equal(t, 401, res.StatusCode)
+
+	entry = logger.find("middleware: API returned an error")
+	assert.NotNil(t, entry)
+	assert.Equal(t, supertokens.LogLevelInfo, entry.level)
+	assert.Equal(t, "test-request-id", entry.keyValues[supertokens.LogFieldRequestID])
+	assert.Equal(t, "session", entry.keyValues[supertokens.LogFieldRecipeID])
+	assert.Equal(t, "public", entry.keyValues[supertokens.LogFieldTenantID])
+	assert.Equal(t, RefreshAPIPath, entry.keyValues[supertokens.LogFieldAPIID])
+}
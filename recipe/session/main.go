@@ -81,7 +81,7 @@ func CreateNewSessionWithoutRequestResponse(tenantId string, userID string, acce
 		_disableAntiCSRF = *disableAntiCSRF
 	}
 
-	return (*instance.RecipeImpl.CreateNewSession)(userID, accessTokenPayload, sessionDataInDatabase, &_disableAntiCSRF, tenantId, userContext[0])
+	return (*instance.RecipeImpl.CreateNewSession)(userID, finalAccessTokenPayload, sessionDataInDatabase, &_disableAntiCSRF, tenantId, userContext[0])
 }
 
 func GetSession(req *http.Request, res http.ResponseWriter, options *sessmodels.VerifySessionOptions, userContext ...supertokens.UserContext) (sessmodels.SessionContainer, error) {
@@ -217,6 +217,37 @@ func GetAllSessionHandlesForUser(userID string, tenantId *string, userContext ..
 	return (*instance.RecipeImpl.GetAllSessionHandlesForUser)(userID, *tenantId, fetchAcrossAllTenants, userContext[0])
 }
 
+// ListSessionsForUser returns the information of all the sessions of the user, including the
+// device they were created from if SessionMetadata is enabled in the config. Like in
+// GetAllSessionHandlesForUser, the sessions across all tenants are returned if tenantId is nil.
+func ListSessionsForUser(userID string, tenantId *string, userContext ...supertokens.UserContext) ([]sessmodels.SessionInformation, error) {
+	instance, err := getRecipeInstanceOrThrowError()
+	if err != nil {
+		return nil, err
+	}
+	if len(userContext) == 0 {
+		userContext = append(userContext, &map[string]interface{}{})
+	}
+
+	sessionHandles, err := GetAllSessionHandlesForUser(userID, tenantId, userContext[0])
+	if err != nil {
+		return nil, err
+	}
+
+	result := []sessmodels.SessionInformation{}
+	for _, sessionHandle := range sessionHandles {
+		sessionInfo, err := (*instance.RecipeImpl.GetSessionInformation)(sessionHandle, userContext[0])
+		if err != nil {
+			return nil, err
+		}
+		// the session may have expired or been revoked since the handles were fetched
+		if sessionInfo != nil {
+			result = append(result, *sessionInfo)
+		}
+	}
+	return result, nil
+}
+
 func RevokeSession(sessionHandle string, userContext ...supertokens.UserContext) (bool, error) {
 	instance, err := getRecipeInstanceOrThrowError()
 	if err != nil {
@@ -0,0 +1,202 @@
+/* Copyright (c) 2021, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+func initWithCoreHostsForTest(t *testing.T, connectionURI string, hostHealth *supertokens.CoreHostHealthConfig) *supertokens.Querier {
+	config := supertokens.TypeInput{
+		Supertokens: &supertokens.ConnectionInfo{
+			// We need the querier to call the test servers and not the core
+			ConnectionURI: connectionURI,
+			HostHealth:    hostHealth,
+		},
+		AppInfo: supertokens.AppInfo{
+			AppName:       "SuperTokens",
+			WebsiteDomain: "supertokens.io",
+			APIDomain:     "api.supertokens.io",
+		},
+		RecipeList: []supertokens.Recipe{
+			Init(nil),
+		},
+	}
+
+	err := supertokens.Init(config)
+	assert.NoError(t, err)
+
+	q, err := supertokens.GetNewQuerierInstanceOrThrowError("")
+	assert.NoError(t, err)
+	supertokens.SetQuerierApiVersionForTests("3.0")
+	return q
+}
+
+func TestThatFailingCoreIsEjected(t *testing.T) {
+	resetAll()
+	defer resetQuerier()
+
+	badCalls := 0
+	badServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		badCalls++
+		rw.WriteHeader(500)
+	}))
+	defer badServer.Close()
+
+	goodCalls := 0
+	goodServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		goodCalls++
+		rw.WriteHeader(200)
+	}))
+	defer goodServer.Close()
+
+	q := initWithCoreHostsForTest(t, badServer.URL+";"+goodServer.URL, &supertokens.CoreHostHealthConfig{
+		FailureThreshold: 2,
+		CooldownMS:       60000,
+	})
+
+	for i := 0; i < 10; i++ {
+		q.SendPostRequest("/testing", map[string]interface{}{}, nil)
+	}
+
+	assert.Equal(t, 2, badCalls)
+	assert.Equal(t, 8, goodCalls)
+
+	states := supertokens.GetQuerierHostStates()
+	assert.Equal(t, 2, len(states))
+	assert.Equal(t, supertokens.CoreHostCircuitOpen, states[0].CircuitState)
+	assert.Equal(t, 2, states[0].ConsecutiveFailures)
+	assert.Equal(t, "status code: 500", states[0].LastError)
+	assert.Equal(t, supertokens.CoreHostCircuitClosed, states[1].CircuitState)
+	assert.Equal(t, uint64(8), states[1].TotalRequests)
+}
+
+func TestThatEjectedCoreIsProbedAfterCooldown(t *testing.T) {
+	resetAll()
+	defer resetQuerier()
+
+	healthy := false
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		calls++
+		if healthy {
+			rw.WriteHeader(200)
+		} else {
+			rw.WriteHeader(500)
+		}
+	}))
+	defer server.Close()
+
+	otherCalls := 0
+	otherServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		otherCalls++
+		rw.WriteHeader(200)
+	}))
+	defer otherServer.Close()
+
+	q := initWithCoreHostsForTest(t, server.URL+";"+otherServer.URL, &supertokens.CoreHostHealthConfig{
+		FailureThreshold: 1,
+		CooldownMS:       200,
+	})
+
+	_, err := q.SendPostRequest("/testing", map[string]interface{}{}, nil)
+	assert.Error(t, err)
+	assert.Equal(t, supertokens.CoreHostCircuitOpen, supertokens.GetQuerierHostStates()[0].CircuitState)
+
+	for i := 0; i < 4; i++ {
+		_, err = q.SendPostRequest("/testing", map[string]interface{}{}, nil)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, 4, otherCalls)
+
+	time.Sleep(300 * time.Millisecond)
+	assert.Equal(t, supertokens.CoreHostCircuitHalfOpen, supertokens.GetQuerierHostStates()[0].CircuitState)
+
+	healthy = true
+	_, err = q.SendPostRequest("/testing", map[string]interface{}{}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, supertokens.CoreHostCircuitClosed, supertokens.GetQuerierHostStates()[0].CircuitState)
+}
+
+func TestThatSingleEjectedCoreIsStillQueried(t *testing.T) {
+	resetAll()
+	defer resetQuerier()
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		calls++
+		rw.WriteHeader(500)
+	}))
+	defer server.Close()
+
+	q := initWithCoreHostsForTest(t, server.URL, &supertokens.CoreHostHealthConfig{
+		FailureThreshold: 1,
+		CooldownMS:       60000,
+	})
+
+	for i := 0; i < 3; i++ {
+		_, err := q.SendPostRequest("/testing", map[string]interface{}{}, nil)
+		assert.Contains(t, err.Error(), "status code: 500")
+	}
+	assert.Equal(t, 3, calls)
+}
+
+func TestThatFailedGETRequestsAreRetriedWithBackoff(t *testing.T) {
+	resetAll()
+	defer resetQuerier()
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			rw.WriteHeader(503)
+		} else {
+			rw.WriteHeader(200)
+		}
+	}))
+	defer server.Close()
+
+	q := initWithCoreHostsForTest(t, server.URL, &supertokens.CoreHostHealthConfig{
+		DisableCircuitBreaker: true,
+		MaxGETRetries:         2,
+		RetryBaseDelayMS:      10,
+		RetryMaxDelayMS:       20,
+	})
+
+	_, err := q.SendGetRequest("/testing", map[string]string{}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+
+	// POST requests are not idempotent and hence are not retried
+	calls = 0
+	_, err = q.SendPostRequest("/testing", map[string]interface{}{}, nil)
+	assert.Contains(t, err.Error(), "status code: 503")
+	assert.Equal(t, 1, calls)
+
+	// the retries are bounded by MaxGETRetries
+	calls = -10
+	_, err = q.SendGetRequest("/testing2", map[string]string{}, nil)
+	assert.Contains(t, err.Error(), "status code: 503")
+	assert.Equal(t, -7, calls)
+}
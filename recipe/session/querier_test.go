@@ -1,6 +1,9 @@
 package session
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"net/http"
@@ -8,6 +11,7 @@ import (
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/supertokens/supertokens-golang/supertokens"
@@ -284,3 +288,412 @@ func TestThatParallelCallsHaveIndependentRetryCounters(t *testing.T) {
 	assert.Equal(t, numberOfTimesFirstCalled, 6)
 	assert.Equal(t, numberOfTimesSecondCalled, 6)
 }
+
+func TestThatCoreCallIsAbortedWhenContextDeadlineIsExceeded(t *testing.T) {
+	resetAll()
+	mux := http.NewServeMux()
+
+	unblock := make(chan struct{})
+	mux.HandleFunc("/slow", func(rw http.ResponseWriter, r *http.Request) {
+		select {
+		case <-unblock:
+		case <-r.Context().Done():
+		}
+		rw.WriteHeader(200)
+	})
+
+	testServer := httptest.NewServer(mux)
+
+	defer func() {
+		close(unblock)
+		testServer.Close()
+	}()
+
+	config := supertokens.TypeInput{
+		Supertokens: &supertokens.ConnectionInfo{
+			// We need the querier to call the test server and not the core
+			ConnectionURI: testServer.URL,
+		},
+		AppInfo: supertokens.AppInfo{
+			AppName:       "SuperTokens",
+			WebsiteDomain: "supertokens.io",
+			APIDomain:     "api.supertokens.io",
+		},
+		RecipeList: []supertokens.Recipe{
+			Init(nil),
+		},
+	}
+
+	err := supertokens.Init(config)
+	assert.NoError(t, err)
+
+	q, err := supertokens.GetNewQuerierInstanceOrThrowError("")
+	assert.NoError(t, err)
+	supertokens.SetQuerierApiVersionForTests("3.0")
+	defer resetQuerier()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = q.SendGetRequest("/slow", map[string]string{}, supertokens.MakeUserContextFromContext(ctx))
+	assert.Error(t, err)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestThatCoreIsNotQueriedWithAnAlreadyCancelledContext(t *testing.T) {
+	resetAll()
+	mux := http.NewServeMux()
+
+	numberOfTimesCalled := 0
+	mux.HandleFunc("/testing", func(rw http.ResponseWriter, r *http.Request) {
+		numberOfTimesCalled++
+		rw.WriteHeader(200)
+	})
+
+	testServer := httptest.NewServer(mux)
+
+	defer func() {
+		testServer.Close()
+	}()
+
+	config := supertokens.TypeInput{
+		Supertokens: &supertokens.ConnectionInfo{
+			// We need the querier to call the test server and not the core
+			ConnectionURI: testServer.URL,
+		},
+		AppInfo: supertokens.AppInfo{
+			AppName:       "SuperTokens",
+			WebsiteDomain: "supertokens.io",
+			APIDomain:     "api.supertokens.io",
+		},
+		RecipeList: []supertokens.Recipe{
+			Init(nil),
+		},
+	}
+
+	err := supertokens.Init(config)
+	assert.NoError(t, err)
+
+	q, err := supertokens.GetNewQuerierInstanceOrThrowError("")
+	assert.NoError(t, err)
+	supertokens.SetQuerierApiVersionForTests("3.0")
+	defer resetQuerier()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = q.SendPostRequest("/testing", map[string]interface{}{}, supertokens.MakeUserContextFromContext(ctx))
+	assert.True(t, errors.Is(err, context.Canceled))
+
+	// the context of the request in the userContext is used if no context is set explicitly
+	req := httptest.NewRequest("GET", "/", nil).WithContext(ctx)
+	_, err = q.SendGetRequest("/testing", map[string]string{}, supertokens.MakeDefaultUserContextFromAPI(req))
+	assert.True(t, errors.Is(err, context.Canceled))
+
+	assert.Equal(t, 0, numberOfTimesCalled)
+}
+
+func TestThatRateLimitRetriesStopWhenContextIsCancelled(t *testing.T) {
+	resetAll()
+	mux := http.NewServeMux()
+
+	numberOfTimesCalled := 0
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mux.HandleFunc("/testing", func(rw http.ResponseWriter, r *http.Request) {
+		numberOfTimesCalled++
+		if numberOfTimesCalled == 2 {
+			cancel()
+		}
+		rw.WriteHeader(supertokens.RateLimitStatusCode)
+	})
+
+	testServer := httptest.NewServer(mux)
+
+	defer func() {
+		testServer.Close()
+	}()
+
+	config := supertokens.TypeInput{
+		Supertokens: &supertokens.ConnectionInfo{
+			// We need the querier to call the test server and not the core
+			ConnectionURI: testServer.URL,
+		},
+		AppInfo: supertokens.AppInfo{
+			AppName:       "SuperTokens",
+			WebsiteDomain: "supertokens.io",
+			APIDomain:     "api.supertokens.io",
+		},
+		RecipeList: []supertokens.Recipe{
+			Init(nil),
+		},
+	}
+
+	err := supertokens.Init(config)
+	assert.NoError(t, err)
+
+	q, err := supertokens.GetNewQuerierInstanceOrThrowError("")
+	assert.NoError(t, err)
+	supertokens.SetQuerierApiVersionForTests("3.0")
+	defer resetQuerier()
+
+	_, err = q.SendGetRequest("/testing", map[string]string{}, supertokens.MakeUserContextFromContext(ctx))
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Equal(t, 2, numberOfTimesCalled)
+}
+
+type countingRoundTripper struct {
+	count int
+}
+
+func (c *countingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.count++
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestThatCustomTransportIsUsedForCoreCalls(t *testing.T) {
+	resetAll()
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/testing", func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(200)
+	})
+
+	testServer := httptest.NewServer(mux)
+
+	defer func() {
+		testServer.Close()
+	}()
+
+	transport := &countingRoundTripper{}
+	config := supertokens.TypeInput{
+		Supertokens: &supertokens.ConnectionInfo{
+			// We need the querier to call the test server and not the core
+			ConnectionURI: testServer.URL,
+			Transport:     transport,
+		},
+		AppInfo: supertokens.AppInfo{
+			AppName:       "SuperTokens",
+			WebsiteDomain: "supertokens.io",
+			APIDomain:     "api.supertokens.io",
+		},
+		RecipeList: []supertokens.Recipe{
+			Init(nil),
+		},
+	}
+
+	err := supertokens.Init(config)
+	assert.NoError(t, err)
+
+	q, err := supertokens.GetNewQuerierInstanceOrThrowError("")
+	assert.NoError(t, err)
+	supertokens.SetQuerierApiVersionForTests("3.0")
+	defer resetQuerier()
+
+	_, err = q.SendGetRequest("/testing", map[string]string{}, nil)
+	assert.NoError(t, err)
+	_, err = q.SendPostRequest("/testing", map[string]interface{}{}, nil)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 2, transport.count)
+}
+
+func TestThatRequestTimeoutIsAppliedToCoreCalls(t *testing.T) {
+	resetAll()
+	mux := http.NewServeMux()
+
+	unblock := make(chan struct{})
+	mux.HandleFunc("/slow", func(rw http.ResponseWriter, r *http.Request) {
+		select {
+		case <-unblock:
+		case <-r.Context().Done():
+		}
+		rw.WriteHeader(200)
+	})
+
+	testServer := httptest.NewServer(mux)
+
+	defer func() {
+		close(unblock)
+		testServer.Close()
+	}()
+
+	config := supertokens.TypeInput{
+		Supertokens: &supertokens.ConnectionInfo{
+			// We need the querier to call the test server and not the core
+			ConnectionURI:    testServer.URL,
+			RequestTimeoutMS: 100,
+		},
+		AppInfo: supertokens.AppInfo{
+			AppName:       "SuperTokens",
+			WebsiteDomain: "supertokens.io",
+			APIDomain:     "api.supertokens.io",
+		},
+		RecipeList: []supertokens.Recipe{
+			Init(nil),
+		},
+	}
+
+	err := supertokens.Init(config)
+	assert.NoError(t, err)
+
+	q, err := supertokens.GetNewQuerierInstanceOrThrowError("")
+	assert.NoError(t, err)
+	supertokens.SetQuerierApiVersionForTests("3.0")
+	defer resetQuerier()
+
+	start := time.Now()
+	_, err = q.SendGetRequest("/slow", map[string]string{}, nil)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Client.Timeout exceeded")
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestThatTLSConfigIsUsedForCoreCalls(t *testing.T) {
+	resetAll()
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/testing", func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(200)
+	})
+
+	testServer := httptest.NewTLSServer(mux)
+
+	defer func() {
+		testServer.Close()
+	}()
+
+	certPool := x509.NewCertPool()
+	certPool.AddCert(testServer.Certificate())
+
+	config := supertokens.TypeInput{
+		Supertokens: &supertokens.ConnectionInfo{
+			// We need the querier to call the test server and not the core
+			ConnectionURI: testServer.URL,
+			TLSConfig: &tls.Config{
+				RootCAs: certPool,
+			},
+			MaxIdleConnsPerHost: 10,
+		},
+		AppInfo: supertokens.AppInfo{
+			AppName:       "SuperTokens",
+			WebsiteDomain: "supertokens.io",
+			APIDomain:     "api.supertokens.io",
+		},
+		RecipeList: []supertokens.Recipe{
+			Init(nil),
+		},
+	}
+
+	err := supertokens.Init(config)
+	assert.NoError(t, err)
+
+	q, err := supertokens.GetNewQuerierInstanceOrThrowError("")
+	assert.NoError(t, err)
+	supertokens.SetQuerierApiVersionForTests("3.0")
+	defer resetQuerier()
+
+	_, err = q.SendGetRequest("/testing", map[string]string{}, nil)
+	assert.NoError(t, err)
+}
+
+func TestThatTransportSettingsCannotBeUsedWithCustomTransport(t *testing.T) {
+	resetAll()
+
+	config := supertokens.TypeInput{
+		Supertokens: &supertokens.ConnectionInfo{
+			ConnectionURI: "http://localhost:8080",
+			Transport:     &countingRoundTripper{},
+			TLSConfig:     &tls.Config{},
+		},
+		AppInfo: supertokens.AppInfo{
+			AppName:       "SuperTokens",
+			WebsiteDomain: "supertokens.io",
+			APIDomain:     "api.supertokens.io",
+		},
+		RecipeList: []supertokens.Recipe{
+			Init(nil),
+		},
+	}
+
+	err := supertokens.Init(config)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot be used along with a custom Transport")
+}
+
+func TestThatSharedCoreCallCacheIsUsedAcrossRequestsAndInvalidatedOnWrites(t *testing.T) {
+	resetAll()
+	mux := http.NewServeMux()
+
+	numberOfRoleCalls := 0
+	mux.HandleFunc("/public/recipe/user/roles", func(rw http.ResponseWriter, r *http.Request) {
+		numberOfRoleCalls++
+		rw.Header().Set("Content-Type", "application/json")
+		rw.WriteHeader(200)
+		rw.Write([]byte(`{"status":"OK","roles":["admin"]}`))
+	})
+	mux.HandleFunc("/public/recipe/user/role", func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(200)
+	})
+	mux.HandleFunc("/recipe/session", func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(200)
+	})
+
+	testServer := httptest.NewServer(mux)
+
+	defer func() {
+		testServer.Close()
+	}()
+
+	config := supertokens.TypeInput{
+		Supertokens: &supertokens.ConnectionInfo{
+			// We need the querier to call the test server and not the core
+			ConnectionURI:       testServer.URL,
+			SharedCoreCallCache: &supertokens.SharedCoreCallCacheConfig{},
+		},
+		AppInfo: supertokens.AppInfo{
+			AppName:       "SuperTokens",
+			WebsiteDomain: "supertokens.io",
+			APIDomain:     "api.supertokens.io",
+		},
+		RecipeList: []supertokens.Recipe{
+			Init(nil),
+		},
+	}
+
+	err := supertokens.Init(config)
+	assert.NoError(t, err)
+
+	q, err := supertokens.GetNewQuerierInstanceOrThrowError("")
+	assert.NoError(t, err)
+	supertokens.SetQuerierApiVersionForTests("3.0")
+	defer resetQuerier()
+
+	for i := 0; i < 3; i++ {
+		resp, err := q.SendGetRequest("/public/recipe/user/roles", map[string]string{"userId": "user1"}, &map[string]interface{}{})
+		assert.NoError(t, err)
+		assert.Equal(t, []interface{}{"admin"}, resp["roles"])
+	}
+	assert.Equal(t, 1, numberOfRoleCalls)
+
+	// a different user is cached separately
+	_, err = q.SendGetRequest("/public/recipe/user/roles", map[string]string{"userId": "user2"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, numberOfRoleCalls)
+
+	// unrelated writes do not invalidate the cache
+	_, err = q.SendPostRequest("/recipe/session", map[string]interface{}{}, nil)
+	assert.NoError(t, err)
+	_, err = q.SendGetRequest("/public/recipe/user/roles", map[string]string{"userId": "user1"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, numberOfRoleCalls)
+
+	_, err = q.SendPutRequest("/public/recipe/user/role", map[string]interface{}{}, nil)
+	assert.NoError(t, err)
+	_, err = q.SendGetRequest("/public/recipe/user/roles", map[string]string{"userId": "user1"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, numberOfRoleCalls)
+}
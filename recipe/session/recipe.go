@@ -24,6 +24,7 @@ import (
 	"github.com/supertokens/supertokens-golang/recipe/openid/openidmodels"
 	"github.com/supertokens/supertokens-golang/recipe/session/claims"
 	"github.com/supertokens/supertokens-golang/recipe/session/errors"
+	"github.com/supertokens/supertokens-golang/recipe/session/sessionclaims"
 	"github.com/supertokens/supertokens-golang/recipe/session/sessmodels"
 	"github.com/supertokens/supertokens-golang/supertokens"
 )
@@ -37,6 +38,7 @@ type Recipe struct {
 
 	claimsAddedByOtherRecipes          []*claims.TypeSessionClaim
 	claimValidatorsAddedByOtherRecipes []claims.SessionClaimValidator
+	localJWKS                          *LocalJWKS
 }
 
 const RECIPE_ID = "session"
@@ -78,6 +80,17 @@ func MakeRecipe(recipeId string, appInfo supertokens.NormalisedAppinfo, config *
 	supertokens.LogDebugMessage("session init: SessionExpiredStatusCode: " + strconv.Itoa(verifiedConfig.SessionExpiredStatusCode))
 
 	r.Config = verifiedConfig
+	if verifiedConfig.JWKSSource != nil {
+		supertokens.LogDebugMessage("session init: JWKSSource: access tokens will be verified using the configured key set")
+		localJWKS, err := NewLocalJWKS(*verifiedConfig.JWKSSource)
+		if err != nil {
+			return Recipe{}, err
+		}
+		r.localJWKS = localJWKS
+	}
+	if !verifiedConfig.SkipAddingLastAuthenticatedAtToAccessToken {
+		r.claimsAddedByOtherRecipes = append(r.claimsAddedByOtherRecipes, sessionclaims.LastAuthenticatedAtClaim)
+	}
 	r.APIImpl = verifiedConfig.Override.APIs(MakeAPIImplementation())
 
 	querierInstance, err := supertokens.GetNewQuerierInstanceOrThrowError(recipeId)
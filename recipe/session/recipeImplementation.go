@@ -137,8 +137,21 @@ error resistant.
 
 Every core instance a backend is connected to is expected to connect to the same database and use the same key set for
 token verification. Otherwise, the result of session verification would depend on which core is currently available.
+
+If a JWKSSource is set in the config, the keys from it are returned instead.
 */
 func GetCombinedJWKS() (*keyfunc.JWKS, error) {
+	return getCombinedJWKSForKID(nil)
+}
+
+// getCombinedJWKSForKID is like GetCombinedJWKS, but lets a JWKSSource reload its keys if kid is
+// not one of them.
+func getCombinedJWKSForKID(kid *string) (*keyfunc.JWKS, error) {
+	sessionInstance, err := getRecipeInstanceOrThrowError()
+	if err == nil && sessionInstance.localJWKS != nil {
+		return sessionInstance.localJWKS.GetJWKS(kid)
+	}
+
 	if supertokens.IsRunningInTestMode() {
 		urlsAttemptedForJWKSFetch = []string{}
 	}
@@ -158,6 +171,12 @@ func MakeRecipeImplementation(querier supertokens.Querier, config sessmodels.Typ
 	createNewSession := func(userID string, accessTokenPayload map[string]interface{}, sessionDataInDatabase map[string]interface{}, disableAntiCsrf *bool, tenantId string, userContext supertokens.UserContext) (sessmodels.SessionContainer, error) {
 		supertokens.LogDebugMessage("createNewSession: Started")
 
+		if config.SessionMetadata != nil && !hasSessionMetadata(config.SessionMetadata, accessTokenPayload, sessionDataInDatabase) {
+			if req := supertokens.GetRequestFromUserContext(userContext); req != nil {
+				accessTokenPayload, sessionDataInDatabase = addSessionMetadata(config.SessionMetadata, req, accessTokenPayload, sessionDataInDatabase, userContext)
+			}
+		}
+
 		sessionResponse, err := createNewSessionHelper(
 			config, querier, userID, disableAntiCsrf != nil && *disableAntiCsrf == true, accessTokenPayload, sessionDataInDatabase, tenantId, userContext,
 		)
@@ -166,6 +185,7 @@ func MakeRecipeImplementation(querier supertokens.Querier, config sessmodels.Typ
 		}
 
 		supertokens.LogDebugMessage("createNewSession: Finished")
+		supertokens.LogInfo("session: created", supertokens.RecipeLogFields(RECIPE_ID, sessionResponse.Session.TenantId, userContext, supertokens.LogFieldUserID, sessionResponse.Session.UserID)...)
 
 		parsedJWT, parseErr := ParseJWTWithoutSignatureVerification(sessionResponse.AccessToken.Token)
 		if parseErr != nil {
@@ -208,7 +228,7 @@ func MakeRecipeImplementation(querier supertokens.Querier, config sessmodels.Typ
 		accessTokenResponse, err := ParseJWTWithoutSignatureVerification(*accessTokenString)
 
 		if err != nil {
-			if options != nil && *options.SessionRequired == false {
+			if options != nil && options.SessionRequired != nil && *options.SessionRequired == false {
 				supertokens.LogDebugMessage("getSession: Returning nil because parsing failed and sessionRequired is false")
 				return nil, nil
 			}
@@ -224,7 +244,7 @@ func MakeRecipeImplementation(querier supertokens.Querier, config sessmodels.Typ
 		err = ValidateAccessTokenStructure(accessTokenResponse.Payload, accessTokenResponse.Version)
 
 		if err != nil {
-			if options != nil && *options.SessionRequired == false {
+			if options != nil && options.SessionRequired != nil && *options.SessionRequired == false {
 				supertokens.LogDebugMessage("getSession: Returning nil because parsing failed and sessionRequired is false")
 				return nil, nil
 			}
@@ -290,7 +310,16 @@ func MakeRecipeImplementation(querier supertokens.Querier, config sessmodels.Typ
 	}
 
 	getSessionInformation := func(sessionHandle string, userContext supertokens.UserContext) (*sessmodels.SessionInformation, error) {
-		return getSessionInformationHelper(querier, sessionHandle, userContext)
+		sessionInfo, err := getSessionInformationHelper(querier, sessionHandle, userContext)
+		if err != nil || sessionInfo == nil {
+			return sessionInfo, err
+		}
+		if config.SessionMetadata != nil {
+			sessionInfo.SessionDataInDatabase, sessionInfo.Metadata = getSessionMetadata(config.SessionMetadata, *sessionInfo)
+		} else {
+			sessionInfo.Metadata = parseSessionMetadata(sessionInfo.CustomClaimsInAccessTokenPayload[sessionMetadataKey])
+		}
+		return sessionInfo, nil
 	}
 
 	refreshSession := func(refreshToken string, antiCsrfToken *string, disableAntiCsrf bool, userContext supertokens.UserContext) (sessmodels.SessionContainer, error) {
@@ -305,6 +334,8 @@ func MakeRecipeImplementation(querier supertokens.Querier, config sessmodels.Typ
 			return nil, err
 		}
 		supertokens.LogDebugMessage("refreshSession: Success!")
+		supertokens.LogInfo("session: refreshed", supertokens.RecipeLogFields(RECIPE_ID, response.Session.TenantId, userContext, supertokens.LogFieldUserID, response.Session.UserID)...)
+		supertokens.AddToCounter(userContext, supertokens.MetricSessionRefreshes, 1, supertokens.RecipeMetricAttributes(RECIPE_ID, response.Session.TenantId))
 
 		responseToken, parseErr := ParseJWTWithoutSignatureVerification(response.AccessToken.Token)
 		if parseErr != nil {
@@ -317,6 +348,10 @@ func MakeRecipeImplementation(querier supertokens.Querier, config sessmodels.Typ
 		sessionContainerInput := makeSessionContainerInput(response.AccessToken.Token, session.Handle, session.UserID, session.TenantId, responseToken.Payload, result, frontToken, response.AntiCsrfToken, nil, &response.RefreshToken, true)
 		sessionContainer := newSessionContainer(config, &sessionContainerInput)
 
+		if config.SessionMetadata != nil {
+			updateLastRefreshTime(config.SessionMetadata, querier, sessionContainer, userContext)
+		}
+
 		return sessionContainer, nil
 	}
 
@@ -337,6 +372,15 @@ func MakeRecipeImplementation(querier supertokens.Querier, config sessmodels.Typ
 	}
 
 	updateSessionDataInDatabase := func(sessionHandle string, newSessionData map[string]interface{}, userContext supertokens.UserContext) (bool, error) {
+		if config.SessionMetadata != nil && config.SessionMetadata.StoreInDatabase {
+			// the metadata is not part of the session data returned to the user, so we need to keep it here
+			var exists bool
+			var err error
+			newSessionData, exists, err = keepSessionMetadataInDatabase(querier, sessionHandle, newSessionData, userContext)
+			if err != nil || !exists {
+				return false, err
+			}
+		}
 		return updateSessionDataInDatabaseHelper(querier, sessionHandle, newSessionData, userContext)
 	}
 
@@ -385,7 +429,8 @@ func MakeRecipeImplementation(querier supertokens.Querier, config sessmodels.Typ
 			return sessmodels.ValidateClaimsResult{}, err
 		}
 
-		for _, validator := range claimValidators {
+		// The validators combined by AllOf, AnyOf and Not have the claims to refetch
+		for _, validator := range claims.GetValidatorsWithClaims(claimValidators) {
 			supertokens.LogDebugMessage("updateClaimsInPayloadIfNeeded checking shouldRefetch for " + validator.ID)
 			claim := validator.Claim
 			if claim != nil && validator.ShouldRefetch != nil {
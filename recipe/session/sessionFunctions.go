@@ -62,7 +62,7 @@ func createNewSessionHelper(config sessmodels.TypeNormalisedInput, querier super
 func getSessionHelper(config sessmodels.TypeNormalisedInput, querier supertokens.Querier, parsedAccessToken sessmodels.ParsedJWTInfo, antiCsrfToken *string, doAntiCsrfCheck, alwaysCheckCore bool, userContext supertokens.UserContext) (sessmodels.GetSessionResponse, error) {
 	var accessTokenInfo *AccessTokenInfoStruct = nil
 	var err error = nil
-	combinedJwks, jwksError := GetCombinedJWKS()
+	combinedJwks, jwksError := getCombinedJWKSForKID(parsedAccessToken.KID)
 	if jwksError != nil {
 		supertokens.LogDebugMessage(fmt.Sprintf("getSessionHelper: Returning TryRefreshTokenError because there was an error fetching JWKs - %s", jwksError))
 		if !defaultErrors.As(jwksError, &errors.TryRefreshTokenError{}) {
@@ -77,7 +77,8 @@ func getSessionHelper(config sessmodels.TypeNormalisedInput, querier supertokens
 			return sessmodels.GetSessionResponse{}, err
 		}
 
-		if parsedAccessToken.Version < 3 {
+		// Verify only services cannot check the tokens with the core
+		if parsedAccessToken.Version < 3 && !isVerifyOnly(config) {
 			payload := parsedAccessToken.Payload
 			expiryTimeInPayload, expiryOk := payload["expiryTime"]
 			timeCreatedInPayload, timeCreatedOk := payload["timeCreated"]
@@ -138,7 +139,10 @@ func getSessionHelper(config sessmodels.TypeNormalisedInput, querier supertokens
 		}
 	}
 
-	if accessTokenInfo != nil && !alwaysCheckCore && accessTokenInfo.ParentRefreshTokenHash1 == nil {
+	// Tokens created by a refresh have a parent refresh token, which the core removes when the token is
+	// first verified. Verify only services skip that, and the parent is removed by the core when the
+	// session is next refreshed instead.
+	if accessTokenInfo != nil && !alwaysCheckCore && (accessTokenInfo.ParentRefreshTokenHash1 == nil || isVerifyOnly(config)) {
 		return sessmodels.GetSessionResponse{
 			Session: sessmodels.SessionStruct{
 				Handle:                accessTokenInfo.SessionHandle,
@@ -202,6 +206,10 @@ func getSessionHelper(config sessmodels.TypeNormalisedInput, querier supertokens
 	}
 }
 
+func isVerifyOnly(config sessmodels.TypeNormalisedInput) bool {
+	return config.JWKSSource != nil && config.JWKSSource.VerifyOnly
+}
+
 func getSessionInformationHelper(querier supertokens.Querier, sessionHandle string, userContext supertokens.UserContext) (*sessmodels.SessionInformation, error) {
 	response, err := querier.SendGetRequest("/recipe/session",
 		map[string]string{
@@ -264,6 +272,8 @@ func refreshSessionHelper(config sessmodels.TypeNormalisedInput, querier superto
 		}
 
 		supertokens.LogDebugMessage("refreshSession: Returning TOKEN_THEFT_DETECTED because of core response")
+		supertokens.LogWarn("session: token theft detected", supertokens.RecipeLogFields(RECIPE_ID, "", userContext, supertokens.LogFieldUserID, sessionInfo.UserID, "sessionHandle", sessionInfo.SessionHandle)...)
+		supertokens.AddToCounter(userContext, supertokens.MetricTokenTheftDetections, 1, supertokens.RecipeMetricAttributes(RECIPE_ID, ""))
 		return sessmodels.CreateOrRefreshAPIResponse{}, errors.TokenTheftDetectedError{
 			Msg:     "Token theft detected",
 			Payload: sessionInfo,
@@ -333,7 +343,9 @@ func revokeSessionHelper(querier supertokens.Querier, sessionHandle string, user
 	if err != nil {
 		return false, err
 	}
-	return len(response["sessionHandlesRevoked"].([]interface{})) == 1, nil
+	revoked := len(response["sessionHandlesRevoked"].([]interface{})) == 1
+	supertokens.LogInfo("session: revoked", supertokens.RecipeLogFields(RECIPE_ID, "", userContext, "sessionHandle", sessionHandle, "revoked", revoked)...)
+	return revoked, nil
 }
 
 func revokeMultipleSessionsHelper(querier supertokens.Querier, sessionHandles []string, userContext supertokens.UserContext) ([]string, error) {
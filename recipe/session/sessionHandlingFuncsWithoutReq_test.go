@@ -557,3 +557,12 @@ func TestUseDynamicAccessTokenSigningKey(t *testing.T) {
 		assert.False(t, tokensAfterVerify2.AccessAndFrontendTokenUpdated)
 	})
 }
+
+func TestGetSessionWithoutSessionRequiredReturnsUnauthorisedForAnInvalidToken(t *testing.T) {
+	key := newTestSigningKey(t, "s-key-1")
+	cleanup := initWithJWKSSourceForTest(t, &sessmodels.JWKSSource{JSON: makeTestJWKS(t, key), VerifyOnly: true})
+	defer cleanup()
+
+	_, err := GetSessionWithoutRequestResponse("not-a-jwt", nil, &sessmodels.VerifySessionOptions{})
+	assert.ErrorAs(t, err, &sessionError.UnauthorizedError{})
+}
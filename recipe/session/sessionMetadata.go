@@ -0,0 +1,243 @@
+/* Copyright (c) 2021, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package session
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/supertokens/supertokens-golang/recipe/session/sessmodels"
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+// The session metadata is stored under this key in the access token payload when the session is
+// created, or in the session data in the database if StoreInDatabase is set. The core keeps the
+// payload when the session is refreshed, so by default the metadata never has to be read and written
+// back. In the database, it is removed from the SessionDataInDatabase returned by
+// GetSessionInformation, and kept when the session data is updated.
+const sessionMetadataKey = "st-session-meta"
+
+func normaliseSessionMetadataConfig(config *sessmodels.SessionMetadataConfig) *sessmodels.SessionMetadataConfig {
+	if config == nil {
+		return nil
+	}
+	result := *config
+	if result.GetClientIP == nil {
+		numberOfTrustedProxies := result.NumberOfTrustedProxies
+		result.GetClientIP = func(req *http.Request, userContext supertokens.UserContext) string {
+			return getClientIP(req, numberOfTrustedProxies)
+		}
+	}
+	if result.GetDevice == nil {
+		result.GetDevice = GetDeviceFromUserAgent
+	}
+	return &result
+}
+
+// getClientIP returns the IP in the X-Forwarded-For header added by the outermost trusted proxy. Each
+// proxy appends the address it received the request from, so the entries before that one are set by
+// the client and cannot be trusted.
+func getClientIP(req *http.Request, numberOfTrustedProxies int) string {
+	if numberOfTrustedProxies > 0 {
+		forwardedFor := []string{}
+		for _, header := range req.Header.Values("X-Forwarded-For") {
+			for _, entry := range strings.Split(header, ",") {
+				if entry = strings.TrimSpace(entry); entry != "" {
+					forwardedFor = append(forwardedFor, entry)
+				}
+			}
+		}
+		if len(forwardedFor) >= numberOfTrustedProxies {
+			return forwardedFor[len(forwardedFor)-numberOfTrustedProxies]
+		}
+		if len(forwardedFor) > 0 {
+			// the request went through fewer proxies than configured, so all the entries were added
+			// by trusted proxies
+			return forwardedFor[0]
+		}
+		if realIP := strings.TrimSpace(req.Header.Get("X-Real-IP")); realIP != "" {
+			return realIP
+		}
+	}
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+// GetDeviceFromUserAgent returns a short description of the browser and OS in the user agent,
+// like "Chrome on macOS". Unknown parts are left out, and an empty string is returned if neither
+// is known.
+func GetDeviceFromUserAgent(userAgent string) string {
+	browser := ""
+	switch {
+	case strings.Contains(userAgent, "Edg/"):
+		browser = "Edge"
+	case strings.Contains(userAgent, "OPR/") || strings.Contains(userAgent, "Opera"):
+		browser = "Opera"
+	case strings.Contains(userAgent, "Firefox/") || strings.Contains(userAgent, "FxiOS/"):
+		browser = "Firefox"
+	case strings.Contains(userAgent, "Chrome/") || strings.Contains(userAgent, "CriOS/"):
+		browser = "Chrome"
+	case strings.Contains(userAgent, "Safari/"):
+		browser = "Safari"
+	}
+
+	os := ""
+	switch {
+	case strings.Contains(userAgent, "iPhone") || strings.Contains(userAgent, "iPad"):
+		os = "iOS"
+	case strings.Contains(userAgent, "Android"):
+		os = "Android"
+	case strings.Contains(userAgent, "Windows"):
+		os = "Windows"
+	case strings.Contains(userAgent, "CrOS"):
+		os = "ChromeOS"
+	case strings.Contains(userAgent, "Mac OS X") || strings.Contains(userAgent, "Macintosh"):
+		os = "macOS"
+	case strings.Contains(userAgent, "Linux"):
+		os = "Linux"
+	}
+
+	if browser != "" && os != "" {
+		return browser + " on " + os
+	}
+	return browser + os
+}
+
+// addSessionMetadata returns a copy of the access token payload, or of the session data in the
+// database if StoreInDatabase is set, with the metadata of req added to it. The other one is returned
+// as is.
+func addSessionMetadata(config *sessmodels.SessionMetadataConfig, req *http.Request, accessTokenPayload map[string]interface{}, sessionDataInDatabase map[string]interface{}, userContext supertokens.UserContext) (map[string]interface{}, map[string]interface{}) {
+	userAgent := req.Header.Get("User-Agent")
+	metadata := map[string]interface{}{
+		"ip":        config.GetClientIP(req, userContext),
+		"userAgent": userAgent,
+		"device":    config.GetDevice(userAgent),
+	}
+	if config.StoreInDatabase {
+		return accessTokenPayload, copyWithSessionMetadata(sessionDataInDatabase, metadata)
+	}
+	return copyWithSessionMetadata(accessTokenPayload, metadata), sessionDataInDatabase
+}
+
+// hasSessionMetadata returns true if the metadata was already added to the access token payload or
+// the session data in the database.
+func hasSessionMetadata(config *sessmodels.SessionMetadataConfig, accessTokenPayload map[string]interface{}, sessionDataInDatabase map[string]interface{}) bool {
+	if config.StoreInDatabase {
+		_, ok := sessionDataInDatabase[sessionMetadataKey]
+		return ok
+	}
+	_, ok := accessTokenPayload[sessionMetadataKey]
+	return ok
+}
+
+func copyWithSessionMetadata(data map[string]interface{}, metadata map[string]interface{}) map[string]interface{} {
+	result := map[string]interface{}{}
+	for k, v := range data {
+		result[k] = v
+	}
+	result[sessionMetadataKey] = metadata
+	return result
+}
+
+// getSessionMetadata reads the metadata from the session information. If StoreInDatabase is set, it
+// is removed from a copy of the session data in the database, which is returned along with it. The
+// metadata is nil if the session was created without SessionMetadata in the config.
+func getSessionMetadata(config *sessmodels.SessionMetadataConfig, sessionInfo sessmodels.SessionInformation) (map[string]interface{}, *sessmodels.SessionMetadata) {
+	if !config.StoreInDatabase {
+		return sessionInfo.SessionDataInDatabase, parseSessionMetadata(sessionInfo.CustomClaimsInAccessTokenPayload[sessionMetadataKey])
+	}
+
+	metadata := parseSessionMetadata(sessionInfo.SessionDataInDatabase[sessionMetadataKey])
+	if metadata == nil {
+		return sessionInfo.SessionDataInDatabase, nil
+	}
+	data := map[string]interface{}{}
+	for k, v := range sessionInfo.SessionDataInDatabase {
+		if k != sessionMetadataKey {
+			data[k] = v
+		}
+	}
+	return data, metadata
+}
+
+func parseSessionMetadata(value interface{}) *sessmodels.SessionMetadata {
+	rawMetadata, ok := value.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	metadata := &sessmodels.SessionMetadata{}
+	metadata.IP, _ = rawMetadata["ip"].(string)
+	metadata.UserAgent, _ = rawMetadata["userAgent"].(string)
+	metadata.Device, _ = rawMetadata["device"].(string)
+	if lastRefreshTime, ok := rawMetadata["lastRefreshTime"].(float64); ok {
+		metadata.LastRefreshTime = uint64(lastRefreshTime)
+	}
+	return metadata
+}
+
+// updateLastRefreshTime records the time of the refresh in the session metadata. This needs an extra
+// call to the core to regenerate the access token, or two to update the session data if
+// StoreInDatabase is set, so it is only done if session metadata is enabled, and it does not fail the
+// refresh.
+func updateLastRefreshTime(config *sessmodels.SessionMetadataConfig, querier supertokens.Querier, sessionContainer sessmodels.SessionContainer, userContext supertokens.UserContext) {
+	var err error
+	if config.StoreInDatabase {
+		err = updateLastRefreshTimeInDatabase(querier, sessionContainer.GetHandle(), userContext)
+	} else if rawMetadata, ok := sessionContainer.GetAccessTokenPayloadWithContext(userContext)[sessionMetadataKey].(map[string]interface{}); ok {
+		metadata := map[string]interface{}{}
+		for k, v := range rawMetadata {
+			metadata[k] = v
+		}
+		metadata["lastRefreshTime"] = GetCurrTimeInMS()
+		err = sessionContainer.MergeIntoAccessTokenPayloadWithContext(map[string]interface{}{sessionMetadataKey: metadata}, userContext)
+	}
+	if err != nil {
+		supertokens.LogWarn("session: could not update the last refresh time of the session", supertokens.RecipeLogFields(RECIPE_ID, sessionContainer.GetTenantId(), userContext, "sessionHandle", sessionContainer.GetHandle(), supertokens.LogFieldError, err.Error())...)
+	}
+}
+
+func updateLastRefreshTimeInDatabase(querier supertokens.Querier, sessionHandle string, userContext supertokens.UserContext) error {
+	sessionInfo, err := getSessionInformationHelper(querier, sessionHandle, userContext)
+	if err != nil || sessionInfo == nil {
+		return err
+	}
+	rawMetadata, ok := sessionInfo.SessionDataInDatabase[sessionMetadataKey].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	rawMetadata["lastRefreshTime"] = GetCurrTimeInMS()
+	_, err = updateSessionDataInDatabaseHelper(querier, sessionHandle, sessionInfo.SessionDataInDatabase, userContext)
+	return err
+}
+
+// keepSessionMetadataInDatabase adds the metadata in the database to a copy of the new session data, so
+// that it is not removed when the session data is updated.
+func keepSessionMetadataInDatabase(querier supertokens.Querier, sessionHandle string, newSessionData map[string]interface{}, userContext supertokens.UserContext) (map[string]interface{}, bool, error) {
+	sessionInfo, err := getSessionInformationHelper(querier, sessionHandle, userContext)
+	if err != nil || sessionInfo == nil {
+		return nil, false, err
+	}
+	metadata, ok := sessionInfo.SessionDataInDatabase[sessionMetadataKey].(map[string]interface{})
+	if !ok {
+		return newSessionData, true, nil
+	}
+	return copyWithSessionMetadata(newSessionData, metadata), true, nil
+}
@@ -0,0 +1,256 @@
+package session
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/supertokens/supertokens-golang/recipe/session/sessmodels"
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+// fakeSessionCore implements the core APIs used for creating, refreshing, regenerating and listing
+// sessions, keeping the session data in memory
+type fakeSessionCore struct {
+	t           *testing.T
+	key         testSigningKey
+	lock        sync.Mutex
+	sessionData map[string]map[string]interface{}
+	jwtData     map[string]interface{}
+	requests    []string
+}
+
+func (c *fakeSessionCore) sessionResponse(rw http.ResponseWriter) {
+	now := uint64(time.Now().UnixNano() / 1000000)
+	token := map[string]interface{}{
+		"token":       makeTestAccessTokenWithPayload(c.t, c.key, "user-1", c.jwtData),
+		"expiry":      now + 3600000,
+		"createdTime": now,
+	}
+	json.NewEncoder(rw).Encode(map[string]interface{}{
+		"status": "OK",
+		"session": map[string]interface{}{
+			"handle":        "test-session-handle",
+			"userId":        "user-1",
+			"userDataInJWT": map[string]interface{}{},
+			"tenantId":      "public",
+		},
+		"accessToken":  token,
+		"refreshToken": token,
+	})
+}
+
+func (c *fakeSessionCore) regenerateResponse(rw http.ResponseWriter) {
+	now := uint64(time.Now().UnixNano() / 1000000)
+	json.NewEncoder(rw).Encode(map[string]interface{}{
+		"status": "OK",
+		"session": map[string]interface{}{
+			"handle":        "test-session-handle",
+			"userId":        "user-1",
+			"userDataInJWT": c.jwtData,
+			"tenantId":      "public",
+		},
+		"accessToken": map[string]interface{}{
+			"token":       makeTestAccessTokenWithPayload(c.t, c.key, "user-1", c.jwtData),
+			"expiry":      now + 3600000,
+			"createdTime": now,
+		},
+	})
+}
+
+func (c *fakeSessionCore) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	body := map[string]interface{}{}
+	if r.Method != http.MethodGet {
+		json.NewDecoder(r.Body).Decode(&body)
+	}
+	c.requests = append(c.requests, r.Method+" "+r.URL.Path)
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/public/recipe/session":
+		c.sessionData["test-session-handle"] = body["userDataInDatabase"].(map[string]interface{})
+		c.jwtData = body["userDataInJWT"].(map[string]interface{})
+		c.sessionResponse(rw)
+	case r.Method == http.MethodPost && r.URL.Path == "/recipe/session/refresh":
+		c.sessionResponse(rw)
+	case r.Method == http.MethodPost && r.URL.Path == "/recipe/session/regenerate":
+		c.jwtData = body["userDataInJWT"].(map[string]interface{})
+		c.regenerateResponse(rw)
+	case r.Method == http.MethodGet && r.URL.Path == "/recipe/session":
+		json.NewEncoder(rw).Encode(map[string]interface{}{
+			"status":             "OK",
+			"sessionHandle":      r.URL.Query().Get("sessionHandle"),
+			"userId":             "user-1",
+			"userDataInDatabase": c.sessionData[r.URL.Query().Get("sessionHandle")],
+			"userDataInJWT":      c.jwtData,
+			"expiry":             0,
+			"timeCreated":        0,
+			"tenantId":           "public",
+		})
+	case r.Method == http.MethodGet && r.URL.Path == "/public/recipe/session/user":
+		handles := []string{}
+		for handle := range c.sessionData {
+			handles = append(handles, handle)
+		}
+		json.NewEncoder(rw).Encode(map[string]interface{}{"status": "OK", "sessionHandles": handles})
+	case r.Method == http.MethodPut && r.URL.Path == "/recipe/session/data":
+		c.sessionData[body["sessionHandle"].(string)] = body["userDataInDatabase"].(map[string]interface{})
+		json.NewEncoder(rw).Encode(map[string]interface{}{"status": "OK"})
+	default:
+		c.t.Errorf("unexpected request to the core: %s %s", r.Method, r.URL.Path)
+		rw.WriteHeader(404)
+	}
+}
+
+func initWithSessionMetadataForTest(t *testing.T, core *fakeSessionCore, config *sessmodels.SessionMetadataConfig) func() {
+	resetAll()
+	coreServer := httptest.NewServer(core)
+
+	False := false
+	err := supertokens.Init(supertokens.TypeInput{
+		Supertokens: &supertokens.ConnectionInfo{
+			ConnectionURI: coreServer.URL,
+		},
+		AppInfo: supertokens.AppInfo{
+			AppName:       "SuperTokens",
+			APIDomain:     "api.supertokens.io",
+			WebsiteDomain: "supertokens.io",
+		},
+		RecipeList: []supertokens.Recipe{
+			Init(&sessmodels.TypeInput{
+				UseDynamicAccessTokenSigningKey: &False,
+				JWKSSource:                      &sessmodels.JWKSSource{JSON: makeTestJWKS(t, core.key)},
+				SessionMetadata:                 config,
+			}),
+		},
+	})
+	assert.NoError(t, err)
+	supertokens.SetQuerierApiVersionForTests("3.0")
+
+	return func() {
+		coreServer.Close()
+		resetQuerier()
+		resetAll()
+	}
+}
+
+func createSessionWithMetadataForTest(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/signin", nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
+	req.Header.Set("X-Forwarded-For", "198.51.100.1, 203.0.113.7, 10.0.0.1")
+	_, err := CreateNewSession(req, httptest.NewRecorder(), "public", "user-1", nil, map[string]interface{}{"key": "value"})
+	assert.NoError(t, err)
+
+	sessions, err := ListSessionsForUser("user-1", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(sessions))
+	assert.Equal(t, map[string]interface{}{"key": "value"}, sessions[0].SessionDataInDatabase)
+	assert.Equal(t, "203.0.113.7", sessions[0].Metadata.IP)
+	assert.Equal(t, "Chrome on macOS", sessions[0].Metadata.Device)
+	assert.Contains(t, sessions[0].Metadata.UserAgent, "Chrome/120")
+	assert.Equal(t, uint64(0), sessions[0].Metadata.LastRefreshTime)
+}
+
+func TestSessionMetadataIsCapturedAndListed(t *testing.T) {
+	key := newTestSigningKey(t, "s-key")
+	core := &fakeSessionCore{t: t, key: key, sessionData: map[string]map[string]interface{}{}}
+	cleanup := initWithSessionMetadataForTest(t, core, &sessmodels.SessionMetadataConfig{
+		NumberOfTrustedProxies: 2,
+	})
+	defer cleanup()
+
+	createSessionWithMetadataForTest(t)
+	assert.NotNil(t, core.jwtData[sessionMetadataKey])
+	assert.Equal(t, map[string]interface{}{"key": "value"}, core.sessionData["test-session-handle"])
+
+	// the metadata is not in the session data, so updating it only needs one request to the core, and
+	// the refresh one more to regenerate the access token with the time of the refresh
+	core.requests = nil
+	updated, err := UpdateSessionDataInDatabase("test-session-handle", map[string]interface{}{"key": "new value"})
+	assert.NoError(t, err)
+	assert.True(t, updated)
+
+	False := false
+	refreshedSession, err := RefreshSessionWithoutRequestResponse("refresh-token", &False, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"PUT /recipe/session/data", "POST /recipe/session/refresh", "POST /recipe/session/regenerate"}, core.requests)
+	metadata := refreshedSession.GetAccessTokenPayload()[sessionMetadataKey].(map[string]interface{})
+	assert.Equal(t, "203.0.113.7", metadata["ip"])
+	assert.NotNil(t, metadata["lastRefreshTime"])
+
+	sessionInfo, err := GetSessionInformation("test-session-handle")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"key": "new value"}, sessionInfo.SessionDataInDatabase)
+	assert.Equal(t, "203.0.113.7", sessionInfo.Metadata.IP)
+	assert.InDelta(t, GetCurrTimeInMS(), sessionInfo.Metadata.LastRefreshTime, 5000)
+}
+
+func TestSessionMetadataCanBeStoredInTheDatabase(t *testing.T) {
+	key := newTestSigningKey(t, "s-key")
+	core := &fakeSessionCore{t: t, key: key, sessionData: map[string]map[string]interface{}{}}
+	cleanup := initWithSessionMetadataForTest(t, core, &sessmodels.SessionMetadataConfig{
+		NumberOfTrustedProxies: 2,
+		StoreInDatabase:        true,
+	})
+	defer cleanup()
+
+	createSessionWithMetadataForTest(t)
+	assert.Nil(t, core.jwtData[sessionMetadataKey])
+	assert.NotNil(t, core.sessionData["test-session-handle"][sessionMetadataKey])
+
+	// the metadata is read and written back when the session data is updated and when the session
+	// is refreshed
+	core.requests = nil
+	updated, err := UpdateSessionDataInDatabase("test-session-handle", map[string]interface{}{"key": "new value"})
+	assert.NoError(t, err)
+	assert.True(t, updated)
+
+	False := false
+	refreshedSession, err := RefreshSessionWithoutRequestResponse("refresh-token", &False, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"GET /recipe/session", "PUT /recipe/session/data", "POST /recipe/session/refresh", "GET /recipe/session", "PUT /recipe/session/data"}, core.requests)
+	assert.Nil(t, refreshedSession.GetAccessTokenPayload()[sessionMetadataKey])
+
+	sessionInfo, err := GetSessionInformation("test-session-handle")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"key": "new value"}, sessionInfo.SessionDataInDatabase)
+	assert.Equal(t, "203.0.113.7", sessionInfo.Metadata.IP)
+	assert.InDelta(t, GetCurrTimeInMS(), sessionInfo.Metadata.LastRefreshTime, 5000)
+}
+
+func TestGetClientIP(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/signin", nil)
+	req.RemoteAddr = "10.0.0.2:1234"
+	req.Header.Add("X-Forwarded-For", "198.51.100.1, 203.0.113.7")
+	req.Header.Add("X-Forwarded-For", "10.0.0.1")
+
+	assert.Equal(t, "10.0.0.2", getClientIP(req, 0))
+	assert.Equal(t, "10.0.0.1", getClientIP(req, 1))
+	// the leftmost entry is set by the client, so it is only used if it was added by a trusted proxy
+	assert.Equal(t, "203.0.113.7", getClientIP(req, 2))
+	assert.Equal(t, "198.51.100.1", getClientIP(req, 3))
+	assert.Equal(t, "198.51.100.1", getClientIP(req, 5))
+
+	req.Header.Del("X-Forwarded-For")
+	req.Header.Set("X-Real-IP", "203.0.113.8")
+	assert.Equal(t, "203.0.113.8", getClientIP(req, 1))
+}
+
+func TestGetDeviceFromUserAgent(t *testing.T) {
+	testCases := map[string]string{
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.0.0":           "Edge on Windows",
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1": "Safari on iOS",
+		"Mozilla/5.0 (Linux; Android 14) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36":                            "Chrome on Android",
+		"Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0":                                                                  "Firefox on Linux",
+		"curl/8.0.1": "",
+	}
+	for userAgent, expected := range testCases {
+		assert.Equal(t, expected, GetDeviceFromUserAgent(userAgent), userAgent)
+	}
+}
@@ -102,6 +102,10 @@ func CreateNewSessionInRequest(req *http.Request, res http.ResponseWriter, tenan
 
 	disableAntiCSRF := outputTokenTransferMethod == sessmodels.HeaderTransferMethod
 
+	if config.SessionMetadata != nil {
+		finalAccessTokenPayload, sessionDataInDatabase = addSessionMetadata(config.SessionMetadata, req, finalAccessTokenPayload, sessionDataInDatabase, userContext)
+	}
+
 	sessionResponse, err := (*recipeImpl.CreateNewSession)(userID, finalAccessTokenPayload, sessionDataInDatabase, &disableAntiCSRF, tenantId, userContext)
 
 	if err != nil {
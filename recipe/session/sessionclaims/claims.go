@@ -0,0 +1,19 @@
+package sessionclaims
+
+import (
+	"github.com/supertokens/supertokens-golang/recipe/session/claims"
+)
+
+// TypeLastAuthenticatedAtClaimValidators does not have the validators of other timestamp claims, since
+// they refetch missing values. That would set the value to now for sessions without the claim.
+type TypeLastAuthenticatedAtClaimValidators struct {
+	// AuthenticatedWithin fails with the REAUTHENTICATION_REQUIRED reason if the user has not signed
+	// in or re-authenticated in the last maxAgeInSeconds.
+	AuthenticatedWithin func(maxAgeInSeconds int64, id *string) claims.SessionClaimValidator
+}
+
+// LastAuthenticatedAtClaim is the time the user last signed in or re-authenticated on the session,
+// in milliseconds since the epoch.
+var LastAuthenticatedAtClaim *claims.TypeSessionClaim
+
+var LastAuthenticatedAtClaimValidators TypeLastAuthenticatedAtClaimValidators
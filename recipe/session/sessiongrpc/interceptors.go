@@ -0,0 +1,205 @@
+/* Copyright (c) 2021, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+// Package sessiongrpc provides gRPC server interceptors that verify the session of each call. It
+// lives in its own module so that the SDK does not depend on gRPC.
+package sessiongrpc
+
+import (
+	"context"
+	"encoding/json"
+	defaultErrors "errors"
+	"strings"
+
+	"github.com/supertokens/supertokens-golang/recipe/session"
+	"github.com/supertokens/supertokens-golang/recipe/session/errors"
+	"github.com/supertokens/supertokens-golang/recipe/session/sessmodels"
+	"github.com/supertokens/supertokens-golang/supertokens"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultMetadataKey = "authorization"
+	errorInfoDomain    = "supertokens.com"
+
+	ReasonUnauthorised    = "UNAUTHORISED"
+	ReasonTryRefreshToken = "TRY_REFRESH_TOKEN"
+	ReasonInvalidClaims   = "INVALID_CLAIMS"
+)
+
+type TypeInput struct {
+	// MetadataKey is the metadata key the access token is read from. Defaults to "authorization",
+	// in which case the value is expected to be of the form "Bearer <access token>".
+	MetadataKey string
+	// VerifySessionOptions are passed to session.GetSessionWithoutRequestResponse. The anti-csrf
+	// check is always disabled, since gRPC calls are not made by browsers with cookies.
+	VerifySessionOptions *sessmodels.VerifySessionOptions
+	// ShouldVerify decides whether the session is verified for the given method (for example,
+	// "/package.Service/Method"). Defaults to verifying all methods.
+	ShouldVerify func(fullMethod string) bool
+}
+
+type normalisedInput struct {
+	metadataKey          string
+	verifySessionOptions *sessmodels.VerifySessionOptions
+	shouldVerify         func(fullMethod string) bool
+}
+
+func normaliseInput(config *TypeInput) normalisedInput {
+	result := normalisedInput{
+		metadataKey: defaultMetadataKey,
+		shouldVerify: func(fullMethod string) bool {
+			return true
+		},
+	}
+
+	False := false
+	options := sessmodels.VerifySessionOptions{}
+	if config != nil {
+		if config.MetadataKey != "" {
+			result.metadataKey = strings.ToLower(config.MetadataKey)
+		}
+		if config.ShouldVerify != nil {
+			result.shouldVerify = config.ShouldVerify
+		}
+		if config.VerifySessionOptions != nil {
+			options = *config.VerifySessionOptions
+		}
+	}
+	options.AntiCsrfCheck = &False
+	result.verifySessionOptions = &options
+
+	return result
+}
+
+// UnaryServerInterceptor verifies the session of each unary call. The session can be read in the
+// handler using session.GetSessionFromRequestContext.
+func UnaryServerInterceptor(config *TypeInput) grpc.UnaryServerInterceptor {
+	input := normaliseInput(config)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !input.shouldVerify(info.FullMethod) {
+			return handler(ctx, req)
+		}
+		ctx, err := verifySession(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor verifies the session of each streaming call when the stream is opened.
+// The session can be read in the handler using session.GetSessionFromRequestContext on the
+// stream's context.
+func StreamServerInterceptor(config *TypeInput) grpc.StreamServerInterceptor {
+	input := normaliseInput(config)
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !input.shouldVerify(info.FullMethod) {
+			return handler(srv, stream)
+		}
+		ctx, err := verifySession(stream.Context(), input)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStreamWithContext{ServerStream: stream, ctx: ctx})
+	}
+}
+
+type serverStreamWithContext struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStreamWithContext) Context() context.Context {
+	return s.ctx
+}
+
+func verifySession(ctx context.Context, input normalisedInput) (context.Context, error) {
+	var accessToken *string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		values := md.Get(input.metadataKey)
+		if len(values) > 0 {
+			token := strings.TrimSpace(values[0])
+			if input.metadataKey == defaultMetadataKey {
+				if len(token) < len("bearer ") || !strings.EqualFold(token[:len("bearer ")], "bearer ") {
+					token = ""
+				} else {
+					token = strings.TrimSpace(token[len("bearer "):])
+				}
+			}
+			if token != "" {
+				accessToken = &token
+			}
+		}
+	}
+
+	if accessToken == nil {
+		if input.verifySessionOptions.SessionRequired != nil && !*input.verifySessionOptions.SessionRequired {
+			return ctx, nil
+		}
+		supertokens.LogDebugMessage("sessiongrpc: Returning UNAUTHORISED because the access token is missing from the metadata")
+		return nil, toStatusError(errors.UnauthorizedError{Msg: "Session does not exist. Are you sending the access token in the " + input.metadataKey + " metadata?"})
+	}
+
+	userContext := supertokens.MakeUserContextFromContext(ctx)
+	sessionContainer, err := session.GetSessionWithoutRequestResponse(*accessToken, nil, input.verifySessionOptions, userContext)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	if sessionContainer == nil {
+		return ctx, nil
+	}
+	return context.WithValue(ctx, sessmodels.SessionContext, sessionContainer), nil
+}
+
+// toStatusError maps the errors of the session recipe to gRPC status errors. The status details
+// contain an ErrorInfo whose reason is the SuperTokens error type, so clients can tell whether
+// they should refresh the session.
+func toStatusError(err error) error {
+	if defaultErrors.As(err, &errors.UnauthorizedError{}) {
+		return statusWithErrorInfo(codes.Unauthenticated, err.Error(), ReasonUnauthorised, nil)
+	}
+	if defaultErrors.As(err, &errors.TryRefreshTokenError{}) {
+		return statusWithErrorInfo(codes.Unauthenticated, err.Error(), ReasonTryRefreshToken, nil)
+	}
+	invalidClaimError := errors.InvalidClaimError{}
+	if defaultErrors.As(err, &invalidClaimError) {
+		invalidClaims, marshalErr := json.Marshal(invalidClaimError.InvalidClaims)
+		if marshalErr != nil {
+			return status.Error(codes.Internal, marshalErr.Error())
+		}
+		return statusWithErrorInfo(codes.PermissionDenied, err.Error(), ReasonInvalidClaims, map[string]string{
+			"invalidClaims": string(invalidClaims),
+		})
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+func statusWithErrorInfo(code codes.Code, message string, reason string, errorMetadata map[string]string) error {
+	st := status.New(code, message)
+	withDetails, err := st.WithDetails(&errdetails.ErrorInfo{
+		Reason:   reason,
+		Domain:   errorInfoDomain,
+		Metadata: errorMetadata,
+	})
+	if err != nil {
+		return st.Err()
+	}
+	return withDetails.Err()
+}
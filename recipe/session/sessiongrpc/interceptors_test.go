@@ -0,0 +1,229 @@
+package sessiongrpc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/supertokens/supertokens-golang/recipe/session"
+	"github.com/supertokens/supertokens-golang/recipe/session/claims"
+	"github.com/supertokens/supertokens-golang/recipe/session/sessmodels"
+	"github.com/supertokens/supertokens-golang/supertokens"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const testKID = "s-test-key"
+
+func initForTest(t *testing.T) (*rsa.PrivateKey, func()) {
+	supertokens.ResetForTest()
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	jwks, err := json.Marshal(map[string]interface{}{
+		"keys": []map[string]interface{}{
+			{
+				"kty": "RSA",
+				"kid": testKID,
+				"alg": "RS256",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(privateKey.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(privateKey.E)).Bytes()),
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	coreServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request to the core: %s", r.URL.Path)
+		rw.WriteHeader(500)
+	}))
+
+	False := false
+	err = supertokens.Init(supertokens.TypeInput{
+		Supertokens: &supertokens.ConnectionInfo{
+			ConnectionURI: coreServer.URL,
+		},
+		AppInfo: supertokens.AppInfo{
+			AppName:       "SuperTokens",
+			APIDomain:     "api.supertokens.io",
+			WebsiteDomain: "supertokens.io",
+		},
+		RecipeList: []supertokens.Recipe{
+			session.Init(&sessmodels.TypeInput{
+				UseDynamicAccessTokenSigningKey: &False,
+				JWKSSource:                      &sessmodels.JWKSSource{JSON: jwks},
+			}),
+		},
+	})
+	assert.NoError(t, err)
+	supertokens.SetQuerierApiVersionForTests("3.0")
+
+	return privateKey, func() {
+		coreServer.Close()
+		supertokens.ResetForTest()
+	}
+}
+
+func makeAccessToken(t *testing.T, privateKey *rsa.PrivateKey, payload map[string]interface{}) string {
+	tokenClaims := jwt.MapClaims{
+		"sub":               "user-1",
+		"tId":               "public",
+		"sessionHandle":     "session-handle",
+		"refreshTokenHash1": "refresh-token-hash",
+		"iat":               time.Now().Unix(),
+		"exp":               time.Now().Add(time.Hour).Unix(),
+	}
+	for k, v := range payload {
+		tokenClaims[k] = v
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, tokenClaims)
+	token.Header["kid"] = testKID
+	token.Header["version"] = "4"
+	signed, err := token.SignedString(privateKey)
+	assert.NoError(t, err)
+	return signed
+}
+
+func assertStatus(t *testing.T, err error, code codes.Code, reason string) {
+	st, ok := status.FromError(err)
+	assert.True(t, ok)
+	assert.Equal(t, code, st.Code())
+	assert.Equal(t, 1, len(st.Details()))
+	assert.Equal(t, reason, st.Details()[0].(*errdetails.ErrorInfo).Reason)
+}
+
+func TestUnaryInterceptorPutsSessionInContext(t *testing.T) {
+	privateKey, cleanup := initForTest(t)
+	defer cleanup()
+
+	interceptor := UnaryServerInterceptor(nil)
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return session.GetSessionFromRequestContext(ctx).GetUserID(), nil
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+makeAccessToken(t, privateKey, nil)))
+	res, err := interceptor(ctx, nil, info, handler)
+	assert.NoError(t, err)
+	assert.Equal(t, "user-1", res)
+}
+
+func TestUnaryInterceptorMapsErrorsToStatusCodes(t *testing.T) {
+	privateKey, cleanup := initForTest(t)
+	defer cleanup()
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		t.Error("the handler should not be called")
+		return nil, nil
+	}
+
+	_, err := UnaryServerInterceptor(nil)(context.Background(), nil, info, handler)
+	assertStatus(t, err, codes.Unauthenticated, ReasonUnauthorised)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer invalid"))
+	_, err = UnaryServerInterceptor(nil)(ctx, nil, info, handler)
+	assertStatus(t, err, codes.Unauthenticated, ReasonUnauthorised)
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+makeAccessToken(t, privateKey, map[string]interface{}{
+		"exp": time.Now().Add(-time.Minute).Unix(),
+	})))
+	_, err = UnaryServerInterceptor(nil)(ctx, nil, info, handler)
+	assertStatus(t, err, codes.Unauthenticated, ReasonTryRefreshToken)
+
+	_, isAdminValidators := claims.BooleanClaim("st-admin", nil, nil)
+	interceptor := UnaryServerInterceptor(&TypeInput{
+		VerifySessionOptions: &sessmodels.VerifySessionOptions{
+			OverrideGlobalClaimValidators: func(globalClaimValidators []claims.SessionClaimValidator, sessionContainer sessmodels.SessionContainer, userContext supertokens.UserContext) ([]claims.SessionClaimValidator, error) {
+				return []claims.SessionClaimValidator{isAdminValidators.IsTrue(nil, nil)}, nil
+			},
+		},
+	})
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+makeAccessToken(t, privateKey, map[string]interface{}{
+		"st-admin": map[string]interface{}{"v": false, "t": time.Now().UnixMilli()},
+	})))
+	_, err = interceptor(ctx, nil, info, handler)
+	assertStatus(t, err, codes.PermissionDenied, ReasonInvalidClaims)
+	st, _ := status.FromError(err)
+	assert.Contains(t, st.Details()[0].(*errdetails.ErrorInfo).Metadata["invalidClaims"], "st-admin")
+}
+
+func TestUnaryInterceptorOptions(t *testing.T) {
+	privateKey, cleanup := initForTest(t)
+	defer cleanup()
+
+	True := true
+	False := false
+	interceptor := UnaryServerInterceptor(&TypeInput{
+		MetadataKey:          "X-Access-Token",
+		VerifySessionOptions: &sessmodels.VerifySessionOptions{SessionRequired: &False, AntiCsrfCheck: &True},
+		ShouldVerify: func(fullMethod string) bool {
+			return fullMethod != "/test.Service/Public"
+		},
+	})
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		sessionContainer := session.GetSessionFromRequestContext(ctx)
+		if sessionContainer == nil {
+			return "", nil
+		}
+		return sessionContainer.GetUserID(), nil
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-access-token", makeAccessToken(t, privateKey, nil)))
+	res, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}, handler)
+	assert.NoError(t, err)
+	assert.Equal(t, "user-1", res)
+
+	res, err = interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}, handler)
+	assert.NoError(t, err)
+	assert.Equal(t, "", res)
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-access-token", "invalid"))
+	res, err = interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/test.Service/Public"}, handler)
+	assert.NoError(t, err)
+	assert.Equal(t, "", res)
+}
+
+type testServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *testServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestStreamInterceptorPutsSessionInContext(t *testing.T) {
+	privateKey, cleanup := initForTest(t)
+	defer cleanup()
+
+	interceptor := StreamServerInterceptor(nil)
+	info := &grpc.StreamServerInfo{FullMethod: "/test.Service/Stream"}
+
+	userId := ""
+	handler := func(srv interface{}, stream grpc.ServerStream) error {
+		userId = session.GetSessionFromRequestContext(stream.Context()).GetUserID()
+		return nil
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+makeAccessToken(t, privateKey, nil)))
+	err := interceptor(nil, &testServerStream{ctx: ctx}, info, handler)
+	assert.NoError(t, err)
+	assert.Equal(t, "user-1", userId)
+
+	err = interceptor(nil, &testServerStream{ctx: context.Background()}, info, handler)
+	assertStatus(t, err, codes.Unauthenticated, ReasonUnauthorised)
+}
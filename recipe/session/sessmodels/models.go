@@ -112,6 +112,53 @@ type TypeInput struct {
 	ExposeAccessTokenToFrontendInCookieBasedAuth bool
 	UseDynamicAccessTokenSigningKey              *bool
 	JWKSRefreshIntervalSec                       *uint64
+	// JWKSSource makes the SDK verify access tokens using the given key set instead of the one
+	// fetched from the core. The core is not called to verify tokens, unless CheckDatabase is set.
+	JWKSSource *JWKSSource
+	// SessionMetadata makes the SDK store the IP, user agent and device of the client in the
+	// access token payload (or the session data in the database) when the session is created, along
+	// with the time of its last refresh.
+	SessionMetadata *SessionMetadataConfig
+	// SkipAddingLastAuthenticatedAtToAccessToken stops the time of the sign in from being added to
+	// the access token payload as the sessionclaims.LastAuthenticatedAtClaim.
+	SkipAddingLastAuthenticatedAtToAccessToken bool
+}
+
+type SessionMetadataConfig struct {
+	// NumberOfTrustedProxies is the number of proxies in front of the API that add to the
+	// X-Forwarded-For header. If it is more than 0, the IP is the entry added by the outermost of
+	// them (or the X-Real-IP header if there is no X-Forwarded-For header), since the entries before
+	// it are set by the client. Otherwise the IP is the address the request came from.
+	NumberOfTrustedProxies int
+	// GetClientIP overrides how the IP of the client is found.
+	GetClientIP func(req *http.Request, userContext supertokens.UserContext) string
+	// GetDevice overrides how the user agent is turned into a description of the device, like
+	// "Chrome on macOS".
+	GetDevice func(userAgent string) string
+	// StoreInDatabase keeps the metadata in the session data in the database instead of the access
+	// token payload, where it can be read by anyone who has the access token. Updating the session
+	// data and the last refresh time then needs an extra call to the core.
+	StoreInDatabase bool
+}
+
+// JWKSSource is a key set used to verify access tokens without connecting to the core. Exactly
+// one of FilePath, JSON or GetJWKS must be set.
+type JWKSSource struct {
+	// FilePath is the path of a file containing the JWKS. The file is read again when it changes.
+	FilePath string
+	// JSON is the JWKS itself. It is never reloaded.
+	JSON []byte
+	// GetJWKS returns the JWKS. It is called again every ReloadIntervalSec, and the keys are
+	// replaced if the returned value has changed.
+	GetJWKS func() ([]byte, error)
+	// ReloadIntervalSec is how often the file or GetJWKS is checked for changes. Defaults to 60
+	// seconds. Tokens signed with an unknown kid also trigger a check, at most once per second.
+	ReloadIntervalSec *uint64
+	// VerifyOnly is for services that verify access tokens but cannot connect to the core. Their
+	// tokens are then never sent to the core, so the parent refresh token of a refreshed session is
+	// only removed, and token theft detected, when the session is refreshed again. Backends that
+	// have a core should leave it unset.
+	VerifyOnly bool
 }
 
 type OverrideStruct struct {
@@ -143,6 +190,9 @@ type TypeNormalisedInput struct {
 	ExposeAccessTokenToFrontendInCookieBasedAuth bool
 	UseDynamicAccessTokenSigningKey              bool
 	JWKSRefreshIntervalSec                       uint64
+	JWKSSource                                   *JWKSSource
+	SessionMetadata                              *SessionMetadataConfig
+	SkipAddingLastAuthenticatedAtToAccessToken   bool
 }
 
 type AntiCsrfFunctionOrString struct {
@@ -249,6 +299,16 @@ type SessionInformation struct {
 	CustomClaimsInAccessTokenPayload map[string]interface{}
 	TimeCreated                      uint64
 	TenantId                         string
+	// Metadata is nil if the session was created without SessionMetadata in the config.
+	Metadata *SessionMetadata
+}
+
+type SessionMetadata struct {
+	IP        string
+	UserAgent string
+	Device    string
+	// LastRefreshTime is in milliseconds since epoch. It is 0 if the session was never refreshed.
+	LastRefreshTime uint64
 }
 
 type ParsedJWTInfo struct {
@@ -227,6 +227,12 @@ func ValidateAndNormaliseUserInput(appInfo supertokens.NormalisedAppinfo, config
 		jwksRefreshIntervalSec = *config.JWKSRefreshIntervalSec
 	}
 
+	if config.JWKSSource != nil {
+		if err := validateJWKSSource(config.JWKSSource); err != nil {
+			return sessmodels.TypeNormalisedInput{}, err
+		}
+	}
+
 	typeNormalisedInput := sessmodels.TypeNormalisedInput{
 		RefreshTokenPath:         appInfo.APIBasePath.AppendPath(refreshAPIPath),
 		CookieDomain:             cookieDomain,
@@ -239,6 +245,9 @@ func ValidateAndNormaliseUserInput(appInfo supertokens.NormalisedAppinfo, config
 		ExposeAccessTokenToFrontendInCookieBasedAuth: config.ExposeAccessTokenToFrontendInCookieBasedAuth,
 		UseDynamicAccessTokenSigningKey:              useDynamicSigningKey,
 		JWKSRefreshIntervalSec:                       jwksRefreshIntervalSec,
+		JWKSSource:                                   config.JWKSSource,
+		SessionMetadata:                              normaliseSessionMetadataConfig(config.SessionMetadata),
+		SkipAddingLastAuthenticatedAtToAccessToken:   config.SkipAddingLastAuthenticatedAtToAccessToken,
 		ErrorHandlers:                                errorHandlers,
 		GetTokenTransferMethod:                       config.GetTokenTransferMethod,
 		Override: sessmodels.OverrideStruct{
@@ -381,6 +390,9 @@ func ValidateClaimsInPayload(claimValidators []claims.SessionClaimValidator, new
 				ID:     validator.ID,
 				Reason: claimValidationResult.Reason,
 			})
+			attributes := supertokens.RecipeMetricAttributes(RECIPE_ID, "")
+			attributes[supertokens.AttributeClaimID] = validator.ID
+			supertokens.AddToCounter(userContext, supertokens.MetricClaimValidationFailures, 1, attributes)
 		}
 	}
 	return validationErrors
@@ -0,0 +1,118 @@
+/* Copyright (c) 2021, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+// Package verifier verifies access tokens issued by SuperTokens without calling supertokens.Init
+// and without connecting to the core. It is meant for services that only need to check the
+// tokens minted by another service that uses the session recipe.
+package verifier
+
+import (
+	"strings"
+
+	"github.com/supertokens/supertokens-golang/recipe/session"
+	"github.com/supertokens/supertokens-golang/recipe/session/claims"
+	"github.com/supertokens/supertokens-golang/recipe/session/errors"
+	"github.com/supertokens/supertokens-golang/recipe/session/sessmodels"
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+type TypeInput struct {
+	JWKSSource sessmodels.JWKSSource
+	// ClaimValidators are checked against the payload of every verified access token. Since the
+	// core is never queried, validators are not given the chance to refetch their claim values.
+	ClaimValidators []claims.SessionClaimValidator
+	// UseDynamicAccessTokenSigningKey, if set, rejects the tokens that are not signed with the
+	// matching kind of key. Tokens signed with either kind are accepted by default.
+	UseDynamicAccessTokenSigningKey *bool
+}
+
+// VerifiedSession is the information from a verified access token.
+type VerifiedSession struct {
+	UserID             string
+	TenantId           string
+	SessionHandle      string
+	AccessTokenPayload map[string]interface{}
+	// ExpiryTime and TimeCreated are in milliseconds since epoch.
+	ExpiryTime  uint64
+	TimeCreated uint64
+}
+
+// Verifier verifies access tokens. It is safe for concurrent use, and multiple verifiers (for
+// example, with different key sets) can be used side by side.
+type Verifier struct {
+	jwks                            *session.LocalJWKS
+	claimValidators                 []claims.SessionClaimValidator
+	useDynamicAccessTokenSigningKey *bool
+}
+
+func NewVerifier(config TypeInput) (*Verifier, error) {
+	jwks, err := session.NewLocalJWKS(config.JWKSSource)
+	if err != nil {
+		return nil, err
+	}
+	return &Verifier{
+		jwks:                            jwks,
+		claimValidators:                 config.ClaimValidators,
+		useDynamicAccessTokenSigningKey: config.UseDynamicAccessTokenSigningKey,
+	}, nil
+}
+
+// Verify checks the signature, expiry and structure of the access token, and then runs the claim
+// validators on its payload. It returns an errors.TryRefreshTokenError if the token is invalid or
+// expired, and an errors.InvalidClaimError if any of the claim validators fail.
+func (v *Verifier) Verify(accessToken string, userContext ...supertokens.UserContext) (*VerifiedSession, error) {
+	if len(userContext) == 0 {
+		userContext = append(userContext, &map[string]interface{}{})
+	}
+
+	parsedAccessToken, err := session.ParseJWTWithoutSignatureVerification(accessToken)
+	if err != nil {
+		return nil, errors.TryRefreshTokenError{Msg: err.Error()}
+	}
+
+	jwks, err := v.jwks.GetJWKS(parsedAccessToken.KID)
+	if err != nil {
+		return nil, err
+	}
+
+	accessTokenInfo, err := session.GetInfoFromAccessToken(parsedAccessToken, jwks, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if v.useDynamicAccessTokenSigningKey != nil && parsedAccessToken.Version >= 3 {
+		tokenUsesDynamicKey := parsedAccessToken.KID != nil && strings.HasPrefix(*parsedAccessToken.KID, "d-")
+		if tokenUsesDynamicKey != *v.useDynamicAccessTokenSigningKey {
+			return nil, errors.TryRefreshTokenError{Msg: "The access token doesn't match the useDynamicAccessTokenSigningKey setting"}
+		}
+	}
+
+	invalidClaims := session.ValidateClaimsInPayload(v.claimValidators, accessTokenInfo.UserData, userContext[0])
+	if len(invalidClaims) > 0 {
+		return nil, errors.InvalidClaimError{
+			Msg:           "invalid claims",
+			InvalidClaims: invalidClaims,
+		}
+	}
+
+	return &VerifiedSession{
+		UserID:             accessTokenInfo.UserID,
+		TenantId:           accessTokenInfo.TenantId,
+		SessionHandle:      accessTokenInfo.SessionHandle,
+		AccessTokenPayload: accessTokenInfo.UserData,
+		ExpiryTime:         accessTokenInfo.ExpiryTime,
+		TimeCreated:        accessTokenInfo.TimeCreated,
+	}, nil
+}
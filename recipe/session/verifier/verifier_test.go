@@ -0,0 +1,119 @@
+package verifier
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/supertokens/supertokens-golang/recipe/session/claims"
+	"github.com/supertokens/supertokens-golang/recipe/session/errors"
+	"github.com/supertokens/supertokens-golang/recipe/session/sessmodels"
+)
+
+func makeKeyAndJWKS(t *testing.T, kid string) (*rsa.PrivateKey, []byte) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	jwks, err := json.Marshal(map[string]interface{}{
+		"keys": []map[string]interface{}{
+			{
+				"kty": "RSA",
+				"kid": kid,
+				"alg": "RS256",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(privateKey.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(privateKey.E)).Bytes()),
+			},
+		},
+	})
+	assert.NoError(t, err)
+	return privateKey, jwks
+}
+
+func makeAccessToken(t *testing.T, privateKey *rsa.PrivateKey, kid string, expiry time.Time, extraClaims map[string]interface{}) string {
+	tokenClaims := jwt.MapClaims{
+		"sub":               "user-1",
+		"tId":               "tenant-1",
+		"sessionHandle":     "session-handle",
+		"refreshTokenHash1": "refresh-token-hash",
+		"iat":               time.Now().Unix(),
+		"exp":               expiry.Unix(),
+	}
+	for k, v := range extraClaims {
+		tokenClaims[k] = v
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, tokenClaims)
+	token.Header["kid"] = kid
+	token.Header["version"] = "4"
+	signed, err := token.SignedString(privateKey)
+	assert.NoError(t, err)
+	return signed
+}
+
+func TestVerifyReturnsSessionInfo(t *testing.T) {
+	privateKey, jwks := makeKeyAndJWKS(t, "d-key")
+	v, err := NewVerifier(TypeInput{JWKSSource: sessmodels.JWKSSource{JSON: jwks}})
+	assert.NoError(t, err)
+
+	verifiedSession, err := v.Verify(makeAccessToken(t, privateKey, "d-key", time.Now().Add(time.Hour), map[string]interface{}{"role": "admin"}))
+	assert.NoError(t, err)
+	assert.Equal(t, "user-1", verifiedSession.UserID)
+	assert.Equal(t, "tenant-1", verifiedSession.TenantId)
+	assert.Equal(t, "session-handle", verifiedSession.SessionHandle)
+	assert.Equal(t, "admin", verifiedSession.AccessTokenPayload["role"])
+}
+
+func TestVerifyRejectsInvalidTokens(t *testing.T) {
+	privateKey, jwks := makeKeyAndJWKS(t, "d-key")
+	otherKey, _ := makeKeyAndJWKS(t, "d-key")
+	False := false
+	v, err := NewVerifier(TypeInput{
+		JWKSSource:                      sessmodels.JWKSSource{JSON: jwks},
+		UseDynamicAccessTokenSigningKey: &False,
+	})
+	assert.NoError(t, err)
+
+	_, err = v.Verify("not a token")
+	assert.ErrorAs(t, err, &errors.TryRefreshTokenError{})
+
+	_, err = v.Verify(makeAccessToken(t, privateKey, "d-key", time.Now().Add(-time.Minute), nil))
+	assert.ErrorAs(t, err, &errors.TryRefreshTokenError{})
+
+	_, err = v.Verify(makeAccessToken(t, otherKey, "d-key", time.Now().Add(time.Hour), nil))
+	assert.ErrorAs(t, err, &errors.TryRefreshTokenError{})
+
+	_, err = v.Verify(makeAccessToken(t, privateKey, "d-key", time.Now().Add(time.Hour), nil))
+	assert.EqualError(t, err, "The access token doesn't match the useDynamicAccessTokenSigningKey setting")
+}
+
+func TestVerifyChecksClaimValidators(t *testing.T) {
+	privateKey, jwks := makeKeyAndJWKS(t, "s-key")
+	_, isAdminValidators := claims.BooleanClaim("st-admin", nil, nil)
+	v, err := NewVerifier(TypeInput{
+		JWKSSource:      sessmodels.JWKSSource{JSON: jwks},
+		ClaimValidators: []claims.SessionClaimValidator{isAdminValidators.IsTrue(nil, nil)},
+	})
+	assert.NoError(t, err)
+
+	_, err = v.Verify(makeAccessToken(t, privateKey, "s-key", time.Now().Add(time.Hour), map[string]interface{}{
+		"st-admin": map[string]interface{}{"v": true, "t": time.Now().UnixMilli()},
+	}))
+	assert.NoError(t, err)
+
+	_, err = v.Verify(makeAccessToken(t, privateKey, "s-key", time.Now().Add(time.Hour), map[string]interface{}{
+		"st-admin": map[string]interface{}{"v": false, "t": time.Now().UnixMilli()},
+	}))
+	invalidClaimError := errors.InvalidClaimError{}
+	assert.ErrorAs(t, err, &invalidClaimError)
+	assert.Equal(t, "st-admin", invalidClaimError.InvalidClaims[0].ID)
+}
+
+func TestNewVerifierRejectsInvalidSource(t *testing.T) {
+	_, err := NewVerifier(TypeInput{})
+	assert.Error(t, err)
+}
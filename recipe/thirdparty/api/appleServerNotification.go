@@ -0,0 +1,90 @@
+/* Copyright (c) 2023, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package api
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/supertokens/supertokens-golang/recipe/thirdparty/providers"
+	"github.com/supertokens/supertokens-golang/recipe/thirdparty/tpmodels"
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+// AppleServerNotificationAPI handles the notifications sent by Apple to the URL configured for the
+// app in the Apple developer account. The thirdPartyId (defaults to apple) and clientType of the
+// provider can be set in the query params of the URL. Since anyone can call this API, only Apple
+// providers (with the id apple, or starting with apple-) are used to verify the notification.
+func AppleServerNotificationAPI(apiImplementation tpmodels.APIInterface, tenantId string, options tpmodels.APIOptions, userContext supertokens.UserContext) error {
+	if apiImplementation.AppleServerNotificationPOST == nil || (*apiImplementation.AppleServerNotificationPOST) == nil {
+		options.OtherHandler(options.Res, options.Req)
+		return nil
+	}
+
+	thirdPartyId := options.Req.URL.Query().Get("thirdPartyId")
+	if thirdPartyId == "" {
+		thirdPartyId = "apple"
+	}
+	if thirdPartyId != "apple" && !strings.HasPrefix(thirdPartyId, "apple-") {
+		return supertokens.BadInputError{Msg: "the provider " + thirdPartyId + " is not an Apple provider"}
+	}
+	var clientType *string = nil
+	if clientTypeFromQuery := options.Req.URL.Query().Get("clientType"); clientTypeFromQuery != "" {
+		clientType = &clientTypeFromQuery
+	}
+
+	body, err := supertokens.ReadFromRequest(options.Req)
+	if err != nil {
+		return err
+	}
+	var bodyParams struct {
+		Payload string `json:"payload"`
+	}
+	err = json.Unmarshal(body, &bodyParams)
+	if err != nil {
+		return err
+	}
+	if bodyParams.Payload == "" {
+		return supertokens.BadInputError{Msg: "Please provide the payload in request body"}
+	}
+
+	provider, err := (*options.RecipeImplementation.GetProvider)(thirdPartyId, clientType, tenantId, userContext)
+	if err != nil {
+		return err
+	}
+	if provider == nil {
+		return supertokens.BadInputError{Msg: "the provider " + thirdPartyId + " could not be found in the configuration"}
+	}
+
+	notification, err := providers.VerifyAppleServerNotification(provider, bodyParams.Payload, userContext)
+	if err != nil {
+		return supertokens.BadInputError{Msg: "Invalid Apple server notification: " + err.Error()}
+	}
+
+	result, err := (*apiImplementation.AppleServerNotificationPOST)(notification, tenantId, options, userContext)
+	if err != nil {
+		return err
+	}
+
+	if result.OK != nil {
+		return supertokens.Send200Response(options.Res, map[string]interface{}{
+			"status": "OK",
+		})
+	} else if result.GeneralError != nil {
+		return supertokens.Send200Response(options.Res, supertokens.ConvertGeneralErrorToJsonResponse(*result.GeneralError))
+	}
+	return supertokens.ErrorIfNoResponse(options.Res)
+}
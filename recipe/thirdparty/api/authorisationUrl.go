@@ -55,6 +55,12 @@ func AuthorisationUrlAPI(apiImplementation tpmodels.APIInterface, tenantId strin
 		return err
 	}
 	if result.OK != nil {
+		if options.Config.OAuthState != nil {
+			err = saveOAuthState(*options.Config.OAuthState, provider, redirectURIOnProviderDashboard, tenantId, result.OK, options, userContext)
+			if err != nil {
+				return err
+			}
+		}
 		respBody := map[string]interface{}{
 			"status":             "OK",
 			"urlWithQueryParams": result.OK.URLWithQueryParams,
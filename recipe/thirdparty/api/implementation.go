@@ -16,6 +16,7 @@
 package api
 
 import (
+	"crypto/subtle"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -25,10 +26,15 @@ import (
 	"github.com/supertokens/supertokens-golang/recipe/emailverification"
 	"github.com/supertokens/supertokens-golang/recipe/session"
 	"github.com/supertokens/supertokens-golang/recipe/session/sessmodels"
+	"github.com/supertokens/supertokens-golang/recipe/thirdparty/providers"
 	"github.com/supertokens/supertokens-golang/recipe/thirdparty/tpmodels"
 	"github.com/supertokens/supertokens-golang/supertokens"
 )
 
+// Identities can only be linked to the users of the thirdparty recipe, and not to the users of other
+// recipes (like emailpassword) that have a session
+const notAThirdPartyUserMessage = "Only users that signed up with a third party provider can link other identities"
+
 func MakeAPIImplementation() tpmodels.APIInterface {
 
 	authorisationUrlGET := func(provider *tpmodels.TypeProvider, redirectURIOnProviderDashboard string, tenantId string, options tpmodels.APIOptions, userContext supertokens.UserContext) (tpmodels.AuthorisationUrlGETResponse, error) {
@@ -43,19 +49,7 @@ func MakeAPIImplementation() tpmodels.APIInterface {
 	}
 
 	signInUpPOST := func(provider *tpmodels.TypeProvider, input tpmodels.TypeSignInUpInput, tenantId string, options tpmodels.APIOptions, userContext supertokens.UserContext) (tpmodels.SignInUpPOSTResponse, error) {
-		var oAuthTokens map[string]interface{} = nil
-		var err error
-
-		if input.RedirectURIInfo != nil {
-			oAuthTokens, err = provider.ExchangeAuthCodeForOAuthTokens(*input.RedirectURIInfo, userContext)
-			if err != nil {
-				return tpmodels.SignInUpPOSTResponse{}, err
-			}
-		} else {
-			oAuthTokens = *input.OAuthTokens
-		}
-
-		userInfo, err := provider.GetUserInfo(oAuthTokens, userContext)
+		oAuthTokens, userInfo, err := getUserInfoFromProvider(provider, input, userContext)
 		if err != nil {
 			return tpmodels.SignInUpPOSTResponse{}, err
 		}
@@ -79,6 +73,13 @@ func MakeAPIImplementation() tpmodels.APIInterface {
 			return tpmodels.SignInUpPOSTResponse{}, err
 		}
 
+		if options.RecipeImplementation.StoreProviderTokens != nil {
+			err = (*options.RecipeImplementation.StoreProviderTokens)(response.OK.User.ID, provider.ID, userInfo.ThirdPartyUserId, oAuthTokens, input.ClientType, tenantId, userContext)
+			if err != nil {
+				return tpmodels.SignInUpPOSTResponse{}, err
+			}
+		}
+
 		if emailInfo.IsVerified {
 			evInstance := emailverification.GetRecipeInstance()
 			if evInstance != nil {
@@ -150,9 +151,204 @@ func MakeAPIImplementation() tpmodels.APIInterface {
 		return nil
 	}
 
+	linkedIdentitiesGET := func(sessionContainer sessmodels.SessionContainer, options tpmodels.APIOptions, userContext supertokens.UserContext) (tpmodels.LinkedIdentitiesGETResponse, error) {
+		response, err := (*options.RecipeImplementation.ListLinkedIdentities)(sessionContainer.GetUserIDWithContext(userContext), userContext)
+		if err != nil {
+			return tpmodels.LinkedIdentitiesGETResponse{}, err
+		}
+		if response.UnknownUserIdError != nil {
+			return tpmodels.LinkedIdentitiesGETResponse{
+				GeneralError: &supertokens.GeneralErrorResponse{
+					Message: notAThirdPartyUserMessage,
+				},
+			}, nil
+		}
+		return tpmodels.LinkedIdentitiesGETResponse{
+			OK: &struct{ Identities []tpmodels.LinkedIdentity }{
+				Identities: response.OK.Identities,
+			},
+		}, nil
+	}
+
+	linkIdentityPOST := func(provider *tpmodels.TypeProvider, input tpmodels.TypeSignInUpInput, sessionContainer sessmodels.SessionContainer, tenantId string, options tpmodels.APIOptions, userContext supertokens.UserContext) (tpmodels.LinkIdentityPOSTResponse, error) {
+		oAuthTokens, userInfo, err := getUserInfoFromProvider(provider, input, userContext)
+		if err != nil {
+			return tpmodels.LinkIdentityPOSTResponse{}, err
+		}
+
+		email := ""
+		if userInfo.Email != nil {
+			email = userInfo.Email.ID
+		}
+
+		userID := sessionContainer.GetUserIDWithContext(userContext)
+		response, err := (*options.RecipeImplementation.LinkIdentity)(userID, provider.ID, userInfo.ThirdPartyUserId, email, tenantId, userContext)
+		if err != nil {
+			return tpmodels.LinkIdentityPOSTResponse{}, err
+		}
+		if response.IdentityAlreadyLinkedError != nil {
+			return tpmodels.LinkIdentityPOSTResponse{
+				IdentityAlreadyLinkedError: &struct{}{},
+			}, nil
+		}
+		if response.UnknownUserIdError != nil {
+			return tpmodels.LinkIdentityPOSTResponse{
+				GeneralError: &supertokens.GeneralErrorResponse{
+					Message: notAThirdPartyUserMessage,
+				},
+			}, nil
+		}
+
+		if options.RecipeImplementation.StoreProviderTokens != nil {
+			err = (*options.RecipeImplementation.StoreProviderTokens)(userID, provider.ID, userInfo.ThirdPartyUserId, oAuthTokens, input.ClientType, tenantId, userContext)
+			if err != nil {
+				return tpmodels.LinkIdentityPOSTResponse{}, err
+			}
+		}
+
+		return tpmodels.LinkIdentityPOSTResponse{
+			OK: &struct {
+				Identity                tpmodels.LinkedIdentity
+				OAuthTokens             tpmodels.TypeOAuthTokens
+				RawUserInfoFromProvider tpmodels.TypeRawUserInfoFromProvider
+			}{
+				Identity:                response.OK.Identity,
+				OAuthTokens:             oAuthTokens,
+				RawUserInfoFromProvider: userInfo.RawUserInfoFromProvider,
+			},
+		}, nil
+	}
+
+	unlinkIdentityPOST := func(thirdPartyID string, thirdPartyUserID string, sessionContainer sessmodels.SessionContainer, options tpmodels.APIOptions, userContext supertokens.UserContext) (tpmodels.UnlinkIdentityPOSTResponse, error) {
+		response, err := (*options.RecipeImplementation.UnlinkIdentity)(sessionContainer.GetUserIDWithContext(userContext), thirdPartyID, thirdPartyUserID, userContext)
+		if err != nil {
+			return tpmodels.UnlinkIdentityPOSTResponse{}, err
+		}
+		return tpmodels.UnlinkIdentityPOSTResponse{
+			OK:                    response.OK,
+			IdentityNotFoundError: response.IdentityNotFoundError,
+			PrimaryIdentityError:  response.PrimaryIdentityError,
+		}, nil
+	}
+
+	appleServerNotificationPOST := func(notification tpmodels.AppleServerNotification, tenantId string, options tpmodels.APIOptions, userContext supertokens.UserContext) (tpmodels.AppleServerNotificationPOSTResponse, error) {
+		user, isLinkedIdentity, err := getUserOfThirdPartyIdentity(notification.ThirdPartyId, notification.ThirdPartyUserId, tenantId, options, userContext)
+		if err != nil {
+			return tpmodels.AppleServerNotificationPOSTResponse{}, err
+		}
+
+		// The app cannot use the Apple ID for the user anymore
+		if user != nil && (notification.Type == tpmodels.AppleServerNotificationConsentRevoked || notification.Type == tpmodels.AppleServerNotificationAccountDelete) {
+			if isLinkedIdentity {
+				// Unlinking the Apple ID (which deletes its provider tokens) stops it from being used to
+				// sign in. The sessions of the user are kept, since they may have been created with the
+				// identity the user signed up with, which the user can still sign in with. Apps that
+				// want to sign the user out can revoke the sessions in OnConsentRevoked or
+				// OnAccountDeleted.
+				_, err = (*options.RecipeImplementation.UnlinkIdentity)(user.ID, notification.ThirdPartyId, notification.ThirdPartyUserId, userContext)
+				if err != nil {
+					return tpmodels.AppleServerNotificationPOSTResponse{}, err
+				}
+			} else {
+				if options.RecipeImplementation.DeleteProviderTokens != nil {
+					err = (*options.RecipeImplementation.DeleteProviderTokens)(user.ID, notification.ThirdPartyId, notification.ThirdPartyUserId, userContext)
+					if err != nil {
+						return tpmodels.AppleServerNotificationPOSTResponse{}, err
+					}
+				}
+				_, err = session.RevokeAllSessionsForUser(user.ID, &tenantId, userContext)
+				if err != nil {
+					return tpmodels.AppleServerNotificationPOSTResponse{}, err
+				}
+			}
+		}
+
+		callbacks := options.Config.AppleServerNotifications
+		var callback func(notification tpmodels.AppleServerNotification, user *tpmodels.User, tenantId string, userContext supertokens.UserContext) error
+		switch notification.Type {
+		case tpmodels.AppleServerNotificationEmailDisabled:
+			callback = callbacks.OnEmailDisabled
+		case tpmodels.AppleServerNotificationEmailEnabled:
+			callback = callbacks.OnEmailEnabled
+		case tpmodels.AppleServerNotificationConsentRevoked:
+			callback = callbacks.OnConsentRevoked
+		case tpmodels.AppleServerNotificationAccountDelete:
+			callback = callbacks.OnAccountDeleted
+		default:
+			supertokens.LogDebugMessage("Ignoring Apple server notification of unknown type " + notification.Type)
+		}
+		if callback != nil {
+			err = callback(notification, user, tenantId, userContext)
+			if err != nil {
+				return tpmodels.AppleServerNotificationPOSTResponse{}, err
+			}
+		}
+
+		return tpmodels.AppleServerNotificationPOSTResponse{
+			OK: &struct{}{},
+		}, nil
+	}
+
 	return tpmodels.APIInterface{
 		AuthorisationUrlGET:      &authorisationUrlGET,
 		SignInUpPOST:             &signInUpPOST,
 		AppleRedirectHandlerPOST: &appleRedirectHandlerPOST,
+		LinkedIdentitiesGET:      &linkedIdentitiesGET,
+		LinkIdentityPOST:         &linkIdentityPOST,
+		UnlinkIdentityPOST:       &unlinkIdentityPOST,
+
+		AppleServerNotificationPOST: &appleServerNotificationPOST,
+	}
+}
+
+// getUserOfThirdPartyIdentity returns the user that signed up with the identity, or else the user it
+// is linked to.
+func getUserOfThirdPartyIdentity(thirdPartyID string, thirdPartyUserID string, tenantId string, options tpmodels.APIOptions, userContext supertokens.UserContext) (*tpmodels.User, bool, error) {
+	user, err := (*options.RecipeImplementation.GetUserByThirdPartyInfo)(thirdPartyID, thirdPartyUserID, tenantId, userContext)
+	if err != nil || user != nil || options.Config.LinkedIdentities == nil {
+		return user, false, err
+	}
+
+	userID, err := options.Config.LinkedIdentities.Store.GetUserIdByIdentity(thirdPartyID, thirdPartyUserID, userContext)
+	if err != nil || userID == nil {
+		return nil, false, err
 	}
+	user, err = (*options.RecipeImplementation.GetUserByID)(*userID, userContext)
+	return user, user != nil, err
+}
+
+// getUserInfoFromProvider gets the tokens of the user from the provider, if the frontend did not
+// send them, and then the info of the user.
+func getUserInfoFromProvider(provider *tpmodels.TypeProvider, input tpmodels.TypeSignInUpInput, userContext supertokens.UserContext) (tpmodels.TypeOAuthTokens, tpmodels.TypeUserInfo, error) {
+	var oAuthTokens map[string]interface{} = nil
+	var err error
+
+	if input.RedirectURIInfo != nil {
+		oAuthTokens, err = provider.ExchangeAuthCodeForOAuthTokens(*input.RedirectURIInfo, userContext)
+		if err != nil {
+			return nil, tpmodels.TypeUserInfo{}, err
+		}
+	} else {
+		oAuthTokens = *input.OAuthTokens
+	}
+
+	if input.OAuthState != nil && input.OAuthState.Nonce != "" {
+		providers.SetExpectedIdTokenNonce(userContext, input.OAuthState.Nonce)
+	}
+
+	userInfo, err := provider.GetUserInfo(oAuthTokens, userContext)
+	if err != nil {
+		return nil, tpmodels.TypeUserInfo{}, err
+	}
+
+	// This is also checked here in case GetUserInfo is overridden and does not use the id token
+	// validation of the providers
+	if input.OAuthState != nil && input.OAuthState.Nonce != "" && userInfo.RawUserInfoFromProvider.FromIdTokenPayload != nil {
+		nonce, _ := userInfo.RawUserInfoFromProvider.FromIdTokenPayload["nonce"].(string)
+		if subtle.ConstantTimeCompare([]byte(nonce), []byte(input.OAuthState.Nonce)) != 1 {
+			return nil, tpmodels.TypeUserInfo{}, supertokens.BadInputError{Msg: "The nonce in the id token does not match the nonce sent to the provider"}
+		}
+	}
+
+	return oAuthTokens, userInfo, nil
 }
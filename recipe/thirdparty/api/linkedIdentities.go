@@ -0,0 +1,139 @@
+/* Copyright (c) 2021, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package api
+
+import (
+	"encoding/json"
+
+	"github.com/supertokens/supertokens-golang/recipe/session"
+	"github.com/supertokens/supertokens-golang/recipe/thirdparty/tpmodels"
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+func LinkedIdentitiesAPI(apiImplementation tpmodels.APIInterface, options tpmodels.APIOptions, userContext supertokens.UserContext) error {
+	if apiImplementation.LinkedIdentitiesGET == nil || (*apiImplementation.LinkedIdentitiesGET) == nil {
+		options.OtherHandler(options.Res, options.Req)
+		return nil
+	}
+
+	sessionContainer, err := session.GetSession(options.Req, options.Res, nil, userContext)
+	if err != nil {
+		return err
+	}
+
+	result, err := (*apiImplementation.LinkedIdentitiesGET)(sessionContainer, options, userContext)
+	if err != nil {
+		return err
+	}
+
+	if result.OK != nil {
+		return supertokens.Send200Response(options.Res, map[string]interface{}{
+			"status":     "OK",
+			"identities": result.OK.Identities,
+		})
+	} else if result.GeneralError != nil {
+		return supertokens.Send200Response(options.Res, supertokens.ConvertGeneralErrorToJsonResponse(*result.GeneralError))
+	}
+	return supertokens.ErrorIfNoResponse(options.Res)
+}
+
+func LinkIdentityAPI(apiImplementation tpmodels.APIInterface, tenantId string, options tpmodels.APIOptions, userContext supertokens.UserContext) error {
+	if apiImplementation.LinkIdentityPOST == nil || (*apiImplementation.LinkIdentityPOST) == nil {
+		options.OtherHandler(options.Res, options.Req)
+		return nil
+	}
+
+	sessionContainer, err := session.GetSession(options.Req, options.Res, nil, userContext)
+	if err != nil {
+		return err
+	}
+
+	provider, input, err := getProviderAndSignInUpInput(tenantId, options, userContext)
+	if err != nil {
+		return err
+	}
+
+	result, err := (*apiImplementation.LinkIdentityPOST)(provider, input, sessionContainer, tenantId, options, userContext)
+	if err != nil {
+		return err
+	}
+
+	if result.OK != nil {
+		return supertokens.Send200Response(options.Res, map[string]interface{}{
+			"status":   "OK",
+			"identity": result.OK.Identity,
+		})
+	} else if result.IdentityAlreadyLinkedError != nil {
+		return supertokens.Send200Response(options.Res, map[string]interface{}{
+			"status": "IDENTITY_ALREADY_LINKED_ERROR",
+		})
+	} else if result.GeneralError != nil {
+		return supertokens.Send200Response(options.Res, supertokens.ConvertGeneralErrorToJsonResponse(*result.GeneralError))
+	}
+	return supertokens.ErrorIfNoResponse(options.Res)
+}
+
+type unlinkIdentityBodyParams struct {
+	ThirdPartyId     string `json:"thirdPartyId"`
+	ThirdPartyUserId string `json:"thirdPartyUserId"`
+}
+
+func UnlinkIdentityAPI(apiImplementation tpmodels.APIInterface, options tpmodels.APIOptions, userContext supertokens.UserContext) error {
+	if apiImplementation.UnlinkIdentityPOST == nil || (*apiImplementation.UnlinkIdentityPOST) == nil {
+		options.OtherHandler(options.Res, options.Req)
+		return nil
+	}
+
+	sessionContainer, err := session.GetSession(options.Req, options.Res, nil, userContext)
+	if err != nil {
+		return err
+	}
+
+	body, err := supertokens.ReadFromRequest(options.Req)
+	if err != nil {
+		return err
+	}
+	var bodyParams unlinkIdentityBodyParams
+	err = json.Unmarshal(body, &bodyParams)
+	if err != nil {
+		return err
+	}
+	if bodyParams.ThirdPartyId == "" || bodyParams.ThirdPartyUserId == "" {
+		return supertokens.BadInputError{Msg: "Please provide the thirdPartyId and thirdPartyUserId in request body"}
+	}
+
+	result, err := (*apiImplementation.UnlinkIdentityPOST)(bodyParams.ThirdPartyId, bodyParams.ThirdPartyUserId, sessionContainer, options, userContext)
+	if err != nil {
+		return err
+	}
+
+	if result.OK != nil {
+		return supertokens.Send200Response(options.Res, map[string]interface{}{
+			"status": "OK",
+		})
+	} else if result.IdentityNotFoundError != nil {
+		return supertokens.Send200Response(options.Res, map[string]interface{}{
+			"status": "IDENTITY_NOT_FOUND_ERROR",
+		})
+	} else if result.PrimaryIdentityError != nil {
+		return supertokens.Send200Response(options.Res, map[string]interface{}{
+			"status": "PRIMARY_IDENTITY_ERROR",
+		})
+	} else if result.GeneralError != nil {
+		return supertokens.Send200Response(options.Res, supertokens.ConvertGeneralErrorToJsonResponse(*result.GeneralError))
+	}
+	return supertokens.ErrorIfNoResponse(options.Res)
+}
@@ -0,0 +1,227 @@
+/* Copyright (c) 2021, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package api
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/supertokens/supertokens-golang/recipe/thirdparty/tpmodels"
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+func generateRandomString() (string, error) {
+	randomBytes := make([]byte, 32)
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(randomBytes), nil
+}
+
+func getOAuthStateCipher(secret string) (cipher.AEAD, error) {
+	key := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func encryptOAuthState(data tpmodels.OAuthStateData, secret string) (string, error) {
+	plainText, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	aead, err := getOAuthStateCipher(secret)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(aead.Seal(nonce, nonce, plainText, nil)), nil
+}
+
+func decryptOAuthState(cookieValue string, secret string) (*tpmodels.OAuthStateData, error) {
+	cipherText, err := base64.RawURLEncoding.DecodeString(cookieValue)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := getOAuthStateCipher(secret)
+	if err != nil {
+		return nil, err
+	}
+	if len(cipherText) < aead.NonceSize() {
+		return nil, errors.New("state cookie is too short")
+	}
+	plainText, err := aead.Open(nil, cipherText[:aead.NonceSize()], cipherText[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, err
+	}
+	var data tpmodels.OAuthStateData
+	err = json.Unmarshal(plainText, &data)
+	if err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+func setOAuthStateCookie(config tpmodels.OAuthStateConfig, options tpmodels.APIOptions, value string, expiry time.Time) {
+	secure := strings.HasPrefix(options.AppInfo.APIDomain.GetAsStringDangerous(), "https://")
+	if config.CookieSecure != nil {
+		secure = *config.CookieSecure
+	}
+	sameSite := http.SameSiteLaxMode
+	if config.CookieSameSite == "strict" {
+		sameSite = http.SameSiteStrictMode
+	} else if config.CookieSameSite == "none" {
+		sameSite = http.SameSiteNoneMode
+	}
+	path := options.AppInfo.APIBasePath.GetAsStringDangerous()
+	if path == "" {
+		path = "/"
+	}
+
+	cookie := &http.Cookie{
+		Name:     config.CookieName,
+		Value:    value,
+		Path:     path,
+		Expires:  expiry,
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: sameSite,
+	}
+	if value == "" {
+		cookie.MaxAge = -1
+	}
+	http.SetCookie(options.Res, cookie)
+}
+
+// saveOAuthState adds the state (and the nonce, for OpenID Connect providers) to the authorisation URL,
+// and saves them along with the PKCE code verifier in the state cookie. The code verifier is removed
+// from the result since the frontend no longer needs to send it back.
+func saveOAuthState(config tpmodels.OAuthStateConfig, provider *tpmodels.TypeProvider, redirectURIOnProviderDashboard string, tenantId string, authRedirect *tpmodels.TypeAuthorisationRedirect, options tpmodels.APIOptions, userContext supertokens.UserContext) error {
+	state, err := generateRandomString()
+	if err != nil {
+		return err
+	}
+	data := tpmodels.OAuthStateData{
+		State:                          state,
+		PKCECodeVerifier:               authRedirect.PKCECodeVerifier,
+		ThirdPartyId:                   provider.ID,
+		TenantId:                       tenantId,
+		RedirectURIOnProviderDashboard: redirectURIOnProviderDashboard,
+	}
+
+	urlObj, err := url.Parse(authRedirect.URLWithQueryParams)
+	if err != nil {
+		return err
+	}
+	queryParams := urlObj.Query()
+	if queryParams.Has("SAMLRequest") {
+		queryParams.Set("RelayState", state)
+	} else {
+		queryParams.Set("state", state)
+	}
+	for _, scope := range provider.Config.Scope {
+		if scope == "openid" {
+			data.Nonce, err = generateRandomString()
+			if err != nil {
+				return err
+			}
+			queryParams.Set("nonce", data.Nonce)
+			break
+		}
+	}
+	urlObj.RawQuery = queryParams.Encode()
+
+	expiry := time.Now().Add(time.Duration(config.ValiditySec) * time.Second)
+	data.ExpiresAt = uint64(expiry.UnixNano() / 1000000)
+
+	cookieValue := ""
+	if config.Store != nil {
+		cookieValue, err = generateRandomString()
+		if err != nil {
+			return err
+		}
+		err = config.Store.Save(cookieValue, data, userContext)
+	} else {
+		cookieValue, err = encryptOAuthState(data, config.Secret)
+	}
+	if err != nil {
+		return err
+	}
+	setOAuthStateCookie(config, options, cookieValue, expiry)
+
+	authRedirect.URLWithQueryParams = urlObj.String()
+	authRedirect.PKCECodeVerifier = nil
+	return nil
+}
+
+// consumeOAuthState reads and clears the state cookie, and checks the saved state against the query
+// params the provider redirected the user back with.
+func consumeOAuthState(config tpmodels.OAuthStateConfig, thirdPartyId string, tenantId string, redirectURIInfo tpmodels.TypeRedirectURIInfo, options tpmodels.APIOptions, userContext supertokens.UserContext) (*tpmodels.OAuthStateData, error) {
+	cookie, err := options.Req.Cookie(config.CookieName)
+	if err != nil || cookie.Value == "" {
+		return nil, supertokens.BadInputError{Msg: "The OAuth state cookie is missing. Please restart the sign in flow"}
+	}
+	setOAuthStateCookie(config, options, "", time.Unix(0, 0))
+
+	var data *tpmodels.OAuthStateData
+	if config.Store != nil {
+		data, err = config.Store.Consume(cookie.Value, userContext)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		data, err = decryptOAuthState(cookie.Value, config.Secret)
+		if err != nil {
+			data = nil
+		}
+	}
+	if data == nil {
+		return nil, supertokens.BadInputError{Msg: "The OAuth state cookie is invalid. Please restart the sign in flow"}
+	}
+
+	state, ok := redirectURIInfo.RedirectURIQueryParams["state"].(string)
+	if !ok {
+		state, _ = redirectURIInfo.RedirectURIQueryParams["RelayState"].(string)
+	}
+	if subtle.ConstantTimeCompare([]byte(state), []byte(data.State)) != 1 {
+		return nil, supertokens.BadInputError{Msg: "The state returned by the provider does not match the state of this browser"}
+	}
+	if data.ExpiresAt < uint64(time.Now().UnixNano()/1000000) {
+		return nil, supertokens.BadInputError{Msg: "The OAuth state has expired. Please restart the sign in flow"}
+	}
+	if data.ThirdPartyId != thirdPartyId || data.TenantId != tenantId || data.RedirectURIOnProviderDashboard != redirectURIInfo.RedirectURIOnProviderDashboard {
+		return nil, supertokens.BadInputError{Msg: fmt.Sprintf("The OAuth state was created for a different provider or redirect URI than %s", thirdPartyId)}
+	}
+	return data, nil
+}
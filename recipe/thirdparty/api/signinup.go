@@ -36,14 +36,44 @@ func SignInUpAPI(apiImplementation tpmodels.APIInterface, tenantId string, optio
 		return nil
 	}
 
-	body, err := supertokens.ReadFromRequest(options.Req)
+	provider, input, err := getProviderAndSignInUpInput(tenantId, options, userContext)
+	if err != nil {
+		return err
+	}
+
+	result, err := (*apiImplementation.SignInUpPOST)(provider, input, tenantId, options, userContext)
+
 	if err != nil {
 		return err
 	}
+
+	if result.OK != nil {
+		return supertokens.Send200Response(options.Res, map[string]interface{}{
+			"status":         "OK",
+			"user":           result.OK.User,
+			"createdNewUser": result.OK.CreatedNewUser,
+		})
+	} else if result.NoEmailGivenByProviderError != nil {
+		return supertokens.Send200Response(options.Res, map[string]interface{}{
+			"status": "NO_EMAIL_GIVEN_BY_PROVIDER",
+		})
+	} else if result.GeneralError != nil {
+		return supertokens.Send200Response(options.Res, supertokens.ConvertGeneralErrorToJsonResponse(*result.GeneralError))
+	}
+	return supertokens.ErrorIfNoResponse(options.Res)
+}
+
+// getProviderAndSignInUpInput reads the thirdPartyId and either the redirectURIInfo or the
+// oAuthTokens from the request body, as sent to the sign in / up and link identity APIs.
+func getProviderAndSignInUpInput(tenantId string, options tpmodels.APIOptions, userContext supertokens.UserContext) (*tpmodels.TypeProvider, tpmodels.TypeSignInUpInput, error) {
+	body, err := supertokens.ReadFromRequest(options.Req)
+	if err != nil {
+		return nil, tpmodels.TypeSignInUpInput{}, err
+	}
 	var bodyParams bodyParams
 	err = json.Unmarshal(body, &bodyParams)
 	if err != nil {
-		return err
+		return nil, tpmodels.TypeSignInUpInput{}, err
 	}
 
 	var clientType *string = nil
@@ -52,51 +82,46 @@ func SignInUpAPI(apiImplementation tpmodels.APIInterface, tenantId string, optio
 	}
 
 	if bodyParams.ThirdPartyId == "" {
-		return supertokens.BadInputError{Msg: "Please provide the thirdPartyId in request body"}
+		return nil, tpmodels.TypeSignInUpInput{}, supertokens.BadInputError{Msg: "Please provide the thirdPartyId in request body"}
 	}
 
-	input := tpmodels.TypeSignInUpInput{}
+	input := tpmodels.TypeSignInUpInput{ClientType: clientType}
 	if bodyParams.RedirectURIInfo != nil {
 		input.RedirectURIInfo = bodyParams.RedirectURIInfo
 		if bodyParams.RedirectURIInfo.RedirectURIOnProviderDashboard == "" {
-			return supertokens.BadInputError{Msg: "Please provide the redirectURIOnProviderDashboard in request body"}
+			return nil, tpmodels.TypeSignInUpInput{}, supertokens.BadInputError{Msg: "Please provide the redirectURIOnProviderDashboard in request body"}
 		}
 
 	} else if bodyParams.OAuthTokens != nil {
 		input.OAuthTokens = bodyParams.OAuthTokens
 	} else {
-		return supertokens.BadInputError{Msg: "Please provide one of redirectURIInfo or oAuthTokens in the request body"}
+		return nil, tpmodels.TypeSignInUpInput{}, supertokens.BadInputError{Msg: "Please provide one of redirectURIInfo or oAuthTokens in the request body"}
 	}
 
 	providerResponse, err := (*options.RecipeImplementation.GetProvider)(bodyParams.ThirdPartyId, clientType, tenantId, userContext)
 	if err != nil {
-		return err
+		return nil, tpmodels.TypeSignInUpInput{}, err
 	}
 
 	provider := providerResponse
 
 	if provider == nil {
-		return supertokens.BadInputError{Msg: "the provider " + bodyParams.ThirdPartyId + " could not be found in the configuration"}
+		return nil, tpmodels.TypeSignInUpInput{}, supertokens.BadInputError{Msg: "the provider " + bodyParams.ThirdPartyId + " could not be found in the configuration"}
 	}
 
-	result, err := (*apiImplementation.SignInUpPOST)(provider, input, tenantId, options, userContext)
-
-	if err != nil {
-		return err
+	if options.Config.OAuthState != nil && input.RedirectURIInfo == nil {
+		// oAuthTokens obtained by the frontend cannot be checked against the state or the nonce
+		if !options.Config.OAuthState.AllowOAuthTokensWithoutState {
+			return nil, tpmodels.TypeSignInUpInput{}, supertokens.BadInputError{Msg: "Please provide redirectURIInfo in the request body, oAuthTokens cannot be used when OAuthState is enabled"}
+		}
+	} else if options.Config.OAuthState != nil {
+		oAuthState, err := consumeOAuthState(*options.Config.OAuthState, bodyParams.ThirdPartyId, tenantId, *input.RedirectURIInfo, options, userContext)
+		if err != nil {
+			return nil, tpmodels.TypeSignInUpInput{}, err
+		}
+		input.RedirectURIInfo.PKCECodeVerifier = oAuthState.PKCECodeVerifier
+		input.OAuthState = oAuthState
 	}
 
-	if result.OK != nil {
-		return supertokens.Send200Response(options.Res, map[string]interface{}{
-			"status":         "OK",
-			"user":           result.OK.User,
-			"createdNewUser": result.OK.CreatedNewUser,
-		})
-	} else if result.NoEmailGivenByProviderError != nil {
-		return supertokens.Send200Response(options.Res, map[string]interface{}{
-			"status": "NO_EMAIL_GIVEN_BY_PROVIDER",
-		})
-	} else if result.GeneralError != nil {
-		return supertokens.Send200Response(options.Res, supertokens.ConvertGeneralErrorToJsonResponse(*result.GeneralError))
-	}
-	return supertokens.ErrorIfNoResponse(options.Res)
+	return provider, input, nil
 }
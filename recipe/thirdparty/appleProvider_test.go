@@ -0,0 +1,233 @@
+/*
+ * Copyright (c) 2021, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thirdparty
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/supertokens/supertokens-golang/recipe/thirdparty/api"
+	"github.com/supertokens/supertokens-golang/recipe/thirdparty/providers"
+	"github.com/supertokens/supertokens-golang/recipe/thirdparty/tpmodels"
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+func newAppleTestPrivateKey(t *testing.T) string {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	encoded, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	assert.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: encoded}))
+}
+
+func getAppleClientSecretKeyId(t *testing.T, clientSecret string) string {
+	token, _, err := jwt.NewParser().ParseUnverified(clientSecret, jwt.MapClaims{})
+	assert.NoError(t, err)
+	return token.Header["kid"].(string)
+}
+
+func TestAppleClientSecretIsReused(t *testing.T) {
+	idp := newBuiltinProviderIdP(t, "")
+	defer idp.server.Close()
+
+	createProvider := func(additionalConfig map[string]interface{}) *tpmodels.TypeProvider {
+		provider, err := providers.FindAndCreateProviderInstance([]tpmodels.ProviderInput{{Config: tpmodels.ProviderConfig{
+			ThirdPartyId:          "apple",
+			OIDCDiscoveryEndpoint: idp.server.URL,
+			Clients:               []tpmodels.ProviderClientConfig{{ClientID: "reused-client", AdditionalConfig: additionalConfig}},
+		}}}, "apple", nil, &map[string]interface{}{})
+		assert.NoError(t, err)
+		return provider
+	}
+	additionalConfig := map[string]interface{}{"teamId": "team", "keyId": "key-1", "privateKey": newAppleTestPrivateKey(t)}
+
+	clientSecret := createProvider(additionalConfig).Config.ClientSecret
+	assert.Equal(t, "key-1", getAppleClientSecretKeyId(t, clientSecret))
+	assert.Equal(t, clientSecret, createProvider(additionalConfig).Config.ClientSecret)
+
+	claims := jwt.MapClaims{}
+	_, _, err := jwt.NewParser().ParseUnverified(clientSecret, claims)
+	assert.NoError(t, err)
+	assert.Equal(t, "team", claims["iss"])
+	assert.Equal(t, "reused-client", claims["sub"])
+	assert.InDelta(t, time.Now().Add(24*time.Hour).Unix(), claims["exp"], 5)
+
+	// A short validity is renewed before it expires
+	additionalConfig["clientSecretValiditySec"] = 1
+	clientSecret = createProvider(additionalConfig).Config.ClientSecret
+	time.Sleep(1100 * time.Millisecond)
+	assert.NotEqual(t, clientSecret, createProvider(additionalConfig).Config.ClientSecret)
+
+	additionalConfig["clientSecretValiditySec"] = 20000000
+	_, err = providers.FindAndCreateProviderInstance([]tpmodels.ProviderInput{{Config: tpmodels.ProviderConfig{
+		ThirdPartyId:          "apple",
+		OIDCDiscoveryEndpoint: idp.server.URL,
+		Clients:               []tpmodels.ProviderClientConfig{{ClientID: "reused-client", AdditionalConfig: additionalConfig}},
+	}}}, "apple", nil, &map[string]interface{}{})
+	assert.Error(t, err)
+}
+
+func TestAppleKeysAreRotatedWhenRejected(t *testing.T) {
+	requestedKeyIds := []string{}
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/.well-known/openid-configuration" {
+			json.NewEncoder(rw).Encode(map[string]interface{}{
+				"issuer":                 server.URL,
+				"authorization_endpoint": server.URL + "/authorize",
+				"token_endpoint":         server.URL + "/token",
+			})
+			return
+		}
+		assert.NoError(t, r.ParseForm())
+		keyId := getAppleClientSecretKeyId(t, r.PostForm.Get("client_secret"))
+		requestedKeyIds = append(requestedKeyIds, keyId)
+		if keyId != "key-2" {
+			rw.WriteHeader(400)
+			json.NewEncoder(rw).Encode(map[string]interface{}{"error": "invalid_client"})
+			return
+		}
+		json.NewEncoder(rw).Encode(map[string]interface{}{"access_token": "access-1"})
+	}))
+	defer server.Close()
+
+	createProvider := func() *tpmodels.TypeProvider {
+		provider, err := providers.FindAndCreateProviderInstance([]tpmodels.ProviderInput{{Config: tpmodels.ProviderConfig{
+			ThirdPartyId:          "apple",
+			OIDCDiscoveryEndpoint: server.URL,
+			Clients: []tpmodels.ProviderClientConfig{{ClientID: "rotated-client", AdditionalConfig: map[string]interface{}{
+				"teamId": "team",
+				"keys": []interface{}{
+					map[string]interface{}{"keyId": "key-1", "privateKey": newAppleTestPrivateKey(t)},
+					map[string]interface{}{"keyId": "key-2", "privateKey": newAppleTestPrivateKey(t)},
+				},
+			}}},
+		}}}, "apple", nil, &map[string]interface{}{})
+		assert.NoError(t, err)
+		return provider
+	}
+	exchange := func(provider *tpmodels.TypeProvider) (tpmodels.TypeOAuthTokens, error) {
+		return provider.ExchangeAuthCodeForOAuthTokens(tpmodels.TypeRedirectURIInfo{
+			RedirectURIOnProviderDashboard: "https://example.com/callback",
+			RedirectURIQueryParams:         map[string]interface{}{"code": "code"},
+		}, &map[string]interface{}{})
+	}
+
+	tokens, err := exchange(createProvider())
+	assert.NoError(t, err)
+	assert.Equal(t, "access-1", tokens["access_token"])
+	assert.Equal(t, []string{"key-1", "key-2"}, requestedKeyIds)
+
+	// The key that worked is used first from then on
+	provider := createProvider()
+	assert.Equal(t, "key-2", getAppleClientSecretKeyId(t, provider.Config.ClientSecret))
+	_, err = exchange(provider)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"key-1", "key-2", "key-2"}, requestedKeyIds)
+}
+
+func TestAppleServerNotificationsAreVerified(t *testing.T) {
+	idp := newBuiltinProviderIdP(t, "")
+	defer idp.server.Close()
+
+	provider, err := createBuiltinProviderForTest(t, tpmodels.ProviderConfig{
+		ThirdPartyId:          "apple",
+		OIDCDiscoveryEndpoint: idp.server.URL,
+		Clients:               []tpmodels.ProviderClientConfig{{}},
+	})
+	assert.NoError(t, err)
+
+	events, err := json.Marshal(map[string]interface{}{
+		"type":             "account-delete",
+		"sub":              "apple-user-1",
+		"email":            "user@privaterelay.appleid.com",
+		"is_private_email": "true",
+		"event_time":       1508184845,
+	})
+	assert.NoError(t, err)
+
+	notification, err := providers.VerifyAppleServerNotification(provider, idp.makeIdToken(t, jwt.MapClaims{
+		"iss":    "https://appleid.apple.com",
+		"events": string(events),
+	}), &map[string]interface{}{})
+	assert.NoError(t, err)
+	assert.Equal(t, tpmodels.AppleServerNotification{
+		Type:             tpmodels.AppleServerNotificationAccountDelete,
+		ThirdPartyId:     "apple",
+		ThirdPartyUserId: "apple-user-1",
+		Email:            "user@privaterelay.appleid.com",
+		IsPrivateEmail:   true,
+		EventTime:        1508184845000,
+	}, notification)
+
+	_, err = providers.VerifyAppleServerNotification(provider, idp.makeIdToken(t, jwt.MapClaims{
+		"iss":    "https://appleid.apple.com",
+		"aud":    "other-client",
+		"events": string(events),
+	}), &map[string]interface{}{})
+	assert.Error(t, err)
+
+	_, err = providers.VerifyAppleServerNotification(provider, newTestIdPKey(t, "key-1").makeIdToken(t, jwt.MapClaims{
+		"iss":    "https://appleid.apple.com",
+		"events": string(events),
+	}), &map[string]interface{}{})
+	assert.Error(t, err)
+}
+
+func TestAppleServerNotificationsAreOnlyVerifiedWithAppleProviders(t *testing.T) {
+	var requestedThirdPartyIds []string
+	getProvider := func(thirdPartyID string, clientType *string, tenantId string, userContext supertokens.UserContext) (*tpmodels.TypeProvider, error) {
+		requestedThirdPartyIds = append(requestedThirdPartyIds, thirdPartyID)
+		return nil, nil
+	}
+	notificationPOST := func(notification tpmodels.AppleServerNotification, tenantId string, options tpmodels.APIOptions, userContext supertokens.UserContext) (tpmodels.AppleServerNotificationPOSTResponse, error) {
+		t.Error("the notification should not be handled")
+		return tpmodels.AppleServerNotificationPOSTResponse{}, nil
+	}
+	handleNotification := func(query string) error {
+		options := tpmodels.APIOptions{
+			RecipeImplementation: tpmodels.RecipeInterface{GetProvider: &getProvider},
+			Req:                  httptest.NewRequest(http.MethodPost, "/auth/notifications/apple"+query, strings.NewReader(`{"payload":"payload"}`)),
+			Res:                  httptest.NewRecorder(),
+		}
+		return api.AppleServerNotificationAPI(tpmodels.APIInterface{AppleServerNotificationPOST: &notificationPOST}, "public", options, &map[string]interface{}{})
+	}
+
+	for _, query := range []string{"?thirdPartyId=google", "?thirdPartyId=custom-oidc", "?thirdPartyId=apples"} {
+		err := handleNotification(query)
+		assert.IsType(t, supertokens.BadInputError{}, err)
+	}
+	assert.Empty(t, requestedThirdPartyIds)
+
+	// Apple providers are looked up, and these are not configured
+	for _, query := range []string{"", "?thirdPartyId=apple-ios"} {
+		err := handleNotification(query)
+		assert.IsType(t, supertokens.BadInputError{}, err)
+	}
+	assert.Equal(t, []string{"apple", "apple-ios"}, requestedThirdPartyIds)
+}
@@ -0,0 +1,235 @@
+/*
+ * Copyright (c) 2021, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thirdparty
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	dsig "github.com/russellhaering/goxmldsig"
+	"github.com/stretchr/testify/assert"
+	"github.com/supertokens/supertokens-golang/recipe/thirdparty/providers"
+	"github.com/supertokens/supertokens-golang/recipe/thirdparty/tpmodels"
+)
+
+// newBuiltinProviderIdP returns an IdP whose issuer is issuerPath on the server, which also serves the
+// userinfo of a user
+func newBuiltinProviderIdP(t *testing.T, issuerPath string) *fakeOIDCIdP {
+	idp := newFakeOIDCIdP(newTestIdPKey(t, "key-1"))
+	idp.issuerPath = issuerPath
+	idp.userInfo = map[string]interface{}{
+		"sub":            idp.server.URL + "/id/org-1/user-1",
+		"user_id":        "user-1",
+		"email":          "user@example.com",
+		"email_verified": true,
+	}
+	return idp
+}
+
+func createBuiltinProviderForTest(t *testing.T, config tpmodels.ProviderConfig) (*tpmodels.TypeProvider, error) {
+	config.Clients = []tpmodels.ProviderClientConfig{{ClientID: "client", ClientSecret: "secret", AdditionalConfig: config.Clients[0].AdditionalConfig}}
+	return providers.FindAndCreateProviderInstance([]tpmodels.ProviderInput{{Config: config}}, config.ThirdPartyId, nil, &map[string]interface{}{})
+}
+
+func getAuthorisationURLQuery(t *testing.T, provider *tpmodels.TypeProvider) url.Values {
+	redirect, err := provider.GetAuthorisationRedirectURL("https://example.com/callback", &map[string]interface{}{})
+	assert.NoError(t, err)
+	redirectURL, err := url.Parse(redirect.URLWithQueryParams)
+	assert.NoError(t, err)
+	return redirectURL.Query()
+}
+
+func TestMicrosoftEntraProviderValidatesTheIssuer(t *testing.T) {
+	idp := newBuiltinProviderIdP(t, "/{tenantid}/v2.0")
+	defer idp.server.Close()
+
+	provider, err := createBuiltinProviderForTest(t, tpmodels.ProviderConfig{
+		ThirdPartyId: "microsoft-entra",
+		Clients:      []tpmodels.ProviderClientConfig{{AdditionalConfig: map[string]interface{}{"authorityUrl": idp.server.URL}}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/common/v2.0/.well-known/openid-configuration"}, idp.getDiscoveryPaths())
+	assert.Equal(t, []string{"openid", "email", "profile"}, provider.Config.Scope)
+
+	userInfo, err := provider.GetUserInfo(tpmodels.TypeOAuthTokens{"id_token": idp.makeIdToken(t, jwt.MapClaims{
+		"iss":   idp.server.URL + "/tenant-1/v2.0",
+		"tid":   "tenant-1",
+		"sub":   "pairwise-sub",
+		"oid":   "object-1",
+		"email": "user@example.com",
+	})}, &map[string]interface{}{})
+	assert.NoError(t, err)
+	assert.Equal(t, "object-1", userInfo.ThirdPartyUserId)
+	assert.Equal(t, "user@example.com", userInfo.Email.ID)
+
+	// The issuer must be the one of the tenant of the user
+	_, err = provider.GetUserInfo(tpmodels.TypeOAuthTokens{"id_token": idp.makeIdToken(t, jwt.MapClaims{
+		"iss": idp.server.URL + "/tenant-2/v2.0",
+		"tid": "tenant-1",
+		"oid": "object-1",
+	})}, &map[string]interface{}{})
+	assert.Error(t, err)
+
+	provider, err = createBuiltinProviderForTest(t, tpmodels.ProviderConfig{
+		ThirdPartyId: "microsoft-entra",
+		Clients: []tpmodels.ProviderClientConfig{{AdditionalConfig: map[string]interface{}{
+			"authorityUrl":     idp.server.URL,
+			"tenant":           "organizations",
+			"allowedTenantIds": []interface{}{"tenant-2"},
+		}}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "/organizations/v2.0/.well-known/openid-configuration", idp.getDiscoveryPaths()[1])
+	_, err = provider.GetUserInfo(tpmodels.TypeOAuthTokens{"id_token": idp.makeIdToken(t, jwt.MapClaims{
+		"iss": idp.server.URL + "/tenant-1/v2.0",
+		"tid": "tenant-1",
+		"oid": "object-1",
+	})}, &map[string]interface{}{})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "tenant-1 is not allowed")
+}
+
+func TestSlackProviderRestrictsTheWorkspace(t *testing.T) {
+	idp := newBuiltinProviderIdP(t, "")
+	defer idp.server.Close()
+
+	provider, err := createBuiltinProviderForTest(t, tpmodels.ProviderConfig{
+		ThirdPartyId:          "slack",
+		OIDCDiscoveryEndpoint: idp.server.URL,
+		Clients:               []tpmodels.ProviderClientConfig{{AdditionalConfig: map[string]interface{}{"teamId": "T1"}}},
+	})
+	assert.NoError(t, err)
+	query := getAuthorisationURLQuery(t, provider)
+	assert.Equal(t, "T1", query.Get("team"))
+	assert.Equal(t, "openid email profile", query.Get("scope"))
+
+	userInfo, err := provider.GetUserInfo(tpmodels.TypeOAuthTokens{"id_token": idp.makeIdToken(t, jwt.MapClaims{
+		"sub":                       "U1",
+		"email":                     "user@example.com",
+		"email_verified":            true,
+		"https://slack.com/team_id": "T1",
+	})}, &map[string]interface{}{})
+	assert.NoError(t, err)
+	assert.Equal(t, "U1", userInfo.ThirdPartyUserId)
+	assert.True(t, userInfo.Email.IsVerified)
+
+	_, err = provider.GetUserInfo(tpmodels.TypeOAuthTokens{"id_token": idp.makeIdToken(t, jwt.MapClaims{
+		"sub":                       "U2",
+		"https://slack.com/team_id": "T2",
+	})}, &map[string]interface{}{})
+	assert.Error(t, err)
+}
+
+func TestSalesforceProviderUsesTheUserId(t *testing.T) {
+	idp := newBuiltinProviderIdP(t, "")
+	defer idp.server.Close()
+
+	provider, err := createBuiltinProviderForTest(t, tpmodels.ProviderConfig{
+		ThirdPartyId: "salesforce",
+		Clients:      []tpmodels.ProviderClientConfig{{AdditionalConfig: map[string]interface{}{"salesforceDomain": idp.server.URL}}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/.well-known/openid-configuration"}, idp.getDiscoveryPaths())
+
+	userInfo, err := provider.GetUserInfo(tpmodels.TypeOAuthTokens{"access_token": "token"}, &map[string]interface{}{})
+	assert.NoError(t, err)
+	assert.Equal(t, "user-1", userInfo.ThirdPartyUserId)
+	assert.Equal(t, "user@example.com", userInfo.Email.ID)
+	assert.True(t, userInfo.Email.IsVerified)
+}
+
+func TestKeycloakProviderUsesTheRealm(t *testing.T) {
+	idp := newBuiltinProviderIdP(t, "")
+	defer idp.server.Close()
+
+	_, err := createBuiltinProviderForTest(t, tpmodels.ProviderConfig{
+		ThirdPartyId: "keycloak",
+		Clients:      []tpmodels.ProviderClientConfig{{AdditionalConfig: map[string]interface{}{"keycloakBaseUrl": idp.server.URL}}},
+	})
+	assert.Error(t, err)
+
+	provider, err := createBuiltinProviderForTest(t, tpmodels.ProviderConfig{
+		ThirdPartyId: "keycloak",
+		Clients: []tpmodels.ProviderClientConfig{{AdditionalConfig: map[string]interface{}{
+			"keycloakBaseUrl": idp.server.URL + "/auth/",
+			"realm":           "My Realm",
+		}}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/auth/realms/My%20Realm/.well-known/openid-configuration"}, idp.getDiscoveryPaths())
+	assert.Equal(t, idp.server.URL+"/authorize", provider.Config.AuthorizationEndpoint)
+}
+
+func TestAuth0ProviderSendsTheAudience(t *testing.T) {
+	idp := newBuiltinProviderIdP(t, "")
+	defer idp.server.Close()
+
+	_, err := createBuiltinProviderForTest(t, tpmodels.ProviderConfig{
+		ThirdPartyId: "auth0",
+		Clients:      []tpmodels.ProviderClientConfig{{}},
+	})
+	assert.Error(t, err)
+
+	provider, err := createBuiltinProviderForTest(t, tpmodels.ProviderConfig{
+		ThirdPartyId: "auth0",
+		Clients: []tpmodels.ProviderClientConfig{{AdditionalConfig: map[string]interface{}{
+			"auth0Domain": idp.server.URL,
+			"audience":    "https://api.example.com",
+		}}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "Auth0", provider.Config.Name)
+	query := getAuthorisationURLQuery(t, provider)
+	assert.Equal(t, "https://api.example.com", query.Get("audience"))
+	assert.Equal(t, "client", query.Get("client_id"))
+}
+
+func TestBuiltinProvidersAreUsedForThirdPartyIdsStartingWithTheirId(t *testing.T) {
+	idp := newBuiltinProviderIdP(t, "")
+	defer idp.server.Close()
+
+	// like the other built-in providers, a suffix can be added to the third party id to have several
+	// configs of the same provider, like one per realm
+	for thirdPartyId, name := range map[string]string{
+		"microsoft-entra-contoso": "Microsoft",
+		"slack-workspace2":        "Slack",
+		"salesforce-sandbox":      "Salesforce",
+		"keycloak-realm2":         "Keycloak",
+		"auth0-staging":           "Auth0",
+	} {
+		provider, err := createBuiltinProviderForTest(t, tpmodels.ProviderConfig{
+			ThirdPartyId:          thirdPartyId,
+			OIDCDiscoveryEndpoint: idp.server.URL,
+			Clients:               []tpmodels.ProviderClientConfig{{}},
+		})
+		if assert.NoError(t, err, thirdPartyId) {
+			assert.Equal(t, name, provider.Config.Name, thirdPartyId)
+			assert.Equal(t, idp.server.URL+"/authorize", provider.Config.AuthorizationEndpoint, thirdPartyId)
+		}
+	}
+
+	provider, err := providers.FindAndCreateProviderInstance([]tpmodels.ProviderInput{{Config: tpmodels.ProviderConfig{
+		ThirdPartyId: "saml-okta",
+		Clients: []tpmodels.ProviderClientConfig{{ClientID: "client", AdditionalConfig: map[string]interface{}{
+			"idpMetadataXML": makeTestSAMLIdPMetadata(t, dsig.RandomKeyStoreForTest()),
+		}}},
+	}}}, "saml-okta", nil, &map[string]interface{}{})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "SAML", provider.Config.Name)
+	}
+}
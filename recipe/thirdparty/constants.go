@@ -16,7 +16,11 @@
 package thirdparty
 
 const (
-	AuthorisationAPI        = "/authorisationurl"
-	SignInUpAPI             = "/signinup"
-	AppleRedirectHandlerAPI = "/callback/apple"
+	AuthorisationAPI           = "/authorisationurl"
+	SignInUpAPI                = "/signinup"
+	AppleRedirectHandlerAPI    = "/callback/apple"
+	LinkedIdentitiesAPI        = "/identities"
+	LinkIdentityAPI            = "/identities/link"
+	UnlinkIdentityAPI          = "/identities/unlink"
+	AppleServerNotificationAPI = "/notifications/apple"
 )
@@ -0,0 +1,140 @@
+/*
+ * Copyright (c) 2021, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thirdparty
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/supertokens/supertokens-golang/recipe/thirdparty/providers"
+	"github.com/supertokens/supertokens-golang/recipe/thirdparty/tperrors"
+	"github.com/supertokens/supertokens-golang/recipe/thirdparty/tpmodels"
+)
+
+func assertIdTokenValidationError(t *testing.T, err error, reason string) {
+	validationError := tperrors.IdTokenValidationError{}
+	if assert.True(t, errors.As(err, &validationError), "expected an IdTokenValidationError, got %v", err) {
+		assert.Equal(t, reason, validationError.Reason)
+	}
+}
+
+func TestIdTokenClaimsAreValidatedByDefault(t *testing.T) {
+	idp := newBuiltinProviderIdP(t, "")
+	defer idp.server.Close()
+
+	provider, err := createBuiltinProviderForTest(t, tpmodels.ProviderConfig{
+		ThirdPartyId:          "custom-oidc",
+		OIDCDiscoveryEndpoint: idp.server.URL + "/.well-known/openid-configuration",
+		Clients:               []tpmodels.ProviderClientConfig{{}},
+	})
+	assert.NoError(t, err)
+
+	getUserInfo := func(claims jwt.MapClaims) error {
+		claims["sub"] = "user-1"
+		_, err := provider.GetUserInfo(tpmodels.TypeOAuthTokens{"id_token": idp.makeIdToken(t, claims)}, &map[string]interface{}{})
+		return err
+	}
+	now := time.Now()
+
+	assert.NoError(t, getUserInfo(jwt.MapClaims{}))
+	// Within the default clock skew
+	assert.NoError(t, getUserInfo(jwt.MapClaims{"exp": now.Add(-30 * time.Second).Unix(), "iat": now.Add(30 * time.Second).Unix()}))
+	assert.NoError(t, getUserInfo(jwt.MapClaims{"aud": []string{"client", "other"}, "azp": "client"}))
+
+	assertIdTokenValidationError(t, getUserInfo(jwt.MapClaims{"iss": "https://attacker.example.com"}), tperrors.IdTokenInvalidIssuer)
+	assertIdTokenValidationError(t, getUserInfo(jwt.MapClaims{"aud": "other-client"}), tperrors.IdTokenInvalidAudience)
+	assertIdTokenValidationError(t, getUserInfo(jwt.MapClaims{"aud": []string{"client", "other"}}), tperrors.IdTokenInvalidAuthorizedParty)
+	assertIdTokenValidationError(t, getUserInfo(jwt.MapClaims{"aud": []string{"client", "other"}, "azp": "other"}), tperrors.IdTokenInvalidAuthorizedParty)
+	assertIdTokenValidationError(t, getUserInfo(jwt.MapClaims{"exp": now.Add(-2 * time.Minute).Unix()}), tperrors.IdTokenExpired)
+	assertIdTokenValidationError(t, getUserInfo(jwt.MapClaims{"exp": nil}), tperrors.IdTokenMissingClaim)
+	assertIdTokenValidationError(t, getUserInfo(jwt.MapClaims{"iat": now.Add(5 * time.Minute).Unix()}), tperrors.IdTokenIssuedInTheFuture)
+	assertIdTokenValidationError(t, getUserInfo(jwt.MapClaims{"nbf": now.Add(5 * time.Minute).Unix()}), tperrors.IdTokenNotYetValid)
+}
+
+func TestIdTokenNonceIsValidatedWhenExpected(t *testing.T) {
+	idp := newBuiltinProviderIdP(t, "")
+	defer idp.server.Close()
+
+	disabled := tpmodels.IdTokenValidationConfig{Disable: true}
+	for _, idTokenValidation := range []*tpmodels.IdTokenValidationConfig{nil, &disabled} {
+		provider, err := createBuiltinProviderForTest(t, tpmodels.ProviderConfig{
+			ThirdPartyId:          "custom-oidc",
+			OIDCDiscoveryEndpoint: idp.server.URL + "/.well-known/openid-configuration",
+			Clients:               []tpmodels.ProviderClientConfig{{}},
+			IdTokenValidation:     idTokenValidation,
+		})
+		assert.NoError(t, err)
+
+		getUserInfo := func(claims jwt.MapClaims, expectedNonce string) error {
+			claims["sub"] = "user-1"
+			userContext := &map[string]interface{}{}
+			if expectedNonce != "" {
+				providers.SetExpectedIdTokenNonce(userContext, expectedNonce)
+			}
+			_, err := provider.GetUserInfo(tpmodels.TypeOAuthTokens{"id_token": idp.makeIdToken(t, claims)}, userContext)
+			return err
+		}
+
+		assert.NoError(t, getUserInfo(jwt.MapClaims{}, ""))
+		assert.NoError(t, getUserInfo(jwt.MapClaims{"nonce": "nonce-1"}, ""))
+		assert.NoError(t, getUserInfo(jwt.MapClaims{"nonce": "nonce-1"}, "nonce-1"))
+		assertIdTokenValidationError(t, getUserInfo(jwt.MapClaims{"nonce": "nonce-2"}, "nonce-1"), tperrors.IdTokenInvalidNonce)
+		assertIdTokenValidationError(t, getUserInfo(jwt.MapClaims{}, "nonce-1"), tperrors.IdTokenInvalidNonce)
+	}
+}
+
+func TestIdTokenValidationCanBeConfigured(t *testing.T) {
+	idp := newBuiltinProviderIdP(t, "")
+	defer idp.server.Close()
+
+	createProvider := func(idTokenValidation tpmodels.IdTokenValidationConfig) *tpmodels.TypeProvider {
+		provider, err := createBuiltinProviderForTest(t, tpmodels.ProviderConfig{
+			ThirdPartyId:          "custom-oidc",
+			OIDCDiscoveryEndpoint: idp.server.URL + "/.well-known/openid-configuration",
+			Clients:               []tpmodels.ProviderClientConfig{{}},
+			IdTokenValidation:     &idTokenValidation,
+		})
+		assert.NoError(t, err)
+		return provider
+	}
+	getUserInfo := func(provider *tpmodels.TypeProvider, claims jwt.MapClaims) error {
+		claims["sub"] = "user-1"
+		_, err := provider.GetUserInfo(tpmodels.TypeOAuthTokens{"id_token": idp.makeIdToken(t, claims)}, &map[string]interface{}{})
+		return err
+	}
+	expiredRecently := jwt.MapClaims{"exp": time.Now().Add(-30 * time.Second).Unix()}
+
+	noClockSkew := uint64(0)
+	provider := createProvider(tpmodels.IdTokenValidationConfig{ClockSkewSec: &noClockSkew})
+	assertIdTokenValidationError(t, getUserInfo(provider, expiredRecently), tperrors.IdTokenExpired)
+
+	provider = createProvider(tpmodels.IdTokenValidationConfig{
+		Issuers:             []string{"https://issuer.example.com"},
+		AdditionalAudiences: []string{"api"},
+	})
+	assert.NoError(t, getUserInfo(provider, jwt.MapClaims{"iss": "https://issuer.example.com", "aud": "api"}))
+	assertIdTokenValidationError(t, getUserInfo(provider, jwt.MapClaims{}), tperrors.IdTokenInvalidIssuer)
+
+	// The signature is still checked when the validation of the claims is disabled
+	provider = createProvider(tpmodels.IdTokenValidationConfig{Disable: true})
+	assert.NoError(t, getUserInfo(provider, jwt.MapClaims{"iss": "https://attacker.example.com", "aud": "other-client"}))
+	_, err := provider.GetUserInfo(tpmodels.TypeOAuthTokens{"id_token": newTestIdPKey(t, "key-1").makeIdToken(t, jwt.MapClaims{"sub": "user-1"})}, &map[string]interface{}{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,176 @@
+/* Copyright (c) 2021, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thirdparty
+
+import (
+	"time"
+
+	"github.com/supertokens/supertokens-golang/recipe/thirdparty/tpmodels"
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+type getUserByIDFunc func(userID string, userContext supertokens.UserContext) (*tpmodels.User, error)
+type getUserByThirdPartyInfoFunc func(thirdPartyID string, thirdPartyUserID string, tenantId string, userContext supertokens.UserContext) (*tpmodels.User, error)
+
+// getUserOfLinkedIdentity returns the user that the identity is linked to, if that user is in the
+// tenant. Otherwise the identity signs in (or up) as a user of its own, like before it was linked.
+// A user that signed up with the identity comes first, so nil is returned if there is one. The
+// Apple server notifications resolve identities to users in the same order.
+func getUserOfLinkedIdentity(config tpmodels.LinkedIdentitiesConfig, thirdPartyID string, thirdPartyUserID string, tenantId string, getUserByID getUserByIDFunc, getUserByThirdPartyInfo getUserByThirdPartyInfoFunc, userContext supertokens.UserContext) (*tpmodels.User, error) {
+	signedUpUser, err := getUserByThirdPartyInfo(thirdPartyID, thirdPartyUserID, tenantId, userContext)
+	if err != nil || signedUpUser != nil {
+		return nil, err
+	}
+
+	userID, err := config.Store.GetUserIdByIdentity(thirdPartyID, thirdPartyUserID, userContext)
+	if err != nil || userID == nil {
+		return nil, err
+	}
+	user, err := getUserByID(*userID, userContext)
+	if err != nil || user == nil {
+		return nil, err
+	}
+	for _, userTenantId := range user.TenantIds {
+		if userTenantId == tenantId {
+			return user, nil
+		}
+	}
+	return nil, nil
+}
+
+func linkIdentity(config tpmodels.LinkedIdentitiesConfig, userID string, thirdPartyID string, thirdPartyUserID string, email string, tenantId string, getUserByID getUserByIDFunc, getUserByThirdPartyInfo getUserByThirdPartyInfoFunc, userContext supertokens.UserContext) (tpmodels.LinkIdentityResponse, error) {
+	user, err := getUserByID(userID, userContext)
+	if err != nil {
+		return tpmodels.LinkIdentityResponse{}, err
+	}
+	if user == nil {
+		return tpmodels.LinkIdentityResponse{
+			UnknownUserIdError: &struct{}{},
+		}, nil
+	}
+
+	// Users that signed up with the identity would not be able to sign in anymore
+	existingUser, err := getUserByThirdPartyInfo(thirdPartyID, thirdPartyUserID, tenantId, userContext)
+	if err != nil {
+		return tpmodels.LinkIdentityResponse{}, err
+	}
+	if existingUser != nil {
+		return tpmodels.LinkIdentityResponse{
+			IdentityAlreadyLinkedError: &struct{}{},
+		}, nil
+	}
+
+	identity := tpmodels.LinkedIdentity{
+		ThirdPartyId:     thirdPartyID,
+		ThirdPartyUserId: thirdPartyUserID,
+		Email:            email,
+		TimeLinked:       uint64(time.Now().UnixNano() / 1000000),
+	}
+	saved, err := config.Store.Save(userID, identity, userContext)
+	if err != nil {
+		return tpmodels.LinkIdentityResponse{}, err
+	}
+	if !saved {
+		return tpmodels.LinkIdentityResponse{
+			IdentityAlreadyLinkedError: &struct{}{},
+		}, nil
+	}
+
+	// Someone may have signed up with the identity after it was checked above. Sign ins use the user
+	// that signed up with an identity before the one it is linked to (see getUserOfLinkedIdentity), so
+	// the link is removed again. A sign up that finishes after this check is not noticed, and the
+	// identity then keeps signing in as the new user until it is unlinked.
+	existingUser, err = getUserByThirdPartyInfo(thirdPartyID, thirdPartyUserID, tenantId, userContext)
+	if err != nil {
+		return tpmodels.LinkIdentityResponse{}, err
+	}
+	if existingUser != nil {
+		_, err = config.Store.Delete(userID, thirdPartyID, thirdPartyUserID, userContext)
+		if err != nil {
+			return tpmodels.LinkIdentityResponse{}, err
+		}
+		return tpmodels.LinkIdentityResponse{
+			IdentityAlreadyLinkedError: &struct{}{},
+		}, nil
+	}
+	supertokens.LogInfo("thirdparty: identity linked", supertokens.RecipeLogFields(RECIPE_ID, tenantId, userContext, supertokens.LogFieldUserID, userID, "thirdPartyId", thirdPartyID)...)
+	return tpmodels.LinkIdentityResponse{
+		OK: &struct{ Identity tpmodels.LinkedIdentity }{
+			Identity: identity,
+		},
+	}, nil
+}
+
+func unlinkIdentity(config tpmodels.LinkedIdentitiesConfig, userID string, thirdPartyID string, thirdPartyUserID string, getUserByID getUserByIDFunc, userContext supertokens.UserContext) (tpmodels.UnlinkIdentityResponse, error) {
+	user, err := getUserByID(userID, userContext)
+	if err != nil {
+		return tpmodels.UnlinkIdentityResponse{}, err
+	}
+	if user != nil && user.ThirdParty.ID == thirdPartyID && user.ThirdParty.UserID == thirdPartyUserID {
+		return tpmodels.UnlinkIdentityResponse{
+			PrimaryIdentityError: &struct{}{},
+		}, nil
+	}
+
+	deleted, err := config.Store.Delete(userID, thirdPartyID, thirdPartyUserID, userContext)
+	if err != nil {
+		return tpmodels.UnlinkIdentityResponse{}, err
+	}
+	if !deleted {
+		return tpmodels.UnlinkIdentityResponse{
+			IdentityNotFoundError: &struct{}{},
+		}, nil
+	}
+	supertokens.LogInfo("thirdparty: identity unlinked", supertokens.RecipeLogFields(RECIPE_ID, "", userContext, supertokens.LogFieldUserID, userID, "thirdPartyId", thirdPartyID)...)
+	return tpmodels.UnlinkIdentityResponse{
+		OK: &struct{}{},
+	}, nil
+}
+
+func listLinkedIdentities(config *tpmodels.LinkedIdentitiesConfig, userID string, getUserByID getUserByIDFunc, userContext supertokens.UserContext) (tpmodels.ListLinkedIdentitiesResponse, error) {
+	user, err := getUserByID(userID, userContext)
+	if err != nil {
+		return tpmodels.ListLinkedIdentitiesResponse{}, err
+	}
+	if user == nil {
+		return tpmodels.ListLinkedIdentitiesResponse{
+			UnknownUserIdError: &struct{}{},
+		}, nil
+	}
+
+	identities := []tpmodels.LinkedIdentity{{
+		ThirdPartyId:     user.ThirdParty.ID,
+		ThirdPartyUserId: user.ThirdParty.UserID,
+		Email:            user.Email,
+		TimeLinked:       user.TimeJoined,
+		IsPrimary:        true,
+	}}
+	if config != nil {
+		linkedIdentities, err := config.Store.ListForUser(userID, userContext)
+		if err != nil {
+			return tpmodels.ListLinkedIdentitiesResponse{}, err
+		}
+		for _, identity := range linkedIdentities {
+			identity.IsPrimary = false
+			identities = append(identities, identity)
+		}
+	}
+	return tpmodels.ListLinkedIdentitiesResponse{
+		OK: &struct{ Identities []tpmodels.LinkedIdentity }{
+			Identities: identities,
+		},
+	}, nil
+}
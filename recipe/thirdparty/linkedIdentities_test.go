@@ -0,0 +1,383 @@
+/*
+ * Copyright (c) 2021, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thirdparty
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/supertokens/supertokens-golang/recipe/session/sessmodels"
+	"github.com/supertokens/supertokens-golang/recipe/thirdparty/api"
+	"github.com/supertokens/supertokens-golang/recipe/thirdparty/tpmodels"
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+type memoryLinkedIdentityStore struct {
+	lock       sync.Mutex
+	identities map[string][]tpmodels.LinkedIdentity
+}
+
+func (s *memoryLinkedIdentityStore) Save(userID string, identity tpmodels.LinkedIdentity, userContext supertokens.UserContext) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, identities := range s.identities {
+		for _, linkedIdentity := range identities {
+			if linkedIdentity.ThirdPartyId == identity.ThirdPartyId && linkedIdentity.ThirdPartyUserId == identity.ThirdPartyUserId {
+				return false, nil
+			}
+		}
+	}
+	s.identities[userID] = append(s.identities[userID], identity)
+	return true, nil
+}
+
+func (s *memoryLinkedIdentityStore) GetUserIdByIdentity(thirdPartyID string, thirdPartyUserID string, userContext supertokens.UserContext) (*string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for userID, identities := range s.identities {
+		for _, identity := range identities {
+			if identity.ThirdPartyId == thirdPartyID && identity.ThirdPartyUserId == thirdPartyUserID {
+				return &userID, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+func (s *memoryLinkedIdentityStore) ListForUser(userID string, userContext supertokens.UserContext) ([]tpmodels.LinkedIdentity, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.identities[userID], nil
+}
+
+func (s *memoryLinkedIdentityStore) Delete(userID string, thirdPartyID string, thirdPartyUserID string, userContext supertokens.UserContext) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for i, identity := range s.identities[userID] {
+		if identity.ThirdPartyId == thirdPartyID && identity.ThirdPartyUserId == thirdPartyUserID {
+			s.identities[userID] = append(s.identities[userID][:i], s.identities[userID][i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// linkedIdentitiesTestUsers has the user "user-1", who signed up with google, and "user-2", who
+// signed up with github
+func linkedIdentitiesTestUsers() (getUserByIDFunc, getUserByThirdPartyInfoFunc) {
+	users := []tpmodels.User{{ID: "user-1", TimeJoined: 1000, Email: "user1@example.com", TenantIds: []string{"public"}}, {ID: "user-2", TimeJoined: 2000, Email: "user2@example.com", TenantIds: []string{"public"}}}
+	users[0].ThirdParty.ID, users[0].ThirdParty.UserID = "google", "google-1"
+	users[1].ThirdParty.ID, users[1].ThirdParty.UserID = "github", "github-2"
+
+	getUserByID := func(userID string, userContext supertokens.UserContext) (*tpmodels.User, error) {
+		for _, user := range users {
+			if user.ID == userID {
+				return &user, nil
+			}
+		}
+		return nil, nil
+	}
+	getUserByThirdPartyInfo := func(thirdPartyID string, thirdPartyUserID string, tenantId string, userContext supertokens.UserContext) (*tpmodels.User, error) {
+		for _, user := range users {
+			if user.ThirdParty.ID == thirdPartyID && user.ThirdParty.UserID == thirdPartyUserID {
+				return &user, nil
+			}
+		}
+		return nil, nil
+	}
+	return getUserByID, getUserByThirdPartyInfo
+}
+
+func TestIdentitiesCanBeLinkedAndUnlinked(t *testing.T) {
+	config := tpmodels.LinkedIdentitiesConfig{Store: &memoryLinkedIdentityStore{identities: map[string][]tpmodels.LinkedIdentity{}}}
+	getUserByID, getUserByThirdPartyInfo := linkedIdentitiesTestUsers()
+	userContext := &map[string]interface{}{}
+
+	linkResponse, err := linkIdentity(config, "user-1", "github", "github-1", "user1@example.com", "public", getUserByID, getUserByThirdPartyInfo, userContext)
+	assert.NoError(t, err)
+	assert.NotNil(t, linkResponse.OK)
+	assert.Equal(t, "github-1", linkResponse.OK.Identity.ThirdPartyUserId)
+
+	// The identity signs in as the user it is linked to, in the tenants of the user
+	user, err := getUserOfLinkedIdentity(config, "github", "github-1", "public", getUserByID, getUserByThirdPartyInfo, userContext)
+	assert.NoError(t, err)
+	assert.Equal(t, "user-1", user.ID)
+	user, err = getUserOfLinkedIdentity(config, "github", "github-1", "tenant-1", getUserByID, getUserByThirdPartyInfo, userContext)
+	assert.NoError(t, err)
+	assert.Nil(t, user)
+
+	listResponse, err := listLinkedIdentities(&config, "user-1", getUserByID, userContext)
+	assert.NoError(t, err)
+	assert.Len(t, listResponse.OK.Identities, 2)
+	assert.Equal(t, tpmodels.LinkedIdentity{ThirdPartyId: "google", ThirdPartyUserId: "google-1", Email: "user1@example.com", TimeLinked: 1000, IsPrimary: true}, listResponse.OK.Identities[0])
+	assert.False(t, listResponse.OK.Identities[1].IsPrimary)
+
+	unlinkResponse, err := unlinkIdentity(config, "user-1", "google", "google-1", getUserByID, userContext)
+	assert.NoError(t, err)
+	assert.NotNil(t, unlinkResponse.PrimaryIdentityError)
+
+	unlinkResponse, err = unlinkIdentity(config, "user-1", "github", "github-1", getUserByID, userContext)
+	assert.NoError(t, err)
+	assert.NotNil(t, unlinkResponse.OK)
+
+	unlinkResponse, err = unlinkIdentity(config, "user-1", "github", "github-1", getUserByID, userContext)
+	assert.NoError(t, err)
+	assert.NotNil(t, unlinkResponse.IdentityNotFoundError)
+
+	user, err = getUserOfLinkedIdentity(config, "github", "github-1", "public", getUserByID, getUserByThirdPartyInfo, userContext)
+	assert.NoError(t, err)
+	assert.Nil(t, user)
+}
+
+func TestIdentitiesCanOnlyBeLinkedOnce(t *testing.T) {
+	config := tpmodels.LinkedIdentitiesConfig{Store: &memoryLinkedIdentityStore{identities: map[string][]tpmodels.LinkedIdentity{}}}
+	getUserByID, getUserByThirdPartyInfo := linkedIdentitiesTestUsers()
+	userContext := &map[string]interface{}{}
+
+	linkResponse, err := linkIdentity(config, "user-3", "github", "github-1", "", "public", getUserByID, getUserByThirdPartyInfo, userContext)
+	assert.NoError(t, err)
+	assert.NotNil(t, linkResponse.UnknownUserIdError)
+
+	// The identity of another user
+	linkResponse, err = linkIdentity(config, "user-1", "github", "github-2", "", "public", getUserByID, getUserByThirdPartyInfo, userContext)
+	assert.NoError(t, err)
+	assert.NotNil(t, linkResponse.IdentityAlreadyLinkedError)
+
+	linkResponse, err = linkIdentity(config, "user-1", "github", "github-1", "", "public", getUserByID, getUserByThirdPartyInfo, userContext)
+	assert.NoError(t, err)
+	assert.NotNil(t, linkResponse.OK)
+	linkResponse, err = linkIdentity(config, "user-2", "github", "github-1", "", "public", getUserByID, getUserByThirdPartyInfo, userContext)
+	assert.NoError(t, err)
+	assert.NotNil(t, linkResponse.IdentityAlreadyLinkedError)
+}
+
+func TestIdentitiesCanOnlyBeLinkedToThirdPartyUsers(t *testing.T) {
+	config := tpmodels.LinkedIdentitiesConfig{Store: &memoryLinkedIdentityStore{identities: map[string][]tpmodels.LinkedIdentity{}}}
+	getUserByID, getUserByThirdPartyInfo := linkedIdentitiesTestUsers()
+	userContext := &map[string]interface{}{}
+
+	// "ep-user" signed up with emailpassword, so the thirdparty recipe does not know them
+	linkResponse, err := linkIdentity(config, "ep-user", "github", "github-1", "", "public", getUserByID, getUserByThirdPartyInfo, userContext)
+	assert.NoError(t, err)
+	assert.NotNil(t, linkResponse.UnknownUserIdError)
+	listResponse, err := listLinkedIdentities(&config, "ep-user", getUserByID, userContext)
+	assert.NoError(t, err)
+	assert.NotNil(t, listResponse.UnknownUserIdError)
+
+	// The APIs tell the frontend why, instead of failing
+	listLinkedIdentitiesImpl := func(userID string, userContext supertokens.UserContext) (tpmodels.ListLinkedIdentitiesResponse, error) {
+		return listLinkedIdentities(&config, userID, getUserByID, userContext)
+	}
+	sessionContainer := &sessmodels.TypeSessionContainer{
+		GetUserIDWithContext: func(userContext supertokens.UserContext) string {
+			return "ep-user"
+		},
+	}
+	apiImpl := api.MakeAPIImplementation()
+	apiResponse, err := (*apiImpl.LinkedIdentitiesGET)(sessionContainer, tpmodels.APIOptions{
+		RecipeImplementation: tpmodels.RecipeInterface{ListLinkedIdentities: &listLinkedIdentitiesImpl},
+	}, userContext)
+	assert.NoError(t, err)
+	if assert.NotNil(t, apiResponse.GeneralError) {
+		assert.Equal(t, "Only users that signed up with a third party provider can link other identities", apiResponse.GeneralError.Message)
+	}
+}
+
+func TestIdentitiesSignedUpWhileBeingLinkedAreNotLinked(t *testing.T) {
+	store := &memoryLinkedIdentityStore{identities: map[string][]tpmodels.LinkedIdentity{}}
+	config := tpmodels.LinkedIdentitiesConfig{Store: store}
+	getUserByID, _ := linkedIdentitiesTestUsers()
+	userContext := &map[string]interface{}{}
+
+	// The identity signs up as a new user after the first check
+	checks := 0
+	getUserByThirdPartyInfo := func(thirdPartyID string, thirdPartyUserID string, tenantId string, userContext supertokens.UserContext) (*tpmodels.User, error) {
+		checks++
+		if checks == 1 {
+			return nil, nil
+		}
+		return &tpmodels.User{ID: "user-3"}, nil
+	}
+
+	linkResponse, err := linkIdentity(config, "user-1", "github", "github-1", "", "public", getUserByID, getUserByThirdPartyInfo, userContext)
+	assert.NoError(t, err)
+	assert.NotNil(t, linkResponse.IdentityAlreadyLinkedError)
+	assert.Equal(t, 2, checks)
+	assert.Empty(t, store.identities["user-1"])
+}
+
+func TestIdentitiesResolveToTheUserThatSignedUpWithThemFirst(t *testing.T) {
+	store := &memoryLinkedIdentityStore{identities: map[string][]tpmodels.LinkedIdentity{}}
+	config := tpmodels.LinkedIdentitiesConfig{Store: store}
+	getUserByID, getUserByThirdPartyInfo := linkedIdentitiesTestUsers()
+	userContext := &map[string]interface{}{}
+
+	// The identity user-2 signed up with is also linked to user-1, for example because the sign up
+	// finished after the checks of linkIdentity
+	saved, err := store.Save("user-1", tpmodels.LinkedIdentity{ThirdPartyId: "github", ThirdPartyUserId: "github-2"}, userContext)
+	assert.NoError(t, err)
+	assert.True(t, saved)
+
+	// Sign ins use user-2, as nil makes them sign in as the user that signed up with the identity
+	user, err := getUserOfLinkedIdentity(config, "github", "github-2", "public", getUserByID, getUserByThirdPartyInfo, userContext)
+	assert.NoError(t, err)
+	assert.Nil(t, user)
+
+	// And so do the Apple server notifications
+	var notifiedUser *tpmodels.User
+	recipeImpl := tpmodels.RecipeInterface{
+		GetUserByID:             (*func(userID string, userContext supertokens.UserContext) (*tpmodels.User, error))(&getUserByID),
+		GetUserByThirdPartyInfo: (*func(thirdPartyID string, thirdPartyUserID string, tenantId string, userContext supertokens.UserContext) (*tpmodels.User, error))(&getUserByThirdPartyInfo),
+	}
+	apiImpl := api.MakeAPIImplementation()
+	_, err = (*apiImpl.AppleServerNotificationPOST)(tpmodels.AppleServerNotification{
+		Type:             tpmodels.AppleServerNotificationEmailDisabled,
+		ThirdPartyId:     "github",
+		ThirdPartyUserId: "github-2",
+	}, "public", tpmodels.APIOptions{
+		RecipeImplementation: recipeImpl,
+		Config: tpmodels.TypeNormalisedInput{
+			LinkedIdentities: &config,
+			AppleServerNotifications: tpmodels.AppleServerNotificationsConfig{
+				OnEmailDisabled: func(notification tpmodels.AppleServerNotification, user *tpmodels.User, tenantId string, userContext supertokens.UserContext) error {
+					notifiedUser = user
+					return nil
+				},
+			},
+		},
+	}, userContext)
+	assert.NoError(t, err)
+	assert.Equal(t, "user-2", notifiedUser.ID)
+}
+
+func TestProviderTokensOfLinkedIdentitiesAreKeptApart(t *testing.T) {
+	resetAll()
+	defer resetAll()
+
+	// The core has the user "user-1", who signed up with the Apple ID "apple-1"
+	user := map[string]interface{}{
+		"id":         "user-1",
+		"email":      "user1@example.com",
+		"timeJoined": 1000,
+		"thirdParty": map[string]interface{}{"id": "apple", "userId": "apple-1"},
+		"tenantIds":  []string{"public"},
+	}
+	coreServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/recipe/user" && r.URL.Query().Get("userId") == "user-1":
+			json.NewEncoder(rw).Encode(map[string]interface{}{"status": "OK", "user": user})
+		case r.URL.Path == "/public/recipe/user" && r.URL.Query().Get("thirdPartyUserId") == "apple-1":
+			json.NewEncoder(rw).Encode(map[string]interface{}{"status": "OK", "user": user})
+		case r.URL.Path == "/recipe/user" || r.URL.Path == "/public/recipe/user":
+			json.NewEncoder(rw).Encode(map[string]interface{}{"status": "UNKNOWN_USER_ID_ERROR"})
+		default:
+			t.Errorf("unexpected request to the core: %s %s", r.Method, r.URL.Path)
+			rw.WriteHeader(404)
+		}
+	}))
+	defer coreServer.Close()
+
+	tokenStore := &memoryProviderTokenStore{tokens: map[string]string{}}
+	err := supertokens.Init(supertokens.TypeInput{
+		Supertokens: &supertokens.ConnectionInfo{
+			ConnectionURI: coreServer.URL,
+		},
+		AppInfo: supertokens.AppInfo{
+			AppName:       "SuperTokens",
+			APIDomain:     "api.supertokens.io",
+			WebsiteDomain: "supertokens.io",
+		},
+		RecipeList: []supertokens.Recipe{
+			Init(&tpmodels.TypeInput{
+				ProviderTokenStorage: &tpmodels.ProviderTokenStorageConfig{
+					Store:         tokenStore,
+					EncryptionKey: "an-encryption-key-that-is-at-least-32-characters-long",
+				},
+				LinkedIdentities: &tpmodels.LinkedIdentitiesConfig{
+					Store: &memoryLinkedIdentityStore{identities: map[string][]tpmodels.LinkedIdentity{}},
+				},
+			}),
+		},
+	})
+	assert.NoError(t, err)
+	supertokens.SetQuerierApiVersionForTests("3.0")
+	defer supertokens.SetQuerierApiVersionForTests("")
+
+	instance, err := GetRecipeInstanceOrThrowError()
+	assert.NoError(t, err)
+	userContext := &map[string]interface{}{}
+
+	// The user signs in with their Apple ID, and then links a second one
+	err = (*instance.RecipeImpl.StoreProviderTokens)("user-1", "apple", "apple-1", tpmodels.TypeOAuthTokens{"access_token": "access-1"}, nil, "public", userContext)
+	assert.NoError(t, err)
+	linkResponse, err := LinkIdentity("public", "user-1", "apple", "apple-2", "")
+	assert.NoError(t, err)
+	assert.NotNil(t, linkResponse.OK)
+	err = (*instance.RecipeImpl.StoreProviderTokens)("user-1", "apple", "apple-2", tpmodels.TypeOAuthTokens{"access_token": "access-2"}, nil, "public", userContext)
+	assert.NoError(t, err)
+
+	getAccessToken := func(thirdPartyUserID string) string {
+		response, err := GetValidProviderAccessTokenForIdentity("user-1", "apple", thirdPartyUserID)
+		assert.NoError(t, err)
+		if response.OK == nil {
+			return ""
+		}
+		return response.OK.AccessToken
+	}
+	assert.Equal(t, "access-1", getAccessToken("apple-1"))
+	assert.Equal(t, "access-2", getAccessToken("apple-2"))
+
+	// Unlinking the second Apple ID keeps the tokens of the first one
+	unlinkResponse, err := UnlinkIdentity("user-1", "apple", "apple-2")
+	assert.NoError(t, err)
+	assert.NotNil(t, unlinkResponse.OK)
+	assert.Equal(t, "access-1", getAccessToken("apple-1"))
+	assert.Equal(t, "", getAccessToken("apple-2"))
+
+	// So does revoking the consent of a linked Apple ID
+	linkResponse, err = LinkIdentity("public", "user-1", "apple", "apple-3", "")
+	assert.NoError(t, err)
+	assert.NotNil(t, linkResponse.OK)
+	err = (*instance.RecipeImpl.StoreProviderTokens)("user-1", "apple", "apple-3", tpmodels.TypeOAuthTokens{"access_token": "access-3"}, nil, "public", userContext)
+	assert.NoError(t, err)
+	notificationResponse, err := (*instance.APIImpl.AppleServerNotificationPOST)(tpmodels.AppleServerNotification{
+		Type:             tpmodels.AppleServerNotificationConsentRevoked,
+		ThirdPartyId:     "apple",
+		ThirdPartyUserId: "apple-3",
+	}, "public", tpmodels.APIOptions{RecipeImplementation: instance.RecipeImpl, Config: instance.Config}, userContext)
+	assert.NoError(t, err)
+	assert.NotNil(t, notificationResponse.OK)
+	assert.Equal(t, "access-1", getAccessToken("apple-1"))
+	assert.Equal(t, "", getAccessToken("apple-3"))
+}
+
+func TestLinkedIdentitiesConfigValidation(t *testing.T) {
+	_, err := validateAndNormaliseLinkedIdentitiesConfig(&tpmodels.LinkedIdentitiesConfig{})
+	assert.Error(t, err)
+
+	config, err := validateAndNormaliseLinkedIdentitiesConfig(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, config)
+
+	recipeImpl := MakeRecipeImplementation(supertokens.Querier{}, nil, nil, nil)
+	_, err = (*recipeImpl.LinkIdentity)("user-1", "github", "github-1", "", "public", &map[string]interface{}{})
+	assert.Error(t, err)
+}
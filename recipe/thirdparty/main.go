@@ -16,6 +16,7 @@
 package thirdparty
 
 import (
+	"github.com/supertokens/supertokens-golang/recipe/thirdparty/providers"
 	"github.com/supertokens/supertokens-golang/recipe/thirdparty/tpmodels"
 	"github.com/supertokens/supertokens-golang/supertokens"
 )
@@ -78,3 +79,100 @@ func GetProvider(tenantId string, thirdPartyID string, clientType *string, userC
 	}
 	return (*instance.RecipeImpl.GetProvider)(thirdPartyID, clientType, tenantId, userContext[0])
 }
+
+// GetValidProviderAccessToken returns the access token of the provider that the user signed up with,
+// refreshing it if it has expired. This needs ProviderTokenStorage to be set in the recipe config.
+func GetValidProviderAccessToken(userID string, thirdPartyID string, userContext ...supertokens.UserContext) (tpmodels.GetValidProviderAccessTokenResponse, error) {
+	instance, err := GetRecipeInstanceOrThrowError()
+	if err != nil {
+		return tpmodels.GetValidProviderAccessTokenResponse{}, err
+	}
+	if len(userContext) == 0 {
+		userContext = append(userContext, &map[string]interface{}{})
+	}
+	return getValidProviderAccessTokenOfSignUpIdentity(instance.RecipeImpl, userID, thirdPartyID, userContext[0])
+}
+
+// DeleteProviderTokens deletes the saved tokens of the provider that the user signed up with.
+func DeleteProviderTokens(userID string, thirdPartyID string, userContext ...supertokens.UserContext) error {
+	instance, err := GetRecipeInstanceOrThrowError()
+	if err != nil {
+		return err
+	}
+	if len(userContext) == 0 {
+		userContext = append(userContext, &map[string]interface{}{})
+	}
+	return deleteProviderTokensOfSignUpIdentity(instance.RecipeImpl, userID, thirdPartyID, userContext[0])
+}
+
+// GetValidProviderAccessTokenForIdentity is like GetValidProviderAccessToken, for any identity of the
+// user, including the ones linked to it with LinkIdentity.
+func GetValidProviderAccessTokenForIdentity(userID string, thirdPartyID string, thirdPartyUserID string, userContext ...supertokens.UserContext) (tpmodels.GetValidProviderAccessTokenResponse, error) {
+	instance, err := GetRecipeInstanceOrThrowError()
+	if err != nil {
+		return tpmodels.GetValidProviderAccessTokenResponse{}, err
+	}
+	if len(userContext) == 0 {
+		userContext = append(userContext, &map[string]interface{}{})
+	}
+	return (*instance.RecipeImpl.GetValidProviderAccessToken)(userID, thirdPartyID, thirdPartyUserID, userContext[0])
+}
+
+// DeleteProviderTokensForIdentity deletes the saved tokens of any identity of the user, including the
+// ones linked to it with LinkIdentity.
+func DeleteProviderTokensForIdentity(userID string, thirdPartyID string, thirdPartyUserID string, userContext ...supertokens.UserContext) error {
+	instance, err := GetRecipeInstanceOrThrowError()
+	if err != nil {
+		return err
+	}
+	if len(userContext) == 0 {
+		userContext = append(userContext, &map[string]interface{}{})
+	}
+	return (*instance.RecipeImpl.DeleteProviderTokens)(userID, thirdPartyID, thirdPartyUserID, userContext[0])
+}
+
+// LinkIdentity links an identity of a provider to the user, so that the user can sign in with it. This
+// needs LinkedIdentities to be set in the recipe config. The user must have signed up with this recipe.
+func LinkIdentity(tenantId string, userID string, thirdPartyID string, thirdPartyUserID string, email string, userContext ...supertokens.UserContext) (tpmodels.LinkIdentityResponse, error) {
+	instance, err := GetRecipeInstanceOrThrowError()
+	if err != nil {
+		return tpmodels.LinkIdentityResponse{}, err
+	}
+	if len(userContext) == 0 {
+		userContext = append(userContext, &map[string]interface{}{})
+	}
+	return (*instance.RecipeImpl.LinkIdentity)(userID, thirdPartyID, thirdPartyUserID, email, tenantId, userContext[0])
+}
+
+func UnlinkIdentity(userID string, thirdPartyID string, thirdPartyUserID string, userContext ...supertokens.UserContext) (tpmodels.UnlinkIdentityResponse, error) {
+	instance, err := GetRecipeInstanceOrThrowError()
+	if err != nil {
+		return tpmodels.UnlinkIdentityResponse{}, err
+	}
+	if len(userContext) == 0 {
+		userContext = append(userContext, &map[string]interface{}{})
+	}
+	return (*instance.RecipeImpl.UnlinkIdentity)(userID, thirdPartyID, thirdPartyUserID, userContext[0])
+}
+
+// ListLinkedIdentities returns the identity the user signed up with, followed by the identities
+// linked to the user.
+func ListLinkedIdentities(userID string, userContext ...supertokens.UserContext) (tpmodels.ListLinkedIdentitiesResponse, error) {
+	instance, err := GetRecipeInstanceOrThrowError()
+	if err != nil {
+		return tpmodels.ListLinkedIdentitiesResponse{}, err
+	}
+	if len(userContext) == 0 {
+		userContext = append(userContext, &map[string]interface{}{})
+	}
+	return (*instance.RecipeImpl.ListLinkedIdentities)(userID, userContext[0])
+}
+
+// ValidateProviderConfig checks a provider config before it is saved for a tenant with
+// multitenancy.CreateOrUpdateThirdPartyConfig. It can be used before the SDK is initialised.
+func ValidateProviderConfig(config tpmodels.ProviderConfig, options tpmodels.ValidateProviderConfigOptions, userContext ...supertokens.UserContext) tpmodels.ProviderConfigValidationResult {
+	if len(userContext) == 0 {
+		userContext = append(userContext, &map[string]interface{}{})
+	}
+	return providers.ValidateProviderConfig(config, options, userContext[0])
+}
@@ -0,0 +1,279 @@
+/*
+ * Copyright (c) 2021, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thirdparty
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/supertokens/supertokens-golang/recipe/thirdparty/api"
+	"github.com/supertokens/supertokens-golang/recipe/thirdparty/providers"
+	"github.com/supertokens/supertokens-golang/recipe/thirdparty/tperrors"
+	"github.com/supertokens/supertokens-golang/recipe/thirdparty/tpmodels"
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+var errTestSignInUpReached = errors.New("sign in up reached")
+
+type memoryOAuthStateStore struct {
+	lock sync.Mutex
+	data map[string]tpmodels.OAuthStateData
+}
+
+func (s *memoryOAuthStateStore) Save(stateId string, data tpmodels.OAuthStateData, userContext supertokens.UserContext) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.data[stateId] = data
+	return nil
+}
+
+func (s *memoryOAuthStateStore) Consume(stateId string, userContext supertokens.UserContext) (*tpmodels.OAuthStateData, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	data, ok := s.data[stateId]
+	if !ok {
+		return nil, nil
+	}
+	delete(s.data, stateId)
+	return &data, nil
+}
+
+// oAuthStateTestSetup has an OIDC provider whose token endpoint records the code verifier it was
+// given and returns an id token with the configured nonce. SignInUp fails with errTestSignInUpReached
+// so that the tests do not need a core.
+type oAuthStateTestSetup struct {
+	idp              *fakeOIDCIdP
+	options          tpmodels.APIOptions
+	apiImpl          tpmodels.APIInterface
+	receivedVerifier string
+	idTokenNonce     string
+}
+
+func newOAuthStateTestSetup(t *testing.T, config tpmodels.OAuthStateConfig) *oAuthStateTestSetup {
+	appInfo, err := supertokens.NormaliseInputAppInfoOrThrowError(supertokens.AppInfo{
+		AppName:       "SuperTokens",
+		APIDomain:     "https://api.supertokens.io",
+		WebsiteDomain: "https://supertokens.io",
+	})
+	assert.NoError(t, err)
+
+	normalisedConfig, err := validateAndNormaliseUserInput(nil, appInfo, &tpmodels.TypeInput{OAuthState: &config})
+	assert.NoError(t, err)
+
+	setup := &oAuthStateTestSetup{idp: newFakeOIDCIdP(newTestIdPKey(t, "key-1")), apiImpl: api.MakeAPIImplementation()}
+	t.Cleanup(setup.idp.server.Close)
+	setup.idp.tokenHandler = func(rw http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, r.ParseForm())
+		setup.receivedVerifier = r.PostForm.Get("code_verifier")
+		json.NewEncoder(rw).Encode(map[string]interface{}{
+			"access_token": "token",
+			"id_token":     setup.makeIdToken(t, setup.idTokenNonce),
+		})
+	}
+
+	provider, err := providers.FindAndCreateProviderInstance([]tpmodels.ProviderInput{
+		{
+			Config: tpmodels.ProviderConfig{
+				ThirdPartyId:          "test-oidc",
+				OIDCDiscoveryEndpoint: setup.idp.server.URL + "/.well-known/openid-configuration",
+				Clients:               []tpmodels.ProviderClientConfig{{ClientID: "client", Scope: []string{"openid", "email"}}},
+			},
+		},
+	}, "test-oidc", nil, &map[string]interface{}{})
+	assert.NoError(t, err)
+	getProvider := func(thirdPartyID string, clientType *string, tenantId string, userContext supertokens.UserContext) (*tpmodels.TypeProvider, error) {
+		return provider, nil
+	}
+	signInUp := func(thirdPartyID string, thirdPartyUserID string, email string, oAuthTokens tpmodels.TypeOAuthTokens, rawUserInfoFromProvider tpmodels.TypeRawUserInfoFromProvider, tenantId string, userContext supertokens.UserContext) (tpmodels.SignInUpResponse, error) {
+		return tpmodels.SignInUpResponse{}, errTestSignInUpReached
+	}
+	setup.options = tpmodels.APIOptions{
+		RecipeImplementation: tpmodels.RecipeInterface{GetProvider: &getProvider, SignInUp: &signInUp},
+		Config:               normalisedConfig,
+		AppInfo:              appInfo,
+	}
+	return setup
+}
+
+func (s *oAuthStateTestSetup) makeIdToken(t *testing.T, nonce string) string {
+	return s.idp.makeIdToken(t, jwt.MapClaims{
+		"sub":            "user-1",
+		"aud":            "client",
+		"email":          "user@example.com",
+		"email_verified": true,
+		"nonce":          nonce,
+	})
+}
+
+// assertReceivedVerifier checks that the token endpoint got the code verifier of the code challenge
+// sent to the provider, and not the one sent by the frontend
+func (s *oAuthStateTestSetup) assertReceivedVerifier(t *testing.T, urlWithQueryParams *url.URL) {
+	challenge := sha256.Sum256([]byte(s.receivedVerifier))
+	assert.Equal(t, urlWithQueryParams.Query().Get("code_challenge"), base64.RawURLEncoding.EncodeToString(challenge[:]))
+}
+
+func (s *oAuthStateTestSetup) getAuthorisationURL(t *testing.T) (*url.URL, *http.Cookie) {
+	res := httptest.NewRecorder()
+	options := s.options
+	options.Req = httptest.NewRequest(http.MethodGet, "/auth/authorisationurl?thirdPartyId=test-oidc&redirectURIOnProviderDashboard="+url.QueryEscape("https://supertokens.io/callback"), nil)
+	options.Res = res
+	assert.NoError(t, api.AuthorisationUrlAPI(s.apiImpl, "public", options, &map[string]interface{}{}))
+
+	respBody := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(res.Body.Bytes(), &respBody))
+	assert.Equal(t, "OK", respBody["status"])
+	assert.NotContains(t, respBody, "pkceCodeVerifier")
+
+	urlWithQueryParams, err := url.Parse(respBody["urlWithQueryParams"].(string))
+	assert.NoError(t, err)
+	cookies := res.Result().Cookies()
+	assert.Equal(t, 1, len(cookies))
+	assert.True(t, cookies[0].HttpOnly)
+	assert.True(t, cookies[0].Secure)
+	assert.Equal(t, "/auth", cookies[0].Path)
+	return urlWithQueryParams, cookies[0]
+}
+
+func (s *oAuthStateTestSetup) signInUp(t *testing.T, state string, cookie *http.Cookie) error {
+	return s.signInUpWithBody(t, map[string]interface{}{
+		"thirdPartyId": "test-oidc",
+		"redirectURIInfo": map[string]interface{}{
+			"redirectURIOnProviderDashboard": "https://supertokens.io/callback",
+			"redirectURIQueryParams":         map[string]interface{}{"code": "code", "state": state},
+			"pkceCodeVerifier":               "verifier-from-frontend",
+		},
+	}, cookie)
+}
+
+func (s *oAuthStateTestSetup) signInUpWithBody(t *testing.T, bodyParams map[string]interface{}, cookie *http.Cookie) error {
+	body, err := json.Marshal(bodyParams)
+	assert.NoError(t, err)
+	options := s.options
+	options.Req = httptest.NewRequest(http.MethodPost, "/auth/signinup", bytes.NewReader(body))
+	if cookie != nil {
+		options.Req.AddCookie(cookie)
+	}
+	options.Res = httptest.NewRecorder()
+	return api.SignInUpAPI(s.apiImpl, "public", options, &map[string]interface{}{})
+}
+
+func TestOAuthStateIsManagedByTheServer(t *testing.T) {
+	configs := map[string]tpmodels.OAuthStateConfig{
+		"encrypted cookie": {Secret: "a-secret-that-is-at-least-32-characters-long"},
+		"store":            {Store: &memoryOAuthStateStore{data: map[string]tpmodels.OAuthStateData{}}},
+	}
+	for name, config := range configs {
+		t.Run(name, func(t *testing.T) {
+			setup := newOAuthStateTestSetup(t, config)
+			urlWithQueryParams, cookie := setup.getAuthorisationURL(t)
+			state := urlWithQueryParams.Query().Get("state")
+			setup.idTokenNonce = urlWithQueryParams.Query().Get("nonce")
+			assert.NotEmpty(t, state)
+			assert.NotEmpty(t, setup.idTokenNonce)
+
+			err := setup.signInUp(t, state, cookie)
+			assert.Equal(t, errTestSignInUpReached, err)
+			setup.assertReceivedVerifier(t, urlWithQueryParams)
+		})
+	}
+}
+
+func TestOAuthStateRejectsInvalidCallbacks(t *testing.T) {
+	config := tpmodels.OAuthStateConfig{Secret: "a-secret-that-is-at-least-32-characters-long"}
+
+	t.Run("state mismatch", func(t *testing.T) {
+		setup := newOAuthStateTestSetup(t, config)
+		urlWithQueryParams, cookie := setup.getAuthorisationURL(t)
+		setup.idTokenNonce = urlWithQueryParams.Query().Get("nonce")
+
+		err := setup.signInUp(t, "some-other-state", cookie)
+		assert.IsType(t, supertokens.BadInputError{}, err)
+		assert.Empty(t, setup.receivedVerifier)
+	})
+
+	t.Run("missing cookie", func(t *testing.T) {
+		setup := newOAuthStateTestSetup(t, config)
+		urlWithQueryParams, _ := setup.getAuthorisationURL(t)
+
+		err := setup.signInUp(t, urlWithQueryParams.Query().Get("state"), nil)
+		assert.IsType(t, supertokens.BadInputError{}, err)
+	})
+
+	t.Run("tampered cookie", func(t *testing.T) {
+		setup := newOAuthStateTestSetup(t, config)
+		urlWithQueryParams, cookie := setup.getAuthorisationURL(t)
+		cookie.Value = cookie.Value[:len(cookie.Value)-4] + "AAAA"
+
+		err := setup.signInUp(t, urlWithQueryParams.Query().Get("state"), cookie)
+		assert.IsType(t, supertokens.BadInputError{}, err)
+	})
+
+	t.Run("nonce mismatch", func(t *testing.T) {
+		setup := newOAuthStateTestSetup(t, config)
+		urlWithQueryParams, cookie := setup.getAuthorisationURL(t)
+		setup.idTokenNonce = "some-other-nonce"
+
+		err := setup.signInUp(t, urlWithQueryParams.Query().Get("state"), cookie)
+		assertIdTokenValidationError(t, err, tperrors.IdTokenInvalidNonce)
+	})
+
+	t.Run("state from a store is single use", func(t *testing.T) {
+		setup := newOAuthStateTestSetup(t, tpmodels.OAuthStateConfig{Store: &memoryOAuthStateStore{data: map[string]tpmodels.OAuthStateData{}}})
+		urlWithQueryParams, cookie := setup.getAuthorisationURL(t)
+		setup.idTokenNonce = urlWithQueryParams.Query().Get("nonce")
+
+		assert.Equal(t, errTestSignInUpReached, setup.signInUp(t, urlWithQueryParams.Query().Get("state"), cookie))
+		assert.IsType(t, supertokens.BadInputError{}, setup.signInUp(t, urlWithQueryParams.Query().Get("state"), cookie))
+	})
+}
+
+func TestOAuthStateRejectsOAuthTokensUnlessAllowed(t *testing.T) {
+	signInUpWithOAuthTokens := func(setup *oAuthStateTestSetup) error {
+		return setup.signInUpWithBody(t, map[string]interface{}{
+			"thirdPartyId": "test-oidc",
+			"oAuthTokens":  map[string]interface{}{"access_token": "token-from-frontend", "id_token": setup.makeIdToken(t, "")},
+		}, nil)
+	}
+
+	setup := newOAuthStateTestSetup(t, tpmodels.OAuthStateConfig{Secret: "a-secret-that-is-at-least-32-characters-long"})
+	err := signInUpWithOAuthTokens(setup)
+	assert.IsType(t, supertokens.BadInputError{}, err)
+
+	setup = newOAuthStateTestSetup(t, tpmodels.OAuthStateConfig{
+		Secret:                       "a-secret-that-is-at-least-32-characters-long",
+		AllowOAuthTokensWithoutState: true,
+	})
+	err = signInUpWithOAuthTokens(setup)
+	assert.Equal(t, errTestSignInUpReached, err)
+}
+
+func TestOAuthStateConfigRequiresALongSecret(t *testing.T) {
+	_, err := validateAndNormaliseUserInput(nil, supertokens.NormalisedAppinfo{}, &tpmodels.TypeInput{
+		OAuthState: &tpmodels.OAuthStateConfig{Secret: "too-short"},
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,262 @@
+/*
+ * Copyright (c) 2021, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thirdparty
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/supertokens/supertokens-golang/recipe/thirdparty/providers"
+	"github.com/supertokens/supertokens-golang/recipe/thirdparty/tpmodels"
+)
+
+type testIdPKey struct {
+	kid        string
+	privateKey *rsa.PrivateKey
+}
+
+func newTestIdPKey(t *testing.T, kid string) testIdPKey {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	return testIdPKey{kid: kid, privateKey: privateKey}
+}
+
+// makeIdToken signs the claims, adding an aud for the client "client" and a valid exp and iat unless
+// they are set
+func (k testIdPKey) makeIdToken(t *testing.T, claims jwt.MapClaims) string {
+	if _, ok := claims["aud"]; !ok {
+		claims["aud"] = "client"
+	}
+	if _, ok := claims["exp"]; !ok {
+		claims["exp"] = time.Now().Add(time.Hour).Unix()
+	}
+	if _, ok := claims["iat"]; !ok {
+		claims["iat"] = time.Now().Unix()
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = k.kid
+	idToken, err := token.SignedString(k.privateKey)
+	assert.NoError(t, err)
+	return idToken
+}
+
+// fakeOIDCIdP serves an OIDC discovery document (on any path ending with the well-known path) and a
+// JWKS, counting the requests for each. The issuer is the URL of the server followed by issuerPath, and
+// a userinfo endpoint is only served if userInfo is set. Requests to the token endpoint are passed to
+// tokenHandler, which is not called with the lock held so that it can use the other methods of the IdP.
+type fakeOIDCIdP struct {
+	server       *httptest.Server
+	tokenHandler http.HandlerFunc
+
+	lock            sync.Mutex
+	keys            []testIdPKey
+	issuerPath      string
+	userInfo        map[string]interface{}
+	discoveryMaxAge int
+	discoveryCalls  int
+	discoveryPaths  []string
+	jwksCalls       int
+}
+
+func newFakeOIDCIdP(keys ...testIdPKey) *fakeOIDCIdP {
+	idp := &fakeOIDCIdP{keys: keys, discoveryMaxAge: 3600}
+	idp.server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" && idp.tokenHandler != nil {
+			idp.tokenHandler(rw, r)
+			return
+		}
+
+		idp.lock.Lock()
+		defer idp.lock.Unlock()
+
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/.well-known/openid-configuration"):
+			idp.discoveryCalls++
+			idp.discoveryPaths = append(idp.discoveryPaths, r.URL.EscapedPath())
+			rw.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(idp.discoveryMaxAge))
+			discoveryDocument := map[string]interface{}{
+				"issuer":                 idp.server.URL + idp.issuerPath,
+				"authorization_endpoint": idp.server.URL + "/authorize",
+				"token_endpoint":         idp.server.URL + "/token",
+				"jwks_uri":               idp.server.URL + "/jwks",
+			}
+			if idp.userInfo != nil {
+				discoveryDocument["userinfo_endpoint"] = idp.server.URL + "/userinfo"
+			}
+			json.NewEncoder(rw).Encode(discoveryDocument)
+		case r.URL.Path == "/jwks":
+			idp.jwksCalls++
+			keys := []interface{}{}
+			for _, key := range idp.keys {
+				keys = append(keys, map[string]interface{}{
+					"kty": "RSA",
+					"kid": key.kid,
+					"alg": "RS256",
+					"use": "sig",
+					"n":   base64.RawURLEncoding.EncodeToString(key.privateKey.N.Bytes()),
+					"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.privateKey.E)).Bytes()),
+				})
+			}
+			rw.Header().Set("Cache-Control", "max-age=3600")
+			json.NewEncoder(rw).Encode(map[string]interface{}{"keys": keys})
+		case r.URL.Path == "/userinfo" && idp.userInfo != nil:
+			json.NewEncoder(rw).Encode(idp.userInfo)
+		default:
+			rw.WriteHeader(404)
+		}
+	}))
+	return idp
+}
+
+// makeIdToken signs the claims with the first key of the IdP, with its issuer unless iss is set
+func (idp *fakeOIDCIdP) makeIdToken(t *testing.T, claims jwt.MapClaims) string {
+	idp.lock.Lock()
+	key := idp.keys[0]
+	issuer := idp.server.URL + idp.issuerPath
+	idp.lock.Unlock()
+
+	if _, ok := claims["iss"]; !ok {
+		claims["iss"] = issuer
+	}
+	return key.makeIdToken(t, claims)
+}
+
+func (idp *fakeOIDCIdP) getDiscoveryPaths() []string {
+	idp.lock.Lock()
+	defer idp.lock.Unlock()
+	return append([]string{}, idp.discoveryPaths...)
+}
+
+func (idp *fakeOIDCIdP) getCalls() (int, int) {
+	idp.lock.Lock()
+	defer idp.lock.Unlock()
+	return idp.discoveryCalls, idp.jwksCalls
+}
+
+func (idp *fakeOIDCIdP) setKeys(keys ...testIdPKey) {
+	idp.lock.Lock()
+	defer idp.lock.Unlock()
+	idp.keys = keys
+}
+
+func configureProviderCacheForTest(t *testing.T, config tpmodels.ProviderCacheConfig) {
+	normalisedConfig, err := validateAndNormaliseProviderCacheConfig(&config)
+	assert.NoError(t, err)
+	providers.ConfigureProviderCache(normalisedConfig)
+}
+
+func getTestOIDCProvider(t *testing.T, idp *fakeOIDCIdP) *tpmodels.TypeProvider {
+	provider, err := providers.FindAndCreateProviderInstance([]tpmodels.ProviderInput{
+		{
+			Config: tpmodels.ProviderConfig{
+				ThirdPartyId:          "test-oidc",
+				OIDCDiscoveryEndpoint: idp.server.URL + "/.well-known/openid-configuration",
+				Clients:               []tpmodels.ProviderClientConfig{{ClientID: "client"}},
+			},
+		},
+	}, "test-oidc", nil, &map[string]interface{}{})
+	assert.NoError(t, err)
+	return provider
+}
+
+func TestProviderCacheHonoursCacheControl(t *testing.T) {
+	configureProviderCacheForTest(t, tpmodels.ProviderCacheConfig{MinTTLSec: 1})
+	defer configureProviderCacheForTest(t, tpmodels.ProviderCacheConfig{})
+
+	idp := newFakeOIDCIdP(newTestIdPKey(t, "key-1"))
+	defer idp.server.Close()
+	idp.discoveryMaxAge = 1
+
+	err := providers.PreWarmProviderCache([]string{idp.server.URL + "/.well-known/openid-configuration"})
+	assert.NoError(t, err)
+	discoveryCalls, jwksCalls := idp.getCalls()
+	assert.Equal(t, 1, discoveryCalls)
+	assert.Equal(t, 1, jwksCalls)
+
+	provider := getTestOIDCProvider(t, idp)
+	assert.Equal(t, idp.server.URL+"/jwks", provider.Config.JwksURI)
+	discoveryCalls, _ = idp.getCalls()
+	assert.Equal(t, 1, discoveryCalls)
+
+	time.Sleep(1100 * time.Millisecond)
+
+	getTestOIDCProvider(t, idp)
+	discoveryCalls, jwksCalls = idp.getCalls()
+	assert.Equal(t, 2, discoveryCalls)
+	assert.Equal(t, 1, jwksCalls)
+
+	metrics := providers.GetProviderCacheMetrics()
+	assert.Equal(t, uint64(1), metrics.DiscoveryHits)
+	assert.Equal(t, uint64(2), metrics.DiscoveryMisses)
+	assert.Equal(t, uint64(1), metrics.JWKSMisses)
+}
+
+func TestProviderCacheRefreshesJWKSOnUnknownKID(t *testing.T) {
+	configureProviderCacheForTest(t, tpmodels.ProviderCacheConfig{UnknownKIDRefreshIntervalSec: 3600})
+	defer configureProviderCacheForTest(t, tpmodels.ProviderCacheConfig{})
+
+	oldKey := newTestIdPKey(t, "key-1")
+	newKey := newTestIdPKey(t, "key-2")
+	idp := newFakeOIDCIdP(oldKey)
+	defer idp.server.Close()
+	provider := getTestOIDCProvider(t, idp)
+	userContext := &map[string]interface{}{}
+
+	userInfo, err := provider.GetUserInfo(tpmodels.TypeOAuthTokens{"id_token": oldKey.makeIdToken(t, jwt.MapClaims{"iss": idp.server.URL, "sub": "user-1"})}, userContext)
+	assert.NoError(t, err)
+	assert.Equal(t, "user-1", userInfo.ThirdPartyUserId)
+
+	// The IdP rotates its signing key
+	idp.setKeys(newKey)
+	userInfo, err = provider.GetUserInfo(tpmodels.TypeOAuthTokens{"id_token": newKey.makeIdToken(t, jwt.MapClaims{"iss": idp.server.URL, "sub": "user-2"})}, userContext)
+	assert.NoError(t, err)
+	assert.Equal(t, "user-2", userInfo.ThirdPartyUserId)
+	_, jwksCalls := idp.getCalls()
+	assert.Equal(t, 2, jwksCalls)
+
+	// Unknown key ids do not make us fetch the JWKS again until the refresh interval has passed
+	_, err = provider.GetUserInfo(tpmodels.TypeOAuthTokens{"id_token": newTestIdPKey(t, "key-3").makeIdToken(t, jwt.MapClaims{"iss": idp.server.URL, "sub": "user-3"})}, userContext)
+	assert.Error(t, err)
+	_, jwksCalls = idp.getCalls()
+	assert.Equal(t, 2, jwksCalls)
+
+	metrics := providers.GetProviderCacheMetrics()
+	assert.Equal(t, uint64(1), metrics.JWKSUnknownKIDRefreshes)
+	assert.Equal(t, uint64(0), metrics.FetchErrors)
+}
+
+func TestProviderCacheConfigValidation(t *testing.T) {
+	_, err := validateAndNormaliseProviderCacheConfig(&tpmodels.ProviderCacheConfig{MinTTLSec: 100, MaxTTLSec: 10})
+	assert.Error(t, err)
+
+	config, err := validateAndNormaliseProviderCacheConfig(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3600), config.DefaultTTLSec)
+	assert.Equal(t, uint64(60), config.MinTTLSec)
+}
@@ -0,0 +1,161 @@
+/*
+ * Copyright (c) 2021, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thirdparty
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/supertokens/supertokens-golang/recipe/thirdparty/providers"
+	"github.com/supertokens/supertokens-golang/recipe/thirdparty/tpmodels"
+)
+
+// stubDiscoveryTransport serves a discovery document for the host sso.example.com, and 404s for
+// every other request
+type stubDiscoveryTransport struct {
+	requestedURLs []string
+}
+
+func (s *stubDiscoveryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	s.requestedURLs = append(s.requestedURLs, req.URL.String())
+	if req.URL.Host != "sso.example.com" || req.URL.Path != "/.well-known/openid-configuration" {
+		return &http.Response{StatusCode: 404, Body: io.NopCloser(bytes.NewBufferString("{}")), Header: http.Header{}, Request: req}, nil
+	}
+	body, _ := json.Marshal(map[string]interface{}{
+		"issuer":                 "https://sso.example.com",
+		"authorization_endpoint": "https://sso.example.com/authorize",
+		"token_endpoint":         "https://sso.example.com/token",
+		"jwks_uri":               "https://sso.example.com/jwks",
+	})
+	return &http.Response{StatusCode: 200, Body: io.NopCloser(bytes.NewBuffer(body)), Header: http.Header{}, Request: req}, nil
+}
+
+func TestProviderConfigValidationChecksTheFields(t *testing.T) {
+	forcePKCE := false
+	result := ValidateProviderConfig(tpmodels.ProviderConfig{
+		ThirdPartyId: "okta",
+		Clients: []tpmodels.ProviderClientConfig{
+			{ClientType: "web", ClientSecret: "secret"},
+			{ClientType: "web", ClientID: "client", AdditionalConfig: map[string]interface{}{"oktaDomain": 1}},
+		},
+	}, tpmodels.ValidateProviderConfigOptions{})
+	assert.False(t, result.IsValid())
+	assert.Equal(t, []tpmodels.ProviderConfigValidationIssue{
+		{Field: "clients[1].clientType", Msg: "the clientType web is used by more than one client"},
+		{Field: "clients[0].clientId", Msg: "the clientId is required"},
+		{Field: "clients[0].additionalConfig.oktaDomain", Msg: "oktaDomain is required in the additionalConfig of the okta provider"},
+		{Field: "clients[1].additionalConfig.oktaDomain", Msg: "oktaDomain must be a string"},
+	}, result.Errors)
+
+	result = ValidateProviderConfig(tpmodels.ProviderConfig{
+		ThirdPartyId: "twitter",
+		Clients:      []tpmodels.ProviderClientConfig{{ClientID: "client", ForcePKCE: &forcePKCE}},
+	}, tpmodels.ValidateProviderConfigOptions{})
+	assert.Equal(t, []tpmodels.ProviderConfigValidationIssue{{Field: "clients[0].forcePKCE", Msg: "Twitter requires PKCE, so forcePKCE cannot be false"}}, result.Errors)
+
+	result = ValidateProviderConfig(tpmodels.ProviderConfig{ThirdPartyId: "apple", Clients: []tpmodels.ProviderClientConfig{{ClientID: "client"}}}, tpmodels.ValidateProviderConfigOptions{})
+	assert.Len(t, result.Errors, 1)
+	assert.Equal(t, "clients[0].additionalConfig", result.Errors[0].Field)
+
+	result = ValidateProviderConfig(tpmodels.ProviderConfig{ThirdPartyId: "custom"}, tpmodels.ValidateProviderConfigOptions{})
+	assert.Equal(t, []tpmodels.ProviderConfigValidationIssue{{Field: "clients", Msg: "at least one client must be configured"}}, result.Errors)
+}
+
+func TestProviderConfigValidationResolvesTheDiscoveryEndpoint(t *testing.T) {
+	transport := &stubDiscoveryTransport{}
+	options := tpmodels.ValidateProviderConfigOptions{
+		RedirectURIOnProviderDashboard: "https://example.com/auth/callback/sso",
+		HTTPClient:                     &http.Client{Transport: transport},
+	}
+
+	result := ValidateProviderConfig(tpmodels.ProviderConfig{
+		ThirdPartyId:          "sso",
+		OIDCDiscoveryEndpoint: "https://sso.example.com/.well-known/openid-configuration",
+		Clients: []tpmodels.ProviderClientConfig{
+			{ClientType: "web", ClientID: "web-client", ClientSecret: "secret", Scope: []string{"openid", "email"}},
+			{ClientType: "mobile", ClientID: "mobile-client", Scope: []string{"email"}},
+		},
+	}, options)
+	assert.True(t, result.IsValid(), "%v", result.Errors)
+	assert.Equal(t, []tpmodels.ProviderConfigValidationIssue{
+		{Field: "clients[1].clientSecret", Msg: "there is no clientSecret, so PKCE is used. The client must be registered as a public client with the provider"},
+		{Field: "clients[1].scope", Msg: "the scope does not include openid, so the provider will not return an id token"},
+	}, result.Warnings)
+	assert.Contains(t, transport.requestedURLs, "https://sso.example.com/.well-known/openid-configuration")
+
+	webURL, err := url.Parse(result.AuthorisationURLs["web"])
+	assert.NoError(t, err)
+	assert.Equal(t, "sso.example.com", webURL.Host)
+	assert.Equal(t, "web-client", webURL.Query().Get("client_id"))
+	assert.Equal(t, "https://example.com/auth/callback/sso", webURL.Query().Get("redirect_uri"))
+	assert.Empty(t, webURL.Query().Get("code_challenge"))
+	mobileURL, err := url.Parse(result.AuthorisationURLs["mobile"])
+	assert.NoError(t, err)
+	assert.NotEmpty(t, mobileURL.Query().Get("code_challenge"))
+
+	result = ValidateProviderConfig(tpmodels.ProviderConfig{
+		ThirdPartyId:          "sso",
+		OIDCDiscoveryEndpoint: "https://unknown.example.com",
+		Clients:               []tpmodels.ProviderClientConfig{{ClientID: "client", ClientSecret: "secret"}},
+	}, options)
+	assert.False(t, result.IsValid())
+	assert.Equal(t, "oidcDiscoveryEndpoint", result.Errors[0].Field)
+	assert.Contains(t, result.Errors[0].Msg, "https://unknown.example.com")
+
+	result = ValidateProviderConfig(tpmodels.ProviderConfig{
+		ThirdPartyId:          "custom",
+		AuthorizationEndpoint: "https://custom.example.com/authorize",
+		Clients:               []tpmodels.ProviderClientConfig{{ClientID: "client", ClientSecret: "secret"}},
+	}, options)
+	assert.Equal(t, []tpmodels.ProviderConfigValidationIssue{
+		{Field: "tokenEndpoint", Msg: "the tokenEndpoint is not set, and could not be found with an oidcDiscoveryEndpoint"},
+		{Field: "userInfoEndpoint", Msg: "either the userInfoEndpoint or jwksURI must be set to get the info of the user, or found with an oidcDiscoveryEndpoint"},
+	}, result.Errors)
+}
+
+func TestProviderConfigValidationReportsAdditionalConfigOfTheWrongType(t *testing.T) {
+	result := ValidateProviderConfig(tpmodels.ProviderConfig{
+		ThirdPartyId: "gitlab",
+		Clients:      []tpmodels.ProviderClientConfig{{ClientID: "client", ClientSecret: "secret", AdditionalConfig: map[string]interface{}{"gitlabBaseUrl": 1}}},
+	}, tpmodels.ValidateProviderConfigOptions{})
+	assert.Equal(t, []tpmodels.ProviderConfigValidationIssue{
+		{Field: "clients[0]", Msg: "could not load the config of the provider: the gitlabBaseUrl in the AdditionalConfig of the Gitlab provider must be a string"},
+	}, result.Errors)
+}
+
+func TestProviderConfigValidationDoesNotFillTheProviderCache(t *testing.T) {
+	transport := &stubDiscoveryTransport{}
+	config := tpmodels.ProviderConfig{
+		ThirdPartyId:          "sso",
+		OIDCDiscoveryEndpoint: "https://sso.example.com/.well-known/openid-configuration",
+		Clients:               []tpmodels.ProviderClientConfig{{ClientID: "client", ClientSecret: "secret"}},
+	}
+	options := tpmodels.ValidateProviderConfigOptions{HTTPClient: &http.Client{Transport: transport}}
+	metricsBefore := providers.GetProviderCacheMetrics()
+
+	assert.True(t, ValidateProviderConfig(config, options).IsValid())
+	assert.True(t, ValidateProviderConfig(config, options).IsValid())
+
+	// Each validation loads the discovery endpoint again, and the cache used to sign in is not touched
+	assert.Len(t, transport.requestedURLs, 2)
+	assert.Equal(t, metricsBefore, providers.GetProviderCacheMetrics())
+}
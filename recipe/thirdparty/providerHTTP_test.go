@@ -0,0 +1,210 @@
+/*
+ * Copyright (c) 2021, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thirdparty
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/supertokens/supertokens-golang/recipe/thirdparty/providers"
+	"github.com/supertokens/supertokens-golang/recipe/thirdparty/tpmodels"
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+type countingRoundTripper struct {
+	calls int32
+}
+
+func (c *countingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&c.calls, 1)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func configureProviderHTTPForTest(t *testing.T, config tpmodels.ProviderHTTPConfig) {
+	normalisedConfig, err := validateAndNormaliseProviderHTTPConfig(&config)
+	assert.NoError(t, err)
+	providers.ConfigureProviderHTTP(normalisedConfig)
+}
+
+func getTestUserInfoProvider(t *testing.T, userInfoEndpoint string, httpClient *http.Client) *tpmodels.TypeProvider {
+	provider := providers.NewProvider(tpmodels.ProviderInput{
+		Config: tpmodels.ProviderConfig{
+			ThirdPartyId:     "test-oauth",
+			UserInfoEndpoint: userInfoEndpoint,
+			Clients:          []tpmodels.ProviderClientConfig{{ClientID: "client"}},
+			HTTPClient:       httpClient,
+		},
+	})
+	config, err := provider.GetConfigForClientType(nil, &map[string]interface{}{})
+	assert.NoError(t, err)
+	provider.Config = config
+	return provider
+}
+
+func writeTestUserInfo(rw http.ResponseWriter) {
+	json.NewEncoder(rw).Encode(map[string]interface{}{"sub": "user-1", "email": "user@example.com"})
+}
+
+func TestProviderRequestsUseTheProviderHTTPClient(t *testing.T) {
+	var requests []tpmodels.ProviderRequestInfo
+	var requestsLock sync.Mutex
+	configureProviderHTTPForTest(t, tpmodels.ProviderHTTPConfig{
+		OnRequest: func(info tpmodels.ProviderRequestInfo, userContext supertokens.UserContext) {
+			requestsLock.Lock()
+			defer requestsLock.Unlock()
+			requests = append(requests, info)
+		},
+	})
+	defer configureProviderHTTPForTest(t, tpmodels.ProviderHTTPConfig{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		writeTestUserInfo(rw)
+	}))
+	defer server.Close()
+
+	transport := &countingRoundTripper{}
+	provider := getTestUserInfoProvider(t, server.URL+"/userinfo", &http.Client{Transport: transport})
+	provider.Config.UserInfoEndpointQueryParams = map[string]interface{}{"secret": "value"}
+
+	userInfo, err := provider.GetUserInfo(tpmodels.TypeOAuthTokens{"access_token": "token"}, &map[string]interface{}{})
+	assert.NoError(t, err)
+	assert.Equal(t, "user-1", userInfo.ThirdPartyUserId)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&transport.calls))
+
+	assert.Equal(t, 1, len(requests))
+	assert.Equal(t, "test-oauth", requests[0].ThirdPartyId)
+	assert.Equal(t, http.MethodGet, requests[0].Method)
+	assert.Equal(t, server.URL+"/userinfo", requests[0].URL)
+	assert.Equal(t, 200, requests[0].StatusCode)
+	assert.Equal(t, 1, requests[0].Attempt)
+	assert.NoError(t, requests[0].Err)
+}
+
+func TestProviderRequestsAreCancelled(t *testing.T) {
+	configureProviderHTTPForTest(t, tpmodels.ProviderHTTPConfig{TimeoutSec: 1})
+	defer configureProviderHTTPForTest(t, tpmodels.ProviderHTTPConfig{})
+
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+	provider := getTestUserInfoProvider(t, server.URL+"/userinfo", nil)
+
+	// by the context in the userContext
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	startTime := time.Now()
+	_, err := provider.GetUserInfo(tpmodels.TypeOAuthTokens{"access_token": "token"}, supertokens.MakeUserContextFromContext(ctx))
+	assert.Error(t, err)
+	assert.Less(t, int64(time.Since(startTime)), int64(900*time.Millisecond))
+
+	// by the timeout
+	startTime = time.Now()
+	_, err = provider.GetUserInfo(tpmodels.TypeOAuthTokens{"access_token": "token"}, &map[string]interface{}{})
+	assert.Error(t, err)
+	assert.Less(t, int64(time.Since(startTime)), int64(3*time.Second))
+}
+
+func TestProviderGETRequestsAreRetried(t *testing.T) {
+	var attempts []int
+	configureProviderHTTPForTest(t, tpmodels.ProviderHTTPConfig{
+		MaxGETRetries: 1,
+		OnRequest: func(info tpmodels.ProviderRequestInfo, userContext supertokens.UserContext) {
+			attempts = append(attempts, info.Attempt)
+		},
+	})
+	defer configureProviderHTTPForTest(t, tpmodels.ProviderHTTPConfig{})
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			rw.WriteHeader(503)
+			rw.Write([]byte("{}"))
+			return
+		}
+		writeTestUserInfo(rw)
+	}))
+	defer server.Close()
+	provider := getTestUserInfoProvider(t, server.URL+"/userinfo", nil)
+
+	userInfo, err := provider.GetUserInfo(tpmodels.TypeOAuthTokens{"access_token": "token"}, &map[string]interface{}{})
+	assert.NoError(t, err)
+	assert.Equal(t, "user-1", userInfo.ThirdPartyUserId)
+	assert.Equal(t, []int{1, 2}, attempts)
+}
+
+func TestProviderGETRetriesWaitBeforeRetrying(t *testing.T) {
+	configureProviderHTTPForTest(t, tpmodels.ProviderHTTPConfig{
+		MaxGETRetries:    3,
+		RetryBaseDelayMS: 60000,
+		RetryMaxDelayMS:  60000,
+	})
+	defer configureProviderHTTPForTest(t, tpmodels.ProviderHTTPConfig{})
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		rw.WriteHeader(503)
+	}))
+	defer server.Close()
+	provider := getTestUserInfoProvider(t, server.URL+"/userinfo", nil)
+
+	// The wait before the retry is cut short when the context is done
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	startTime := time.Now()
+	_, err := provider.GetUserInfo(tpmodels.TypeOAuthTokens{"access_token": "token"}, supertokens.MakeUserContextFromContext(ctx))
+	assert.Error(t, err)
+	assert.Less(t, int64(time.Since(startTime)), int64(2*time.Second))
+	assert.LessOrEqual(t, atomic.LoadInt32(&calls), int32(2))
+}
+
+func TestProviderHTTPAndCacheCanBeConfiguredWhileInUse(t *testing.T) {
+	defer configureProviderHTTPForTest(t, tpmodels.ProviderHTTPConfig{})
+	defer configureProviderCacheForTest(t, tpmodels.ProviderCacheConfig{})
+
+	idp := newFakeOIDCIdP(newTestIdPKey(t, "key-1"))
+	defer idp.server.Close()
+
+	// The thirdparty recipe pre-warms the cache in a goroutine, which can run while the recipe is
+	// initialised again (like in tests)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			assert.NoError(t, providers.PreWarmProviderCache([]string{idp.server.URL + "/.well-known/openid-configuration"}))
+			providers.GetProviderCacheMetrics()
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		configureProviderHTTPForTest(t, tpmodels.ProviderHTTPConfig{})
+		configureProviderCacheForTest(t, tpmodels.ProviderCacheConfig{})
+	}
+	<-done
+}
@@ -0,0 +1,298 @@
+/* Copyright (c) 2021, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thirdparty
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"hash/fnv"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/supertokens/supertokens-golang/recipe/thirdparty/tperrors"
+	"github.com/supertokens/supertokens-golang/recipe/thirdparty/tpmodels"
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+// providerTokenLocks makes sure that the tokens of an identity of a user are only refreshed once at a
+// time, since some providers invalidate the refresh token when it is used.
+var providerTokenLocks [64]sync.Mutex
+
+func getProviderTokenLock(userID string, thirdPartyID string, thirdPartyUserID string) *sync.Mutex {
+	h := fnv.New32a()
+	h.Write([]byte(userID + "\x00" + thirdPartyID + "\x00" + thirdPartyUserID))
+	return &providerTokenLocks[h.Sum32()%uint32(len(providerTokenLocks))]
+}
+
+func getProviderTokensCipher(encryptionKey string) (cipher.AEAD, error) {
+	key := sha256.Sum256([]byte(encryptionKey))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// The user id and identity are used as additional data, so that the tokens saved for one user (or
+// identity) cannot be copied over to another one in the store.
+func encryptProviderTokens(tokens tpmodels.ProviderTokens, encryptionKey string, userID string, thirdPartyID string, thirdPartyUserID string) (string, error) {
+	plainText, err := json.Marshal(tokens)
+	if err != nil {
+		return "", err
+	}
+	aead, err := getProviderTokensCipher(encryptionKey)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return "", err
+	}
+	cipherText := aead.Seal(nonce, nonce, plainText, []byte(userID+"\x00"+thirdPartyID+"\x00"+thirdPartyUserID))
+	return base64.StdEncoding.EncodeToString(cipherText), nil
+}
+
+func decryptProviderTokens(encryptedTokens string, encryptionKey string, userID string, thirdPartyID string, thirdPartyUserID string) (tpmodels.ProviderTokens, error) {
+	cipherText, err := base64.StdEncoding.DecodeString(encryptedTokens)
+	if err != nil {
+		return tpmodels.ProviderTokens{}, err
+	}
+	aead, err := getProviderTokensCipher(encryptionKey)
+	if err != nil {
+		return tpmodels.ProviderTokens{}, err
+	}
+	if len(cipherText) < aead.NonceSize() {
+		return tpmodels.ProviderTokens{}, errors.New("the saved provider tokens are invalid")
+	}
+	plainText, err := aead.Open(nil, cipherText[:aead.NonceSize()], cipherText[aead.NonceSize():], []byte(userID+"\x00"+thirdPartyID+"\x00"+thirdPartyUserID))
+	if err != nil {
+		return tpmodels.ProviderTokens{}, errors.New("the saved provider tokens could not be decrypted")
+	}
+	var tokens tpmodels.ProviderTokens
+	err = json.Unmarshal(plainText, &tokens)
+	return tokens, err
+}
+
+// getProviderTokensFromOAuthTokens reads the response of the provider's token endpoint. Providers do
+// not always send a new refresh token when the tokens are refreshed, in which case the previous one
+// is kept.
+func getProviderTokensFromOAuthTokens(oAuthTokens tpmodels.TypeOAuthTokens, previous tpmodels.ProviderTokens) (tpmodels.ProviderTokens, bool) {
+	accessToken, ok := oAuthTokens["access_token"].(string)
+	if !ok || accessToken == "" {
+		return tpmodels.ProviderTokens{}, false
+	}
+	tokens := tpmodels.ProviderTokens{
+		AccessToken:  accessToken,
+		RefreshToken: previous.RefreshToken,
+		TenantId:     previous.TenantId,
+		ClientType:   previous.ClientType,
+	}
+	if refreshToken, ok := oAuthTokens["refresh_token"].(string); ok && refreshToken != "" {
+		tokens.RefreshToken = refreshToken
+	}
+
+	var expiresIn float64
+	switch value := oAuthTokens["expires_in"].(type) {
+	case float64:
+		expiresIn = value
+	case int:
+		expiresIn = float64(value)
+	case int64:
+		expiresIn = float64(value)
+	case json.Number:
+		expiresIn, _ = value.Float64()
+	case string:
+		expiresIn, _ = strconv.ParseFloat(value, 64)
+	}
+	if expiresIn > 0 {
+		tokens.ExpiresAt = uint64(time.Now().Add(time.Duration(expiresIn*1000)*time.Millisecond).UnixNano() / 1000000)
+	}
+	return tokens, true
+}
+
+func storeProviderTokens(config tpmodels.ProviderTokenStorageConfig, userID string, thirdPartyID string, thirdPartyUserID string, tokens tpmodels.ProviderTokens, userContext supertokens.UserContext) error {
+	encryptedTokens, err := encryptProviderTokens(tokens, config.EncryptionKey, userID, thirdPartyID, thirdPartyUserID)
+	if err != nil {
+		return err
+	}
+	return config.Store.Save(userID, thirdPartyID, thirdPartyUserID, encryptedTokens, userContext)
+}
+
+// getStoredProviderTokens returns nil if there are no tokens saved for the identity of the user
+func getStoredProviderTokens(config tpmodels.ProviderTokenStorageConfig, userID string, thirdPartyID string, thirdPartyUserID string, userContext supertokens.UserContext) (*tpmodels.ProviderTokens, error) {
+	encryptedTokens, err := config.Store.Get(userID, thirdPartyID, thirdPartyUserID, userContext)
+	if err != nil || encryptedTokens == nil {
+		return nil, err
+	}
+	tokens, err := decryptProviderTokens(*encryptedTokens, config.EncryptionKey, userID, thirdPartyID, thirdPartyUserID)
+	if err != nil {
+		return nil, err
+	}
+	return &tokens, nil
+}
+
+// storeProviderTokensAfterSignIn saves the tokens the user got when signing in. The provider may not
+// send a refresh token if the user had already consented, so the saved one is kept if it was issued
+// to the same client.
+func storeProviderTokensAfterSignIn(config tpmodels.ProviderTokenStorageConfig, userID string, thirdPartyID string, thirdPartyUserID string, oAuthTokens tpmodels.TypeOAuthTokens, clientType *string, tenantId string, userContext supertokens.UserContext) error {
+	if _, ok := oAuthTokens["access_token"].(string); !ok {
+		// Providers like SAML do not give us tokens that can be used later
+		return nil
+	}
+
+	lock := getProviderTokenLock(userID, thirdPartyID, thirdPartyUserID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	previous := tpmodels.ProviderTokens{TenantId: tenantId, ClientType: clientType}
+	storedTokens, err := getStoredProviderTokens(config, userID, thirdPartyID, thirdPartyUserID, userContext)
+	if err != nil {
+		// The tokens may have been saved with another encryption key, in which case they are replaced
+		supertokens.LogDebugMessage("thirdparty: could not read the saved tokens of " + thirdPartyID + ": " + err.Error())
+	} else if storedTokens != nil && storedTokens.TenantId == tenantId && isSameClientType(storedTokens.ClientType, clientType) {
+		previous.RefreshToken = storedTokens.RefreshToken
+	}
+
+	tokens, ok := getProviderTokensFromOAuthTokens(oAuthTokens, previous)
+	if !ok {
+		return nil
+	}
+	return storeProviderTokens(config, userID, thirdPartyID, thirdPartyUserID, tokens, userContext)
+}
+
+func isSameClientType(a *string, b *string) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+func getValidProviderAccessToken(config tpmodels.ProviderTokenStorageConfig, userID string, thirdPartyID string, thirdPartyUserID string, getProvider func(thirdPartyID string, clientType *string, tenantId string, userContext supertokens.UserContext) (*tpmodels.TypeProvider, error), userContext supertokens.UserContext) (tpmodels.GetValidProviderAccessTokenResponse, error) {
+	lock := getProviderTokenLock(userID, thirdPartyID, thirdPartyUserID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	storedTokens, err := getStoredProviderTokens(config, userID, thirdPartyID, thirdPartyUserID, userContext)
+	if err != nil {
+		return tpmodels.GetValidProviderAccessTokenResponse{}, err
+	}
+	if storedTokens == nil {
+		return tpmodels.GetValidProviderAccessTokenResponse{
+			TokensNotFoundError: &struct{}{},
+		}, nil
+	}
+	tokens := *storedTokens
+
+	refreshAfter := uint64(time.Now().Add(time.Duration(config.RefreshBeforeExpirySec)*time.Second).UnixNano() / 1000000)
+	if tokens.ExpiresAt == 0 || tokens.ExpiresAt > refreshAfter {
+		return validProviderAccessTokenResponse(tokens), nil
+	}
+
+	if tokens.RefreshToken == "" {
+		return tpmodels.GetValidProviderAccessTokenResponse{
+			ReauthenticationRequiredError: &struct{}{},
+		}, nil
+	}
+
+	provider, err := getProvider(thirdPartyID, tokens.ClientType, tokens.TenantId, userContext)
+	if err != nil {
+		return tpmodels.GetValidProviderAccessTokenResponse{}, err
+	}
+	if provider == nil {
+		return tpmodels.GetValidProviderAccessTokenResponse{}, errors.New("the provider " + thirdPartyID + " could not be found in the configuration")
+	}
+	if provider.RefreshOAuthTokens == nil {
+		return tpmodels.GetValidProviderAccessTokenResponse{}, errors.New("the provider " + thirdPartyID + " does not support refreshing tokens")
+	}
+
+	oAuthTokens, err := provider.RefreshOAuthTokens(tokens.RefreshToken, userContext)
+	if err != nil {
+		if errors.As(err, &tperrors.RefreshTokenRejectedError{}) {
+			supertokens.LogDebugMessage("thirdparty: the refresh token of " + thirdPartyID + " was rejected: " + err.Error())
+			err = config.Store.Delete(userID, thirdPartyID, thirdPartyUserID, userContext)
+			if err != nil {
+				return tpmodels.GetValidProviderAccessTokenResponse{}, err
+			}
+			return tpmodels.GetValidProviderAccessTokenResponse{
+				ReauthenticationRequiredError: &struct{}{},
+			}, nil
+		}
+		return tpmodels.GetValidProviderAccessTokenResponse{}, err
+	}
+
+	refreshedTokens, ok := getProviderTokensFromOAuthTokens(oAuthTokens, tokens)
+	if !ok {
+		return tpmodels.GetValidProviderAccessTokenResponse{}, errors.New("the provider " + thirdPartyID + " did not return an access token when refreshing the tokens")
+	}
+	err = storeProviderTokens(config, userID, thirdPartyID, thirdPartyUserID, refreshedTokens, userContext)
+	if err != nil {
+		return tpmodels.GetValidProviderAccessTokenResponse{}, err
+	}
+	return validProviderAccessTokenResponse(refreshedTokens), nil
+}
+
+// getSignUpThirdPartyUserID returns the id of the user at the provider if they signed up with it, or
+// nil otherwise.
+func getSignUpThirdPartyUserID(recipeImpl tpmodels.RecipeInterface, userID string, thirdPartyID string, userContext supertokens.UserContext) (*string, error) {
+	user, err := (*recipeImpl.GetUserByID)(userID, userContext)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || user.ThirdParty.ID != thirdPartyID {
+		return nil, nil
+	}
+	return &user.ThirdParty.UserID, nil
+}
+
+func getValidProviderAccessTokenOfSignUpIdentity(recipeImpl tpmodels.RecipeInterface, userID string, thirdPartyID string, userContext supertokens.UserContext) (tpmodels.GetValidProviderAccessTokenResponse, error) {
+	thirdPartyUserID, err := getSignUpThirdPartyUserID(recipeImpl, userID, thirdPartyID, userContext)
+	if err != nil {
+		return tpmodels.GetValidProviderAccessTokenResponse{}, err
+	}
+	if thirdPartyUserID == nil {
+		return tpmodels.GetValidProviderAccessTokenResponse{
+			TokensNotFoundError: &struct{}{},
+		}, nil
+	}
+	return (*recipeImpl.GetValidProviderAccessToken)(userID, thirdPartyID, *thirdPartyUserID, userContext)
+}
+
+func deleteProviderTokensOfSignUpIdentity(recipeImpl tpmodels.RecipeInterface, userID string, thirdPartyID string, userContext supertokens.UserContext) error {
+	thirdPartyUserID, err := getSignUpThirdPartyUserID(recipeImpl, userID, thirdPartyID, userContext)
+	if err != nil || thirdPartyUserID == nil {
+		return err
+	}
+	return (*recipeImpl.DeleteProviderTokens)(userID, thirdPartyID, *thirdPartyUserID, userContext)
+}
+
+func validProviderAccessTokenResponse(tokens tpmodels.ProviderTokens) tpmodels.GetValidProviderAccessTokenResponse {
+	return tpmodels.GetValidProviderAccessTokenResponse{
+		OK: &struct {
+			AccessToken string
+			ExpiresAt   uint64
+		}{
+			AccessToken: tokens.AccessToken,
+			ExpiresAt:   tokens.ExpiresAt,
+		},
+	}
+}
@@ -0,0 +1,290 @@
+/*
+ * Copyright (c) 2021, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thirdparty
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/supertokens/supertokens-golang/recipe/thirdparty/providers"
+	"github.com/supertokens/supertokens-golang/recipe/thirdparty/tpmodels"
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+type memoryProviderTokenStore struct {
+	lock   sync.Mutex
+	tokens map[string]string
+}
+
+func (s *memoryProviderTokenStore) Save(userID string, thirdPartyID string, thirdPartyUserID string, encryptedTokens string, userContext supertokens.UserContext) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.tokens[userID+"/"+thirdPartyID+"/"+thirdPartyUserID] = encryptedTokens
+	return nil
+}
+
+func (s *memoryProviderTokenStore) Get(userID string, thirdPartyID string, thirdPartyUserID string, userContext supertokens.UserContext) (*string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	encryptedTokens, ok := s.tokens[userID+"/"+thirdPartyID+"/"+thirdPartyUserID]
+	if !ok {
+		return nil, nil
+	}
+	return &encryptedTokens, nil
+}
+
+func (s *memoryProviderTokenStore) Delete(userID string, thirdPartyID string, thirdPartyUserID string, userContext supertokens.UserContext) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.tokens, userID+"/"+thirdPartyID+"/"+thirdPartyUserID)
+	return nil
+}
+
+// providerTokensTestSetup has an IdP whose token endpoint accepts the refresh token "refresh-1" and
+// rejects every other one
+type providerTokensTestSetup struct {
+	store         *memoryProviderTokenStore
+	recipeImpl    tpmodels.RecipeInterface
+	config        tpmodels.ProviderTokenStorageConfig
+	idp           *fakeOIDCIdP
+	provider      *tpmodels.TypeProvider
+	refreshCalls  int
+	requestedType *string
+}
+
+func newProviderTokensTestSetup(t *testing.T) *providerTokensTestSetup {
+	setup := &providerTokensTestSetup{store: &memoryProviderTokenStore{tokens: map[string]string{}}}
+	setup.idp = newFakeOIDCIdP()
+	t.Cleanup(setup.idp.server.Close)
+	setup.idp.tokenHandler = func(rw http.ResponseWriter, r *http.Request) {
+		setup.refreshCalls++
+		assert.NoError(t, r.ParseForm())
+		assert.Equal(t, "refresh_token", r.PostForm.Get("grant_type"))
+		assert.Equal(t, "client", r.PostForm.Get("client_id"))
+		assert.Equal(t, "secret", r.PostForm.Get("client_secret"))
+		if r.PostForm.Get("refresh_token") != "refresh-1" {
+			rw.WriteHeader(400)
+			json.NewEncoder(rw).Encode(map[string]interface{}{"error": "invalid_grant"})
+			return
+		}
+		json.NewEncoder(rw).Encode(map[string]interface{}{
+			"access_token": "access-2",
+			"expires_in":   3600,
+		})
+	}
+
+	setup.provider = providers.NewProvider(tpmodels.ProviderInput{
+		Config: tpmodels.ProviderConfig{
+			ThirdPartyId:  "test-oauth",
+			TokenEndpoint: setup.idp.server.URL + "/token",
+			Clients:       []tpmodels.ProviderClientConfig{{ClientID: "client", ClientSecret: "secret"}},
+		},
+	})
+	config, err := setup.provider.GetConfigForClientType(nil, &map[string]interface{}{})
+	assert.NoError(t, err)
+	setup.provider.Config = config
+
+	storageConfig, err := validateAndNormaliseProviderTokenStorageConfig(&tpmodels.ProviderTokenStorageConfig{
+		Store:         setup.store,
+		EncryptionKey: "an-encryption-key-that-is-at-least-32-characters-long",
+	})
+	assert.NoError(t, err)
+	setup.config = *storageConfig
+	setup.recipeImpl = MakeRecipeImplementation(supertokens.Querier{}, nil, storageConfig, nil)
+	return setup
+}
+
+func (s *providerTokensTestSetup) getValidProviderAccessToken(t *testing.T, userID string) tpmodels.GetValidProviderAccessTokenResponse {
+	getProvider := func(thirdPartyID string, clientType *string, tenantId string, userContext supertokens.UserContext) (*tpmodels.TypeProvider, error) {
+		assert.Equal(t, "test-oauth", thirdPartyID)
+		assert.Equal(t, "public", tenantId)
+		s.requestedType = clientType
+		return s.provider, nil
+	}
+	response, err := getValidProviderAccessToken(s.config, userID, "test-oauth", "tp-user-1", getProvider, &map[string]interface{}{})
+	assert.NoError(t, err)
+	return response
+}
+
+func TestProviderTokensAreStoredEncrypted(t *testing.T) {
+	setup := newProviderTokensTestSetup(t)
+
+	webClientType := "web"
+	err := (*setup.recipeImpl.StoreProviderTokens)("user-1", "test-oauth", "tp-user-1", tpmodels.TypeOAuthTokens{
+		"access_token":  "access-1",
+		"refresh_token": "refresh-1",
+		"expires_in":    3600,
+	}, &webClientType, "public", &map[string]interface{}{})
+	assert.NoError(t, err)
+
+	encryptedTokens := setup.store.tokens["user-1/test-oauth/tp-user-1"]
+	assert.NotContains(t, encryptedTokens, "access-1")
+	assert.NotContains(t, encryptedTokens, "refresh-1")
+
+	response := setup.getValidProviderAccessToken(t, "user-1")
+	assert.NotNil(t, response.OK)
+	assert.Equal(t, "access-1", response.OK.AccessToken)
+	assert.Equal(t, 0, setup.refreshCalls)
+
+	// The tokens of one user cannot be used for another one
+	setup.store.tokens["user-2/test-oauth/tp-user-1"] = encryptedTokens
+	_, err = getValidProviderAccessToken(setup.config, "user-2", "test-oauth", "tp-user-1", nil, &map[string]interface{}{})
+	assert.Error(t, err)
+
+	response = setup.getValidProviderAccessToken(t, "user-3")
+	assert.NotNil(t, response.TokensNotFoundError)
+}
+
+func TestProviderTokensAreRefreshed(t *testing.T) {
+	setup := newProviderTokensTestSetup(t)
+
+	webClientType := "web"
+	err := (*setup.recipeImpl.StoreProviderTokens)("user-1", "test-oauth", "tp-user-1", tpmodels.TypeOAuthTokens{
+		"access_token":  "access-1",
+		"refresh_token": "refresh-1",
+		"expires_in":    "30", // expires within RefreshBeforeExpirySec
+	}, &webClientType, "public", &map[string]interface{}{})
+	assert.NoError(t, err)
+
+	response := setup.getValidProviderAccessToken(t, "user-1")
+	assert.NotNil(t, response.OK)
+	assert.Equal(t, "access-2", response.OK.AccessToken)
+	assert.Equal(t, 1, setup.refreshCalls)
+	assert.Equal(t, "web", *setup.requestedType)
+
+	// The refreshed token is saved, and the refresh token is kept since the provider did not send a new one
+	response = setup.getValidProviderAccessToken(t, "user-1")
+	assert.Equal(t, "access-2", response.OK.AccessToken)
+	assert.Equal(t, 1, setup.refreshCalls)
+	tokens, err := decryptProviderTokens(setup.store.tokens["user-1/test-oauth/tp-user-1"], setup.config.EncryptionKey, "user-1", "test-oauth", "tp-user-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "refresh-1", tokens.RefreshToken)
+}
+
+func TestProviderTokensKeepTheRefreshTokenWhenSigningInAgain(t *testing.T) {
+	setup := newProviderTokensTestSetup(t)
+
+	webClientType := "web"
+	err := (*setup.recipeImpl.StoreProviderTokens)("user-1", "test-oauth", "tp-user-1", tpmodels.TypeOAuthTokens{
+		"access_token":  "access-1",
+		"refresh_token": "refresh-1",
+		"expires_in":    3600,
+	}, &webClientType, "public", &map[string]interface{}{})
+	assert.NoError(t, err)
+
+	// Providers like Google only send a refresh token the first time the user consents
+	err = (*setup.recipeImpl.StoreProviderTokens)("user-1", "test-oauth", "tp-user-1", tpmodels.TypeOAuthTokens{
+		"access_token": "access-3",
+		"expires_in":   3600,
+	}, &webClientType, "public", &map[string]interface{}{})
+	assert.NoError(t, err)
+
+	tokens, err := decryptProviderTokens(setup.store.tokens["user-1/test-oauth/tp-user-1"], setup.config.EncryptionKey, "user-1", "test-oauth", "tp-user-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "access-3", tokens.AccessToken)
+	assert.Equal(t, "refresh-1", tokens.RefreshToken)
+
+	// A refresh token issued to another client cannot be used with this one
+	err = (*setup.recipeImpl.StoreProviderTokens)("user-1", "test-oauth", "tp-user-1", tpmodels.TypeOAuthTokens{
+		"access_token": "access-4",
+		"expires_in":   3600,
+	}, nil, "public", &map[string]interface{}{})
+	assert.NoError(t, err)
+
+	tokens, err = decryptProviderTokens(setup.store.tokens["user-1/test-oauth/tp-user-1"], setup.config.EncryptionKey, "user-1", "test-oauth", "tp-user-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "access-4", tokens.AccessToken)
+	assert.Equal(t, "", tokens.RefreshToken)
+}
+
+func TestProviderTokensRequireReauthenticationWhenRefreshIsNotPossible(t *testing.T) {
+	setup := newProviderTokensTestSetup(t)
+
+	err := (*setup.recipeImpl.StoreProviderTokens)("user-1", "test-oauth", "tp-user-1", tpmodels.TypeOAuthTokens{
+		"access_token":  "access-1",
+		"refresh_token": "revoked-refresh-token",
+		"expires_in":    1,
+	}, nil, "public", &map[string]interface{}{})
+	assert.NoError(t, err)
+
+	response := setup.getValidProviderAccessToken(t, "user-1")
+	assert.NotNil(t, response.ReauthenticationRequiredError)
+	assert.Equal(t, 1, setup.refreshCalls)
+	assert.Empty(t, setup.store.tokens)
+
+	err = (*setup.recipeImpl.StoreProviderTokens)("user-1", "test-oauth", "tp-user-1", tpmodels.TypeOAuthTokens{
+		"access_token": "access-1",
+		"expires_in":   1,
+	}, nil, "public", &map[string]interface{}{})
+	assert.NoError(t, err)
+
+	response = setup.getValidProviderAccessToken(t, "user-1")
+	assert.NotNil(t, response.ReauthenticationRequiredError)
+	assert.Equal(t, 1, setup.refreshCalls)
+}
+
+func TestProviderTokensOfTheSignUpIdentity(t *testing.T) {
+	setup := newProviderTokensTestSetup(t)
+
+	getUserByID := func(userID string, userContext supertokens.UserContext) (*tpmodels.User, error) {
+		if userID != "user-1" {
+			return nil, nil
+		}
+		user := &tpmodels.User{ID: "user-1"}
+		user.ThirdParty.ID = "test-oauth"
+		user.ThirdParty.UserID = "tp-user-1"
+		return user, nil
+	}
+	setup.recipeImpl.GetUserByID = &getUserByID
+	userContext := &map[string]interface{}{}
+
+	err := (*setup.recipeImpl.StoreProviderTokens)("user-1", "test-oauth", "tp-user-1", tpmodels.TypeOAuthTokens{"access_token": "access-1"}, nil, "public", userContext)
+	assert.NoError(t, err)
+
+	response, err := getValidProviderAccessTokenOfSignUpIdentity(setup.recipeImpl, "user-1", "test-oauth", userContext)
+	assert.NoError(t, err)
+	assert.NotNil(t, response.OK)
+	assert.Equal(t, "access-1", response.OK.AccessToken)
+
+	// the user did not sign up with these
+	response, err = getValidProviderAccessTokenOfSignUpIdentity(setup.recipeImpl, "user-1", "other-oauth", userContext)
+	assert.NoError(t, err)
+	assert.NotNil(t, response.TokensNotFoundError)
+	response, err = getValidProviderAccessTokenOfSignUpIdentity(setup.recipeImpl, "user-2", "test-oauth", userContext)
+	assert.NoError(t, err)
+	assert.NotNil(t, response.TokensNotFoundError)
+
+	assert.NoError(t, deleteProviderTokensOfSignUpIdentity(setup.recipeImpl, "user-1", "test-oauth", userContext))
+	assert.Empty(t, setup.store.tokens)
+}
+
+func TestProviderTokenStorageConfigValidation(t *testing.T) {
+	_, err := validateAndNormaliseProviderTokenStorageConfig(&tpmodels.ProviderTokenStorageConfig{
+		Store:         &memoryProviderTokenStore{},
+		EncryptionKey: "too-short",
+	})
+	assert.True(t, strings.Contains(err.Error(), "EncryptionKey"))
+
+	_, err = validateAndNormaliseProviderTokenStorageConfig(&tpmodels.ProviderTokenStorageConfig{
+		EncryptionKey: "an-encryption-key-that-is-at-least-32-characters-long",
+	})
+	assert.Error(t, err)
+}
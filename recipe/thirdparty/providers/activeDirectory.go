@@ -34,6 +34,24 @@ func ActiveDirectory(input tpmodels.ProviderInput) *tpmodels.TypeProvider {
 				}
 			} else {
 				config.OIDCDiscoveryEndpoint = fmt.Sprintf("https://login.microsoftonline.com/%s/v2.0/.well-known/openid-configuration", config.AdditionalConfig["directoryId"])
+
+				// The issuer in the discovery document of the multi-tenant endpoints has to be filled in
+				// with the tenant of the user
+				switch config.AdditionalConfig["directoryId"] {
+				case "common", "organizations", "consumers":
+					config.IdTokenValidation = withoutIdTokenIssuerCheck(config.IdTokenValidation)
+					oValidateIdTokenPayload := config.ValidateIdTokenPayload
+					config.ValidateIdTokenPayload = func(idTokenPayload map[string]interface{}, clientConfig tpmodels.ProviderConfigForClientType, userContext supertokens.UserContext) error {
+						err := validateMicrosoftEntraIdTokenPayload(idTokenPayload, clientConfig, userContext)
+						if err != nil {
+							return err
+						}
+						if oValidateIdTokenPayload != nil {
+							return oValidateIdTokenPayload(idTokenPayload, clientConfig, userContext)
+						}
+						return nil
+					}
+				}
 			}
 
 			// The config could be coming from core where we didn't add the well-known previously
@@ -77,14 +95,22 @@ func getADClientAssertion(config tpmodels.ProviderConfigForClientType) (string,
 		Issuer:    getActualClientIdFromDevelopmentClientId(config.ClientID),
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
-	thumbBytes, err := hex.DecodeString(config.AdditionalConfig["certificateThumbprint"].(string))
+	certificateThumbprint, ok := config.AdditionalConfig["certificateThumbprint"].(string)
+	if !ok {
+		return "", errors.New("the certificateThumbprint in the AdditionalConfig of the Active Directory provider must be a string")
+	}
+	thumbBytes, err := hex.DecodeString(certificateThumbprint)
 	if err != nil {
 		return "", err
 	}
 	token.Header["x5t"] = base64.StdEncoding.EncodeToString(thumbBytes)
 	token.Header["alg"] = "RS256"
 
-	pfxbytes, err := base64.StdEncoding.DecodeString(config.AdditionalConfig["certificate"].(string))
+	certificate, ok := config.AdditionalConfig["certificate"].(string)
+	if !ok {
+		return "", errors.New("the certificate in the AdditionalConfig of the Active Directory provider must be a string")
+	}
+	pfxbytes, err := base64.StdEncoding.DecodeString(certificate)
 	if err != nil {
 		return "", err
 	}
@@ -21,13 +21,16 @@ import (
 	"encoding/json"
 	"encoding/pem"
 	"errors"
-	"time"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/supertokens/supertokens-golang/recipe/thirdparty/tpmodels"
 	"github.com/supertokens/supertokens-golang/supertokens"
 )
 
+// Apple generates the client secret from the teamId and the keyId and privateKey in the
+// AdditionalConfig, unless the ClientSecret is set. To rotate keys, set keys to a list of maps with a
+// keyId and privateKey instead: the first key is used, and the others are tried if Apple rejects it.
+// The client secret is reused until it is about to expire, after clientSecretValiditySec (defaults
+// to a day).
 func Apple(input tpmodels.ProviderInput) *tpmodels.TypeProvider {
 	if input.Config.Name == "" {
 		input.Config.Name = "Apple"
@@ -60,12 +63,7 @@ func Apple(input tpmodels.ProviderInput) *tpmodels.TypeProvider {
 			}
 
 			if config.ClientSecret == "" {
-
-				if config.AdditionalConfig == nil || config.AdditionalConfig["teamId"] == nil || config.AdditionalConfig["keyId"] == nil || config.AdditionalConfig["privateKey"] == nil {
-					return tpmodels.ProviderConfigForClientType{}, errors.New("please ensure that keyId, teamId and privateKey are provided in the AdditionalConfig")
-				}
-
-				clientSecret, err := getClientSecret(config.ClientID, config.AdditionalConfig)
+				clientSecret, err := getAppleClientSecret(config.ClientID, config.AdditionalConfig)
 				if err != nil {
 					return tpmodels.ProviderConfigForClientType{}, err
 				}
@@ -80,7 +78,9 @@ func Apple(input tpmodels.ProviderInput) *tpmodels.TypeProvider {
 
 		oExchangeAuthCodeForOAuthTokens := originalImplementation.ExchangeAuthCodeForOAuthTokens
 		originalImplementation.ExchangeAuthCodeForOAuthTokens = func(redirectURIInfo tpmodels.TypeRedirectURIInfo, userContext supertokens.UserContext) (tpmodels.TypeOAuthTokens, error) {
-			res, err := oExchangeAuthCodeForOAuthTokens(redirectURIInfo, userContext)
+			res, err := withAppleKeyRotation(originalImplementation, func() (tpmodels.TypeOAuthTokens, error) {
+				return oExchangeAuthCodeForOAuthTokens(redirectURIInfo, userContext)
+			})
 			if err != nil {
 				return tpmodels.TypeOAuthTokens{}, err
 			}
@@ -100,6 +100,13 @@ func Apple(input tpmodels.ProviderInput) *tpmodels.TypeProvider {
 			return res, nil
 		}
 
+		oRefreshOAuthTokens := originalImplementation.RefreshOAuthTokens
+		originalImplementation.RefreshOAuthTokens = func(refreshToken string, userContext supertokens.UserContext) (tpmodels.TypeOAuthTokens, error) {
+			return withAppleKeyRotation(originalImplementation, func() (tpmodels.TypeOAuthTokens, error) {
+				return oRefreshOAuthTokens(refreshToken, userContext)
+			})
+		}
+
 		oGetUserInfo := originalImplementation.GetUserInfo
 		originalImplementation.GetUserInfo = func(oAuthTokens tpmodels.TypeOAuthTokens, userContext supertokens.UserContext) (tpmodels.TypeUserInfo, error) {
 			res, err := oGetUserInfo(oAuthTokens, userContext)
@@ -130,27 +137,6 @@ func Apple(input tpmodels.ProviderInput) *tpmodels.TypeProvider {
 	return NewProvider(input)
 }
 
-func getClientSecret(clientId string, additionalConfig map[string]interface{}) (string, error) {
-	claims := jwt.RegisteredClaims{
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(1 * time.Hour)),
-		IssuedAt:  jwt.NewNumericDate(time.Now()),
-		Audience:  jwt.ClaimStrings{"https://appleid.apple.com"},
-		Subject:   getActualClientIdFromDevelopmentClientId(clientId),
-		Issuer:    additionalConfig["teamId"].(string),
-	}
-	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
-	token.Header["kid"] = additionalConfig["keyId"].(string)
-	token.Header["alg"] = "ES256"
-
-	ecdsaPrivateKey, err := getECDSPrivateKey(additionalConfig["privateKey"].(string))
-	if err != nil {
-		return "", err
-	}
-
-	// Finally sign the token with the value of type *ecdsa.PrivateKey
-	return token.SignedString(ecdsaPrivateKey)
-}
-
 func getECDSPrivateKey(privateKey string) (*ecdsa.PrivateKey, error) {
 	block, _ := pem.Decode([]byte(privateKey))
 	// Check if it's a private key
@@ -0,0 +1,230 @@
+/* Copyright (c) 2023, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package providers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/supertokens/supertokens-golang/recipe/thirdparty/tpmodels"
+)
+
+const (
+	defaultAppleClientSecretValiditySec = 24 * 60 * 60
+	// Apple does not accept client secrets that are valid for more than 6 months
+	maxAppleClientSecretValiditySec = 15777000
+)
+
+type appleSigningKey struct {
+	keyId      string
+	privateKey string
+}
+
+type appleClientSecret struct {
+	secret    string
+	keyId     string
+	expiresAt time.Time
+}
+
+// appleClientSecretCache keeps the generated client secrets, and which key worked last for each
+// client, so that a key that Apple rejected is not tried first again.
+type appleClientSecretCache struct {
+	lock          sync.Mutex
+	secrets       map[string]appleClientSecret
+	preferredKeys map[string]string
+}
+
+var appleClientSecrets = &appleClientSecretCache{
+	secrets:       map[string]appleClientSecret{},
+	preferredKeys: map[string]string{},
+}
+
+func getAppleSigningKeys(additionalConfig map[string]interface{}) ([]appleSigningKey, error) {
+	keys := []appleSigningKey{}
+	if configKeys, ok := additionalConfig["keys"]; ok && configKeys != nil {
+		var keyConfigs []map[string]interface{}
+		switch configKeys := configKeys.(type) {
+		case []map[string]interface{}:
+			keyConfigs = configKeys
+		case []interface{}:
+			for _, keyConfig := range configKeys {
+				if keyConfig, ok := keyConfig.(map[string]interface{}); ok {
+					keyConfigs = append(keyConfigs, keyConfig)
+				}
+			}
+		}
+		for _, keyConfig := range keyConfigs {
+			keyId, _ := keyConfig["keyId"].(string)
+			privateKey, _ := keyConfig["privateKey"].(string)
+			if keyId == "" || privateKey == "" {
+				return nil, errors.New("please ensure that each of the keys in the AdditionalConfig has a keyId and privateKey")
+			}
+			keys = append(keys, appleSigningKey{keyId: keyId, privateKey: privateKey})
+		}
+	} else if additionalConfig["keyId"] != nil && additionalConfig["privateKey"] != nil {
+		keys = append(keys, appleSigningKey{keyId: fmt.Sprint(additionalConfig["keyId"]), privateKey: fmt.Sprint(additionalConfig["privateKey"])})
+	}
+
+	if len(keys) == 0 || additionalConfig["teamId"] == nil {
+		return nil, errors.New("please ensure that keyId, teamId and privateKey are provided in the AdditionalConfig")
+	}
+	return keys, nil
+}
+
+func getAppleClientSecretValidity(additionalConfig map[string]interface{}) (time.Duration, error) {
+	validitySec := uint64(defaultAppleClientSecretValiditySec)
+	if additionalConfig["clientSecretValiditySec"] != nil {
+		var err error
+		validitySec, err = strconv.ParseUint(fmt.Sprint(additionalConfig["clientSecretValiditySec"]), 10, 64)
+		if err != nil || validitySec == 0 || validitySec > maxAppleClientSecretValiditySec {
+			return 0, fmt.Errorf("clientSecretValiditySec must be between 1 and %d", maxAppleClientSecretValiditySec)
+		}
+	}
+	return time.Duration(validitySec) * time.Second, nil
+}
+
+func getAppleClientCacheKey(clientId string, teamId string) string {
+	return teamId + "/" + clientId
+}
+
+// getAppleClientSecret returns the client secret for the preferred key of the client
+func getAppleClientSecret(clientId string, additionalConfig map[string]interface{}) (string, error) {
+	keys, err := getAppleSigningKeys(additionalConfig)
+	if err != nil {
+		return "", err
+	}
+
+	key := keys[0]
+	appleClientSecrets.lock.Lock()
+	preferredKeyId := appleClientSecrets.preferredKeys[getAppleClientCacheKey(clientId, fmt.Sprint(additionalConfig["teamId"]))]
+	appleClientSecrets.lock.Unlock()
+	for _, k := range keys {
+		if k.keyId == preferredKeyId {
+			key = k
+		}
+	}
+	return getAppleClientSecretForKey(clientId, additionalConfig, key)
+}
+
+func getAppleClientSecretForKey(clientId string, additionalConfig map[string]interface{}, key appleSigningKey) (string, error) {
+	validity, err := getAppleClientSecretValidity(additionalConfig)
+	if err != nil {
+		return "", err
+	}
+	teamId := fmt.Sprint(additionalConfig["teamId"])
+
+	privateKeyHash := sha256.Sum256([]byte(key.privateKey))
+	cacheKey := fmt.Sprintf("%s/%s/%s/%d", getAppleClientCacheKey(clientId, teamId), key.keyId, hex.EncodeToString(privateKeyHash[:]), validity)
+
+	// The secret is renewed a little before it expires, so that it does not expire while in use
+	renewBefore := validity / 10
+	if renewBefore > 5*time.Minute {
+		renewBefore = 5 * time.Minute
+	}
+
+	appleClientSecrets.lock.Lock()
+	defer appleClientSecrets.lock.Unlock()
+
+	if cached, ok := appleClientSecrets.secrets[cacheKey]; ok && time.Now().Add(renewBefore).Before(cached.expiresAt) {
+		return cached.secret, nil
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(validity)
+	claims := jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+		IssuedAt:  jwt.NewNumericDate(now),
+		Audience:  jwt.ClaimStrings{"https://appleid.apple.com"},
+		Subject:   getActualClientIdFromDevelopmentClientId(clientId),
+		Issuer:    teamId,
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["kid"] = key.keyId
+	token.Header["alg"] = "ES256"
+
+	ecdsaPrivateKey, err := getECDSPrivateKey(key.privateKey)
+	if err != nil {
+		return "", err
+	}
+
+	// Finally sign the token with the value of type *ecdsa.PrivateKey
+	secret, err := token.SignedString(ecdsaPrivateKey)
+	if err != nil {
+		return "", err
+	}
+	appleClientSecrets.secrets[cacheKey] = appleClientSecret{secret: secret, keyId: key.keyId, expiresAt: expiresAt}
+	return secret, nil
+}
+
+// getKeyIdOfAppleClientSecret returns the key the secret was generated with, or false if the secret
+// was not generated by the SDK
+func getKeyIdOfAppleClientSecret(secret string) (string, bool) {
+	appleClientSecrets.lock.Lock()
+	defer appleClientSecrets.lock.Unlock()
+	for _, cached := range appleClientSecrets.secrets {
+		if cached.secret == secret {
+			return cached.keyId, true
+		}
+	}
+	return "", false
+}
+
+// withAppleKeyRotation calls the token endpoint with the client secret of the other keys in the
+// config if Apple rejects the client secret, like it does after a key is revoked.
+func withAppleKeyRotation(impl *tpmodels.TypeProvider, call func() (tpmodels.TypeOAuthTokens, error)) (tpmodels.TypeOAuthTokens, error) {
+	res, err := call()
+	if err == nil || !strings.Contains(err.Error(), "invalid_client") {
+		return res, err
+	}
+
+	usedKeyId, generated := getKeyIdOfAppleClientSecret(impl.Config.ClientSecret)
+	if !generated {
+		return res, err
+	}
+	keys, keysErr := getAppleSigningKeys(impl.Config.AdditionalConfig)
+	if keysErr != nil {
+		return res, err
+	}
+
+	for _, key := range keys {
+		if key.keyId == usedKeyId {
+			continue
+		}
+		secret, secretErr := getAppleClientSecretForKey(impl.Config.ClientID, impl.Config.AdditionalConfig, key)
+		if secretErr != nil {
+			return res, err
+		}
+		impl.Config.ClientSecret = secret
+		res, err = call()
+		if err == nil {
+			appleClientSecrets.lock.Lock()
+			appleClientSecrets.preferredKeys[getAppleClientCacheKey(impl.Config.ClientID, fmt.Sprint(impl.Config.AdditionalConfig["teamId"]))] = key.keyId
+			appleClientSecrets.lock.Unlock()
+			return res, nil
+		}
+		if !strings.Contains(err.Error(), "invalid_client") {
+			return res, err
+		}
+	}
+	return res, err
+}
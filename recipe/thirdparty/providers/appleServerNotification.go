@@ -0,0 +1,91 @@
+/* Copyright (c) 2023, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package providers
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/supertokens/supertokens-golang/recipe/thirdparty/tpmodels"
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+const appleIssuer = "https://appleid.apple.com"
+
+// VerifyAppleServerNotification checks the signature, issuer and audience of the payload of a
+// server-to-server notification from Apple, and returns the event in it.
+func VerifyAppleServerNotification(provider *tpmodels.TypeProvider, payload string, userContext supertokens.UserContext) (tpmodels.AppleServerNotification, error) {
+	config := provider.Config
+	if config.JwksURI == "" {
+		return tpmodels.AppleServerNotification{}, errors.New("the jwksURI of the Apple provider is not configured")
+	}
+	jwksKeyfunc, err := getJWKSKeyfunc(config, config.JwksURI, userContext)
+	if err != nil {
+		return tpmodels.AppleServerNotification{}, err
+	}
+
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(payload, claims, jwksKeyfunc, jwt.WithIssuer(appleIssuer), jwt.WithAudience(getActualClientIdFromDevelopmentClientId(config.ClientID)))
+	if err != nil {
+		return tpmodels.AppleServerNotification{}, err
+	}
+	if !token.Valid {
+		return tpmodels.AppleServerNotification{}, errors.New("invalid Apple server notification")
+	}
+
+	// events is a JSON string in the notifications sent by Apple
+	events := map[string]interface{}{}
+	switch value := claims["events"].(type) {
+	case string:
+		err = json.Unmarshal([]byte(value), &events)
+		if err != nil {
+			return tpmodels.AppleServerNotification{}, err
+		}
+	case map[string]interface{}:
+		events = value
+	default:
+		return tpmodels.AppleServerNotification{}, errors.New("the Apple server notification does not have an events claim")
+	}
+
+	notification := tpmodels.AppleServerNotification{
+		ThirdPartyId: provider.ID,
+	}
+	notification.Type, _ = events["type"].(string)
+	notification.ThirdPartyUserId, _ = events["sub"].(string)
+	notification.Email, _ = events["email"].(string)
+	if notification.Type == "" || notification.ThirdPartyUserId == "" {
+		return tpmodels.AppleServerNotification{}, errors.New("the Apple server notification does not have a type and sub")
+	}
+
+	switch isPrivateEmail := events["is_private_email"].(type) {
+	case bool:
+		notification.IsPrivateEmail = isPrivateEmail
+	case string:
+		notification.IsPrivateEmail = strings.ToLower(isPrivateEmail) == "true"
+	}
+
+	if eventTime, ok := events["event_time"].(float64); ok {
+		// Apple has sent the time in both seconds and milliseconds
+		if eventTime < 1e11 {
+			eventTime *= 1000
+		}
+		notification.EventTime = uint64(eventTime)
+	}
+
+	return notification, nil
+}
@@ -0,0 +1,82 @@
+/* Copyright (c) 2023, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package providers
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/supertokens/supertokens-golang/recipe/thirdparty/tpmodels"
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+// Auth0 needs the auth0Domain (for example mycompany.eu.auth0.com or a custom domain) in the
+// AdditionalConfig. The audience in the AdditionalConfig is sent to the authorization endpoint, so
+// that the access token can be used with that API.
+func Auth0(input tpmodels.ProviderInput) *tpmodels.TypeProvider {
+	if input.Config.Name == "" {
+		input.Config.Name = "Auth0"
+	}
+
+	oOverride := input.Override
+
+	input.Override = func(originalImplementation *tpmodels.TypeProvider) *tpmodels.TypeProvider {
+		oGetConfig := originalImplementation.GetConfigForClientType
+		originalImplementation.GetConfigForClientType = func(clientType *string, userContext supertokens.UserContext) (tpmodels.ProviderConfigForClientType, error) {
+			config, err := oGetConfig(clientType, userContext)
+			if err != nil {
+				return tpmodels.ProviderConfigForClientType{}, err
+			}
+
+			if config.AdditionalConfig == nil || config.AdditionalConfig["auth0Domain"] == nil {
+				if config.OIDCDiscoveryEndpoint == "" {
+					return tpmodels.ProviderConfigForClientType{}, errors.New("please provide the auth0Domain in the AdditionalConfig of the Auth0 provider.")
+				}
+			} else {
+				oidcDomain, err := supertokens.NewNormalisedURLDomain(fmt.Sprint(config.AdditionalConfig["auth0Domain"]))
+				if err != nil {
+					return tpmodels.ProviderConfigForClientType{}, err
+				}
+				config.OIDCDiscoveryEndpoint = oidcDomain.GetAsStringDangerous()
+			}
+
+			// The config could be coming from core where we didn't add the well-known previously
+			config.OIDCDiscoveryEndpoint = normaliseOIDCEndpointToIncludeWellKnown(config.OIDCDiscoveryEndpoint)
+
+			if len(config.Scope) == 0 {
+				config.Scope = []string{"openid", "email", "profile"}
+			}
+
+			if config.AdditionalConfig != nil && config.AdditionalConfig["audience"] != nil {
+				authorizationEndpointQueryParams := map[string]interface{}{}
+				for k, v := range config.AuthorizationEndpointQueryParams {
+					authorizationEndpointQueryParams[k] = v
+				}
+				authorizationEndpointQueryParams["audience"] = config.AdditionalConfig["audience"]
+				config.AuthorizationEndpointQueryParams = authorizationEndpointQueryParams
+			}
+
+			return config, nil
+		}
+
+		if oOverride != nil {
+			originalImplementation = oOverride(originalImplementation)
+		}
+		return originalImplementation
+	}
+
+	return NewProvider(input)
+}
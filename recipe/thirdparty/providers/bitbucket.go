@@ -73,9 +73,11 @@ func Bitbucket(input tpmodels.ProviderInput) *tpmodels.TypeProvider {
 			}
 			rawUserInfoFromProvider := tpmodels.TypeRawUserInfoFromProvider{}
 			userInfoFromAccessToken, err := doGetRequest(
+				originalImplementation.Config,
 				"https://api.bitbucket.org/2.0/user",
 				nil,
 				headers,
+				userContext,
 			)
 			if err != nil {
 				return tpmodels.TypeUserInfo{}, err
@@ -83,9 +85,11 @@ func Bitbucket(input tpmodels.ProviderInput) *tpmodels.TypeProvider {
 			rawUserInfoFromProvider.FromUserInfoAPI = userInfoFromAccessToken.(map[string]interface{})
 
 			userInfoFromEmail, err := doGetRequest(
+				originalImplementation.Config,
 				"https://api.bitbucket.org/2.0/user/emails",
 				nil,
 				headers,
+				userContext,
 			)
 			rawUserInfoFromProvider.FromUserInfoAPI["email"] = userInfoFromEmail
 
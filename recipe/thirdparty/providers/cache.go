@@ -0,0 +1,335 @@
+/* Copyright (c) 2021, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package providers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/MicahParks/keyfunc/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/supertokens/supertokens-golang/recipe/thirdparty/tpmodels"
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+// ProviderCacheMetrics counts the lookups in the cache of OIDC discovery documents, provider JWKS and
+// SAML IdP metadata.
+type ProviderCacheMetrics struct {
+	DiscoveryHits   uint64
+	DiscoveryMisses uint64
+	JWKSHits        uint64
+	JWKSMisses      uint64
+	// JWKSUnknownKIDRefreshes counts the JWKS that were fetched again because an id token was
+	// signed with a key that was not in the cached JWKS.
+	JWKSUnknownKIDRefreshes uint64
+	SAMLMetadataHits        uint64
+	SAMLMetadataMisses      uint64
+	// FetchErrors counts the failed fetches. If there is an expired entry for the URL, it is used
+	// until a fetch succeeds.
+	FetchErrors uint64
+}
+
+type providerCacheEntry struct {
+	discoveryInfo         map[string]interface{}
+	jwks                  *keyfunc.JWKS
+	samlMetadata          *SAMLIdPMetadata
+	expiresAt             time.Time
+	lastUnknownKIDRefresh time.Time
+}
+
+type providerCache struct {
+	config  tpmodels.ProviderCacheConfig
+	lock    sync.Mutex
+	entries map[string]*providerCacheEntry
+	// fetchLocks makes sure that there is only one fetch at a time for the same URL
+	fetchLocks map[string]*sync.Mutex
+	metrics    ProviderCacheMetrics
+}
+
+var defaultProviderCacheConfig = tpmodels.ProviderCacheConfig{
+	DefaultTTLSec:                3600,
+	MinTTLSec:                    60,
+	MaxTTLSec:                    86400,
+	UnknownKIDRefreshIntervalSec: 10,
+}
+
+// cache is replaced when the recipe is initialised, while the goroutine that pre-warms it may be
+// reading it, so it is only accessed using getProviderCache
+var cache = newProviderCache(defaultProviderCacheConfig)
+var cacheLock sync.RWMutex
+
+// validationProviderCacheKey is the key of the userContext that ValidateProviderConfig uses to
+// give the providers a cache of their own, so that checking a config does not fill the cache used
+// for signing in
+const validationProviderCacheKey = "_thirdPartyValidationProviderCache"
+
+func getProviderCache(userContext supertokens.UserContext) *providerCache {
+	if c := getValidationProviderCache(userContext); c != nil {
+		return c
+	}
+	cacheLock.RLock()
+	defer cacheLock.RUnlock()
+	return cache
+}
+
+func getValidationProviderCache(userContext supertokens.UserContext) *providerCache {
+	if userContext == nil {
+		return nil
+	}
+	c, _ := (*userContext)[validationProviderCacheKey].(*providerCache)
+	return c
+}
+
+func newProviderCache(config tpmodels.ProviderCacheConfig) *providerCache {
+	return &providerCache{
+		config:     config,
+		entries:    map[string]*providerCacheEntry{},
+		fetchLocks: map[string]*sync.Mutex{},
+	}
+}
+
+// ConfigureProviderCache sets the TTLs of the cache of OIDC discovery documents, provider JWKS and SAML
+// IdP metadata, and clears it. The thirdparty recipe calls this when it is initialised.
+func ConfigureProviderCache(config tpmodels.ProviderCacheConfig) {
+	cacheLock.Lock()
+	defer cacheLock.Unlock()
+	cache = newProviderCache(config)
+}
+
+// GetProviderCacheMetrics returns the counters of the cache of OIDC discovery documents, provider JWKS
+// and SAML IdP metadata.
+func GetProviderCacheMetrics() ProviderCacheMetrics {
+	c := getProviderCache(nil)
+	return ProviderCacheMetrics{
+		DiscoveryHits:           atomic.LoadUint64(&c.metrics.DiscoveryHits),
+		DiscoveryMisses:         atomic.LoadUint64(&c.metrics.DiscoveryMisses),
+		JWKSHits:                atomic.LoadUint64(&c.metrics.JWKSHits),
+		JWKSMisses:              atomic.LoadUint64(&c.metrics.JWKSMisses),
+		JWKSUnknownKIDRefreshes: atomic.LoadUint64(&c.metrics.JWKSUnknownKIDRefreshes),
+		SAMLMetadataHits:        atomic.LoadUint64(&c.metrics.SAMLMetadataHits),
+		SAMLMetadataMisses:      atomic.LoadUint64(&c.metrics.SAMLMetadataMisses),
+		FetchErrors:             atomic.LoadUint64(&c.metrics.FetchErrors),
+	}
+}
+
+// PreWarmProviderCache fetches the OIDC discovery documents, and the JWKS they point to, so that the
+// first sign in with these providers does not have to wait for them.
+func PreWarmProviderCache(oidcDiscoveryEndpoints []string) error {
+	for _, endpoint := range oidcDiscoveryEndpoints {
+		oidcInfo, err := getOIDCDiscoveryInfo(tpmodels.ProviderConfigForClientType{}, endpoint, nil)
+		if err != nil {
+			return err
+		}
+		if jwksURI, ok := oidcInfo["jwks_uri"].(string); ok {
+			if _, err := getJWKSFromURL(tpmodels.ProviderConfigForClientType{}, jwksURI, nil); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (c *providerCache) getEntry(url string) *providerCacheEntry {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.entries[url]
+}
+
+func (c *providerCache) getFetchLock(url string) *sync.Mutex {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	fetchLock, ok := c.fetchLocks[url]
+	if !ok {
+		fetchLock = &sync.Mutex{}
+		c.fetchLocks[url] = fetchLock
+	}
+	return fetchLock
+}
+
+// get returns the entry for the url, fetching it if it is missing, expired or if forceRefresh returns
+// true for the current entry. forceRefresh is called again once the fetch lock is held, so that an
+// entry fetched by a concurrent call is not fetched again.
+func (c *providerCache) get(url string, hits *uint64, misses *uint64, forceRefresh func(entry *providerCacheEntry) bool, fetch func() (*providerCacheEntry, http.Header, error)) (*providerCacheEntry, error) {
+	isUsable := func(entry *providerCacheEntry) bool {
+		return entry != nil && time.Now().Before(entry.expiresAt) && (forceRefresh == nil || !forceRefresh(entry))
+	}
+
+	if entry := c.getEntry(url); isUsable(entry) {
+		atomic.AddUint64(hits, 1)
+		return entry, nil
+	}
+
+	fetchLock := c.getFetchLock(url)
+	fetchLock.Lock()
+	defer fetchLock.Unlock()
+
+	// Check again to see if it was fetched while we were waiting for the lock
+	oldEntry := c.getEntry(url)
+	if isUsable(oldEntry) {
+		atomic.AddUint64(hits, 1)
+		return oldEntry, nil
+	}
+
+	atomic.AddUint64(misses, 1)
+	newEntry, headers, err := fetch()
+	if err != nil {
+		atomic.AddUint64(&c.metrics.FetchErrors, 1)
+		if oldEntry != nil {
+			supertokens.LogDebugMessage(fmt.Sprintf("Failed to refresh %s, using the cached response: %s", url, err.Error()))
+			return oldEntry, nil
+		}
+		return nil, err
+	}
+	newEntry.expiresAt = time.Now().Add(c.getTTL(headers))
+	if oldEntry != nil && newEntry.lastUnknownKIDRefresh.IsZero() {
+		newEntry.lastUnknownKIDRefresh = oldEntry.lastUnknownKIDRefresh
+	}
+
+	c.lock.Lock()
+	c.entries[url] = newEntry
+	c.lock.Unlock()
+	return newEntry, nil
+}
+
+func (c *providerCache) getTTL(headers http.Header) time.Duration {
+	ttlSec := c.config.DefaultTTLSec
+	for _, directive := range strings.Split(headers.Get("Cache-Control"), ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		if directive == "no-cache" || directive == "no-store" {
+			ttlSec = 0
+			break
+		}
+		if strings.HasPrefix(directive, "max-age=") {
+			if maxAge, err := strconv.ParseUint(strings.TrimPrefix(directive, "max-age="), 10, 64); err == nil {
+				ttlSec = maxAge
+			}
+		}
+	}
+	if ttlSec < c.config.MinTTLSec {
+		ttlSec = c.config.MinTTLSec
+	}
+	if ttlSec > c.config.MaxTTLSec {
+		ttlSec = c.config.MaxTTLSec
+	}
+	return time.Duration(ttlSec) * time.Second
+}
+
+func getOIDCDiscoveryInfo(config tpmodels.ProviderConfigForClientType, issuer string, userContext supertokens.UserContext) (map[string]interface{}, error) {
+	normalizedDomain, err := supertokens.NewNormalisedURLDomain(issuer)
+	if err != nil {
+		return nil, err
+	}
+	normalizedPath, err := supertokens.NewNormalisedURLPath(issuer)
+	if err != nil {
+		return nil, err
+	}
+	// Paths can be case sensitive (like the realm of Keycloak), so only the domain is lower cased
+	path := normalizedPath.GetAsStringDangerous()
+	if parsedIssuer, err := neturl.Parse(issuer); err == nil && strings.EqualFold(strings.TrimSuffix(parsedIssuer.Path, "/"), path) {
+		path = strings.TrimSuffix(parsedIssuer.EscapedPath(), "/")
+	}
+	url := normalizedDomain.GetAsStringDangerous() + path
+
+	c := getProviderCache(userContext)
+	entry, err := c.get(url, &c.metrics.DiscoveryHits, &c.metrics.DiscoveryMisses, nil, func() (*providerCacheEntry, http.Header, error) {
+		oidcInfo, headers, err := doGetRequestWithResponseHeaders(config, url, nil, nil, userContext)
+		if err != nil {
+			return nil, nil, err
+		}
+		oidcInfoMap, ok := oidcInfo.(map[string]interface{})
+		if !ok {
+			return nil, nil, errors.New("the OIDC discovery endpoint " + url + " did not return a JSON object")
+		}
+		return &providerCacheEntry{discoveryInfo: oidcInfoMap}, headers, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry.discoveryInfo, nil
+}
+
+func fetchJWKS(config tpmodels.ProviderConfigForClientType, url string, userContext supertokens.UserContext) (*providerCacheEntry, http.Header, error) {
+	jwksJSON, headers, err := doGetRequestWithResponseHeaders(config, url, nil, nil, userContext)
+	if err != nil {
+		return nil, nil, err
+	}
+	rawJWKS, err := json.Marshal(jwksJSON)
+	if err != nil {
+		return nil, nil, err
+	}
+	jwks, err := keyfunc.NewJSON(rawJWKS)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &providerCacheEntry{jwks: jwks}, headers, nil
+}
+
+func getJWKSFromURL(config tpmodels.ProviderConfigForClientType, url string, userContext supertokens.UserContext) (*keyfunc.JWKS, error) {
+	c := getProviderCache(userContext)
+	entry, err := c.get(url, &c.metrics.JWKSHits, &c.metrics.JWKSMisses, nil, func() (*providerCacheEntry, http.Header, error) {
+		return fetchJWKS(config, url, userContext)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry.jwks, nil
+}
+
+// getJWKSKeyfunc returns a jwt.Keyfunc that uses the cached JWKS of the url, and fetches it again if
+// the token is signed with a key that is not in it. These refreshes are rate limited, so that tokens
+// with random key ids cannot be used to make us call the provider on every request.
+func getJWKSKeyfunc(config tpmodels.ProviderConfigForClientType, url string, userContext supertokens.UserContext) (jwt.Keyfunc, error) {
+	jwks, err := getJWKSFromURL(config, url, userContext)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(token *jwt.Token) (interface{}, error) {
+		key, err := jwks.Keyfunc(token)
+		if err == nil || !errors.Is(err, keyfunc.ErrKIDNotFound) {
+			return key, err
+		}
+
+		c := getProviderCache(userContext)
+		refreshInterval := time.Duration(c.config.UnknownKIDRefreshIntervalSec) * time.Second
+		entry, fetchErr := c.get(url, &c.metrics.JWKSHits, &c.metrics.JWKSMisses, func(entry *providerCacheEntry) bool {
+			if entry.jwks != jwks {
+				// Another request has already fetched it again
+				return false
+			}
+			return time.Since(entry.lastUnknownKIDRefresh) >= refreshInterval
+		}, func() (*providerCacheEntry, http.Header, error) {
+			atomic.AddUint64(&c.metrics.JWKSUnknownKIDRefreshes, 1)
+			entry, headers, err := fetchJWKS(config, url, userContext)
+			if err == nil {
+				entry.lastUnknownKIDRefresh = time.Now()
+			}
+			return entry, headers, err
+		})
+		if fetchErr != nil {
+			return nil, err
+		}
+		return entry.jwks.Keyfunc(token)
+	}, nil
+}
@@ -0,0 +1,209 @@
+/* Copyright (c) 2023, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package providers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/supertokens/supertokens-golang/recipe/thirdparty/tpmodels"
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+type providerConfigValidation struct {
+	result tpmodels.ProviderConfigValidationResult
+}
+
+func (v *providerConfigValidation) addError(field string, msg string) {
+	// The errors of the fields shared by the clients would be repeated for each client
+	for _, issue := range v.result.Errors {
+		if issue.Field == field && issue.Msg == msg {
+			return
+		}
+	}
+	v.result.Errors = append(v.result.Errors, tpmodels.ProviderConfigValidationIssue{Field: field, Msg: msg})
+}
+
+func (v *providerConfigValidation) addWarning(field string, msg string) {
+	v.result.Warnings = append(v.result.Warnings, tpmodels.ProviderConfigValidationIssue{Field: field, Msg: msg})
+}
+
+// ValidateProviderConfig checks a provider config, like one that is about to be saved for a tenant
+// with CreateOrUpdateThirdPartyConfig. Besides the fields of the config, it checks that the OIDC
+// discovery endpoint or SAML metadata can be loaded and, if a redirect URI is given in the options,
+// that an authorisation URL can be built for each client.
+func ValidateProviderConfig(config tpmodels.ProviderConfig, options tpmodels.ValidateProviderConfigOptions, userContext supertokens.UserContext) tpmodels.ProviderConfigValidationResult {
+	v := &providerConfigValidation{}
+
+	if options.HTTPClient != nil {
+		config.HTTPClient = options.HTTPClient
+	}
+
+	// The config may not be saved, so what is loaded to check it is kept in a cache that is
+	// dropped after the check
+	validationUserContext := map[string]interface{}{}
+	if userContext != nil {
+		for key, value := range *userContext {
+			validationUserContext[key] = value
+		}
+	}
+	validationUserContext[validationProviderCacheKey] = newProviderCache(getProviderCache(nil).config)
+	userContext = &validationUserContext
+
+	if config.ThirdPartyId == "" {
+		v.addError("thirdPartyId", "the thirdPartyId is required")
+		return v.result
+	}
+
+	if len(config.Clients) == 0 {
+		v.addError("clients", "at least one client must be configured")
+		return v.result
+	}
+
+	clientTypes := map[string]bool{}
+	for i, client := range config.Clients {
+		if len(config.Clients) > 1 {
+			field := fmt.Sprintf("clients[%d].clientType", i)
+			if client.ClientType == "" {
+				v.addError(field, "the clientType is required when there is more than one client, so that the frontend can pick the client")
+			} else if clientTypes[client.ClientType] {
+				v.addError(field, "the clientType "+client.ClientType+" is used by more than one client")
+			}
+			clientTypes[client.ClientType] = true
+		}
+	}
+
+	for i, client := range config.Clients {
+		errorCount := len(v.result.Errors)
+		v.validateClient(config, client, fmt.Sprintf("clients[%d]", i))
+
+		// The provider is only created for clients without errors, since the providers expect the
+		// fields that were checked to be set
+		if len(v.result.Errors) == errorCount {
+			v.dryRunClient(config, client, fmt.Sprintf("clients[%d]", i), options, userContext)
+		}
+	}
+
+	return v.result
+}
+
+func (v *providerConfigValidation) validateClient(config tpmodels.ProviderConfig, client tpmodels.ProviderClientConfig, field string) {
+	thirdPartyId := config.ThirdPartyId
+	isSAML := strings.HasPrefix(thirdPartyId, "saml")
+
+	if client.ClientID == "" {
+		v.addError(field+".clientId", "the clientId is required")
+	}
+
+	requireAdditionalConfig := func(keys ...string) {
+		for _, key := range keys {
+			value, ok := client.AdditionalConfig[key]
+			if !ok || value == nil || value == "" {
+				v.addError(field+".additionalConfig."+key, key+" is required in the additionalConfig of the "+thirdPartyId+" provider")
+			} else if _, ok := value.(string); !ok {
+				v.addError(field+".additionalConfig."+key, key+" must be a string")
+			}
+		}
+	}
+
+	switch {
+	case strings.HasPrefix(thirdPartyId, "active-directory"):
+		requireAdditionalConfig("directoryId")
+	case strings.HasPrefix(thirdPartyId, "apple"):
+		if client.ClientSecret == "" {
+			_, err := getAppleSigningKeys(client.AdditionalConfig)
+			if err != nil {
+				v.addError(field+".additionalConfig", err.Error()+", or set the clientSecret")
+			}
+		}
+	case strings.HasPrefix(thirdPartyId, "okta"):
+		requireAdditionalConfig("oktaDomain")
+	case strings.HasPrefix(thirdPartyId, "boxy-saml"):
+		requireAdditionalConfig("boxyURL")
+	case isSAML:
+		if client.AdditionalConfig["idpMetadataXML"] == nil && client.AdditionalConfig["idpMetadataURL"] == nil {
+			v.addError(field+".additionalConfig", "either idpMetadataXML or idpMetadataURL is required in the additionalConfig of the SAML provider")
+		}
+	case strings.HasPrefix(thirdPartyId, "auth0"):
+		requireAdditionalConfig("auth0Domain")
+	case strings.HasPrefix(thirdPartyId, "keycloak"):
+		if config.OIDCDiscoveryEndpoint == "" {
+			requireAdditionalConfig("keycloakBaseUrl", "realm")
+		}
+	case strings.HasPrefix(thirdPartyId, "twitter"):
+		if client.ForcePKCE != nil && !*client.ForcePKCE {
+			v.addError(field+".forcePKCE", "Twitter requires PKCE, so forcePKCE cannot be false")
+		}
+	}
+
+	if isSAML {
+		if client.ForcePKCE != nil && *client.ForcePKCE {
+			v.addError(field+".forcePKCE", "PKCE is not used by SAML providers")
+		}
+		return
+	}
+
+	if client.ClientSecret == "" && !strings.HasPrefix(thirdPartyId, "apple") && client.AdditionalConfig["privateKey"] == nil && client.AdditionalConfig["certificate"] == nil {
+		v.addWarning(field+".clientSecret", "there is no clientSecret, so PKCE is used. The client must be registered as a public client with the provider")
+	}
+
+	if len(client.Scope) > 0 && config.OIDCDiscoveryEndpoint != "" && !containsString(client.Scope, "openid") {
+		v.addWarning(field+".scope", "the scope does not include openid, so the provider will not return an id token")
+	}
+}
+
+// dryRunClient creates the provider for the client, which loads its OIDC discovery endpoint or SAML
+// metadata, and builds the authorisation URL.
+func (v *providerConfigValidation) dryRunClient(config tpmodels.ProviderConfig, client tpmodels.ProviderClientConfig, field string, options tpmodels.ValidateProviderConfigOptions, userContext supertokens.UserContext) {
+	// The config of the provider only has the client being checked, so that clientType is not needed
+	config.Clients = []tpmodels.ProviderClientConfig{client}
+
+	provider := createProvider(tpmodels.ProviderInput{Config: config})
+	err := fetchAndSetConfig(provider, nil, userContext)
+	if err != nil {
+		if config.OIDCDiscoveryEndpoint != "" {
+			v.addError("oidcDiscoveryEndpoint", "could not load the OIDC discovery endpoint "+config.OIDCDiscoveryEndpoint+": "+err.Error())
+		} else {
+			v.addError(field, "could not load the config of the provider: "+err.Error())
+		}
+		return
+	}
+
+	if provider.Config.AuthorizationEndpoint == "" {
+		v.addError("authorizationEndpoint", "the authorizationEndpoint is not set, and could not be found with an oidcDiscoveryEndpoint")
+	}
+	if !strings.HasPrefix(config.ThirdPartyId, "saml") {
+		if provider.Config.TokenEndpoint == "" {
+			v.addError("tokenEndpoint", "the tokenEndpoint is not set, and could not be found with an oidcDiscoveryEndpoint")
+		}
+		if provider.Config.JwksURI == "" && provider.Config.UserInfoEndpoint == "" {
+			v.addError("userInfoEndpoint", "either the userInfoEndpoint or jwksURI must be set to get the info of the user, or found with an oidcDiscoveryEndpoint")
+		}
+	}
+
+	if options.RedirectURIOnProviderDashboard == "" || provider.Config.AuthorizationEndpoint == "" {
+		return
+	}
+	redirect, err := provider.GetAuthorisationRedirectURL(options.RedirectURIOnProviderDashboard, userContext)
+	if err != nil {
+		v.addError(field, "could not build the authorisation URL: "+err.Error())
+		return
+	}
+	if v.result.AuthorisationURLs == nil {
+		v.result.AuthorisationURLs = map[string]string{}
+	}
+	v.result.AuthorisationURLs[client.ClientType] = redirect.URLWithQueryParams
+}
@@ -15,7 +15,8 @@ func getProviderConfigForClient(config tpmodels.ProviderConfig, clientConfig tpm
 		ForcePKCE:        clientConfig.ForcePKCE,
 		AdditionalConfig: clientConfig.AdditionalConfig,
 
-		Name: config.Name,
+		ThirdPartyId: config.ThirdPartyId,
+		Name:         config.Name,
 
 		AuthorizationEndpoint:            config.AuthorizationEndpoint,
 		AuthorizationEndpointQueryParams: config.AuthorizationEndpointQueryParams,
@@ -29,8 +30,10 @@ func getProviderConfigForClient(config tpmodels.ProviderConfig, clientConfig tpm
 		UserInfoMap:                      config.UserInfoMap,
 		ValidateIdTokenPayload:           config.ValidateIdTokenPayload,
 		ValidateAccessToken:              config.ValidateAccessToken,
+		IdTokenValidation:                config.IdTokenValidation,
 		RequireEmail:                     config.RequireEmail,
 		GenerateFakeEmail:                config.GenerateFakeEmail,
+		HTTPClient:                       config.HTTPClient,
 	}
 }
 
@@ -76,8 +79,20 @@ func createProvider(input tpmodels.ProviderInput) *tpmodels.TypeProvider {
 		return Linkedin(input)
 	} else if strings.HasPrefix(input.Config.ThirdPartyId, "boxy-saml") {
 		return BoxySaml(input)
+	} else if strings.HasPrefix(input.Config.ThirdPartyId, "saml") {
+		return Saml(input)
 	} else if strings.HasPrefix(input.Config.ThirdPartyId, "twitter") {
 		return Twitter(input)
+	} else if strings.HasPrefix(input.Config.ThirdPartyId, "microsoft-entra") {
+		return MicrosoftEntra(input)
+	} else if strings.HasPrefix(input.Config.ThirdPartyId, "slack") {
+		return Slack(input)
+	} else if strings.HasPrefix(input.Config.ThirdPartyId, "salesforce") {
+		return Salesforce(input)
+	} else if strings.HasPrefix(input.Config.ThirdPartyId, "keycloak") {
+		return Keycloak(input)
+	} else if strings.HasPrefix(input.Config.ThirdPartyId, "auth0") {
+		return Auth0(input)
 	}
 
 	return NewProvider(input)
@@ -89,7 +104,7 @@ func fetchAndSetConfig(provider *tpmodels.TypeProvider, clientType *string, user
 		return err
 	}
 
-	config, err = discoverOIDCEndpoints(config)
+	config, err = discoverOIDCEndpoints(config, userContext)
 	if err != nil {
 		return err
 	}
@@ -89,6 +89,10 @@ func NewProvider(input tpmodels.ProviderInput) *tpmodels.TypeProvider {
 		return oauth2_GetUserInfo(impl.Config, oAuthTokens, userContext)
 	}
 
+	impl.RefreshOAuthTokens = func(refreshToken string, userContext supertokens.UserContext) (tpmodels.TypeOAuthTokens, error) {
+		return oauth2_RefreshOAuthTokens(impl.Config, refreshToken, userContext)
+	}
+
 	if input.Override != nil {
 		impl = input.Override(impl)
 	}
@@ -42,12 +42,12 @@ func Github(input tpmodels.ProviderInput) *tpmodels.TypeProvider {
 			basicAuthToken := base64.StdEncoding.EncodeToString([]byte(clientConfig.ClientID + ":" + clientConfig.ClientSecret))
 			wrongClientIdError := errors.New("Access token does not belong to your application")
 
-			resp, status, err := doPostRequest("https://api.github.com/applications/"+clientConfig.ClientID+"/token", map[string]interface{}{
+			resp, status, err := doPostRequest(clientConfig, "https://api.github.com/applications/"+clientConfig.ClientID+"/token", map[string]interface{}{
 				"access_token": accessToken,
 			}, map[string]interface{}{
 				"Authorization": "Basic " + basicAuthToken,
 				"Content-Type":  "application/json",
-			})
+			}, userContext)
 
 			if err != nil || status != 200 {
 				return errors.New("Invalid access token")
@@ -96,13 +96,13 @@ func Github(input tpmodels.ProviderInput) *tpmodels.TypeProvider {
 				"Accept":        "application/vnd.github.v3+json",
 			}
 			rawResponse := map[string]interface{}{}
-			emailInfo, err := doGetRequest("https://api.github.com/user/emails", nil, headers)
+			emailInfo, err := doGetRequest(originalImplementation.Config, "https://api.github.com/user/emails", nil, headers, userContext)
 			if err != nil {
 				return tpmodels.TypeUserInfo{}, err
 			}
 			rawResponse["emails"] = emailInfo
 
-			userInfo, err := doGetRequest("https://api.github.com/user", nil, headers)
+			userInfo, err := doGetRequest(originalImplementation.Config, "https://api.github.com/user", nil, headers, userContext)
 			if err != nil {
 				return tpmodels.TypeUserInfo{}, err
 			}
@@ -16,6 +16,8 @@
 package providers
 
 import (
+	"errors"
+
 	"github.com/supertokens/supertokens-golang/recipe/thirdparty/tpmodels"
 	"github.com/supertokens/supertokens-golang/supertokens"
 )
@@ -40,7 +42,10 @@ func Gitlab(input tpmodels.ProviderInput) *tpmodels.TypeProvider {
 			}
 
 			if config.AdditionalConfig != nil && config.AdditionalConfig["gitlabBaseUrl"] != nil {
-				gitlabBaseUrl := config.AdditionalConfig["gitlabBaseUrl"].(string)
+				gitlabBaseUrl, ok := config.AdditionalConfig["gitlabBaseUrl"].(string)
+				if !ok {
+					return tpmodels.ProviderConfigForClientType{}, errors.New("the gitlabBaseUrl in the AdditionalConfig of the Gitlab provider must be a string")
+				}
 				oidcDomain, err := supertokens.NewNormalisedURLDomain(gitlabBaseUrl)
 				if err != nil {
 					return tpmodels.ProviderConfigForClientType{}, err
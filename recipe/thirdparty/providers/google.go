@@ -25,6 +25,9 @@ func Google(input tpmodels.ProviderInput) *tpmodels.TypeProvider {
 		input.Config.AuthorizationEndpointQueryParams["access_type"] = "offline"
 	}
 
+	// Id tokens from Google can have either of these issuers
+	input.Config.IdTokenValidation = withDefaultIdTokenIssuers(input.Config.IdTokenValidation, []string{"https://accounts.google.com", "accounts.google.com"})
+
 	oOverride := input.Override
 
 	input.Override = func(originalImplementation *tpmodels.TypeProvider) *tpmodels.TypeProvider {
@@ -0,0 +1,181 @@
+/* Copyright (c) 2021, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package providers
+
+import (
+	"context"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/supertokens/supertokens-golang/recipe/thirdparty/tpmodels"
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+var defaultProviderHTTPConfig = tpmodels.ProviderHTTPConfig{
+	Client:           &http.Client{},
+	TimeoutSec:       30,
+	RetryBaseDelayMS: 50,
+	RetryMaxDelayMS:  1000,
+}
+
+var providerHTTPConfig = defaultProviderHTTPConfig
+var providerHTTPConfigLock sync.RWMutex
+
+func getProviderHTTPConfig() tpmodels.ProviderHTTPConfig {
+	providerHTTPConfigLock.RLock()
+	defer providerHTTPConfigLock.RUnlock()
+	return providerHTTPConfig
+}
+
+// ConfigureProviderHTTP sets the HTTP client, timeout, retries and request hook used for the requests
+// to the providers. The thirdparty recipe calls this when it is initialised.
+func ConfigureProviderHTTP(config tpmodels.ProviderHTTPConfig) {
+	if config.Client == nil {
+		config.Client = defaultProviderHTTPConfig.Client
+	}
+	if config.RetryBaseDelayMS == 0 {
+		config.RetryBaseDelayMS = defaultProviderHTTPConfig.RetryBaseDelayMS
+	}
+	if config.RetryMaxDelayMS == 0 {
+		config.RetryMaxDelayMS = defaultProviderHTTPConfig.RetryMaxDelayMS
+	}
+	providerHTTPConfigLock.Lock()
+	defer providerHTTPConfigLock.Unlock()
+	providerHTTPConfig = config
+}
+
+type providerResponse struct {
+	statusCode int
+	headers    http.Header
+	body       []byte
+}
+
+// sendProviderRequest sends the request with the provider's HTTP client, and reads the whole response.
+// newRequest is called for every attempt, so that the body of the request can be read again.
+func sendProviderRequest(config tpmodels.ProviderConfigForClientType, method string, url string, newRequest func(ctx context.Context) (*http.Request, error), userContext supertokens.UserContext) (*providerResponse, error) {
+	httpConfig := getProviderHTTPConfig()
+	client := httpConfig.Client
+	if config.HTTPClient != nil {
+		client = config.HTTPClient
+	}
+
+	maxAttempts := 1
+	if method == http.MethodGet {
+		maxAttempts += httpConfig.MaxGETRetries
+	}
+
+	var response *providerResponse
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if attempt > 1 {
+			if waitErr := waitBeforeProviderRetry(supertokens.GetContextFromUserContext(userContext), httpConfig, attempt-1); waitErr != nil {
+				break
+			}
+		}
+		response, err = sendProviderRequestOnce(client, httpConfig, config, method, url, attempt, newRequest, userContext)
+		if err == nil && response.statusCode < 500 {
+			return response, nil
+		}
+		if supertokens.GetContextFromUserContext(userContext).Err() != nil {
+			break
+		}
+	}
+	return response, err
+}
+
+// waitBeforeProviderRetry waits for a random time of up to RetryBaseDelayMS * 2^(retriesMade-1),
+// capped at RetryMaxDelayMS, so that the retries of many requests do not all hit the provider at once.
+func waitBeforeProviderRetry(ctx context.Context, httpConfig tpmodels.ProviderHTTPConfig, retriesMade int) error {
+	maxDelay := httpConfig.RetryBaseDelayMS << uint(retriesMade-1)
+	if maxDelay > httpConfig.RetryMaxDelayMS || maxDelay < httpConfig.RetryBaseDelayMS {
+		maxDelay = httpConfig.RetryMaxDelayMS
+	}
+	delay := time.Duration(rand.Int63n(int64(maxDelay)+1)) * time.Millisecond
+
+	timer := time.NewTimer(delay)
+	select {
+	case <-ctx.Done():
+		timer.Stop()
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func sendProviderRequestOnce(client *http.Client, httpConfig tpmodels.ProviderHTTPConfig, config tpmodels.ProviderConfigForClientType, method string, url string, attempt int, newRequest func(ctx context.Context) (*http.Request, error), userContext supertokens.UserContext) (*providerResponse, error) {
+	ctx := supertokens.GetContextFromUserContext(userContext)
+	if httpConfig.TimeoutSec > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(httpConfig.TimeoutSec)*time.Second)
+		defer cancel()
+	}
+
+	attributes := map[string]interface{}{
+		supertokens.AttributeThirdPartyID: config.ThirdPartyId,
+		supertokens.AttributeHTTPMethod:   method,
+	}
+	span, endSpan := supertokens.StartSpan(userContext, supertokens.SpanNameProviderRequest, attributes)
+	defer endSpan()
+	startTime := time.Now()
+
+	response, err := func() (*providerResponse, error) {
+		req, err := newRequest(ctx)
+		if err != nil {
+			return nil, err
+		}
+		attributes[supertokens.AttributeCoreHost] = req.URL.Host
+		attributes[supertokens.AttributeURLPath] = req.URL.Path
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return &providerResponse{statusCode: resp.StatusCode, headers: resp.Header}, err
+		}
+		return &providerResponse{statusCode: resp.StatusCode, headers: resp.Header, body: body}, nil
+	}()
+
+	duration := time.Since(startTime)
+	info := tpmodels.ProviderRequestInfo{
+		ThirdPartyId: config.ThirdPartyId,
+		Method:       method,
+		URL:          strings.SplitN(url, "?", 2)[0],
+		Duration:     duration,
+		Err:          err,
+		Attempt:      attempt,
+	}
+	if response != nil {
+		info.StatusCode = response.statusCode
+		span.SetAttributes(map[string]interface{}{supertokens.AttributeHTTPStatusCode: response.statusCode})
+		attributes[supertokens.AttributeHTTPStatusCode] = response.statusCode
+	}
+	if err != nil {
+		span.RecordError(err)
+	}
+	supertokens.RecordDuration(userContext, supertokens.MetricProviderRequestDuration, float64(duration.Microseconds())/1000, attributes)
+	if httpConfig.OnRequest != nil {
+		httpConfig.OnRequest(info, userContext)
+	}
+	return response, err
+}
@@ -0,0 +1,221 @@
+/* Copyright (c) 2021, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package providers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/supertokens/supertokens-golang/recipe/thirdparty/tperrors"
+	"github.com/supertokens/supertokens-golang/recipe/thirdparty/tpmodels"
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+const defaultIdTokenClockSkewSec = 60
+
+const expectedIdTokenNonceKey = "_thirdPartyExpectedIdTokenNonce"
+
+// SetExpectedIdTokenNonce makes the id token validation of the providers check that the nonce claim
+// of the id token is nonce, for the requests made with this userContext. The sign in up API does this
+// when OAuthState is enabled. Apps that send their own nonce to the provider can call this in an
+// override of the sign in up API.
+func SetExpectedIdTokenNonce(userContext supertokens.UserContext, nonce string) {
+	if userContext != nil {
+		(*userContext)[expectedIdTokenNonceKey] = nonce
+	}
+}
+
+func getExpectedIdTokenNonce(userContext supertokens.UserContext) string {
+	if userContext == nil {
+		return ""
+	}
+	nonce, _ := (*userContext)[expectedIdTokenNonceKey].(string)
+	return nonce
+}
+
+func isIdTokenValidationDisabled(config tpmodels.ProviderConfigForClientType) bool {
+	return config.IdTokenValidation != nil && config.IdTokenValidation.Disable
+}
+
+// validateIdTokenClaims does the checks of section 3.1.3.7 of the OpenID Connect Core spec, other
+// than the signature (which is checked while parsing the id token). The nonce is only checked if
+// one is expected, see SetExpectedIdTokenNonce.
+func validateIdTokenClaims(claims map[string]interface{}, config tpmodels.ProviderConfigForClientType, userContext supertokens.UserContext) error {
+	// The nonce is checked even if the validation is disabled, since it is only expected when the
+	// app sent it to the provider
+	if expectedNonce := getExpectedIdTokenNonce(userContext); expectedNonce != "" {
+		nonce, _ := claims["nonce"].(string)
+		if subtle.ConstantTimeCompare([]byte(nonce), []byte(expectedNonce)) != 1 {
+			return tperrors.IdTokenValidationError{Reason: tperrors.IdTokenInvalidNonce, Msg: "the nonce in the id token does not match the nonce sent to the provider"}
+		}
+	}
+
+	validationConfig := tpmodels.IdTokenValidationConfig{}
+	if config.IdTokenValidation != nil {
+		validationConfig = *config.IdTokenValidation
+	}
+	if validationConfig.Disable {
+		return nil
+	}
+
+	clockSkew := time.Duration(defaultIdTokenClockSkewSec) * time.Second
+	if validationConfig.ClockSkewSec != nil {
+		clockSkew = time.Duration(*validationConfig.ClockSkewSec) * time.Second
+	}
+
+	if !validationConfig.SkipIssuerCheck {
+		issuers := validationConfig.Issuers
+		if len(issuers) == 0 && config.OIDCDiscoveryEndpoint != "" {
+			oidcInfo, err := getOIDCDiscoveryInfo(config, config.OIDCDiscoveryEndpoint, userContext)
+			if err != nil {
+				return err
+			}
+			if issuer, ok := oidcInfo["issuer"].(string); ok && issuer != "" {
+				issuers = []string{issuer}
+			}
+		}
+		if len(issuers) > 0 {
+			issuer, _ := claims["iss"].(string)
+			if !containsString(issuers, issuer) {
+				return tperrors.IdTokenValidationError{Reason: tperrors.IdTokenInvalidIssuer, Msg: fmt.Sprintf("the issuer of the id token (%s) is not one of %s", issuer, strings.Join(issuers, ", "))}
+			}
+		}
+	}
+
+	allowedAudiences := append([]string{getActualClientIdFromDevelopmentClientId(config.ClientID)}, validationConfig.AdditionalAudiences...)
+	audiences := getIdTokenAudiences(claims["aud"])
+	if len(audiences) == 0 {
+		return tperrors.IdTokenValidationError{Reason: tperrors.IdTokenMissingClaim, Msg: "the id token does not have an aud claim"}
+	}
+	audienceAllowed := false
+	for _, audience := range audiences {
+		audienceAllowed = audienceAllowed || containsString(allowedAudiences, audience)
+	}
+	if !audienceAllowed {
+		return tperrors.IdTokenValidationError{Reason: tperrors.IdTokenInvalidAudience, Msg: "the id token was not issued for the client " + allowedAudiences[0]}
+	}
+
+	// Some providers (like Google) set azp to the id of another client of the app when there is a
+	// single audience, so azp is only checked for id tokens with multiple audiences.
+	if len(audiences) > 1 {
+		authorizedParty, _ := claims["azp"].(string)
+		if !containsString(allowedAudiences, authorizedParty) {
+			return tperrors.IdTokenValidationError{Reason: tperrors.IdTokenInvalidAuthorizedParty, Msg: "the id token has multiple audiences, and its azp claim is not the client " + allowedAudiences[0]}
+		}
+	}
+
+	now := time.Now()
+
+	expiresAt, ok := getIdTokenNumericDate(claims["exp"])
+	if !ok {
+		return tperrors.IdTokenValidationError{Reason: tperrors.IdTokenMissingClaim, Msg: "the id token does not have an exp claim"}
+	}
+	if now.After(expiresAt.Add(clockSkew)) {
+		return tperrors.IdTokenValidationError{Reason: tperrors.IdTokenExpired, Msg: "the id token has expired"}
+	}
+
+	issuedAt, ok := getIdTokenNumericDate(claims["iat"])
+	if !ok {
+		return tperrors.IdTokenValidationError{Reason: tperrors.IdTokenMissingClaim, Msg: "the id token does not have an iat claim"}
+	}
+	if issuedAt.After(now.Add(clockSkew)) {
+		return tperrors.IdTokenValidationError{Reason: tperrors.IdTokenIssuedInTheFuture, Msg: "the id token was issued in the future"}
+	}
+
+	if claims["nbf"] != nil {
+		notBefore, ok := getIdTokenNumericDate(claims["nbf"])
+		if !ok || notBefore.After(now.Add(clockSkew)) {
+			return tperrors.IdTokenValidationError{Reason: tperrors.IdTokenNotYetValid, Msg: "the id token is not valid yet"}
+		}
+	}
+
+	return nil
+}
+
+func getIdTokenAudiences(aud interface{}) []string {
+	switch value := aud.(type) {
+	case string:
+		if value == "" {
+			return nil
+		}
+		return []string{value}
+	case []string:
+		return value
+	case []interface{}:
+		audiences := []string{}
+		for _, audience := range value {
+			if audience, ok := audience.(string); ok {
+				audiences = append(audiences, audience)
+			}
+		}
+		return audiences
+	}
+	return nil
+}
+
+func getIdTokenNumericDate(value interface{}) (time.Time, bool) {
+	var seconds float64
+	switch value := value.(type) {
+	case float64:
+		seconds = value
+	case int64:
+		seconds = float64(value)
+	case json.Number:
+		var err error
+		seconds, err = value.Float64()
+		if err != nil {
+			return time.Time{}, false
+		}
+	default:
+		return time.Time{}, false
+	}
+	return time.Unix(0, int64(seconds*float64(time.Second))), true
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// withDefaultIdTokenIssuers is used by providers that know the issuers of their id tokens better than
+// their discovery document does. Issuers set in the config are kept.
+func withDefaultIdTokenIssuers(idTokenValidation *tpmodels.IdTokenValidationConfig, issuers []string) *tpmodels.IdTokenValidationConfig {
+	result := tpmodels.IdTokenValidationConfig{}
+	if idTokenValidation != nil {
+		result = *idTokenValidation
+	}
+	if len(result.Issuers) == 0 {
+		result.Issuers = issuers
+	}
+	return &result
+}
+
+// withoutIdTokenIssuerCheck is used by providers that check the issuer in ValidateIdTokenPayload
+func withoutIdTokenIssuerCheck(idTokenValidation *tpmodels.IdTokenValidationConfig) *tpmodels.IdTokenValidationConfig {
+	result := tpmodels.IdTokenValidationConfig{}
+	if idTokenValidation != nil {
+		result = *idTokenValidation
+	}
+	result.SkipIssuerCheck = true
+	return &result
+}
@@ -0,0 +1,78 @@
+/* Copyright (c) 2023, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package providers
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/supertokens/supertokens-golang/recipe/thirdparty/tpmodels"
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+func Keycloak(input tpmodels.ProviderInput) *tpmodels.TypeProvider {
+	if input.Config.Name == "" {
+		input.Config.Name = "Keycloak"
+	}
+
+	oOverride := input.Override
+
+	input.Override = func(originalImplementation *tpmodels.TypeProvider) *tpmodels.TypeProvider {
+		oGetConfig := originalImplementation.GetConfigForClientType
+		originalImplementation.GetConfigForClientType = func(clientType *string, userContext supertokens.UserContext) (tpmodels.ProviderConfigForClientType, error) {
+			config, err := oGetConfig(clientType, userContext)
+			if err != nil {
+				return tpmodels.ProviderConfigForClientType{}, err
+			}
+
+			if config.AdditionalConfig == nil || config.AdditionalConfig["keycloakBaseUrl"] == nil || config.AdditionalConfig["realm"] == nil {
+				if config.OIDCDiscoveryEndpoint == "" {
+					return tpmodels.ProviderConfigForClientType{}, errors.New("please provide the keycloakBaseUrl and realm in the AdditionalConfig of the Keycloak provider.")
+				}
+			} else {
+				oidcDomain, err := supertokens.NewNormalisedURLDomain(fmt.Sprint(config.AdditionalConfig["keycloakBaseUrl"]))
+				if err != nil {
+					return tpmodels.ProviderConfigForClientType{}, err
+				}
+				// Keycloak can be served from a path, like /auth in older versions
+				basePath, err := supertokens.NewNormalisedURLPath(fmt.Sprint(config.AdditionalConfig["keycloakBaseUrl"]))
+				if err != nil {
+					return tpmodels.ProviderConfigForClientType{}, err
+				}
+				// The realm is not normalised since realm names are case sensitive
+				realm := url.PathEscape(fmt.Sprint(config.AdditionalConfig["realm"]))
+				config.OIDCDiscoveryEndpoint = oidcDomain.GetAsStringDangerous() + basePath.GetAsStringDangerous() + "/realms/" + realm + "/.well-known/openid-configuration"
+			}
+
+			// The config could be coming from core where we didn't add the well-known previously
+			config.OIDCDiscoveryEndpoint = normaliseOIDCEndpointToIncludeWellKnown(config.OIDCDiscoveryEndpoint)
+
+			if len(config.Scope) == 0 {
+				config.Scope = []string{"openid", "email", "profile"}
+			}
+
+			return config, nil
+		}
+
+		if oOverride != nil {
+			originalImplementation = oOverride(originalImplementation)
+		}
+		return originalImplementation
+	}
+
+	return NewProvider(input)
+}
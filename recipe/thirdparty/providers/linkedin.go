@@ -49,7 +49,7 @@ func Linkedin(input tpmodels.ProviderInput) *tpmodels.TypeProvider {
 			}
 			rawUserInfoFromProvider := tpmodels.TypeRawUserInfoFromProvider{}
 			// https://learn.microsoft.com/en-us/linkedin/consumer/integrations/self-serve/sign-in-with-linkedin-v2?context=linkedin%2Fconsumer%2Fcontext#sample-api-response
-			userInfoFromAccessToken, err := doGetRequest("https://api.linkedin.com/v2/userinfo", nil, headers)
+			userInfoFromAccessToken, err := doGetRequest(originalImplementation.Config, "https://api.linkedin.com/v2/userinfo", nil, headers, userContext)
 			if err != nil {
 				return tpmodels.TypeUserInfo{}, err
 			}
@@ -0,0 +1,139 @@
+/* Copyright (c) 2023, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package providers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/supertokens/supertokens-golang/recipe/thirdparty/tperrors"
+	"github.com/supertokens/supertokens-golang/recipe/thirdparty/tpmodels"
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+// MicrosoftEntra signs in users of any Microsoft Entra ID tenant by default. Set the tenant in the
+// AdditionalConfig to "organizations", "consumers" or a tenant id to limit who can sign in, or list
+// the allowed tenant ids in allowedTenantIds.
+func MicrosoftEntra(input tpmodels.ProviderInput) *tpmodels.TypeProvider {
+	if input.Config.Name == "" {
+		input.Config.Name = "Microsoft"
+	}
+
+	// The oid claim identifies the user across all the applications of the tenant, unlike sub
+	// which is different for each application.
+	if input.Config.UserInfoMap.FromIdTokenPayload.UserId == "" {
+		input.Config.UserInfoMap.FromIdTokenPayload.UserId = "oid"
+	}
+	if input.Config.UserInfoMap.FromUserInfoAPI.UserId == "" {
+		input.Config.UserInfoMap.FromUserInfoAPI.UserId = "sub"
+	}
+
+	oOverride := input.Override
+
+	input.Override = func(originalImplementation *tpmodels.TypeProvider) *tpmodels.TypeProvider {
+		oGetConfig := originalImplementation.GetConfigForClientType
+		originalImplementation.GetConfigForClientType = func(clientType *string, userContext supertokens.UserContext) (tpmodels.ProviderConfigForClientType, error) {
+			config, err := oGetConfig(clientType, userContext)
+			if err != nil {
+				return tpmodels.ProviderConfigForClientType{}, err
+			}
+
+			if config.OIDCDiscoveryEndpoint == "" || config.AdditionalConfig["tenant"] != nil {
+				tenant := "common"
+				if config.AdditionalConfig["tenant"] != nil {
+					tenant = fmt.Sprint(config.AdditionalConfig["tenant"])
+				}
+				authorityUrl := "https://login.microsoftonline.com"
+				if config.AdditionalConfig["authorityUrl"] != nil {
+					authorityUrl = strings.TrimSuffix(fmt.Sprint(config.AdditionalConfig["authorityUrl"]), "/")
+				}
+				config.OIDCDiscoveryEndpoint = authorityUrl + "/" + tenant + "/v2.0/.well-known/openid-configuration"
+			}
+
+			// The config could be coming from core where we didn't add the well-known previously
+			config.OIDCDiscoveryEndpoint = normaliseOIDCEndpointToIncludeWellKnown(config.OIDCDiscoveryEndpoint)
+
+			if len(config.Scope) == 0 {
+				config.Scope = []string{"openid", "email", "profile"}
+			}
+
+			config.IdTokenValidation = withoutIdTokenIssuerCheck(config.IdTokenValidation)
+			oValidateIdTokenPayload := config.ValidateIdTokenPayload
+			config.ValidateIdTokenPayload = func(idTokenPayload map[string]interface{}, clientConfig tpmodels.ProviderConfigForClientType, userContext supertokens.UserContext) error {
+				err := validateMicrosoftEntraIdTokenPayload(idTokenPayload, clientConfig, userContext)
+				if err != nil {
+					return err
+				}
+				if oValidateIdTokenPayload != nil {
+					return oValidateIdTokenPayload(idTokenPayload, clientConfig, userContext)
+				}
+				return nil
+			}
+
+			return config, nil
+		}
+
+		if oOverride != nil {
+			originalImplementation = oOverride(originalImplementation)
+		}
+		return originalImplementation
+	}
+
+	return NewProvider(input)
+}
+
+// validateMicrosoftEntraIdTokenPayload checks the issuer of the id token. The multi-tenant endpoints
+// have an issuer like https://login.microsoftonline.com/{tenantid}/v2.0 in their discovery document,
+// which has to be filled in with the tenant of the user before comparing it.
+func validateMicrosoftEntraIdTokenPayload(idTokenPayload map[string]interface{}, config tpmodels.ProviderConfigForClientType, userContext supertokens.UserContext) error {
+	tid, ok := idTokenPayload["tid"].(string)
+	if !ok || tid == "" {
+		return tperrors.IdTokenValidationError{Reason: tperrors.IdTokenMissingClaim, Msg: "the id token from Microsoft does not have a tid claim"}
+	}
+
+	if allowedTenantIds, ok := config.AdditionalConfig["allowedTenantIds"]; ok && allowedTenantIds != nil {
+		allowed := false
+		switch tenantIds := allowedTenantIds.(type) {
+		case []string:
+			for _, tenantId := range tenantIds {
+				allowed = allowed || tenantId == tid
+			}
+		case []interface{}:
+			for _, tenantId := range tenantIds {
+				allowed = allowed || fmt.Sprint(tenantId) == tid
+			}
+		}
+		if !allowed {
+			return errors.New("the Microsoft tenant " + tid + " is not allowed to sign in")
+		}
+	}
+
+	oidcInfo, err := getOIDCDiscoveryInfo(config, config.OIDCDiscoveryEndpoint, userContext)
+	if err != nil {
+		return err
+	}
+	expectedIssuer, ok := oidcInfo["issuer"].(string)
+	if !ok {
+		return errors.New("the Microsoft discovery document does not have an issuer")
+	}
+	expectedIssuer = strings.Replace(expectedIssuer, "{tenantid}", tid, 1)
+
+	if idTokenPayload["iss"] != expectedIssuer {
+		return tperrors.IdTokenValidationError{Reason: tperrors.IdTokenInvalidIssuer, Msg: "the id token from Microsoft has an invalid issuer"}
+	}
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/supertokens/supertokens-golang/recipe/thirdparty/tperrors"
 	"github.com/supertokens/supertokens-golang/recipe/thirdparty/tpmodels"
 	"github.com/supertokens/supertokens-golang/supertokens"
 )
@@ -106,7 +107,7 @@ func oauth2_ExchangeAuthCodeForOAuthTokens(config tpmodels.ProviderConfigForClie
 	}
 	/* Transformation needed for dev keys END */
 
-	oAuthTokens, _, err := doPostRequest(tokenAPIURL, accessTokenAPIParams, nil)
+	oAuthTokens, _, err := doPostRequest(config, tokenAPIURL, accessTokenAPIParams, nil, userContext)
 	if err != nil {
 		return nil, err
 	}
@@ -114,6 +115,38 @@ func oauth2_ExchangeAuthCodeForOAuthTokens(config tpmodels.ProviderConfigForClie
 	return oAuthTokens, nil
 }
 
+func oauth2_RefreshOAuthTokens(config tpmodels.ProviderConfigForClientType, refreshToken string, userContext supertokens.UserContext) (tpmodels.TypeOAuthTokens, error) {
+	if config.TokenEndpoint == "" {
+		return nil, errors.New("ThirdParty provider's tokenEndpoint is not configured.")
+	}
+
+	refreshTokenAPIParams := map[string]interface{}{
+		"client_id":     config.ClientID,
+		"refresh_token": refreshToken,
+		"grant_type":    "refresh_token",
+	}
+	if config.ClientSecret != "" {
+		refreshTokenAPIParams["client_secret"] = config.ClientSecret
+	}
+
+	/* Transformation needed for dev keys BEGIN */
+	if isUsingDevelopmentClientId(config.ClientID) {
+		refreshTokenAPIParams["client_id"] = getActualClientIdFromDevelopmentClientId(config.ClientID)
+	}
+	/* Transformation needed for dev keys END */
+
+	oAuthTokens, statusCode, err := doPostRequest(config, config.TokenEndpoint, refreshTokenAPIParams, nil, userContext)
+	if err != nil {
+		// invalid_grant is returned with a 400, and invalid_client with a 401
+		if statusCode == 400 || statusCode == 401 {
+			return nil, tperrors.RefreshTokenRejectedError{Msg: err.Error()}
+		}
+		return nil, err
+	}
+
+	return oAuthTokens, nil
+}
+
 func oauth2_GetUserInfo(config tpmodels.ProviderConfigForClientType, oAuthTokens tpmodels.TypeOAuthTokens, userContext supertokens.UserContext) (tpmodels.TypeUserInfo, error) {
 	accessToken, accessTokenOk := oAuthTokens["access_token"].(string)
 	idToken, idTokenOk := oAuthTokens["id_token"].(string)
@@ -123,11 +156,16 @@ func oauth2_GetUserInfo(config tpmodels.ProviderConfigForClientType, oAuthTokens
 	if idTokenOk && config.JwksURI != "" {
 		claims := jwt.MapClaims{}
 		jwksURL := config.JwksURI
-		jwks, err := getJWKSFromURL(jwksURL)
+		jwksKeyfunc, err := getJWKSKeyfunc(config, jwksURL, userContext)
 		if err != nil {
 			return tpmodels.TypeUserInfo{}, err
 		}
-		token, err := jwt.ParseWithClaims(idToken, claims, jwks.Keyfunc)
+		parserOptions := []jwt.ParserOption{}
+		if !isIdTokenValidationDisabled(config) {
+			// The claims are checked by validateIdTokenClaims, which allows for clock skew
+			parserOptions = append(parserOptions, jwt.WithoutClaimsValidation())
+		}
+		token, err := jwt.ParseWithClaims(idToken, claims, jwksKeyfunc, parserOptions...)
 		if err != nil {
 			return tpmodels.TypeUserInfo{}, err
 		}
@@ -135,6 +173,10 @@ func oauth2_GetUserInfo(config tpmodels.ProviderConfigForClientType, oAuthTokens
 		if !token.Valid {
 			return tpmodels.TypeUserInfo{}, errors.New("invalid id_token supplied")
 		}
+		err = validateIdTokenClaims(claims, config, userContext)
+		if err != nil {
+			return tpmodels.TypeUserInfo{}, err
+		}
 		rawUserInfoFromProvider.FromIdTokenPayload = map[string]interface{}(claims)
 		if config.ValidateIdTokenPayload != nil {
 			err := config.ValidateIdTokenPayload(rawUserInfoFromProvider.FromIdTokenPayload, config, userContext)
@@ -173,12 +215,12 @@ func oauth2_GetUserInfo(config tpmodels.ProviderConfigForClientType, oAuthTokens
 			}
 		}
 
-		userInfoFromAccessToken, err := doGetRequest(config.UserInfoEndpoint, queryParams, headers)
-		rawUserInfoFromProvider.FromUserInfoAPI = userInfoFromAccessToken.(map[string]interface{})
-
+		userInfoFromAccessToken, err := doGetRequest(config, config.UserInfoEndpoint, queryParams, headers, userContext)
 		if err != nil {
 			return tpmodels.TypeUserInfo{}, err
 		}
+
+		rawUserInfoFromProvider.FromUserInfoAPI = userInfoFromAccessToken.(map[string]interface{})
 	}
 
 	userInfoResult, err := oauth2_getSupertokensUserInfoResultFromRawUserInfo(config, rawUserInfoFromProvider)
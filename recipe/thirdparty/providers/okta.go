@@ -32,7 +32,11 @@ func Okta(input tpmodels.ProviderInput) *tpmodels.TypeProvider {
 					return tpmodels.ProviderConfigForClientType{}, errors.New("please provide the oktaDomain in the AdditionalConfig of the Okta provider.")
 				}
 			} else {
-				oidcDomain, err := supertokens.NewNormalisedURLDomain(config.AdditionalConfig["oktaDomain"].(string))
+				oktaDomain, ok := config.AdditionalConfig["oktaDomain"].(string)
+				if !ok {
+					return tpmodels.ProviderConfigForClientType{}, errors.New("the oktaDomain in the AdditionalConfig of the Okta provider must be a string")
+				}
+				oidcDomain, err := supertokens.NewNormalisedURLDomain(oktaDomain)
 				if err != nil {
 					return tpmodels.ProviderConfigForClientType{}, err
 				}
@@ -87,7 +91,10 @@ func getOktaClientAssertion(config tpmodels.ProviderConfigForClientType) (string
 	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
 	token.Header["alg"] = "RS256"
 
-	privateKey := config.AdditionalConfig["privateKey"].(string)
+	privateKey, ok := config.AdditionalConfig["privateKey"].(string)
+	if !ok {
+		return "", errors.New("the privateKey in the AdditionalConfig of the Okta provider must be a string")
+	}
 
 	block, _ := pem.Decode([]byte(privateKey))
 	// Check if it's a private key
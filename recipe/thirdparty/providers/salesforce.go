@@ -0,0 +1,78 @@
+/* Copyright (c) 2023, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package providers
+
+import (
+	"fmt"
+
+	"github.com/supertokens/supertokens-golang/recipe/thirdparty/tpmodels"
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+// Salesforce uses login.salesforce.com by default. Set the salesforceDomain in the AdditionalConfig
+// to use a My Domain (for example mycompany.my.salesforce.com) or test.salesforce.com for sandboxes.
+func Salesforce(input tpmodels.ProviderInput) *tpmodels.TypeProvider {
+	if input.Config.Name == "" {
+		input.Config.Name = "Salesforce"
+	}
+
+	// The user info API of Salesforce returns the id of the user in user_id, while sub is the
+	// identity URL of the user
+	if input.Config.UserInfoMap.FromUserInfoAPI.UserId == "" {
+		input.Config.UserInfoMap.FromUserInfoAPI.UserId = "user_id"
+	}
+	if input.Config.UserInfoMap.FromIdTokenPayload.UserId == "" {
+		input.Config.UserInfoMap.FromIdTokenPayload.UserId = "user_id"
+	}
+
+	oOverride := input.Override
+
+	input.Override = func(originalImplementation *tpmodels.TypeProvider) *tpmodels.TypeProvider {
+		oGetConfig := originalImplementation.GetConfigForClientType
+		originalImplementation.GetConfigForClientType = func(clientType *string, userContext supertokens.UserContext) (tpmodels.ProviderConfigForClientType, error) {
+			config, err := oGetConfig(clientType, userContext)
+			if err != nil {
+				return tpmodels.ProviderConfigForClientType{}, err
+			}
+
+			if config.AdditionalConfig != nil && config.AdditionalConfig["salesforceDomain"] != nil {
+				oidcDomain, err := supertokens.NewNormalisedURLDomain(fmt.Sprint(config.AdditionalConfig["salesforceDomain"]))
+				if err != nil {
+					return tpmodels.ProviderConfigForClientType{}, err
+				}
+				config.OIDCDiscoveryEndpoint = oidcDomain.GetAsStringDangerous()
+			} else if config.OIDCDiscoveryEndpoint == "" {
+				config.OIDCDiscoveryEndpoint = "https://login.salesforce.com/.well-known/openid-configuration"
+			}
+
+			// The config could be coming from core where we didn't add the well-known previously
+			config.OIDCDiscoveryEndpoint = normaliseOIDCEndpointToIncludeWellKnown(config.OIDCDiscoveryEndpoint)
+
+			if len(config.Scope) == 0 {
+				config.Scope = []string{"openid", "email", "profile"}
+			}
+
+			return config, nil
+		}
+
+		if oOverride != nil {
+			originalImplementation = oOverride(originalImplementation)
+		}
+		return originalImplementation
+	}
+
+	return NewProvider(input)
+}
@@ -0,0 +1,782 @@
+/* Copyright (c) 2021, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package providers
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/beevik/etree"
+	dsig "github.com/russellhaering/goxmldsig"
+	"github.com/supertokens/supertokens-golang/recipe/thirdparty/tpmodels"
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+const (
+	samlProtocolNamespace   = "urn:oasis:names:tc:SAML:2.0:protocol"
+	samlHTTPRedirectBinding = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect"
+	samlHTTPPostBinding     = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST"
+	samlStatusSuccess       = "urn:oasis:names:tc:SAML:2.0:status:Success"
+	samlBearerConfirmation  = "urn:oasis:names:tc:SAML:2.0:cm:bearer"
+	samlNameIDFormatDefault = "urn:oasis:names:tc:SAML:1.1:nameid-format:unspecified"
+
+	samlDefaultClockSkew = 60 * time.Second
+)
+
+// Saml is a SAML 2.0 service provider. Unlike BoxySaml, it does not need a separate service to
+// translate SAML into OAuth.
+//
+// The IdP is configured using either the "idpMetadataXML" or the "idpMetadataURL" key in the
+// AdditionalConfig of the client. The SP entity id is the "spEntityId" key, and defaults to the
+// client id. The ACS URL of the SP is the redirectURIOnProviderDashboard.
+//
+// The AuthnRequest is sent using the HTTP-Redirect binding, and its ID is returned as the
+// PKCECodeVerifier of the authorisation redirect, so that the InResponseTo of the response can be
+// checked during sign in. The IdP posts the SAMLResponse to the ACS URL, and it must be passed to
+// the sign in up API as the "SAMLResponse" redirect URI query param. IdP initiated sign ins (that
+// have no AuthnRequest ID) are rejected unless "allowIdPInitiated" is set to true in the
+// AdditionalConfig.
+//
+// The InResponseTo check only ties the response to the browser that started the sign in when
+// OAuthState is set in the config of the thirdparty recipe, since the code verifier is then read from
+// the state cookie. Without it, the code verifier comes from the request to the sign in up API, so a
+// SAMLResponse issued for another AuthnRequest is accepted along with the ID of that request. An
+// assertion can only be used once until it expires, but the assertion ids that were used are kept
+// in memory, so they are not shared between the instances of the backend.
+//
+// The NameID and the attributes of the assertion are available in RawUserInfoFromProvider.FromUserInfoAPI
+// (keyed by both the attribute Name and FriendlyName), and are mapped using
+// UserInfoMap.FromUserInfoAPI. The user id defaults to "nameId" and the email to "email".
+func Saml(input tpmodels.ProviderInput) *tpmodels.TypeProvider {
+	if input.Config.Name == "" {
+		input.Config.Name = "SAML"
+	}
+
+	if input.Config.UserInfoMap.FromUserInfoAPI.UserId == "" {
+		input.Config.UserInfoMap.FromUserInfoAPI.UserId = "nameId"
+	}
+	if input.Config.UserInfoMap.FromUserInfoAPI.Email == "" {
+		input.Config.UserInfoMap.FromUserInfoAPI.Email = "email"
+	}
+
+	oOverride := input.Override
+
+	input.Override = func(originalImplementation *tpmodels.TypeProvider) *tpmodels.TypeProvider {
+		oGetConfig := originalImplementation.GetConfigForClientType
+		originalImplementation.GetConfigForClientType = func(clientType *string, userContext supertokens.UserContext) (tpmodels.ProviderConfigForClientType, error) {
+			config, err := oGetConfig(clientType, userContext)
+			if err != nil {
+				return tpmodels.ProviderConfigForClientType{}, err
+			}
+
+			metadata, err := getSAMLIdPMetadata(config, userContext)
+			if err != nil {
+				return tpmodels.ProviderConfigForClientType{}, err
+			}
+
+			if config.AuthorizationEndpoint == "" {
+				config.AuthorizationEndpoint = metadata.SSOURL
+			}
+
+			return config, nil
+		}
+
+		originalImplementation.GetAuthorisationRedirectURL = func(redirectURIOnProviderDashboard string, userContext supertokens.UserContext) (tpmodels.TypeAuthorisationRedirect, error) {
+			return saml_GetAuthorisationRedirectURL(originalImplementation.Config, redirectURIOnProviderDashboard)
+		}
+
+		originalImplementation.ExchangeAuthCodeForOAuthTokens = func(redirectURIInfo tpmodels.TypeRedirectURIInfo, userContext supertokens.UserContext) (tpmodels.TypeOAuthTokens, error) {
+			return saml_ValidateResponse(originalImplementation.Config, redirectURIInfo, userContext)
+		}
+
+		originalImplementation.GetUserInfo = func(oAuthTokens tpmodels.TypeOAuthTokens, userContext supertokens.UserContext) (tpmodels.TypeUserInfo, error) {
+			return saml_GetUserInfo(originalImplementation.Config, oAuthTokens)
+		}
+
+		if oOverride != nil {
+			originalImplementation = oOverride(originalImplementation)
+		}
+		return originalImplementation
+	}
+
+	return NewProvider(input)
+}
+
+// SAMLIdPMetadata is the information read from the metadata of an IdP
+type SAMLIdPMetadata struct {
+	EntityID string
+	// SSOURL is the location of the SingleSignOnService with the HTTP-Redirect binding
+	SSOURL       string
+	Certificates []*x509.Certificate
+}
+
+type samlEntityDescriptorXML struct {
+	XMLName           xml.Name
+	EntityID          string                    `xml:"entityID,attr"`
+	IDPSSODescriptors []samlIDPSSODescriptorXML `xml:"IDPSSODescriptor"`
+	EntityDescriptors []samlEntityDescriptorXML `xml:"EntityDescriptor"`
+}
+
+type samlIDPSSODescriptorXML struct {
+	KeyDescriptors []struct {
+		Use          string   `xml:"use,attr"`
+		Certificates []string `xml:"KeyInfo>X509Data>X509Certificate"`
+	} `xml:"KeyDescriptor"`
+	SingleSignOnServices []struct {
+		Binding  string `xml:"Binding,attr"`
+		Location string `xml:"Location,attr"`
+	} `xml:"SingleSignOnService"`
+}
+
+var samlWhiteSpace = regexp.MustCompile(`\s+`)
+
+// ParseSAMLIdPMetadata reads the entity id, the HTTP-Redirect SSO URL and the signing certificates
+// of the IdP from its metadata. If the metadata is an EntitiesDescriptor, the first entity with an
+// IDPSSODescriptor is used.
+func ParseSAMLIdPMetadata(metadataXML []byte) (*SAMLIdPMetadata, error) {
+	descriptor := samlEntityDescriptorXML{}
+	err := xml.Unmarshal(metadataXML, &descriptor)
+	if err != nil {
+		return nil, err
+	}
+
+	if descriptor.XMLName.Local == "EntitiesDescriptor" {
+		found := false
+		for _, entity := range descriptor.EntityDescriptors {
+			if len(entity.IDPSSODescriptors) > 0 {
+				descriptor = entity
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, errors.New("no IDPSSODescriptor found in the SAML IdP metadata")
+		}
+	}
+
+	if len(descriptor.IDPSSODescriptors) == 0 {
+		return nil, errors.New("no IDPSSODescriptor found in the SAML IdP metadata")
+	}
+	idpDescriptor := descriptor.IDPSSODescriptors[0]
+
+	result := &SAMLIdPMetadata{
+		EntityID: descriptor.EntityID,
+	}
+
+	for _, sso := range idpDescriptor.SingleSignOnServices {
+		if sso.Binding == samlHTTPRedirectBinding {
+			result.SSOURL = sso.Location
+			break
+		}
+	}
+	if result.SSOURL == "" {
+		return nil, errors.New("no SingleSignOnService with the HTTP-Redirect binding found in the SAML IdP metadata")
+	}
+
+	for _, keyDescriptor := range idpDescriptor.KeyDescriptors {
+		if keyDescriptor.Use != "" && keyDescriptor.Use != "signing" {
+			continue
+		}
+		for _, certData := range keyDescriptor.Certificates {
+			certBytes, err := base64.StdEncoding.DecodeString(samlWhiteSpace.ReplaceAllString(certData, ""))
+			if err != nil {
+				return nil, fmt.Errorf("could not decode the certificate in the SAML IdP metadata: %s", err.Error())
+			}
+			cert, err := x509.ParseCertificate(certBytes)
+			if err != nil {
+				return nil, fmt.Errorf("could not parse the certificate in the SAML IdP metadata: %s", err.Error())
+			}
+			result.Certificates = append(result.Certificates, cert)
+		}
+	}
+	if len(result.Certificates) == 0 {
+		return nil, errors.New("no signing certificate found in the SAML IdP metadata")
+	}
+
+	return result, nil
+}
+
+type samlSPMetadataXML struct {
+	XMLName         xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:metadata EntityDescriptor"`
+	EntityID        string   `xml:"entityID,attr"`
+	SPSSODescriptor struct {
+		AuthnRequestsSigned        bool   `xml:"AuthnRequestsSigned,attr"`
+		WantAssertionsSigned       bool   `xml:"WantAssertionsSigned,attr"`
+		ProtocolSupportEnumeration string `xml:"protocolSupportEnumeration,attr"`
+		NameIDFormat               string `xml:"NameIDFormat"`
+		AssertionConsumerService   struct {
+			Binding  string `xml:"Binding,attr"`
+			Location string `xml:"Location,attr"`
+			Index    int    `xml:"index,attr"`
+		} `xml:"AssertionConsumerService"`
+	} `xml:"SPSSODescriptor"`
+}
+
+// GenerateSAMLSPMetadata returns the metadata of the service provider, to be uploaded to the IdP.
+// acsURL must be the redirectURIOnProviderDashboard used for the provider.
+func GenerateSAMLSPMetadata(spEntityId string, acsURL string) (string, error) {
+	metadata := samlSPMetadataXML{
+		EntityID: spEntityId,
+	}
+	metadata.SPSSODescriptor.WantAssertionsSigned = true
+	metadata.SPSSODescriptor.ProtocolSupportEnumeration = samlProtocolNamespace
+	metadata.SPSSODescriptor.NameIDFormat = samlNameIDFormatDefault
+	metadata.SPSSODescriptor.AssertionConsumerService.Binding = samlHTTPPostBinding
+	metadata.SPSSODescriptor.AssertionConsumerService.Location = acsURL
+	metadata.SPSSODescriptor.AssertionConsumerService.Index = 0
+
+	result, err := xml.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return xml.Header + string(result), nil
+}
+
+// samlIdPMetadataFromXMLMap caches the parsed idpMetadataXML of the providers. The metadata fetched
+// from an idpMetadataURL is kept in the provider cache instead, so that it is fetched again when it
+// expires and a rotated IdP certificate is picked up without a restart.
+var samlIdPMetadataFromXMLMap = map[string]*SAMLIdPMetadata{}
+var samlIdPMetadataFromXMLMapLock = sync.Mutex{}
+
+func getSAMLIdPMetadata(config tpmodels.ProviderConfigForClientType, userContext supertokens.UserContext) (*SAMLIdPMetadata, error) {
+	metadataXML, _ := config.AdditionalConfig["idpMetadataXML"].(string)
+	metadataURL, _ := config.AdditionalConfig["idpMetadataURL"].(string)
+
+	if metadataXML == "" && metadataURL == "" {
+		return nil, errors.New("please provide either idpMetadataXML or idpMetadataURL in the AdditionalConfig of the SAML provider")
+	}
+
+	if metadataXML == "" {
+		c := getProviderCache(userContext)
+		entry, err := c.get(metadataURL, &c.metrics.SAMLMetadataHits, &c.metrics.SAMLMetadataMisses, nil, func() (*providerCacheEntry, http.Header, error) {
+			fetched, headers, err := fetchSAMLIdPMetadata(config, metadataURL, userContext)
+			if err != nil {
+				return nil, nil, err
+			}
+			metadata, err := ParseSAMLIdPMetadata(fetched)
+			if err != nil {
+				return nil, nil, err
+			}
+			return &providerCacheEntry{samlMetadata: metadata}, headers, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return entry.samlMetadata, nil
+	}
+
+	if getValidationProviderCache(userContext) != nil {
+		return ParseSAMLIdPMetadata([]byte(metadataXML))
+	}
+
+	samlIdPMetadataFromXMLMapLock.Lock()
+	defer samlIdPMetadataFromXMLMapLock.Unlock()
+
+	if metadata, ok := samlIdPMetadataFromXMLMap[metadataXML]; ok {
+		return metadata, nil
+	}
+	metadata, err := ParseSAMLIdPMetadata([]byte(metadataXML))
+	if err != nil {
+		return nil, err
+	}
+	samlIdPMetadataFromXMLMap[metadataXML] = metadata
+	return metadata, nil
+}
+
+func fetchSAMLIdPMetadata(config tpmodels.ProviderConfigForClientType, metadataURL string, userContext supertokens.UserContext) ([]byte, http.Header, error) {
+	supertokens.LogDebugMessage(fmt.Sprintf("GET request to %s for the SAML IdP metadata", metadataURL))
+
+	resp, err := sendProviderRequest(config, http.MethodGet, metadataURL, func(ctx context.Context) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, metadataURL, nil)
+	}, userContext)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.statusCode >= 300 {
+		return nil, nil, fmt.Errorf("GET request to %s resulted in %d status with body %s", metadataURL, resp.statusCode, string(resp.body))
+	}
+	return resp.body, resp.headers, nil
+}
+
+func getSAMLSPEntityId(config tpmodels.ProviderConfigForClientType) string {
+	if spEntityId, ok := config.AdditionalConfig["spEntityId"].(string); ok && spEntityId != "" {
+		return spEntityId
+	}
+	return config.ClientID
+}
+
+func getSAMLClockSkew(config tpmodels.ProviderConfigForClientType) time.Duration {
+	if skew, ok := config.AdditionalConfig["allowedClockSkewSec"].(float64); ok {
+		return time.Duration(skew * float64(time.Second))
+	}
+	if skew, ok := config.AdditionalConfig["allowedClockSkewSec"].(int); ok {
+		return time.Duration(skew) * time.Second
+	}
+	return samlDefaultClockSkew
+}
+
+type samlAuthnRequestXML struct {
+	XMLName                     xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:protocol AuthnRequest"`
+	ID                          string   `xml:"ID,attr"`
+	Version                     string   `xml:"Version,attr"`
+	IssueInstant                string   `xml:"IssueInstant,attr"`
+	Destination                 string   `xml:"Destination,attr"`
+	AssertionConsumerServiceURL string   `xml:"AssertionConsumerServiceURL,attr"`
+	ProtocolBinding             string   `xml:"ProtocolBinding,attr"`
+	Issuer                      struct {
+		XMLName xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:assertion Issuer"`
+		Value   string   `xml:",chardata"`
+	}
+	NameIDPolicy struct {
+		XMLName     xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:protocol NameIDPolicy"`
+		Format      string   `xml:"Format,attr"`
+		AllowCreate bool     `xml:"AllowCreate,attr"`
+	}
+}
+
+func saml_GetAuthorisationRedirectURL(config tpmodels.ProviderConfigForClientType, redirectURIOnProviderDashboard string) (tpmodels.TypeAuthorisationRedirect, error) {
+	if config.AuthorizationEndpoint == "" {
+		return tpmodels.TypeAuthorisationRedirect{}, errors.New("ThirdParty provider's authorizationEndpoint is not configured.")
+	}
+
+	idBytes := make([]byte, 20)
+	if _, err := rand.Read(idBytes); err != nil {
+		return tpmodels.TypeAuthorisationRedirect{}, err
+	}
+	requestId := "_" + hex.EncodeToString(idBytes)
+
+	request := samlAuthnRequestXML{
+		ID:                          requestId,
+		Version:                     "2.0",
+		IssueInstant:                time.Now().UTC().Format(time.RFC3339),
+		Destination:                 config.AuthorizationEndpoint,
+		AssertionConsumerServiceURL: redirectURIOnProviderDashboard,
+		ProtocolBinding:             samlHTTPPostBinding,
+	}
+	request.Issuer.Value = getSAMLSPEntityId(config)
+	request.NameIDPolicy.Format = samlNameIDFormatDefault
+	request.NameIDPolicy.AllowCreate = true
+
+	requestXML, err := xml.Marshal(request)
+	if err != nil {
+		return tpmodels.TypeAuthorisationRedirect{}, err
+	}
+
+	// The HTTP-Redirect binding uses raw DEFLATE followed by base64
+	var compressed bytes.Buffer
+	writer, err := flate.NewWriter(&compressed, flate.BestCompression)
+	if err != nil {
+		return tpmodels.TypeAuthorisationRedirect{}, err
+	}
+	if _, err := writer.Write(requestXML); err != nil {
+		return tpmodels.TypeAuthorisationRedirect{}, err
+	}
+	if err := writer.Close(); err != nil {
+		return tpmodels.TypeAuthorisationRedirect{}, err
+	}
+
+	urlObj, err := url.Parse(config.AuthorizationEndpoint)
+	if err != nil {
+		return tpmodels.TypeAuthorisationRedirect{}, err
+	}
+	queryParamsObj := urlObj.Query()
+	queryParamsObj.Set("SAMLRequest", base64.StdEncoding.EncodeToString(compressed.Bytes()))
+	for k, v := range config.AuthorizationEndpointQueryParams {
+		if v == nil {
+			queryParamsObj.Del(k)
+		} else {
+			queryParamsObj.Set(k, fmt.Sprint(v))
+		}
+	}
+	urlObj.RawQuery = queryParamsObj.Encode()
+
+	return tpmodels.TypeAuthorisationRedirect{
+		URLWithQueryParams: urlObj.String(),
+		PKCECodeVerifier:   &requestId,
+	}, nil
+}
+
+func saml_ValidateResponse(config tpmodels.ProviderConfigForClientType, redirectURIInfo tpmodels.TypeRedirectURIInfo, userContext supertokens.UserContext) (tpmodels.TypeOAuthTokens, error) {
+	samlResponse, ok := redirectURIInfo.RedirectURIQueryParams["SAMLResponse"].(string)
+	if !ok || samlResponse == "" {
+		return nil, supertokens.BadInputError{Msg: "SAMLResponse not found in redirect URI query params"}
+	}
+
+	metadata, err := getSAMLIdPMetadata(config, userContext)
+	if err != nil {
+		return nil, err
+	}
+
+	expectedRequestId := ""
+	if redirectURIInfo.PKCECodeVerifier != nil {
+		expectedRequestId = *redirectURIInfo.PKCECodeVerifier
+	} else if allowIdPInitiated, _ := config.AdditionalConfig["allowIdPInitiated"].(bool); !allowIdPInitiated {
+		return nil, errors.New("the AuthnRequest ID is missing. IdP initiated sign ins are not allowed")
+	}
+
+	return validateSAMLResponse(samlResponse, samlValidationInput{
+		metadata:          metadata,
+		spEntityId:        getSAMLSPEntityId(config),
+		acsURL:            redirectURIInfo.RedirectURIOnProviderDashboard,
+		expectedRequestId: expectedRequestId,
+		clockSkew:         getSAMLClockSkew(config),
+		now:               time.Now(),
+	})
+}
+
+type samlValidationInput struct {
+	metadata          *SAMLIdPMetadata
+	spEntityId        string
+	acsURL            string
+	expectedRequestId string // empty for IdP initiated sign ins
+	clockSkew         time.Duration
+	now               time.Time
+}
+
+func validateSAMLResponse(samlResponse string, input samlValidationInput) (tpmodels.TypeOAuthTokens, error) {
+	responseXML, err := base64.StdEncoding.DecodeString(samlWhiteSpace.ReplaceAllString(samlResponse, ""))
+	if err != nil {
+		return nil, errors.New("could not decode the SAMLResponse")
+	}
+
+	doc := etree.NewDocument()
+	if err := doc.ReadFromBytes(responseXML); err != nil {
+		return nil, errors.New("could not parse the SAMLResponse")
+	}
+	response := doc.Root()
+	if response == nil || response.Tag != "Response" || response.NamespaceURI() != samlProtocolNamespace {
+		return nil, errors.New("the SAMLResponse is not a SAML 2.0 Response")
+	}
+
+	statusCode := response.FindElement("./Status/StatusCode")
+	if statusCode == nil || statusCode.SelectAttrValue("Value", "") != samlStatusSuccess {
+		status := ""
+		if statusCode != nil {
+			status = statusCode.SelectAttrValue("Value", "")
+		}
+		return nil, fmt.Errorf("the SAML IdP returned an unsuccessful status: %s", status)
+	}
+
+	if len(response.SelectElements("EncryptedAssertion")) > 0 {
+		return nil, errors.New("encrypted SAML assertions are not supported")
+	}
+
+	validationContext := dsig.NewDefaultValidationContext(&dsig.MemoryX509CertificateStore{
+		Roots: input.metadata.Certificates,
+	})
+	validationContext.Clock = dsig.NewFakeClockAt(input.now)
+
+	// Only the elements returned by the validation context are used after this, so that content
+	// outside of the signed element (for example, a second unsigned assertion) is never read.
+	var assertion *etree.Element
+	if response.SelectElement("Signature") != nil {
+		validatedResponse, err := validationContext.Validate(response)
+		if err != nil {
+			return nil, fmt.Errorf("invalid signature on the SAMLResponse: %s", err.Error())
+		}
+		response = validatedResponse
+		assertions := response.SelectElements("Assertion")
+		if len(assertions) != 1 {
+			return nil, errors.New("the SAMLResponse must contain exactly one assertion")
+		}
+		assertion = assertions[0]
+	} else {
+		assertions := response.SelectElements("Assertion")
+		if len(assertions) != 1 {
+			return nil, errors.New("the SAMLResponse must contain exactly one assertion")
+		}
+		validatedAssertion, err := validationContext.Validate(samlWithAncestorNamespaces(assertions[0]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid signature on the SAML assertion: %s", err.Error())
+		}
+		assertion = validatedAssertion
+	}
+
+	if destination := response.SelectAttrValue("Destination", ""); destination != "" && destination != input.acsURL {
+		return nil, errors.New("the Destination of the SAMLResponse does not match the ACS URL")
+	}
+	if response.SelectAttrValue("InResponseTo", "") != input.expectedRequestId {
+		return nil, errors.New("the InResponseTo of the SAMLResponse does not match the AuthnRequest")
+	}
+
+	if input.metadata.EntityID != "" {
+		issuer := assertion.SelectElement("Issuer")
+		if issuer == nil || strings.TrimSpace(issuer.Text()) != input.metadata.EntityID {
+			return nil, errors.New("the Issuer of the SAML assertion does not match the IdP")
+		}
+	}
+
+	if err := validateSAMLConditions(assertion, input); err != nil {
+		return nil, err
+	}
+
+	subject := assertion.SelectElement("Subject")
+	if subject == nil {
+		return nil, errors.New("the SAML assertion has no Subject")
+	}
+	notOnOrAfter, err := validateSAMLSubjectConfirmation(subject, input)
+	if err != nil {
+		return nil, err
+	}
+
+	nameId := subject.SelectElement("NameID")
+	if nameId == nil || strings.TrimSpace(nameId.Text()) == "" {
+		return nil, errors.New("the SAML assertion has no NameID")
+	}
+
+	assertionId := assertion.SelectAttrValue("ID", "")
+	if assertionId == "" {
+		return nil, errors.New("the SAML assertion has no ID")
+	}
+	if !samlUsedAssertions.markAsUsed(input.metadata.EntityID+" "+assertionId, notOnOrAfter.Add(input.clockSkew), input.now) {
+		return nil, errors.New("the SAML assertion has already been used")
+	}
+
+	attributes := map[string]interface{}{}
+	for _, attributeStatement := range assertion.SelectElements("AttributeStatement") {
+		for _, attribute := range attributeStatement.SelectElements("Attribute") {
+			values := []interface{}{}
+			for _, value := range attribute.SelectElements("AttributeValue") {
+				values = append(values, strings.TrimSpace(value.Text()))
+			}
+			var attributeValue interface{} = values
+			if len(values) == 1 {
+				attributeValue = values[0]
+			}
+			for _, key := range []string{attribute.SelectAttrValue("Name", ""), attribute.SelectAttrValue("FriendlyName", "")} {
+				if key != "" {
+					attributes[key] = attributeValue
+				}
+			}
+		}
+	}
+
+	result := tpmodels.TypeOAuthTokens{
+		"nameId":     strings.TrimSpace(nameId.Text()),
+		"attributes": attributes,
+	}
+	if authnStatement := assertion.SelectElement("AuthnStatement"); authnStatement != nil {
+		if sessionIndex := authnStatement.SelectAttrValue("SessionIndex", ""); sessionIndex != "" {
+			result["sessionIndex"] = sessionIndex
+		}
+	}
+	return result, nil
+}
+
+func validateSAMLConditions(assertion *etree.Element, input samlValidationInput) error {
+	conditions := assertion.SelectElement("Conditions")
+	if conditions == nil {
+		return errors.New("the SAML assertion has no Conditions")
+	}
+
+	if err := validateSAMLTimeWindow(conditions, input); err != nil {
+		return err
+	}
+
+	audienceRestrictions := conditions.SelectElements("AudienceRestriction")
+	if len(audienceRestrictions) == 0 {
+		return errors.New("the SAML assertion has no AudienceRestriction")
+	}
+	// Each AudienceRestriction must include the SP
+	for _, audienceRestriction := range audienceRestrictions {
+		found := false
+		for _, audience := range audienceRestriction.SelectElements("Audience") {
+			if strings.TrimSpace(audience.Text()) == input.spEntityId {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errors.New("the SAML assertion is not meant for this service provider")
+		}
+	}
+	return nil
+}
+
+// validateSAMLSubjectConfirmation returns the NotOnOrAfter of the bearer SubjectConfirmation that
+// matches the request
+func validateSAMLSubjectConfirmation(subject *etree.Element, input samlValidationInput) (time.Time, error) {
+	for _, confirmation := range subject.SelectElements("SubjectConfirmation") {
+		if confirmation.SelectAttrValue("Method", "") != samlBearerConfirmation {
+			continue
+		}
+		data := confirmation.SelectElement("SubjectConfirmationData")
+		if data == nil {
+			continue
+		}
+		if recipient := data.SelectAttrValue("Recipient", ""); recipient != input.acsURL {
+			continue
+		}
+		if data.SelectAttrValue("InResponseTo", "") != input.expectedRequestId {
+			continue
+		}
+		notOnOrAfter, err := time.Parse(time.RFC3339, data.SelectAttrValue("NotOnOrAfter", ""))
+		if err != nil {
+			continue
+		}
+		if validateSAMLTimeWindow(data, input) != nil {
+			continue
+		}
+		return notOnOrAfter, nil
+	}
+	return time.Time{}, errors.New("the SAML assertion has no valid bearer SubjectConfirmation")
+}
+
+// samlUsedAssertionCache keeps the ids of the assertions that were used to sign in until they
+// expire, so that a SAMLResponse cannot be used more than once
+type samlUsedAssertionCache struct {
+	lock      sync.Mutex
+	expiresAt map[string]time.Time
+}
+
+var samlUsedAssertions = &samlUsedAssertionCache{expiresAt: map[string]time.Time{}}
+
+// markAsUsed returns false if the assertion has already been used
+func (c *samlUsedAssertionCache) markAsUsed(key string, expiresAt time.Time, now time.Time) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for usedKey, usedExpiresAt := range c.expiresAt {
+		if !now.Before(usedExpiresAt) {
+			delete(c.expiresAt, usedKey)
+		}
+	}
+	if _, ok := c.expiresAt[key]; ok {
+		return false
+	}
+	c.expiresAt[key] = expiresAt
+	return true
+}
+
+func validateSAMLTimeWindow(el *etree.Element, input samlValidationInput) error {
+	if notBefore := el.SelectAttrValue("NotBefore", ""); notBefore != "" {
+		t, err := time.Parse(time.RFC3339, notBefore)
+		if err != nil {
+			return fmt.Errorf("could not parse NotBefore in the SAML assertion: %s", err.Error())
+		}
+		if input.now.Add(input.clockSkew).Before(t) {
+			return errors.New("the SAML assertion is not valid yet")
+		}
+	}
+	if notOnOrAfter := el.SelectAttrValue("NotOnOrAfter", ""); notOnOrAfter != "" {
+		t, err := time.Parse(time.RFC3339, notOnOrAfter)
+		if err != nil {
+			return fmt.Errorf("could not parse NotOnOrAfter in the SAML assertion: %s", err.Error())
+		}
+		if !input.now.Add(-input.clockSkew).Before(t) {
+			return errors.New("the SAML assertion has expired")
+		}
+	}
+	return nil
+}
+
+// samlWithAncestorNamespaces returns a copy of el that also declares the namespaces declared on its
+// ancestors, since the validation context validates a detached copy of the element. Declarations
+// that are not used by the element are left out by the canonicalization, so the digest does not
+// change.
+func samlWithAncestorNamespaces(el *etree.Element) *etree.Element {
+	result := el.Copy()
+	declared := map[string]bool{}
+	for _, attr := range result.Attr {
+		if attr.Space == "xmlns" {
+			declared[attr.Key] = true
+		} else if attr.Space == "" && attr.Key == "xmlns" {
+			declared[""] = true
+		}
+	}
+	for parent := el.Parent(); parent != nil; parent = parent.Parent() {
+		for _, attr := range parent.Attr {
+			if attr.Space == "xmlns" && !declared[attr.Key] {
+				result.CreateAttr("xmlns:"+attr.Key, attr.Value)
+				declared[attr.Key] = true
+			} else if attr.Space == "" && attr.Key == "xmlns" && !declared[""] {
+				result.CreateAttr("xmlns", attr.Value)
+				declared[""] = true
+			}
+		}
+	}
+	return result
+}
+
+func saml_GetUserInfo(config tpmodels.ProviderConfigForClientType, oAuthTokens tpmodels.TypeOAuthTokens) (tpmodels.TypeUserInfo, error) {
+	rawUserInfo := map[string]interface{}{}
+	if attributes, ok := oAuthTokens["attributes"].(map[string]interface{}); ok {
+		for k, v := range attributes {
+			rawUserInfo[k] = v
+		}
+	}
+	rawUserInfo["nameId"] = oAuthTokens["nameId"]
+
+	rawUserInfoFromProvider := tpmodels.TypeRawUserInfoFromProvider{
+		FromUserInfoAPI: rawUserInfo,
+	}
+
+	result := tpmodels.TypeUserInfo{
+		RawUserInfoFromProvider: rawUserInfoFromProvider,
+	}
+
+	if userId, ok := getSAMLAttribute(rawUserInfo, config.UserInfoMap.FromUserInfoAPI.UserId); ok {
+		result.ThirdPartyUserId = fmt.Sprint(userId)
+	}
+	if result.ThirdPartyUserId == "" {
+		return tpmodels.TypeUserInfo{}, errors.New("third party user id is missing")
+	}
+
+	if email, ok := getSAMLAttribute(rawUserInfo, config.UserInfoMap.FromUserInfoAPI.Email); ok && fmt.Sprint(email) != "" {
+		result.Email = &tpmodels.EmailStruct{
+			ID:         fmt.Sprint(email),
+			IsVerified: false,
+		}
+		if emailVerified, ok := getSAMLAttribute(rawUserInfo, config.UserInfoMap.FromUserInfoAPI.EmailVerified); ok {
+			result.Email.IsVerified = strings.ToLower(fmt.Sprint(emailVerified)) == "true"
+		}
+	}
+
+	return result, nil
+}
+
+// getSAMLAttribute looks up an attribute by its exact name, since SAML attribute names are often
+// URIs that contain dots. Multi valued attributes resolve to their first value.
+func getSAMLAttribute(rawUserInfo map[string]interface{}, key string) (interface{}, bool) {
+	if key == "" {
+		return nil, false
+	}
+	value, ok := rawUserInfo[key]
+	if !ok {
+		return nil, false
+	}
+	if values, isList := value.([]interface{}); isList {
+		if len(values) == 0 {
+			return nil, false
+		}
+		return values[0], true
+	}
+	return value, true
+}
@@ -0,0 +1,86 @@
+/* Copyright (c) 2023, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package providers
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/supertokens/supertokens-golang/recipe/thirdparty/tpmodels"
+	"github.com/supertokens/supertokens-golang/supertokens"
+)
+
+// Slack uses Sign in with Slack (OpenID Connect). Set the teamId in the AdditionalConfig to only
+// allow the users of a workspace to sign in.
+func Slack(input tpmodels.ProviderInput) *tpmodels.TypeProvider {
+	if input.Config.Name == "" {
+		input.Config.Name = "Slack"
+	}
+
+	if input.Config.OIDCDiscoveryEndpoint == "" {
+		input.Config.OIDCDiscoveryEndpoint = "https://slack.com/.well-known/openid-configuration"
+	}
+
+	oOverride := input.Override
+
+	input.Override = func(originalImplementation *tpmodels.TypeProvider) *tpmodels.TypeProvider {
+		oGetConfig := originalImplementation.GetConfigForClientType
+		originalImplementation.GetConfigForClientType = func(clientType *string, userContext supertokens.UserContext) (tpmodels.ProviderConfigForClientType, error) {
+			config, err := oGetConfig(clientType, userContext)
+			if err != nil {
+				return tpmodels.ProviderConfigForClientType{}, err
+			}
+
+			// The config could be coming from core where we didn't add the well-known previously
+			config.OIDCDiscoveryEndpoint = normaliseOIDCEndpointToIncludeWellKnown(config.OIDCDiscoveryEndpoint)
+
+			if len(config.Scope) == 0 {
+				config.Scope = []string{"openid", "email", "profile"}
+			}
+
+			if config.AdditionalConfig != nil && config.AdditionalConfig["teamId"] != nil {
+				teamId := fmt.Sprint(config.AdditionalConfig["teamId"])
+
+				authorizationEndpointQueryParams := map[string]interface{}{}
+				for k, v := range config.AuthorizationEndpointQueryParams {
+					authorizationEndpointQueryParams[k] = v
+				}
+				authorizationEndpointQueryParams["team"] = teamId
+				config.AuthorizationEndpointQueryParams = authorizationEndpointQueryParams
+
+				oValidateIdTokenPayload := config.ValidateIdTokenPayload
+				config.ValidateIdTokenPayload = func(idTokenPayload map[string]interface{}, clientConfig tpmodels.ProviderConfigForClientType, userContext supertokens.UserContext) error {
+					if idTokenPayload["https://slack.com/team_id"] != teamId {
+						return errors.New("the user is not a member of the Slack workspace " + teamId)
+					}
+					if oValidateIdTokenPayload != nil {
+						return oValidateIdTokenPayload(idTokenPayload, clientConfig, userContext)
+					}
+					return nil
+				}
+			}
+
+			return config, nil
+		}
+
+		if oOverride != nil {
+			originalImplementation = oOverride(originalImplementation)
+		}
+		return originalImplementation
+	}
+
+	return NewProvider(input)
+}
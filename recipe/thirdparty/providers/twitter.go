@@ -87,9 +87,9 @@ func Twitter(input tpmodels.ProviderInput) *tpmodels.TypeProvider {
 			twitterOauthParams["redirect_uri"] = redirectUri
 			twitterOauthParams["code"] = redirectURIInfo.RedirectURIQueryParams["code"]
 
-			resp, _, err := doPostRequest(originalImplementation.Config.TokenEndpoint, twitterOauthParams, map[string]interface{}{
+			resp, _, err := doPostRequest(originalImplementation.Config, originalImplementation.Config.TokenEndpoint, twitterOauthParams, map[string]interface{}{
 				"Authorization": "Basic " + basicAuthToken,
-			})
+			}, userContext)
 
 			return resp, err
 		}
@@ -17,34 +17,36 @@ package providers
 
 import (
 	"bytes"
+	"context"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"net/http"
 	"strings"
-	"sync"
-	"time"
 
 	urllib "net/url"
 
-	"github.com/MicahParks/keyfunc/v2"
 	"github.com/derekstavis/go-qs"
 	"github.com/supertokens/supertokens-golang/recipe/thirdparty/tpmodels"
 	"github.com/supertokens/supertokens-golang/supertokens"
 )
 
 // Network utils
-func doGetRequest(url string, queryParams map[string]interface{}, headers map[string]string) (interface{}, error) {
+func doGetRequest(config tpmodels.ProviderConfigForClientType, url string, queryParams map[string]interface{}, headers map[string]string, userContext supertokens.UserContext) (interface{}, error) {
+	result, _, err := doGetRequestWithResponseHeaders(config, url, queryParams, headers, userContext)
+	return result, err
+}
+
+func doGetRequestWithResponseHeaders(config tpmodels.ProviderConfigForClientType, url string, queryParams map[string]interface{}, headers map[string]string, userContext supertokens.UserContext) (interface{}, http.Header, error) {
 	supertokens.LogDebugMessage(fmt.Sprintf("GET request to %s, with query params %v and headers %v", url, queryParams, headers))
 
 	if queryParams != nil {
 		urlObj, err := urllib.Parse(url)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		queryParamsObj := urlObj.Query()
@@ -58,108 +60,73 @@ func doGetRequest(url string, queryParams map[string]interface{}, headers map[st
 		url = urlObj.String()
 	}
 
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-	for key, value := range headers {
-		req.Header.Set(key, value)
-	}
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	body, err := ioutil.ReadAll(resp.Body)
+	resp, err := sendProviderRequest(config, http.MethodGet, url, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		return req, nil
+	}, userContext)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	supertokens.LogDebugMessage(fmt.Sprintf("Received response with status %d and body %s", resp.StatusCode, string(body)))
+	supertokens.LogDebugMessage(fmt.Sprintf("Received response with status %d and body %s", resp.statusCode, string(resp.body)))
 
 	var result interface{}
-	err = json.Unmarshal(body, &result)
+	err = json.Unmarshal(resp.body, &result)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	if resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("GET request to %s resulted in %d status with body %s", url, resp.StatusCode, string(body))
+	if resp.statusCode >= 300 {
+		return nil, nil, fmt.Errorf("GET request to %s resulted in %d status with body %s", url, resp.statusCode, string(resp.body))
 	}
-	return result, nil
+	return result, resp.headers, nil
 }
 
-func doPostRequest(url string, params map[string]interface{}, headers map[string]interface{}) (map[string]interface{}, int, error) {
+func doPostRequest(config tpmodels.ProviderConfigForClientType, url string, params map[string]interface{}, headers map[string]interface{}, userContext supertokens.UserContext) (map[string]interface{}, int, error) {
 	supertokens.LogDebugMessage(fmt.Sprintf("POST request to %s, with form fields %v and headers %v", url, params, headers))
 
 	postBody, err := qs.Marshal(params)
 	if err != nil {
 		return nil, -1, err
 	}
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer([]byte(postBody)))
-	if err != nil {
-		return nil, -1, err
-	}
-	for key, value := range headers {
-		req.Header.Set(key, value.(string))
-	}
-	req.Header.Set("content-type", "application/x-www-form-urlencoded")
-	req.Header.Set("accept", "application/json") // few providers like github don't send back json response by default
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, resp.StatusCode, err
-	}
-	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
+	resp, err := sendProviderRequest(config, http.MethodPost, url, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer([]byte(postBody)))
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range headers {
+			req.Header.Set(key, value.(string))
+		}
+		req.Header.Set("content-type", "application/x-www-form-urlencoded")
+		req.Header.Set("accept", "application/json") // few providers like github don't send back json response by default
+		return req, nil
+	}, userContext)
 	if err != nil {
-		return nil, resp.StatusCode, err
+		if resp != nil {
+			return nil, resp.statusCode, err
+		}
+		return nil, -1, err
 	}
 
-	supertokens.LogDebugMessage(fmt.Sprintf("Received response with status %d and body %s", resp.StatusCode, string(body)))
+	supertokens.LogDebugMessage(fmt.Sprintf("Received response with status %d and body %s", resp.statusCode, string(resp.body)))
 
 	var result map[string]interface{}
-	err = json.Unmarshal(body, &result)
+	err = json.Unmarshal(resp.body, &result)
 	if err != nil {
-		return nil, resp.StatusCode, err
+		return nil, resp.statusCode, err
 	}
 
-	if resp.StatusCode >= 300 {
-		return nil, resp.StatusCode, fmt.Errorf("POST request to %s resulted in %d status with body %s", url, resp.StatusCode, string(body))
+	if resp.statusCode >= 300 {
+		return nil, resp.statusCode, fmt.Errorf("POST request to %s resulted in %d status with body %s", url, resp.statusCode, string(resp.body))
 	}
 
-	return result, resp.StatusCode, nil
-}
-
-// JWKS utils
-var jwksKeys = map[string]*keyfunc.JWKS{}
-var jwksKeysLock = sync.Mutex{}
-
-func getJWKSFromURL(url string) (*keyfunc.JWKS, error) {
-	if jwks, ok := jwksKeys[url]; ok {
-		return jwks, nil
-	}
-
-	jwksKeysLock.Lock()
-	defer jwksKeysLock.Unlock()
-
-	// Check again to see if it was added while we were waiting for the lock
-	if jwks, ok := jwksKeys[url]; ok {
-		return jwks, nil
-	}
-
-	options := keyfunc.Options{
-		RefreshInterval: time.Hour,
-	}
-	jwks, err := keyfunc.Get(url, options)
-	if err != nil {
-		return nil, err
-	}
-	jwksKeys[url] = jwks
-	return jwks, nil
+	return result, resp.statusCode, nil
 }
 
 // User map utils
@@ -256,9 +223,9 @@ func generateCodeChallengeS256(length int) (codeChallenge string, codeVerifier s
 
 // OIDC utils
 
-func discoverOIDCEndpoints(config tpmodels.ProviderConfigForClientType) (tpmodels.ProviderConfigForClientType, error) {
+func discoverOIDCEndpoints(config tpmodels.ProviderConfigForClientType, userContext supertokens.UserContext) (tpmodels.ProviderConfigForClientType, error) {
 	if config.OIDCDiscoveryEndpoint != "" {
-		oidcInfo, err := getOIDCDiscoveryInfo(config.OIDCDiscoveryEndpoint)
+		oidcInfo, err := getOIDCDiscoveryInfo(config, config.OIDCDiscoveryEndpoint, userContext)
 		if err != nil {
 			return tpmodels.ProviderConfigForClientType{}, err
 		}
@@ -288,39 +255,6 @@ func discoverOIDCEndpoints(config tpmodels.ProviderConfigForClientType) (tpmodel
 	return config, nil
 }
 
-var oidcInfoMap = map[string]map[string]interface{}{}
-var oidcInfoMapLock = sync.Mutex{}
-
-func getOIDCDiscoveryInfo(issuer string) (map[string]interface{}, error) {
-	if oidcInfo, ok := oidcInfoMap[issuer]; ok {
-		return oidcInfo, nil
-	}
-
-	normalizedDomain, err := supertokens.NewNormalisedURLDomain(issuer)
-	if err != nil {
-		return nil, err
-	}
-	normalizedPath, err := supertokens.NewNormalisedURLPath(issuer)
-	if err != nil {
-		return nil, err
-	}
-
-	oidcInfoMapLock.Lock()
-	defer oidcInfoMapLock.Unlock()
-
-	// Check again to see if it was added while we were waiting for the lock
-	if oidcInfo, ok := oidcInfoMap[issuer]; ok {
-		return oidcInfo, nil
-	}
-
-	oidcInfo, err := doGetRequest(normalizedDomain.GetAsStringDangerous()+normalizedPath.GetAsStringDangerous(), nil, nil)
-	if err != nil {
-		return nil, err
-	}
-	oidcInfoMap[issuer] = oidcInfo.(map[string]interface{})
-	return oidcInfoMap[issuer], nil
-}
-
 func normaliseOIDCEndpointToIncludeWellKnown(url string) string {
 	// we call this only for built-in providers that use OIDC. We no longer generically add well-known in the custom provider
 	if strings.HasSuffix(url, "/.well-known/openid-configuration") {
@@ -24,6 +24,7 @@ import (
 	"github.com/supertokens/supertokens-golang/recipe/emailverification/evmodels"
 	"github.com/supertokens/supertokens-golang/recipe/multitenancy"
 	"github.com/supertokens/supertokens-golang/recipe/thirdparty/api"
+	"github.com/supertokens/supertokens-golang/recipe/thirdparty/providers"
 	"github.com/supertokens/supertokens-golang/recipe/thirdparty/tperrors"
 	"github.com/supertokens/supertokens-golang/recipe/thirdparty/tpmodels"
 	"github.com/supertokens/supertokens-golang/supertokens"
@@ -56,9 +57,20 @@ func MakeRecipe(recipeId string, appInfo supertokens.NormalisedAppinfo, config *
 	}
 	r.Config = verifiedConfig
 	r.APIImpl = verifiedConfig.Override.APIs(api.MakeAPIImplementation())
-	r.RecipeImpl = verifiedConfig.Override.Functions(MakeRecipeImplementation(*querierInstance, verifiedConfig.SignInAndUpFeature.Providers))
+	r.RecipeImpl = verifiedConfig.Override.Functions(MakeRecipeImplementation(*querierInstance, verifiedConfig.SignInAndUpFeature.Providers, verifiedConfig.ProviderTokenStorage, verifiedConfig.LinkedIdentities))
 	r.Providers = verifiedConfig.SignInAndUpFeature.Providers
 
+	providers.ConfigureProviderHTTP(verifiedConfig.ProviderHTTP)
+	providers.ConfigureProviderCache(verifiedConfig.ProviderCache)
+	if len(verifiedConfig.ProviderCache.PreWarmOIDCDiscoveryEndpoints) > 0 {
+		go func() {
+			err := providers.PreWarmProviderCache(verifiedConfig.ProviderCache.PreWarmOIDCDiscoveryEndpoints)
+			if err != nil {
+				supertokens.LogDebugMessage("Failed to pre-warm the provider cache: " + err.Error())
+			}
+		}()
+	}
+
 	supertokens.AddPostInitCallback(func() error {
 		evRecipe := emailverification.GetRecipeInstance()
 		if evRecipe != nil {
@@ -114,6 +126,22 @@ func (r *Recipe) getAPIsHandled() ([]supertokens.APIHandled, error) {
 	if err != nil {
 		return nil, err
 	}
+	linkedIdentitiesAPI, err := supertokens.NewNormalisedURLPath(LinkedIdentitiesAPI)
+	if err != nil {
+		return nil, err
+	}
+	linkIdentityAPI, err := supertokens.NewNormalisedURLPath(LinkIdentityAPI)
+	if err != nil {
+		return nil, err
+	}
+	unlinkIdentityAPI, err := supertokens.NewNormalisedURLPath(UnlinkIdentityAPI)
+	if err != nil {
+		return nil, err
+	}
+	appleServerNotificationAPI, err := supertokens.NewNormalisedURLPath(AppleServerNotificationAPI)
+	if err != nil {
+		return nil, err
+	}
 	return append([]supertokens.APIHandled{{
 		Method:                 http.MethodPost,
 		PathWithoutAPIBasePath: signInUpAPI,
@@ -129,6 +157,26 @@ func (r *Recipe) getAPIsHandled() ([]supertokens.APIHandled, error) {
 		PathWithoutAPIBasePath: appleRedirectHandlerAPI,
 		ID:                     AppleRedirectHandlerAPI,
 		Disabled:               r.APIImpl.AppleRedirectHandlerPOST == nil,
+	}, {
+		Method:                 http.MethodGet,
+		PathWithoutAPIBasePath: linkedIdentitiesAPI,
+		ID:                     LinkedIdentitiesAPI,
+		Disabled:               r.APIImpl.LinkedIdentitiesGET == nil || r.Config.LinkedIdentities == nil,
+	}, {
+		Method:                 http.MethodPost,
+		PathWithoutAPIBasePath: linkIdentityAPI,
+		ID:                     LinkIdentityAPI,
+		Disabled:               r.APIImpl.LinkIdentityPOST == nil || r.Config.LinkedIdentities == nil,
+	}, {
+		Method:                 http.MethodPost,
+		PathWithoutAPIBasePath: unlinkIdentityAPI,
+		ID:                     UnlinkIdentityAPI,
+		Disabled:               r.APIImpl.UnlinkIdentityPOST == nil || r.Config.LinkedIdentities == nil,
+	}, {
+		Method:                 http.MethodPost,
+		PathWithoutAPIBasePath: appleServerNotificationAPI,
+		ID:                     AppleServerNotificationAPI,
+		Disabled:               r.APIImpl.AppleServerNotificationPOST == nil,
 	}}), nil
 }
 
@@ -149,6 +197,14 @@ func (r *Recipe) handleAPIRequest(id string, tenantId string, req *http.Request,
 		return api.AuthorisationUrlAPI(r.APIImpl, tenantId, options, userContext)
 	} else if id == AppleRedirectHandlerAPI {
 		return api.AppleRedirectHandler(r.APIImpl, options, userContext)
+	} else if id == LinkedIdentitiesAPI {
+		return api.LinkedIdentitiesAPI(r.APIImpl, options, userContext)
+	} else if id == LinkIdentityAPI {
+		return api.LinkIdentityAPI(r.APIImpl, tenantId, options, userContext)
+	} else if id == UnlinkIdentityAPI {
+		return api.UnlinkIdentityAPI(r.APIImpl, options, userContext)
+	} else if id == AppleServerNotificationAPI {
+		return api.AppleServerNotificationAPI(r.APIImpl, tenantId, options, userContext)
 	}
 	return errors.New("should never come here")
 }
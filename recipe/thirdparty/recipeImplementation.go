@@ -25,7 +25,7 @@ import (
 	"github.com/supertokens/supertokens-golang/supertokens"
 )
 
-func MakeRecipeImplementation(querier supertokens.Querier, providers []tpmodels.ProviderInput) tpmodels.RecipeInterface {
+func MakeRecipeImplementation(querier supertokens.Querier, providers []tpmodels.ProviderInput, providerTokenStorage *tpmodels.ProviderTokenStorageConfig, linkedIdentities *tpmodels.LinkedIdentitiesConfig) tpmodels.RecipeInterface {
 
 	getProvider := func(thirdPartyID string, clientType *string, tenantId string, userContext supertokens.UserContext) (*tpmodels.TypeProvider, error) {
 
@@ -47,7 +47,66 @@ func MakeRecipeImplementation(querier supertokens.Querier, providers []tpmodels.
 		return provider, nil
 	}
 
+	getUserByID := func(userID string, userContext supertokens.UserContext) (*tpmodels.User, error) {
+		response, err := querier.SendGetRequest("/recipe/user", map[string]string{
+			"userId": userID,
+		}, userContext)
+		if err != nil {
+			return nil, err
+		}
+		if response["status"] == "OK" {
+			user, err := parseUser(response["user"])
+			if err != nil {
+				return nil, err
+			}
+			return user, nil
+		}
+		return nil, nil
+	}
+
+	getUserByThirdPartyInfo := func(thirdPartyID, thirdPartyUserID string, tenantId string, userContext supertokens.UserContext) (*tpmodels.User, error) {
+		response, err := querier.SendGetRequest(tenantId+"/recipe/user", map[string]string{
+			"thirdPartyId":     thirdPartyID,
+			"thirdPartyUserId": thirdPartyUserID,
+		}, userContext)
+		if err != nil {
+			return nil, err
+		}
+		if response["status"] == "OK" {
+			user, err := parseUser(response["user"])
+			if err != nil {
+				return nil, err
+			}
+			return user, nil
+		}
+		return nil, nil
+	}
+
 	signInUp := func(thirdPartyID, thirdPartyUserID string, email string, oAuthTokens tpmodels.TypeOAuthTokens, rawUserInfoFromProvider tpmodels.TypeRawUserInfoFromProvider, tenantId string, userContext supertokens.UserContext) (tpmodels.SignInUpResponse, error) {
+		if linkedIdentities != nil {
+			user, err := getUserOfLinkedIdentity(*linkedIdentities, thirdPartyID, thirdPartyUserID, tenantId, getUserByID, getUserByThirdPartyInfo, userContext)
+			if err != nil {
+				return tpmodels.SignInUpResponse{}, err
+			}
+			if user != nil {
+				supertokens.LogInfo("thirdparty: user signed in with a linked identity", supertokens.RecipeLogFields(RECIPE_ID, tenantId, userContext, supertokens.LogFieldUserID, user.ID, "thirdPartyId", thirdPartyID)...)
+				supertokens.AddToCounter(userContext, supertokens.SignInUpMetricName(false), 1, supertokens.RecipeMetricAttributes(RECIPE_ID, tenantId))
+				return tpmodels.SignInUpResponse{
+					OK: &struct {
+						CreatedNewUser          bool
+						User                    tpmodels.User
+						OAuthTokens             tpmodels.TypeOAuthTokens
+						RawUserInfoFromProvider tpmodels.TypeRawUserInfoFromProvider
+					}{
+						CreatedNewUser:          false,
+						User:                    *user,
+						OAuthTokens:             oAuthTokens,
+						RawUserInfoFromProvider: rawUserInfoFromProvider,
+					},
+				}, nil
+			}
+		}
+
 		response, err := querier.SendPostRequest(tenantId+"/recipe/signinup", map[string]interface{}{
 			"thirdPartyId":     thirdPartyID,
 			"thirdPartyUserId": thirdPartyUserID,
@@ -60,6 +119,8 @@ func MakeRecipeImplementation(querier supertokens.Querier, providers []tpmodels.
 		if err != nil {
 			return tpmodels.SignInUpResponse{}, err
 		}
+		supertokens.LogInfo("thirdparty: user signed in or up", supertokens.RecipeLogFields(RECIPE_ID, tenantId, userContext, supertokens.LogFieldUserID, user.ID, "thirdPartyId", thirdPartyID, "createdNewUser", response["createdNewUser"])...)
+		supertokens.AddToCounter(userContext, supertokens.SignInUpMetricName(response["createdNewUser"].(bool)), 1, supertokens.RecipeMetricAttributes(RECIPE_ID, tenantId))
 		return tpmodels.SignInUpResponse{
 			OK: &struct {
 				CreatedNewUser          bool
@@ -99,41 +160,6 @@ func MakeRecipeImplementation(querier supertokens.Querier, providers []tpmodels.
 		}, nil
 	}
 
-	getUserByID := func(userID string, userContext supertokens.UserContext) (*tpmodels.User, error) {
-		response, err := querier.SendGetRequest("/recipe/user", map[string]string{
-			"userId": userID,
-		}, userContext)
-		if err != nil {
-			return nil, err
-		}
-		if response["status"] == "OK" {
-			user, err := parseUser(response["user"])
-			if err != nil {
-				return nil, err
-			}
-			return user, nil
-		}
-		return nil, nil
-	}
-
-	getUserByThirdPartyInfo := func(thirdPartyID, thirdPartyUserID string, tenantId string, userContext supertokens.UserContext) (*tpmodels.User, error) {
-		response, err := querier.SendGetRequest(tenantId+"/recipe/user", map[string]string{
-			"thirdPartyId":     thirdPartyID,
-			"thirdPartyUserId": thirdPartyUserID,
-		}, userContext)
-		if err != nil {
-			return nil, err
-		}
-		if response["status"] == "OK" {
-			user, err := parseUser(response["user"])
-			if err != nil {
-				return nil, err
-			}
-			return user, nil
-		}
-		return nil, nil
-	}
-
 	getUsersByEmail := func(email string, tenantId string, userContext supertokens.UserContext) ([]tpmodels.User, error) {
 		response, err := querier.SendGetRequest(tenantId+"/recipe/users/by-email", map[string]string{
 			"email": email,
@@ -148,12 +174,65 @@ func MakeRecipeImplementation(querier supertokens.Querier, providers []tpmodels.
 		return users, nil
 	}
 
+	storeProviderTokensFunc := func(userID string, thirdPartyID string, thirdPartyUserID string, oAuthTokens tpmodels.TypeOAuthTokens, clientType *string, tenantId string, userContext supertokens.UserContext) error {
+		if providerTokenStorage == nil {
+			return nil
+		}
+		return storeProviderTokensAfterSignIn(*providerTokenStorage, userID, thirdPartyID, thirdPartyUserID, oAuthTokens, clientType, tenantId, userContext)
+	}
+
+	getValidProviderAccessTokenFunc := func(userID string, thirdPartyID string, thirdPartyUserID string, userContext supertokens.UserContext) (tpmodels.GetValidProviderAccessTokenResponse, error) {
+		if providerTokenStorage == nil {
+			return tpmodels.GetValidProviderAccessTokenResponse{}, errors.New("please configure ProviderTokenStorage in the thirdparty recipe to use GetValidProviderAccessToken")
+		}
+		return getValidProviderAccessToken(*providerTokenStorage, userID, thirdPartyID, thirdPartyUserID, getProvider, userContext)
+	}
+
+	deleteProviderTokensFunc := func(userID string, thirdPartyID string, thirdPartyUserID string, userContext supertokens.UserContext) error {
+		if providerTokenStorage == nil {
+			return nil
+		}
+		lock := getProviderTokenLock(userID, thirdPartyID, thirdPartyUserID)
+		lock.Lock()
+		defer lock.Unlock()
+		return providerTokenStorage.Store.Delete(userID, thirdPartyID, thirdPartyUserID, userContext)
+	}
+
+	linkIdentityFunc := func(userID string, thirdPartyID string, thirdPartyUserID string, email string, tenantId string, userContext supertokens.UserContext) (tpmodels.LinkIdentityResponse, error) {
+		if linkedIdentities == nil {
+			return tpmodels.LinkIdentityResponse{}, errors.New("please configure LinkedIdentities in the thirdparty recipe to link identities")
+		}
+		return linkIdentity(*linkedIdentities, userID, thirdPartyID, thirdPartyUserID, email, tenantId, getUserByID, getUserByThirdPartyInfo, userContext)
+	}
+
+	unlinkIdentityFunc := func(userID string, thirdPartyID string, thirdPartyUserID string, userContext supertokens.UserContext) (tpmodels.UnlinkIdentityResponse, error) {
+		if linkedIdentities == nil {
+			return tpmodels.UnlinkIdentityResponse{}, errors.New("please configure LinkedIdentities in the thirdparty recipe to unlink identities")
+		}
+		response, err := unlinkIdentity(*linkedIdentities, userID, thirdPartyID, thirdPartyUserID, getUserByID, userContext)
+		if err != nil || response.OK == nil {
+			return response, err
+		}
+		// The tokens of the identity cannot be used for the user anymore
+		return response, deleteProviderTokensFunc(userID, thirdPartyID, thirdPartyUserID, userContext)
+	}
+
+	listLinkedIdentitiesFunc := func(userID string, userContext supertokens.UserContext) (tpmodels.ListLinkedIdentitiesResponse, error) {
+		return listLinkedIdentities(linkedIdentities, userID, getUserByID, userContext)
+	}
+
 	return tpmodels.RecipeInterface{
-		GetUserByID:                &getUserByID,
-		GetUsersByEmail:            &getUsersByEmail,
-		GetUserByThirdPartyInfo:    &getUserByThirdPartyInfo,
-		GetProvider:                &getProvider,
-		SignInUp:                   &signInUp,
-		ManuallyCreateOrUpdateUser: &manuallyCreateOrUpdateUser,
+		GetUserByID:                 &getUserByID,
+		GetUsersByEmail:             &getUsersByEmail,
+		GetUserByThirdPartyInfo:     &getUserByThirdPartyInfo,
+		GetProvider:                 &getProvider,
+		SignInUp:                    &signInUp,
+		ManuallyCreateOrUpdateUser:  &manuallyCreateOrUpdateUser,
+		StoreProviderTokens:         &storeProviderTokensFunc,
+		GetValidProviderAccessToken: &getValidProviderAccessTokenFunc,
+		DeleteProviderTokens:        &deleteProviderTokensFunc,
+		LinkIdentity:                &linkIdentityFunc,
+		UnlinkIdentity:              &unlinkIdentityFunc,
+		ListLinkedIdentities:        &listLinkedIdentitiesFunc,
 	}
 }
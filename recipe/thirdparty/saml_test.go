@@ -0,0 +1,373 @@
+/*
+ * Copyright (c) 2021, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package thirdparty
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/beevik/etree"
+	dsig "github.com/russellhaering/goxmldsig"
+	"github.com/stretchr/testify/assert"
+	"github.com/supertokens/supertokens-golang/recipe/thirdparty/providers"
+	"github.com/supertokens/supertokens-golang/recipe/thirdparty/tpmodels"
+)
+
+const (
+	testSAMLIdPEntityId = "https://idp.example.com/metadata"
+	testSAMLSPEntityId  = "https://sp.example.com"
+	testSAMLACSURL      = "https://sp.example.com/auth/callback/saml"
+)
+
+func makeTestSAMLIdPMetadata(t *testing.T, keyStore dsig.X509KeyStore) string {
+	_, cert, err := keyStore.GetKeyPair()
+	assert.NoError(t, err)
+	return `<md:EntityDescriptor xmlns:md="urn:oasis:names:tc:SAML:2.0:metadata" entityID="` + testSAMLIdPEntityId + `">
+  <md:IDPSSODescriptor protocolSupportEnumeration="urn:oasis:names:tc:SAML:2.0:protocol">
+    <md:KeyDescriptor use="signing">
+      <ds:KeyInfo xmlns:ds="http://www.w3.org/2000/09/xmldsig#">
+        <ds:X509Data><ds:X509Certificate>` + base64.StdEncoding.EncodeToString(cert) + `</ds:X509Certificate></ds:X509Data>
+      </ds:KeyInfo>
+    </md:KeyDescriptor>
+    <md:SingleSignOnService Binding="urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST" Location="https://idp.example.com/sso/post"/>
+    <md:SingleSignOnService Binding="urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect" Location="https://idp.example.com/sso/redirect"/>
+  </md:IDPSSODescriptor>
+</md:EntityDescriptor>`
+}
+
+type testSAMLResponseInput struct {
+	// assertionId defaults to a new id for each response
+	assertionId  string
+	requestId    string
+	audience     string
+	notOnOrAfter time.Time
+	nameId       string
+	// tamper modifies the assertion after it is signed
+	tamper func(assertion *etree.Element)
+}
+
+func makeTestSAMLResponse(t *testing.T, keyStore dsig.X509KeyStore, input testSAMLResponseInput) string {
+	now := time.Now().UTC()
+	assertionId := input.assertionId
+	if assertionId == "" {
+		assertionId = "_assertion-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	}
+	assertion := etree.NewElement("saml:Assertion")
+	assertion.CreateAttr("xmlns:saml", "urn:oasis:names:tc:SAML:2.0:assertion")
+	assertion.CreateAttr("ID", assertionId)
+	assertion.CreateAttr("Version", "2.0")
+	assertion.CreateAttr("IssueInstant", now.Format(time.RFC3339))
+	assertion.CreateElement("saml:Issuer").SetText(testSAMLIdPEntityId)
+
+	subject := assertion.CreateElement("saml:Subject")
+	subject.CreateElement("saml:NameID").SetText(input.nameId)
+	confirmation := subject.CreateElement("saml:SubjectConfirmation")
+	confirmation.CreateAttr("Method", "urn:oasis:names:tc:SAML:2.0:cm:bearer")
+	confirmationData := confirmation.CreateElement("saml:SubjectConfirmationData")
+	confirmationData.CreateAttr("Recipient", testSAMLACSURL)
+	confirmationData.CreateAttr("InResponseTo", input.requestId)
+	confirmationData.CreateAttr("NotOnOrAfter", input.notOnOrAfter.Format(time.RFC3339))
+
+	conditions := assertion.CreateElement("saml:Conditions")
+	conditions.CreateAttr("NotBefore", now.Add(-time.Minute).Format(time.RFC3339))
+	conditions.CreateAttr("NotOnOrAfter", input.notOnOrAfter.Format(time.RFC3339))
+	conditions.CreateElement("saml:AudienceRestriction").CreateElement("saml:Audience").SetText(input.audience)
+
+	assertion.CreateElement("saml:AuthnStatement").CreateAttr("SessionIndex", "session-index-1")
+
+	attribute := assertion.CreateElement("saml:AttributeStatement").CreateElement("saml:Attribute")
+	attribute.CreateAttr("Name", "http://schemas.xmlsoap.org/ws/2005/05/identity/claims/emailaddress")
+	attribute.CreateAttr("FriendlyName", "email")
+	attribute.CreateElement("saml:AttributeValue").SetText("johndoe@example.com")
+
+	// SAML requires exclusive canonicalization, so that the signature does not depend on the
+	// namespaces declared by the Response
+	signingContext := dsig.NewDefaultSigningContext(keyStore)
+	signingContext.Canonicalizer = dsig.MakeC14N10ExclusiveCanonicalizerWithPrefixList("")
+	signedAssertion, err := signingContext.SignEnveloped(assertion)
+	assert.NoError(t, err)
+	if input.tamper != nil {
+		input.tamper(signedAssertion)
+	}
+
+	response := etree.NewElement("samlp:Response")
+	response.CreateAttr("xmlns:samlp", "urn:oasis:names:tc:SAML:2.0:protocol")
+	response.CreateAttr("ID", "_response-1")
+	response.CreateAttr("Version", "2.0")
+	response.CreateAttr("Destination", testSAMLACSURL)
+	response.CreateAttr("InResponseTo", input.requestId)
+	response.CreateElement("samlp:Status").CreateElement("samlp:StatusCode").CreateAttr("Value", "urn:oasis:names:tc:SAML:2.0:status:Success")
+	response.AddChild(signedAssertion)
+
+	doc := etree.NewDocument()
+	doc.SetRoot(response)
+	responseXML, err := doc.WriteToBytes()
+	assert.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(responseXML)
+}
+
+func makeTestSAMLProvider(t *testing.T, keyStore dsig.X509KeyStore) *tpmodels.TypeProvider {
+	provider := providers.Saml(tpmodels.ProviderInput{
+		Config: tpmodels.ProviderConfig{
+			ThirdPartyId: "saml",
+			Clients: []tpmodels.ProviderClientConfig{
+				{
+					ClientID: testSAMLSPEntityId,
+					AdditionalConfig: map[string]interface{}{
+						"idpMetadataXML": makeTestSAMLIdPMetadata(t, keyStore),
+					},
+				},
+			},
+		},
+	})
+	config, err := provider.GetConfigForClientType(nil, &map[string]interface{}{})
+	assert.NoError(t, err)
+	provider.Config = config
+	return provider
+}
+
+func TestSAMLProviderSignIn(t *testing.T) {
+	keyStore := dsig.RandomKeyStoreForTest()
+	provider := makeTestSAMLProvider(t, keyStore)
+	userContext := &map[string]interface{}{}
+
+	redirect, err := provider.GetAuthorisationRedirectURL(testSAMLACSURL, userContext)
+	assert.NoError(t, err)
+	assert.NotNil(t, redirect.PKCECodeVerifier)
+
+	redirectURL, err := url.Parse(redirect.URLWithQueryParams)
+	assert.NoError(t, err)
+	assert.Equal(t, "idp.example.com", redirectURL.Host)
+	assert.Equal(t, "/sso/redirect", redirectURL.Path)
+	compressedRequest, err := base64.StdEncoding.DecodeString(redirectURL.Query().Get("SAMLRequest"))
+	assert.NoError(t, err)
+	authnRequest, err := ioutil.ReadAll(flate.NewReader(bytes.NewReader(compressedRequest)))
+	assert.NoError(t, err)
+	assert.Contains(t, string(authnRequest), `ID="`+*redirect.PKCECodeVerifier+`"`)
+	assert.Contains(t, string(authnRequest), `AssertionConsumerServiceURL="`+testSAMLACSURL+`"`)
+	assert.Contains(t, string(authnRequest), testSAMLSPEntityId+"</Issuer>")
+
+	samlResponse := makeTestSAMLResponse(t, keyStore, testSAMLResponseInput{
+		requestId:    *redirect.PKCECodeVerifier,
+		audience:     testSAMLSPEntityId,
+		notOnOrAfter: time.Now().Add(5 * time.Minute),
+		nameId:       "user-1",
+	})
+	oAuthTokens, err := provider.ExchangeAuthCodeForOAuthTokens(tpmodels.TypeRedirectURIInfo{
+		RedirectURIOnProviderDashboard: testSAMLACSURL,
+		RedirectURIQueryParams:         map[string]interface{}{"SAMLResponse": samlResponse},
+		PKCECodeVerifier:               redirect.PKCECodeVerifier,
+	}, userContext)
+	assert.NoError(t, err)
+	assert.Equal(t, "session-index-1", oAuthTokens["sessionIndex"])
+
+	userInfo, err := provider.GetUserInfo(oAuthTokens, userContext)
+	assert.NoError(t, err)
+	assert.Equal(t, "user-1", userInfo.ThirdPartyUserId)
+	assert.Equal(t, "johndoe@example.com", userInfo.Email.ID)
+	assert.False(t, userInfo.Email.IsVerified)
+	assert.Equal(t, "johndoe@example.com", userInfo.RawUserInfoFromProvider.FromUserInfoAPI["http://schemas.xmlsoap.org/ws/2005/05/identity/claims/emailaddress"])
+}
+
+func TestSAMLProviderRejectsInvalidResponses(t *testing.T) {
+	keyStore := dsig.RandomKeyStoreForTest()
+	provider := makeTestSAMLProvider(t, keyStore)
+	userContext := &map[string]interface{}{}
+	requestId := "_request-1"
+
+	validInput := testSAMLResponseInput{
+		requestId:    requestId,
+		audience:     testSAMLSPEntityId,
+		notOnOrAfter: time.Now().Add(5 * time.Minute),
+		nameId:       "user-1",
+	}
+
+	testCases := map[string]struct {
+		keyStore         dsig.X509KeyStore
+		modify           func(input *testSAMLResponseInput)
+		pkceCodeVerifier *string
+		errorContains    string
+	}{
+		"wrong InResponseTo": {
+			modify:        func(input *testSAMLResponseInput) { input.requestId = "_other-request" },
+			errorContains: "InResponseTo",
+		},
+		"wrong audience": {
+			modify:        func(input *testSAMLResponseInput) { input.audience = "https://other-sp.example.com" },
+			errorContains: "not meant for this service provider",
+		},
+		"expired assertion": {
+			modify:        func(input *testSAMLResponseInput) { input.notOnOrAfter = time.Now().Add(-5 * time.Minute) },
+			errorContains: "expired",
+		},
+		"tampered assertion": {
+			modify: func(input *testSAMLResponseInput) {
+				input.tamper = func(assertion *etree.Element) {
+					assertion.FindElement("./Subject/NameID").SetText("admin")
+				}
+			},
+			errorContains: "invalid signature",
+		},
+		"signed by another key": {
+			keyStore:      dsig.RandomKeyStoreForTest(),
+			errorContains: "invalid signature",
+		},
+		"IdP initiated": {
+			errorContains: "IdP initiated",
+		},
+	}
+
+	for name, testCase := range testCases {
+		input := validInput
+		if testCase.modify != nil {
+			testCase.modify(&input)
+		}
+		signingKeyStore := keyStore
+		if testCase.keyStore != nil {
+			signingKeyStore = testCase.keyStore
+		}
+		pkceCodeVerifier := &requestId
+		if name == "IdP initiated" {
+			pkceCodeVerifier = nil
+		}
+
+		_, err := provider.ExchangeAuthCodeForOAuthTokens(tpmodels.TypeRedirectURIInfo{
+			RedirectURIOnProviderDashboard: testSAMLACSURL,
+			RedirectURIQueryParams:         map[string]interface{}{"SAMLResponse": makeTestSAMLResponse(t, signingKeyStore, input)},
+			PKCECodeVerifier:               pkceCodeVerifier,
+		}, userContext)
+		if assert.Error(t, err, name) {
+			assert.Contains(t, err.Error(), testCase.errorContains, name)
+		}
+	}
+}
+
+func TestSAMLProviderRejectsReplayedAssertions(t *testing.T) {
+	keyStore := dsig.RandomKeyStoreForTest()
+	provider := makeTestSAMLProvider(t, keyStore)
+	userContext := &map[string]interface{}{}
+	requestId := "_request-1"
+
+	redirectURIInfo := tpmodels.TypeRedirectURIInfo{
+		RedirectURIOnProviderDashboard: testSAMLACSURL,
+		RedirectURIQueryParams: map[string]interface{}{"SAMLResponse": makeTestSAMLResponse(t, keyStore, testSAMLResponseInput{
+			requestId:    requestId,
+			audience:     testSAMLSPEntityId,
+			notOnOrAfter: time.Now().Add(5 * time.Minute),
+			nameId:       "user-1",
+		})},
+		PKCECodeVerifier: &requestId,
+	}
+
+	_, err := provider.ExchangeAuthCodeForOAuthTokens(redirectURIInfo, userContext)
+	assert.NoError(t, err)
+
+	_, err = provider.ExchangeAuthCodeForOAuthTokens(redirectURIInfo, userContext)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "already been used")
+	}
+}
+
+func TestSAMLProviderRefreshesIdPMetadataFromURL(t *testing.T) {
+	configureProviderCacheForTest(t, tpmodels.ProviderCacheConfig{MinTTLSec: 1})
+	defer configureProviderCacheForTest(t, tpmodels.ProviderCacheConfig{})
+
+	oldKeyStore := dsig.RandomKeyStoreForTest()
+	newKeyStore := dsig.RandomKeyStoreForTest()
+
+	lock := sync.Mutex{}
+	metadataKeyStore := oldKeyStore
+	metadataCalls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		metadataCalls++
+		rw.Header().Set("Cache-Control", "max-age=1")
+		rw.Write([]byte(makeTestSAMLIdPMetadata(t, metadataKeyStore)))
+	}))
+	defer server.Close()
+
+	provider := providers.Saml(tpmodels.ProviderInput{
+		Config: tpmodels.ProviderConfig{
+			ThirdPartyId: "saml",
+			Clients: []tpmodels.ProviderClientConfig{
+				{
+					ClientID: testSAMLSPEntityId,
+					AdditionalConfig: map[string]interface{}{
+						"idpMetadataURL": server.URL + "/metadata",
+					},
+				},
+			},
+		},
+	})
+	userContext := &map[string]interface{}{}
+	config, err := provider.GetConfigForClientType(nil, userContext)
+	assert.NoError(t, err)
+	provider.Config = config
+
+	signIn := func(keyStore dsig.X509KeyStore) error {
+		requestId := "_request-1"
+		_, err := provider.ExchangeAuthCodeForOAuthTokens(tpmodels.TypeRedirectURIInfo{
+			RedirectURIOnProviderDashboard: testSAMLACSURL,
+			RedirectURIQueryParams: map[string]interface{}{"SAMLResponse": makeTestSAMLResponse(t, keyStore, testSAMLResponseInput{
+				requestId:    requestId,
+				audience:     testSAMLSPEntityId,
+				notOnOrAfter: time.Now().Add(5 * time.Minute),
+				nameId:       "user-1",
+			})},
+			PKCECodeVerifier: &requestId,
+		}, userContext)
+		return err
+	}
+
+	assert.NoError(t, signIn(oldKeyStore))
+
+	// The IdP rotates its certificate
+	lock.Lock()
+	metadataKeyStore = newKeyStore
+	lock.Unlock()
+
+	// The cached metadata is used until it expires
+	assert.Error(t, signIn(newKeyStore))
+	time.Sleep(1100 * time.Millisecond)
+	assert.NoError(t, signIn(newKeyStore))
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, 2, metadataCalls)
+}
+
+func TestGenerateSAMLSPMetadata(t *testing.T) {
+	metadata, err := providers.GenerateSAMLSPMetadata(testSAMLSPEntityId, testSAMLACSURL)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(metadata, "<?xml"))
+	assert.Contains(t, metadata, `entityID="`+testSAMLSPEntityId+`"`)
+	assert.Contains(t, metadata, `Location="`+testSAMLACSURL+`"`)
+
+	_, err = providers.ParseSAMLIdPMetadata([]byte(metadata))
+	assert.EqualError(t, err, "no IDPSSODescriptor found in the SAML IdP metadata")
+}
@@ -7,3 +7,35 @@ type ClientTypeNotFoundError struct {
 func (e ClientTypeNotFoundError) Error() string {
 	return e.Msg
 }
+
+// RefreshTokenRejectedError is returned when the provider does not accept a refresh token, for
+// example because the user has revoked access to the app. The user has to sign in again.
+type RefreshTokenRejectedError struct {
+	Msg string
+}
+
+func (e RefreshTokenRejectedError) Error() string {
+	return e.Msg
+}
+
+const (
+	IdTokenInvalidIssuer          = "INVALID_ISSUER"
+	IdTokenInvalidAudience        = "INVALID_AUDIENCE"
+	IdTokenInvalidAuthorizedParty = "INVALID_AUTHORIZED_PARTY"
+	IdTokenExpired                = "EXPIRED"
+	IdTokenIssuedInTheFuture      = "ISSUED_IN_THE_FUTURE"
+	IdTokenNotYetValid            = "NOT_YET_VALID"
+	IdTokenMissingClaim           = "MISSING_CLAIM"
+	IdTokenInvalidNonce           = "INVALID_NONCE"
+)
+
+// IdTokenValidationError is returned when the claims of an id token are not valid. Reason is one of
+// the IdToken* constants above.
+type IdTokenValidationError struct {
+	Reason string
+	Msg    string
+}
+
+func (e IdTokenValidationError) Error() string {
+	return e.Msg
+}
@@ -27,6 +27,12 @@ type APIInterface struct {
 	AuthorisationUrlGET      *func(provider *TypeProvider, redirectURIOnProviderDashboard string, tenantId string, options APIOptions, userContext supertokens.UserContext) (AuthorisationUrlGETResponse, error)
 	SignInUpPOST             *func(provider *TypeProvider, input TypeSignInUpInput, tenantId string, options APIOptions, userContext supertokens.UserContext) (SignInUpPOSTResponse, error)
 	AppleRedirectHandlerPOST *func(formPostInfoFromProvider map[string]interface{}, options APIOptions, userContext supertokens.UserContext) error
+	LinkedIdentitiesGET      *func(sessionContainer sessmodels.SessionContainer, options APIOptions, userContext supertokens.UserContext) (LinkedIdentitiesGETResponse, error)
+	LinkIdentityPOST         *func(provider *TypeProvider, input TypeSignInUpInput, sessionContainer sessmodels.SessionContainer, tenantId string, options APIOptions, userContext supertokens.UserContext) (LinkIdentityPOSTResponse, error)
+	UnlinkIdentityPOST       *func(thirdPartyID string, thirdPartyUserID string, sessionContainer sessmodels.SessionContainer, options APIOptions, userContext supertokens.UserContext) (UnlinkIdentityPOSTResponse, error)
+	// AppleServerNotificationPOST is called with the notifications from Apple after their signature
+	// has been checked.
+	AppleServerNotificationPOST *func(notification AppleServerNotification, tenantId string, options APIOptions, userContext supertokens.UserContext) (AppleServerNotificationPOSTResponse, error)
 }
 
 type AuthorisationUrlGETResponse struct {
@@ -38,6 +44,12 @@ type TypeSignInUpInput struct {
 	// Either of the below
 	RedirectURIInfo *TypeRedirectURIInfo `json:"redirectURIInfo"`
 	OAuthTokens     *TypeOAuthTokens     `json:"oAuthTokens"`
+
+	// OAuthState is the state saved by the authorisation URL API, if the state is managed by the
+	// SDK. It has already been checked against the redirect URI query params.
+	OAuthState *OAuthStateData `json:"-"`
+	// ClientType is the clientType the provider was created for.
+	ClientType *string `json:"-"`
 }
 
 type SignInUpPOSTResponse struct {
@@ -52,6 +64,33 @@ type SignInUpPOSTResponse struct {
 	GeneralError                *supertokens.GeneralErrorResponse
 }
 
+type LinkedIdentitiesGETResponse struct {
+	OK           *struct{ Identities []LinkedIdentity }
+	GeneralError *supertokens.GeneralErrorResponse
+}
+
+type LinkIdentityPOSTResponse struct {
+	OK *struct {
+		Identity                LinkedIdentity
+		OAuthTokens             TypeOAuthTokens
+		RawUserInfoFromProvider TypeRawUserInfoFromProvider
+	}
+	IdentityAlreadyLinkedError *struct{}
+	GeneralError               *supertokens.GeneralErrorResponse
+}
+
+type UnlinkIdentityPOSTResponse struct {
+	OK                    *struct{}
+	IdentityNotFoundError *struct{}
+	PrimaryIdentityError  *struct{}
+	GeneralError          *supertokens.GeneralErrorResponse
+}
+
+type AppleServerNotificationPOSTResponse struct {
+	OK           *struct{}
+	GeneralError *supertokens.GeneralErrorResponse
+}
+
 type APIOptions struct {
 	RecipeImplementation RecipeInterface
 	Config               TypeNormalisedInput
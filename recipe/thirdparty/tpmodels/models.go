@@ -16,6 +16,9 @@
 package tpmodels
 
 import (
+	"net/http"
+	"time"
+
 	"github.com/supertokens/supertokens-golang/supertokens"
 )
 
@@ -88,12 +91,240 @@ type TypeNormalisedInputSignInAndUp struct {
 
 type TypeInput struct {
 	SignInAndUpFeature TypeInputSignInAndUp
-	Override           *OverrideStruct
+	// OAuthState makes the SDK manage the OAuth state, nonce and PKCE code verifier instead of
+	// leaving them to the frontend. This is meant for apps that do not use the frontend SDK.
+	OAuthState *OAuthStateConfig
+	// ProviderCache configures how long OIDC discovery documents, provider JWKS and SAML IdP metadata
+	// are cached.
+	ProviderCache *ProviderCacheConfig
+	// ProviderTokenStorage makes the SDK keep the OAuth tokens of the provider after sign in, so that
+	// they can be used later with GetValidProviderAccessToken.
+	ProviderTokenStorage *ProviderTokenStorageConfig
+	// ProviderHTTP configures the HTTP requests made to the providers.
+	ProviderHTTP *ProviderHTTPConfig
+	// LinkedIdentities lets signed in users link more thirdparty identities to their account, which
+	// they can then sign in with. Only the users of this recipe can link identities, since signing in
+	// with one must give a thirdparty user.
+	LinkedIdentities *LinkedIdentitiesConfig
+	// AppleServerNotifications are called for the server-to-server notifications that Apple sends
+	// to the /notifications/apple API.
+	AppleServerNotifications *AppleServerNotificationsConfig
+	Override                 *OverrideStruct
 }
 
 type TypeNormalisedInput struct {
-	SignInAndUpFeature TypeNormalisedInputSignInAndUp
-	Override           OverrideStruct
+	SignInAndUpFeature       TypeNormalisedInputSignInAndUp
+	OAuthState               *OAuthStateConfig
+	ProviderCache            ProviderCacheConfig
+	ProviderTokenStorage     *ProviderTokenStorageConfig
+	ProviderHTTP             ProviderHTTPConfig
+	LinkedIdentities         *LinkedIdentitiesConfig
+	AppleServerNotifications AppleServerNotificationsConfig
+	Override                 OverrideStruct
+}
+
+// ProviderHTTPConfig configures the HTTP requests made to the providers and their discovery, JWKS and
+// metadata endpoints. A provider can use its own client by setting HTTPClient in its ProviderConfig.
+type ProviderHTTPConfig struct {
+	// Client is used for the requests to the providers. Set its Transport to use a proxy or custom
+	// TLS settings. Defaults to a client that uses the proxy from the environment, like
+	// http.DefaultClient.
+	Client *http.Client
+	// TimeoutSec is the timeout of each request. Defaults to 30. Requests are also cancelled when
+	// the context in the userContext is done.
+	TimeoutSec uint64
+	// MaxGETRetries is the number of times a GET request is retried if it fails because of a network
+	// error or a 5xx response. Defaults to 0.
+	MaxGETRetries int
+	// RetryBaseDelayMS and RetryMaxDelayMS bound the exponential backoff (with full jitter) between
+	// GET retries. They default to 50 and 1000.
+	RetryBaseDelayMS uint64
+	RetryMaxDelayMS  uint64
+	// OnRequest is called after every request to a provider, for example to record its latency.
+	OnRequest func(info ProviderRequestInfo, userContext supertokens.UserContext)
+}
+
+type ProviderRequestInfo struct {
+	ThirdPartyId string
+	Method       string
+	// URL does not include the query params, since they can contain secrets.
+	URL string
+	// StatusCode is 0 if no response was received.
+	StatusCode int
+	Duration   time.Duration
+	Err        error
+	// Attempt starts at 1 and is incremented for each retry.
+	Attempt int
+}
+
+// ProviderCacheConfig configures the cache of OIDC discovery documents, provider JWKS and SAML IdP
+// metadata. An entry is kept for the max-age in the Cache-Control header of the provider's response,
+// clamped between MinTTLSec and MaxTTLSec, and for DefaultTTLSec if there is no max-age.
+type ProviderCacheConfig struct {
+	// DefaultTTLSec defaults to 1 hour.
+	DefaultTTLSec uint64
+	// MinTTLSec defaults to 1 minute.
+	MinTTLSec uint64
+	// MaxTTLSec defaults to 1 day.
+	MaxTTLSec uint64
+	// UnknownKIDRefreshIntervalSec is the minimum time between refreshes of a JWKS caused by an id
+	// token signed with a key that is not in it. Defaults to 10 seconds.
+	UnknownKIDRefreshIntervalSec uint64
+	// PreWarmOIDCDiscoveryEndpoints are fetched, along with their JWKS, in the background when the
+	// recipe is initialised.
+	PreWarmOIDCDiscoveryEndpoints []string
+}
+
+// OAuthStateConfig configures the server managed OAuth state. When it is set, the authorisation URL
+// API adds a random state (and a nonce, for OpenID Connect providers) to the URL and saves them,
+// along with the PKCE code verifier, in a cookie bound to the browser. The sign in up API then
+// checks the state returned by the provider against the cookie, uses the saved code verifier and
+// checks the nonce of the ID token.
+type OAuthStateConfig struct {
+	// Secret is used to encrypt the state cookie if Store is nil. It must be at least 32 characters long.
+	Secret string
+	// Store saves the state on the server, in which case the cookie only contains the state id.
+	Store OAuthStateStore
+	// CookieName defaults to "sOAuthState".
+	CookieName string
+	// CookieSameSite is one of "lax" (default), "strict" or "none".
+	CookieSameSite string
+	// CookieSecure defaults to true if the apiDomain uses https.
+	CookieSecure *bool
+	// ValiditySec is how long the user has to sign in with the provider. Defaults to 10 minutes.
+	ValiditySec uint64
+	// AllowOAuthTokensWithoutState makes the sign in up API accept oAuthTokens (for example from a
+	// native mobile SDK) instead of redirectURIInfo. Their state and nonce cannot be checked, so such
+	// requests are rejected by default.
+	AllowOAuthTokensWithoutState bool
+}
+
+// ProviderTokenStorageConfig configures the storage of the OAuth tokens returned by the providers.
+// The tokens are encrypted before they are given to the Store. They are saved for each identity of the
+// user, since a user can link more than one identity of the same provider.
+type ProviderTokenStorageConfig struct {
+	Store ProviderTokenStore
+	// EncryptionKey is used to encrypt the tokens. It must be at least 32 characters long.
+	EncryptionKey string
+	// RefreshBeforeExpirySec is how long before it expires an access token is refreshed. Defaults to 60.
+	RefreshBeforeExpirySec uint64
+}
+
+type ProviderTokenStore interface {
+	Save(userID string, thirdPartyID string, thirdPartyUserID string, encryptedTokens string, userContext supertokens.UserContext) error
+	// Get returns nil if there are no tokens saved for the identity of the user.
+	Get(userID string, thirdPartyID string, thirdPartyUserID string, userContext supertokens.UserContext) (*string, error)
+	Delete(userID string, thirdPartyID string, thirdPartyUserID string, userContext supertokens.UserContext) error
+}
+
+type ProviderTokens struct {
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken,omitempty"`
+	// ExpiresAt is the time in milliseconds at which the access token expires, or 0 if the
+	// provider did not say.
+	ExpiresAt  uint64  `json:"expiresAt,omitempty"`
+	TenantId   string  `json:"tenantId"`
+	ClientType *string `json:"clientType,omitempty"`
+}
+
+type LinkedIdentitiesConfig struct {
+	Store LinkedIdentityStore
+}
+
+// LinkedIdentityStore saves the thirdparty identities linked to users, other than the one they signed
+// up with. An identity can only be linked to one user.
+type LinkedIdentityStore interface {
+	// Save returns false if the identity is already linked to a user.
+	Save(userID string, identity LinkedIdentity, userContext supertokens.UserContext) (bool, error)
+	// GetUserIdByIdentity returns nil if the identity is not linked to a user.
+	GetUserIdByIdentity(thirdPartyID string, thirdPartyUserID string, userContext supertokens.UserContext) (*string, error)
+	ListForUser(userID string, userContext supertokens.UserContext) ([]LinkedIdentity, error)
+	// Delete returns false if the identity is not linked to the user.
+	Delete(userID string, thirdPartyID string, thirdPartyUserID string, userContext supertokens.UserContext) (bool, error)
+}
+
+type LinkedIdentity struct {
+	ThirdPartyId     string `json:"thirdPartyId"`
+	ThirdPartyUserId string `json:"thirdPartyUserId"`
+	Email            string `json:"email"`
+	// TimeLinked is in milliseconds. It is the time the user joined for their primary identity.
+	TimeLinked uint64 `json:"timeLinked"`
+	// IsPrimary is true for the identity the user signed up with, which cannot be unlinked.
+	IsPrimary bool `json:"isPrimary"`
+}
+
+const (
+	AppleServerNotificationEmailDisabled  = "email-disabled"
+	AppleServerNotificationEmailEnabled   = "email-enabled"
+	AppleServerNotificationConsentRevoked = "consent-revoked"
+	AppleServerNotificationAccountDelete  = "account-delete"
+)
+
+// AppleServerNotificationsConfig has the callbacks for the notifications that Apple sends when a
+// user changes their Apple ID settings. The user is nil if the Apple ID did not sign up or was not
+// linked in the tenant. The sessions and provider tokens of the user are already removed when the
+// callbacks for consent-revoked and account-delete are called, and a linked identity is unlinked.
+type AppleServerNotificationsConfig struct {
+	OnEmailDisabled  func(notification AppleServerNotification, user *User, tenantId string, userContext supertokens.UserContext) error
+	OnEmailEnabled   func(notification AppleServerNotification, user *User, tenantId string, userContext supertokens.UserContext) error
+	OnConsentRevoked func(notification AppleServerNotification, user *User, tenantId string, userContext supertokens.UserContext) error
+	// OnAccountDeleted should delete the data of the user, as required by Apple.
+	OnAccountDeleted func(notification AppleServerNotification, user *User, tenantId string, userContext supertokens.UserContext) error
+}
+
+type AppleServerNotification struct {
+	// Type is one of the AppleServerNotification constants
+	Type             string
+	ThirdPartyId     string
+	ThirdPartyUserId string
+	Email            string
+	IsPrivateEmail   bool
+	// EventTime is the time of the event, in milliseconds
+	EventTime uint64
+}
+
+type ValidateProviderConfigOptions struct {
+	// RedirectURIOnProviderDashboard is used to build the authorisation URL of each client, as a dry
+	// run of the sign in. The dry run is skipped if it is empty.
+	RedirectURIOnProviderDashboard string
+	// HTTPClient is used for the OIDC discovery and SAML metadata requests instead of the HTTPClient
+	// of the config.
+	HTTPClient *http.Client
+}
+
+type ProviderConfigValidationResult struct {
+	Errors   []ProviderConfigValidationIssue
+	Warnings []ProviderConfigValidationIssue
+	// AuthorisationURLs has the authorisation URL of each client type, if the dry run was done.
+	AuthorisationURLs map[string]string
+}
+
+func (r ProviderConfigValidationResult) IsValid() bool {
+	return len(r.Errors) == 0
+}
+
+type ProviderConfigValidationIssue struct {
+	// Field is the path of the field in the config, like clients[0].clientId
+	Field string `json:"field"`
+	Msg   string `json:"message"`
+}
+
+type OAuthStateData struct {
+	State                          string  `json:"state"`
+	Nonce                          string  `json:"nonce,omitempty"`
+	PKCECodeVerifier               *string `json:"pkceCodeVerifier,omitempty"`
+	ThirdPartyId                   string  `json:"thirdPartyId"`
+	TenantId                       string  `json:"tenantId"`
+	RedirectURIOnProviderDashboard string  `json:"redirectURIOnProviderDashboard"`
+	ExpiresAt                      uint64  `json:"expiresAt"`
+}
+
+type OAuthStateStore interface {
+	// Save saves the data until data.ExpiresAt (in milliseconds).
+	Save(stateId string, data OAuthStateData, userContext supertokens.UserContext) error
+	// Consume returns the data saved for the state id and deletes it, so that it can only be used
+	// once. It returns nil if there is no data for the state id.
+	Consume(stateId string, userContext supertokens.UserContext) (*OAuthStateData, error)
 }
 
 type OverrideStruct struct {
@@ -129,6 +360,34 @@ type ProviderConfig struct {
 	ValidateIdTokenPayload func(idTokenPayload map[string]interface{}, clientConfig ProviderConfigForClientType, userContext supertokens.UserContext) error `json:"-"`
 	ValidateAccessToken    func(accessToken string, clientConfig ProviderConfigForClientType, userContext supertokens.UserContext) error                    `json:"-"`
 	GenerateFakeEmail      func(thirdPartyUserId string, tenantId string, userContext supertokens.UserContext) string                                       `json:"-"`
+
+	// HTTPClient is used for the requests to this provider instead of ProviderHTTPConfig.Client
+	HTTPClient *http.Client `json:"-"`
+
+	// IdTokenValidation configures the checks of the claims of id tokens, which are done before
+	// ValidateIdTokenPayload is called
+	IdTokenValidation *IdTokenValidationConfig `json:"-"`
+}
+
+// IdTokenValidationConfig configures the OpenID Connect checks of the iss, aud, azp, exp, iat and nbf
+// claims of id tokens. These are done for every provider that verifies id tokens with a JwksURI (or an
+// OIDCDiscoveryEndpoint). The nonce is checked if one is expected (see
+// providers.SetExpectedIdTokenNonce), which is the case in the sign in up API when OAuthState is
+// enabled, and is checked even if Disable is set.
+type IdTokenValidationConfig struct {
+	// Disable turns off the checks of the claims. The signature of the id token is always verified.
+	Disable bool
+
+	// Issuers are the accepted values of iss. If empty, the issuer from the discovery document is
+	// used, and iss is not checked for providers that only have a JwksURI.
+	Issuers         []string
+	SkipIssuerCheck bool
+
+	// AdditionalAudiences are accepted in aud (and azp) besides the client id
+	AdditionalAudiences []string
+
+	// ClockSkewSec is the tolerance used when checking exp, iat and nbf. Defaults to 60.
+	ClockSkewSec *uint64
 }
 
 type ProviderClientConfig struct {
@@ -141,7 +400,8 @@ type ProviderClientConfig struct {
 }
 
 type ProviderConfigForClientType struct {
-	Name string
+	ThirdPartyId string
+	Name         string
 
 	ClientID         string
 	ClientSecret     string
@@ -161,9 +421,12 @@ type ProviderConfigForClientType struct {
 	UserInfoMap                      TypeUserInfoMap
 	ValidateIdTokenPayload           func(idTokenPayload map[string]interface{}, clientConfig ProviderConfigForClientType, userContext supertokens.UserContext) error
 	ValidateAccessToken              func(accessToken string, clientConfig ProviderConfigForClientType, userContext supertokens.UserContext) error
+	IdTokenValidation                *IdTokenValidationConfig
 
 	RequireEmail      *bool
 	GenerateFakeEmail func(thirdPartyUserId string, tenantId string, userContext supertokens.UserContext) string
+
+	HTTPClient *http.Client
 }
 
 type TypeProvider struct {
@@ -174,4 +437,5 @@ type TypeProvider struct {
 	GetAuthorisationRedirectURL    func(redirectURIOnProviderDashboard string, userContext supertokens.UserContext) (TypeAuthorisationRedirect, error)
 	ExchangeAuthCodeForOAuthTokens func(redirectURIInfo TypeRedirectURIInfo, userContext supertokens.UserContext) (TypeOAuthTokens, error) // For apple, add userInfo from callbackInfo to oAuthTOkens
 	GetUserInfo                    func(oAuthTokens TypeOAuthTokens, userContext supertokens.UserContext) (TypeUserInfo, error)
+	RefreshOAuthTokens             func(refreshToken string, userContext supertokens.UserContext) (TypeOAuthTokens, error)
 }
@@ -26,6 +26,14 @@ type RecipeInterface struct {
 
 	SignInUp                   *func(thirdPartyID string, thirdPartyUserID string, email string, oAuthTokens TypeOAuthTokens, rawUserInfoFromProvider TypeRawUserInfoFromProvider, tenantId string, userContext supertokens.UserContext) (SignInUpResponse, error)
 	ManuallyCreateOrUpdateUser *func(thirdPartyID string, thirdPartyUserID string, email string, tenantId string, userContext supertokens.UserContext) (ManuallyCreateOrUpdateUserResponse, error)
+
+	StoreProviderTokens         *func(userID string, thirdPartyID string, thirdPartyUserID string, oAuthTokens TypeOAuthTokens, clientType *string, tenantId string, userContext supertokens.UserContext) error
+	GetValidProviderAccessToken *func(userID string, thirdPartyID string, thirdPartyUserID string, userContext supertokens.UserContext) (GetValidProviderAccessTokenResponse, error)
+	DeleteProviderTokens        *func(userID string, thirdPartyID string, thirdPartyUserID string, userContext supertokens.UserContext) error
+
+	LinkIdentity         *func(userID string, thirdPartyID string, thirdPartyUserID string, email string, tenantId string, userContext supertokens.UserContext) (LinkIdentityResponse, error)
+	UnlinkIdentity       *func(userID string, thirdPartyID string, thirdPartyUserID string, userContext supertokens.UserContext) (UnlinkIdentityResponse, error)
+	ListLinkedIdentities *func(userID string, userContext supertokens.UserContext) (ListLinkedIdentitiesResponse, error)
 }
 
 type SignInUpResponse struct {
@@ -43,3 +51,44 @@ type ManuallyCreateOrUpdateUserResponse struct {
 		User           User
 	}
 }
+
+type GetValidProviderAccessTokenResponse struct {
+	OK *struct {
+		AccessToken string
+		// ExpiresAt is the time in milliseconds at which the access token expires, or 0 if the
+		// provider did not say.
+		ExpiresAt uint64
+	}
+	TokensNotFoundError *struct{}
+	// ReauthenticationRequiredError is returned if the access token has expired and it could not be
+	// refreshed, because there is no refresh token or the provider rejected it.
+	ReauthenticationRequiredError *struct{}
+}
+
+type LinkIdentityResponse struct {
+	OK *struct {
+		Identity LinkedIdentity
+	}
+	// IdentityAlreadyLinkedError is returned if the identity is already linked to a user, or if a
+	// user has signed up with it.
+	IdentityAlreadyLinkedError *struct{}
+	// UnknownUserIdError is returned if the user does not exist or is not a thirdparty user.
+	UnknownUserIdError *struct{}
+}
+
+type UnlinkIdentityResponse struct {
+	OK                    *struct{}
+	IdentityNotFoundError *struct{}
+	// PrimaryIdentityError is returned for the identity the user signed up with, since it cannot be
+	// unlinked. This makes sure that the user always has a way to sign in.
+	PrimaryIdentityError *struct{}
+}
+
+type ListLinkedIdentitiesResponse struct {
+	OK *struct {
+		// Identities starts with the primary identity of the user
+		Identities []LinkedIdentity
+	}
+	// UnknownUserIdError is returned if the user does not exist or is not a thirdparty user.
+	UnknownUserIdError *struct{}
+}
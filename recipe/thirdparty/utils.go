@@ -17,6 +17,7 @@ package thirdparty
 
 import (
 	"encoding/json"
+	"strings"
 
 	"github.com/supertokens/supertokens-golang/recipe/thirdparty/tpmodels"
 	"github.com/supertokens/supertokens-golang/supertokens"
@@ -35,6 +36,37 @@ func validateAndNormaliseUserInput(recipeInstance *Recipe, appInfo supertokens.N
 	}
 	typeNormalisedInput.SignInAndUpFeature = signInAndUpFeature
 
+	oAuthState, err := validateAndNormaliseOAuthStateConfig(config.OAuthState)
+	if err != nil {
+		return tpmodels.TypeNormalisedInput{}, err
+	}
+	typeNormalisedInput.OAuthState = oAuthState
+
+	typeNormalisedInput.ProviderCache, err = validateAndNormaliseProviderCacheConfig(config.ProviderCache)
+	if err != nil {
+		return tpmodels.TypeNormalisedInput{}, err
+	}
+
+	providerTokenStorage, err := validateAndNormaliseProviderTokenStorageConfig(config.ProviderTokenStorage)
+	if err != nil {
+		return tpmodels.TypeNormalisedInput{}, err
+	}
+	typeNormalisedInput.ProviderTokenStorage = providerTokenStorage
+
+	typeNormalisedInput.ProviderHTTP, err = validateAndNormaliseProviderHTTPConfig(config.ProviderHTTP)
+	if err != nil {
+		return tpmodels.TypeNormalisedInput{}, err
+	}
+
+	typeNormalisedInput.LinkedIdentities, err = validateAndNormaliseLinkedIdentitiesConfig(config.LinkedIdentities)
+	if err != nil {
+		return tpmodels.TypeNormalisedInput{}, err
+	}
+
+	if config.AppleServerNotifications != nil {
+		typeNormalisedInput.AppleServerNotifications = *config.AppleServerNotifications
+	}
+
 	if config != nil && config.Override != nil {
 		if config.Override.Functions != nil {
 			typeNormalisedInput.Override.Functions = config.Override.Functions
@@ -79,6 +111,103 @@ func validateAndNormaliseSignInAndUpConfig(config tpmodels.TypeInputSignInAndUp)
 	}, nil
 }
 
+func validateAndNormaliseOAuthStateConfig(config *tpmodels.OAuthStateConfig) (*tpmodels.OAuthStateConfig, error) {
+	if config == nil {
+		return nil, nil
+	}
+	result := *config
+
+	if result.Store == nil && len(result.Secret) < 32 {
+		return nil, supertokens.BadInputError{Msg: "OAuthState.Secret must be at least 32 characters long if OAuthState.Store is not provided"}
+	}
+
+	if result.CookieName == "" {
+		result.CookieName = "sOAuthState"
+	}
+
+	result.CookieSameSite = strings.ToLower(strings.TrimSpace(result.CookieSameSite))
+	if result.CookieSameSite == "" {
+		result.CookieSameSite = "lax"
+	}
+	if result.CookieSameSite != "lax" && result.CookieSameSite != "strict" && result.CookieSameSite != "none" {
+		return nil, supertokens.BadInputError{Msg: "OAuthState.CookieSameSite must be one of \"lax\", \"strict\" or \"none\""}
+	}
+	if result.CookieSameSite == "none" && result.CookieSecure != nil && !*result.CookieSecure {
+		return nil, supertokens.BadInputError{Msg: "OAuthState.CookieSecure must be true if OAuthState.CookieSameSite is \"none\""}
+	}
+
+	if result.ValiditySec == 0 {
+		result.ValiditySec = 600
+	}
+
+	return &result, nil
+}
+
+func validateAndNormaliseProviderCacheConfig(config *tpmodels.ProviderCacheConfig) (tpmodels.ProviderCacheConfig, error) {
+	result := tpmodels.ProviderCacheConfig{}
+	if config != nil {
+		result = *config
+	}
+	if result.DefaultTTLSec == 0 {
+		result.DefaultTTLSec = 3600
+	}
+	if result.MinTTLSec == 0 {
+		result.MinTTLSec = 60
+	}
+	if result.MaxTTLSec == 0 {
+		result.MaxTTLSec = 86400
+	}
+	if result.UnknownKIDRefreshIntervalSec == 0 {
+		result.UnknownKIDRefreshIntervalSec = 10
+	}
+	if result.MinTTLSec > result.MaxTTLSec {
+		return tpmodels.ProviderCacheConfig{}, supertokens.BadInputError{Msg: "ProviderCache.MinTTLSec must not be greater than ProviderCache.MaxTTLSec"}
+	}
+	return result, nil
+}
+
+func validateAndNormaliseProviderTokenStorageConfig(config *tpmodels.ProviderTokenStorageConfig) (*tpmodels.ProviderTokenStorageConfig, error) {
+	if config == nil {
+		return nil, nil
+	}
+	result := *config
+	if result.Store == nil {
+		return nil, supertokens.BadInputError{Msg: "ProviderTokenStorage.Store must be provided"}
+	}
+	if len(result.EncryptionKey) < 32 {
+		return nil, supertokens.BadInputError{Msg: "ProviderTokenStorage.EncryptionKey must be at least 32 characters long"}
+	}
+	if result.RefreshBeforeExpirySec == 0 {
+		result.RefreshBeforeExpirySec = 60
+	}
+	return &result, nil
+}
+
+func validateAndNormaliseLinkedIdentitiesConfig(config *tpmodels.LinkedIdentitiesConfig) (*tpmodels.LinkedIdentitiesConfig, error) {
+	if config == nil {
+		return nil, nil
+	}
+	result := *config
+	if result.Store == nil {
+		return nil, supertokens.BadInputError{Msg: "LinkedIdentities.Store must be provided"}
+	}
+	return &result, nil
+}
+
+func validateAndNormaliseProviderHTTPConfig(config *tpmodels.ProviderHTTPConfig) (tpmodels.ProviderHTTPConfig, error) {
+	result := tpmodels.ProviderHTTPConfig{}
+	if config != nil {
+		result = *config
+	}
+	if result.TimeoutSec == 0 {
+		result.TimeoutSec = 30
+	}
+	if result.MaxGETRetries < 0 {
+		return tpmodels.ProviderHTTPConfig{}, supertokens.BadInputError{Msg: "ProviderHTTP.MaxGETRetries must not be negative"}
+	}
+	return result, nil
+}
+
 func parseUser(value interface{}) (*tpmodels.User, error) {
 	respJSON, err := json.Marshal(value)
 	if err != nil {
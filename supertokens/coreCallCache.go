@@ -0,0 +1,221 @@
+/* Copyright (c) 2021, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package supertokens
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CoreCallCache is a store for the responses of GET requests to the core that is shared across
+// requests. Its methods may be called concurrently. Writes made to the core by other processes
+// (or directly to the core) are only seen once the cached responses expire.
+type CoreCallCache interface {
+	// Get returns the cached response body for the key, if it is present and has not expired.
+	Get(key string) ([]byte, bool)
+	// Set caches the response body for the key for ttlMS milliseconds.
+	Set(key string, value []byte, ttlMS uint64)
+}
+
+type CoreCallCachePathConfig struct {
+	TTLMS uint64
+	// InvalidatedByPaths are the core paths that modify the data returned by this path. A POST,
+	// PUT or DELETE request made by the SDK to any of these paths (or their sub paths)
+	// invalidates the responses cached for this path.
+	InvalidatedByPaths []string
+}
+
+type SharedCoreCallCacheConfig struct {
+	// Cache defaults to an in memory LRU cache with 1000 entries.
+	Cache CoreCallCache
+	// Paths are the core paths (without the tenant id prefix) whose GET responses are cached.
+	// Defaults to DefaultSharedCoreCallCachePaths().
+	Paths map[string]CoreCallCachePathConfig
+}
+
+const defaultSharedCoreCallCacheTTLMS = 60000
+
+// DefaultSharedCoreCallCachePaths returns the cache configuration for the user metadata, user
+// roles and tenant lookups.
+func DefaultSharedCoreCallCachePaths() map[string]CoreCallCachePathConfig {
+	userRolesWrites := []string{"/recipe/role", "/recipe/user/role", "/user/remove"}
+	roleWrites := []string{"/recipe/role"}
+	tenantWrites := []string{"/recipe/multitenancy"}
+	return map[string]CoreCallCachePathConfig{
+		"/recipe/user/metadata": {
+			TTLMS:              defaultSharedCoreCallCacheTTLMS,
+			InvalidatedByPaths: []string{"/recipe/user/metadata", "/user/remove"},
+		},
+		"/recipe/user/roles":               {TTLMS: defaultSharedCoreCallCacheTTLMS, InvalidatedByPaths: userRolesWrites},
+		"/recipe/role/users":               {TTLMS: defaultSharedCoreCallCacheTTLMS, InvalidatedByPaths: userRolesWrites},
+		"/recipe/roles":                    {TTLMS: defaultSharedCoreCallCacheTTLMS, InvalidatedByPaths: roleWrites},
+		"/recipe/role/permissions":         {TTLMS: defaultSharedCoreCallCacheTTLMS, InvalidatedByPaths: roleWrites},
+		"/recipe/permission/roles":         {TTLMS: defaultSharedCoreCallCacheTTLMS, InvalidatedByPaths: roleWrites},
+		"/recipe/multitenancy/tenant":      {TTLMS: defaultSharedCoreCallCacheTTLMS, InvalidatedByPaths: tenantWrites},
+		"/recipe/multitenancy/tenant/list": {TTLMS: defaultSharedCoreCallCacheTTLMS, InvalidatedByPaths: tenantWrites},
+	}
+}
+
+type lruCacheEntry struct {
+	key       string
+	value     []byte
+	expiresAt uint64
+}
+
+type inMemoryLRUCoreCallCache struct {
+	maxEntries int
+	entries    map[string]*list.Element
+	order      *list.List
+	lock       sync.Mutex
+}
+
+// NewInMemoryLRUCoreCallCache creates a CoreCallCache that keeps at most maxEntries responses
+// in memory, evicting the least recently used ones first.
+func NewInMemoryLRUCoreCallCache(maxEntries int) CoreCallCache {
+	if maxEntries <= 0 {
+		maxEntries = 1000
+	}
+	return &inMemoryLRUCoreCallCache{
+		maxEntries: maxEntries,
+		entries:    map[string]*list.Element{},
+		order:      list.New(),
+	}
+}
+
+func (c *inMemoryLRUCoreCallCache) Get(key string) ([]byte, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*lruCacheEntry)
+	if entry.expiresAt <= GetCurrTimeInMS() {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return entry.value, true
+}
+
+func (c *inMemoryLRUCoreCallCache) Set(key string, value []byte, ttlMS uint64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	expiresAt := GetCurrTimeInMS() + ttlMS
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*lruCacheEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&lruCacheEntry{key: key, value: value, expiresAt: expiresAt})
+	for c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruCacheEntry).key)
+	}
+}
+
+var (
+	sharedCoreCallCache            CoreCallCache = nil
+	sharedCoreCallCachePaths       map[string]CoreCallCachePathConfig
+	sharedCoreCallCacheGenerations map[string]uint64
+	sharedCoreCallCacheLock        sync.Mutex
+)
+
+func initSharedCoreCallCache(config *SharedCoreCallCacheConfig) {
+	sharedCoreCallCacheLock.Lock()
+	defer sharedCoreCallCacheLock.Unlock()
+
+	sharedCoreCallCacheGenerations = map[string]uint64{}
+	if config == nil {
+		sharedCoreCallCache = nil
+		sharedCoreCallCachePaths = nil
+		return
+	}
+
+	sharedCoreCallCache = config.Cache
+	if sharedCoreCallCache == nil {
+		sharedCoreCallCache = NewInMemoryLRUCoreCallCache(1000)
+	}
+	sharedCoreCallCachePaths = config.Paths
+	if sharedCoreCallCachePaths == nil {
+		sharedCoreCallCachePaths = DefaultSharedCoreCallCachePaths()
+	}
+}
+
+// stripTenantPrefixFromCorePath removes the tenant id from paths like /{tenantId}/recipe/user/roles
+func stripTenantPrefixFromCorePath(path string) string {
+	index := strings.Index(path, "/recipe/")
+	if index > 0 {
+		return path[index:]
+	}
+	return path
+}
+
+// getSharedCoreCallCache returns the shared cache along with the key under which the response
+// for the request identified by uniqueKey is cached, and the TTL for it. ok is false if the path
+// is not cacheable. The key contains the generation of the path, so that invalidating a path
+// does not require deleting entries from the (possibly external) cache. The unique key is hashed
+// since it contains the API key.
+func getSharedCoreCallCache(path NormalisedURLPath, uniqueKey string) (cache CoreCallCache, key string, ttlMS uint64, ok bool) {
+	sharedCoreCallCacheLock.Lock()
+	defer sharedCoreCallCacheLock.Unlock()
+
+	if sharedCoreCallCache == nil {
+		return nil, "", 0, false
+	}
+
+	pathWithoutTenant := stripTenantPrefixFromCorePath(path.GetAsStringDangerous())
+	pathConfig, ok := sharedCoreCallCachePaths[pathWithoutTenant]
+	if !ok || pathConfig.TTLMS == 0 {
+		return nil, "", 0, false
+	}
+
+	hash := sha256.Sum256([]byte(uniqueKey))
+	key = "st-core:" + strconv.FormatUint(sharedCoreCallCacheGenerations[pathWithoutTenant], 10) + ":" + hex.EncodeToString(hash[:])
+	return sharedCoreCallCache, key, pathConfig.TTLMS, true
+}
+
+// invalidateSharedCoreCallCache is called after every write to the core, and invalidates the
+// cached responses of all the paths whose data could be modified by the write.
+func invalidateSharedCoreCallCache(path NormalisedURLPath) {
+	sharedCoreCallCacheLock.Lock()
+	defer sharedCoreCallCacheLock.Unlock()
+
+	if sharedCoreCallCache == nil {
+		return
+	}
+
+	writePath := stripTenantPrefixFromCorePath(path.GetAsStringDangerous())
+	for cachedPath, pathConfig := range sharedCoreCallCachePaths {
+		for _, invalidatedBy := range pathConfig.InvalidatedByPaths {
+			if writePath == invalidatedBy || strings.HasPrefix(writePath, strings.TrimSuffix(invalidatedBy, "/")+"/") {
+				sharedCoreCallCacheGenerations[cachedPath]++
+				break
+			}
+		}
+	}
+}
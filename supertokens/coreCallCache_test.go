@@ -0,0 +1,78 @@
+/* Copyright (c) 2021, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package supertokens
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInMemoryLRUCoreCallCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := NewInMemoryLRUCoreCallCache(2)
+	cache.Set("a", []byte("1"), 60000)
+	cache.Set("b", []byte("2"), 60000)
+
+	_, ok := cache.Get("a")
+	assert.True(t, ok)
+
+	cache.Set("c", []byte("3"), 60000)
+
+	_, ok = cache.Get("b")
+	assert.False(t, ok)
+	value, ok := cache.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, "1", string(value))
+	value, ok = cache.Get("c")
+	assert.True(t, ok)
+	assert.Equal(t, "3", string(value))
+}
+
+func TestInMemoryLRUCoreCallCacheExpiresEntries(t *testing.T) {
+	cache := NewInMemoryLRUCoreCallCache(10)
+	cache.Set("a", []byte("1"), 50)
+
+	_, ok := cache.Get("a")
+	assert.True(t, ok)
+
+	time.Sleep(100 * time.Millisecond)
+
+	_, ok = cache.Get("a")
+	assert.False(t, ok)
+}
+
+func TestSharedCoreCallCacheInvalidation(t *testing.T) {
+	initSharedCoreCallCache(&SharedCoreCallCacheConfig{})
+	defer initSharedCoreCallCache(nil)
+
+	rolesPath := NormalisedURLPath{value: "/public/recipe/user/roles"}
+	_, keyBefore, ttl, ok := getSharedCoreCallCache(rolesPath, "key")
+	assert.True(t, ok)
+	assert.Equal(t, uint64(defaultSharedCoreCallCacheTTLMS), ttl)
+
+	// writes to unrelated paths do not invalidate the cached roles
+	invalidateSharedCoreCallCache(NormalisedURLPath{value: "/recipe/user/metadata"})
+	_, key, _, _ := getSharedCoreCallCache(rolesPath, "key")
+	assert.Equal(t, keyBefore, key)
+
+	invalidateSharedCoreCallCache(NormalisedURLPath{value: "/public/recipe/user/role/remove"})
+	_, key, _, _ = getSharedCoreCallCache(rolesPath, "key")
+	assert.NotEqual(t, keyBefore, key)
+
+	_, _, _, ok = getSharedCoreCallCache(NormalisedURLPath{value: "/recipe/session"}, "key")
+	assert.False(t, ok)
+}
@@ -0,0 +1,165 @@
+/* Copyright (c) 2021, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package supertokens
+
+import (
+	"context"
+	"time"
+)
+
+// Names of the spans started by the SDK
+const (
+	SpanNameAPI             = "supertokens.api"
+	SpanNameCoreRequest     = "supertokens.core.request"
+	SpanNameProviderRequest = "supertokens.thirdparty.provider.request"
+)
+
+// Names of the metrics recorded by the SDK
+const (
+	MetricAPIDuration             = "supertokens.api.duration"
+	MetricCoreRequestDuration     = "supertokens.core.request.duration"
+	MetricProviderRequestDuration = "supertokens.thirdparty.provider.request.duration"
+	MetricSignIns                 = "supertokens.sign_ins"
+	MetricSignUps                 = "supertokens.sign_ups"
+	MetricSessionRefreshes        = "supertokens.session.refreshes"
+	MetricTokenTheftDetections    = "supertokens.session.token_theft_detections"
+	MetricClaimValidationFailures = "supertokens.session.claim_validation_failures"
+)
+
+// Attribute keys used in spans and metrics
+const (
+	AttributeAPIID          = "supertokens.api.id"
+	AttributeRecipeID       = "supertokens.recipe.id"
+	AttributeTenantID       = "supertokens.tenant.id"
+	AttributeHTTPMethod     = "http.request.method"
+	AttributeHTTPStatusCode = "http.response.status_code"
+	AttributeCorePath       = "supertokens.core.path"
+	AttributeCoreHost       = "server.address"
+	AttributeClaimID        = "supertokens.claim.id"
+	AttributeThirdPartyID   = "supertokens.thirdparty.id"
+	AttributeURLPath        = "url.path"
+)
+
+// Span is a unit of work started via Instrumentation.StartSpan.
+type Span interface {
+	SetAttributes(attributes map[string]interface{})
+	RecordError(err error)
+	End()
+}
+
+// Instrumentation receives the traces and metrics of the SDK. It can be implemented on top of
+// OpenTelemetry (or any other library) without the SDK depending on it. All methods must be safe
+// for concurrent use.
+type Instrumentation interface {
+	// StartSpan starts a span that is a child of the span in ctx (if any). The returned context
+	// contains the new span and is used for the spans started while it is active.
+	StartSpan(ctx context.Context, name string, attributes map[string]interface{}) (context.Context, Span)
+	// AddToCounter adds value to the counter with the given name.
+	AddToCounter(ctx context.Context, name string, value int64, attributes map[string]interface{})
+	// RecordDuration records a duration (in milliseconds) in the histogram with the given name.
+	RecordDuration(ctx context.Context, name string, durationMS float64, attributes map[string]interface{})
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(attributes map[string]interface{}) {}
+func (noopSpan) RecordError(err error)                           {}
+func (noopSpan) End()                                            {}
+
+type noopInstrumentation struct{}
+
+func (noopInstrumentation) StartSpan(ctx context.Context, name string, attributes map[string]interface{}) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+func (noopInstrumentation) AddToCounter(ctx context.Context, name string, value int64, attributes map[string]interface{}) {
+}
+
+func (noopInstrumentation) RecordDuration(ctx context.Context, name string, durationMS float64, attributes map[string]interface{}) {
+}
+
+var sdkInstrumentation Instrumentation = noopInstrumentation{}
+
+// SetInstrumentation replaces the instrumentation used by the SDK. Passing nil disables it.
+func SetInstrumentation(instrumentation Instrumentation) {
+	if instrumentation == nil {
+		instrumentation = noopInstrumentation{}
+	}
+	sdkInstrumentation = instrumentation
+}
+
+// StartSpan starts a span that is a child of the span in the userContext's context. The context
+// with the new span is set in the userContext until the returned function is called, so that the
+// spans started for core requests made with the userContext are its children.
+func StartSpan(userContext UserContext, name string, attributes map[string]interface{}) (Span, func()) {
+	if _, ok := sdkInstrumentation.(noopInstrumentation); ok || userContext == nil {
+		return noopSpan{}, func() {}
+	}
+
+	parentCtx := getContextFromUserContext(userContext)
+	defaultObj, _ := (*userContext)["_default"].(map[string]interface{})
+	previousCtx, hadCtx := defaultObj["context"]
+
+	ctx, span := sdkInstrumentation.StartSpan(parentCtx, name, attributes)
+	SetContextInUserContext(userContext, ctx)
+
+	return span, func() {
+		span.End()
+		defaultObj, ok := (*userContext)["_default"].(map[string]interface{})
+		if !ok {
+			return
+		}
+		if hadCtx {
+			defaultObj["context"] = previousCtx
+		} else {
+			delete(defaultObj, "context")
+		}
+	}
+}
+
+// AddToCounter adds value to the counter with the given name.
+func AddToCounter(userContext UserContext, name string, value int64, attributes map[string]interface{}) {
+	sdkInstrumentation.AddToCounter(getContextFromUserContext(userContext), name, value, attributes)
+}
+
+// RecordDuration records durationMS for the metric with the given name.
+func RecordDuration(userContext UserContext, name string, durationMS float64, attributes map[string]interface{}) {
+	sdkInstrumentation.RecordDuration(getContextFromUserContext(userContext), name, durationMS, attributes)
+}
+
+// RecipeMetricAttributes returns the attributes for a metric recorded by a recipe. The tenant id
+// is omitted if it is empty.
+func RecipeMetricAttributes(recipeId string, tenantId string) map[string]interface{} {
+	attributes := map[string]interface{}{
+		AttributeRecipeID: recipeId,
+	}
+	if tenantId != "" {
+		attributes[AttributeTenantID] = tenantId
+	}
+	return attributes
+}
+
+// SignInUpMetricName returns the counter to increment for a sign in / up API that can create users.
+func SignInUpMetricName(createdNewUser bool) string {
+	if createdNewUser {
+		return MetricSignUps
+	}
+	return MetricSignIns
+}
+
+func recordDurationSince(ctx context.Context, name string, startTime time.Time, attributes map[string]interface{}) {
+	sdkInstrumentation.RecordDuration(ctx, name, float64(time.Since(startTime).Microseconds())/1000, attributes)
+}
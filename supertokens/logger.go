@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"runtime"
+	"strings"
 	"time"
 )
 
@@ -20,14 +21,164 @@ var (
 	DebugEnabled = false
 )
 
-func formatMessage(message string) string {
-	_, file, line, _ := runtime.Caller(2)
-	return fmt.Sprintf(" {t: \"%s\", message: \"%s\", file: \"%s:%d\" sdkVer: \"%s\"}\n\n", time.Now().Format(time.RFC3339), message, file, line, VERSION)
+type LogLevel int
+
+const (
+	LogLevelDebug LogLevel = iota
+	LogLevelInfo
+	LogLevelWarn
+	LogLevelError
+)
+
+func (l LogLevel) String() string {
+	switch l {
+	case LogLevelDebug:
+		return "debug"
+	case LogLevelInfo:
+		return "info"
+	case LogLevelWarn:
+		return "warn"
+	case LogLevelError:
+		return "error"
+	}
+	return fmt.Sprintf("level(%d)", int(l))
 }
 
-func LogDebugMessage(message string) {
+// The keys below are used for the structured fields that the SDK attaches to its log messages.
+const (
+	LogFieldRequestID  = "requestId"
+	LogFieldTenantID   = "tenantId"
+	LogFieldRecipeID   = "recipeId"
+	LogFieldAPIID      = "apiId"
+	LogFieldUserID     = "userId"
+	LogFieldCorePath   = "corePath"
+	LogFieldCoreHost   = "coreHost"
+	LogFieldMethod     = "method"
+	LogFieldStatusCode = "statusCode"
+	LogFieldLatencyMS  = "latencyMs"
+	LogFieldError      = "error"
+)
+
+// StructuredLogger receives all the log messages of the SDK. keyValues is a list of alternating
+// keys (strings) and values, like in log/slog.
+type StructuredLogger interface {
+	Enabled(level LogLevel) bool
+	Log(level LogLevel, message string, keyValues ...interface{})
+}
+
+// defaultLogger writes to Logger (stdout by default) only if debug logging is enabled, which
+// keeps the behaviour of the SDK from before structured loggers were supported.
+type defaultLogger struct{}
+
+func (defaultLogger) Enabled(level LogLevel) bool {
 	_, exists := os.LookupEnv("SUPERTOKENS_DEBUG")
-	if exists || DebugEnabled == true {
-		Logger.Printf(formatMessage(message))
+	return exists || DebugEnabled
+}
+
+func (defaultLogger) Log(level LogLevel, message string, keyValues ...interface{}) {
+	Logger.Print(formatMessage(level, message, keyValues))
+}
+
+var sdkLogger StructuredLogger = defaultLogger{}
+
+// SetLogger replaces the logger used by the SDK. Passing nil restores the default logger.
+func SetLogger(logger StructuredLogger) {
+	if logger == nil {
+		logger = defaultLogger{}
+	}
+	sdkLogger = logger
+}
+
+func formatMessage(level LogLevel, message string, keyValues []interface{}) string {
+	file, line := getCallerOutsideLogger()
+	fields := ""
+	for i := 0; i < len(keyValues); i += 2 {
+		if i+1 < len(keyValues) {
+			fields += fmt.Sprintf(", %v: \"%v\"", keyValues[i], keyValues[i+1])
+		} else {
+			fields += fmt.Sprintf(", %v: \"\"", keyValues[i])
+		}
+	}
+	levelField := ""
+	if level != LogLevelDebug {
+		levelField = fmt.Sprintf(", level: \"%s\"", level)
+	}
+	return fmt.Sprintf(" {t: \"%s\", message: \"%s\"%s%s, file: \"%s:%d\" sdkVer: \"%s\"}\n\n", time.Now().Format(time.RFC3339), message, levelField, fields, file, line, VERSION)
+}
+
+// getCallerOutsideLogger returns the location of the code that called one of the log functions
+func getCallerOutsideLogger() (string, int) {
+	for skip := 2; ; skip++ {
+		_, file, line, ok := runtime.Caller(skip)
+		if !ok {
+			return "", 0
+		}
+		if !strings.HasSuffix(file, "supertokens/logger.go") {
+			return file, line
+		}
+	}
+}
+
+func logMessage(level LogLevel, message string, keyValues []interface{}) {
+	if sdkLogger.Enabled(level) {
+		sdkLogger.Log(level, message, keyValues...)
+	}
+}
+
+func LogDebugMessage(message string) {
+	logMessage(LogLevelDebug, message, nil)
+}
+
+func LogDebug(message string, keyValues ...interface{}) {
+	logMessage(LogLevelDebug, message, keyValues)
+}
+
+func LogInfo(message string, keyValues ...interface{}) {
+	logMessage(LogLevelInfo, message, keyValues)
+}
+
+func LogWarn(message string, keyValues ...interface{}) {
+	logMessage(LogLevelWarn, message, keyValues)
+}
+
+func LogError(message string, keyValues ...interface{}) {
+	logMessage(LogLevelError, message, keyValues)
+}
+
+// LogFieldsFromUserContext returns the request id and tenant id (if known) of the request
+// being handled, so that they can be attached to log messages.
+func LogFieldsFromUserContext(userContext UserContext) []interface{} {
+	fields := []interface{}{}
+	if userContext == nil {
+		return fields
+	}
+	defaultObj, ok := (*userContext)["_default"].(map[string]interface{})
+	if !ok {
+		return fields
+	}
+	if requestId, ok := defaultObj["requestId"].(string); ok {
+		fields = append(fields, LogFieldRequestID, requestId)
+	}
+	if tenantId, ok := defaultObj["tenantId"].(string); ok {
+		fields = append(fields, LogFieldTenantID, tenantId)
+	}
+	return fields
+}
+
+// RecipeLogFields returns the fields for a log message emitted by a recipe. If tenantId is empty,
+// the tenant id of the request being handled (if any) is used.
+func RecipeLogFields(recipeId string, tenantId string, userContext UserContext, keyValues ...interface{}) []interface{} {
+	fields := []interface{}{}
+	contextFields := LogFieldsFromUserContext(userContext)
+	for i := 0; i+1 < len(contextFields); i += 2 {
+		if contextFields[i] == LogFieldTenantID && tenantId != "" {
+			continue
+		}
+		fields = append(fields, contextFields[i], contextFields[i+1])
+	}
+	fields = append(fields, LogFieldRecipeID, recipeId)
+	if tenantId != "" {
+		fields = append(fields, LogFieldTenantID, tenantId)
 	}
+	return append(fields, keyValues...)
 }
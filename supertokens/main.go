@@ -16,6 +16,7 @@
 package supertokens
 
 import (
+	"context"
 	"net/http"
 )
 
@@ -86,3 +87,10 @@ func DeleteUser(userId string) error {
 func GetRequestFromUserContext(userContext UserContext) *http.Request {
 	return getRequestFromUserContext(userContext)
 }
+
+// GetContextFromUserContext returns the context.Context that is used for the core calls made with
+// this userContext. If no context was set and no request is present in the userContext,
+// context.Background() is returned.
+func GetContextFromUserContext(userContext UserContext) context.Context {
+	return getContextFromUserContext(userContext)
+}
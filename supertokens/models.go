@@ -16,6 +16,7 @@
 package supertokens
 
 import (
+	"crypto/tls"
 	"net/http"
 )
 
@@ -50,6 +51,12 @@ type TypeInput struct {
 	Telemetry             *bool
 	Debug                 bool
 	OnSuperTokensAPIError func(err error, req *http.Request, res http.ResponseWriter)
+	// Logger receives all the log messages of the SDK. If it is not provided, debug messages are
+	// written to stdout if Debug is true (or the SUPERTOKENS_DEBUG env var is set).
+	Logger StructuredLogger
+	// Instrumentation receives the traces and metrics of the SDK. They are not recorded if this
+	// is nil.
+	Instrumentation Instrumentation
 }
 
 type ConnectionInfo struct {
@@ -57,6 +64,51 @@ type ConnectionInfo struct {
 	APIKey               string
 	NetworkInterceptor   func(*http.Request, UserContext) (*http.Request, error)
 	DisableCoreCallCache bool
+
+	// HTTPClient is used for all requests to the core if provided. Its Transport and Timeout
+	// are overridden by Transport and RequestTimeoutMS if those are set as well.
+	HTTPClient *http.Client
+	// Transport is used to send requests to the core if provided. If it is nil, a pooled
+	// transport is created using the connection pool and TLS settings below.
+	Transport http.RoundTripper
+	// RequestTimeoutMS is the time limit for a single request to the core, including reading
+	// the response body. 0 means that there is no timeout (apart from the context deadline).
+	RequestTimeoutMS uint64
+
+	// The settings below are only used if neither Transport nor HTTPClient.Transport is provided.
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	MaxConnsPerHost     int
+	IdleConnTimeoutSec  uint64
+	// TLSConfig can be used to trust a private CA or to present client certificates to a
+	// core that requires mTLS.
+	TLSConfig *tls.Config
+
+	// HostHealth configures how failing cores are detected and skipped, and how failed GET
+	// requests are retried.
+	HostHealth *CoreHostHealthConfig
+
+	// SharedCoreCallCache enables caching the responses of some GET requests to the core across
+	// requests. It is disabled if this is nil.
+	SharedCoreCallCache *SharedCoreCallCacheConfig
+}
+
+type CoreHostHealthConfig struct {
+	// DisableCircuitBreaker stops failing cores from being ejected. Failures are still tracked.
+	DisableCircuitBreaker bool
+	// FailureThreshold is the number of consecutive failed requests after which a core is
+	// ejected. Defaults to 3.
+	FailureThreshold int
+	// CooldownMS is how long an ejected core is skipped before a request is let through to
+	// check if it has recovered. Defaults to 10000.
+	CooldownMS uint64
+	// MaxGETRetries is the number of times a GET request is retried (on the next available
+	// core) if it fails because of a network error or a 5xx response. Defaults to 0.
+	MaxGETRetries int
+	// RetryBaseDelayMS and RetryMaxDelayMS bound the exponential backoff (with full jitter)
+	// between GET retries. They default to 50 and 1000.
+	RetryBaseDelayMS uint64
+	RetryMaxDelayMS  uint64
 }
 
 type APIHandled struct {
@@ -49,6 +49,7 @@ var (
 	querierInterceptor    func(*http.Request, UserContext) (*http.Request, error)
 	querierGlobalCacheTag uint64
 	querierDisableCache   bool
+	querierHTTPClient     *http.Client = &http.Client{}
 )
 
 func SetQuerierApiVersionForTests(version string) {
@@ -84,8 +85,8 @@ func (q *Querier) GetQuerierAPIVersion(userContextIn ...UserContext) (string, er
 	}
 	queryString := strings.Join(queryParams, "&")
 
-	response, _, err := q.sendRequestHelper(NormalisedURLPath{value: "/apiversion"}, func(url string) (*http.Response, []byte, error) {
-		req, err := http.NewRequest("GET", url, nil)
+	response, _, err := q.sendRequestHelper(NormalisedURLPath{value: "/apiversion"}, "GET", func(url string) (*http.Response, []byte, error) {
+		req, err := http.NewRequestWithContext(getContextFromUserContext(userContext), "GET", url, nil)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -97,11 +98,11 @@ func (q *Querier) GetQuerierAPIVersion(userContextIn ...UserContext) (string, er
 
 		// Apply network interceptor if available
 		if querierInterceptor != nil {
-			interceptedReq := &http.Request{
+			interceptedReq := (&http.Request{
 				URL:    req.URL,
 				Method: req.Method,
 				Header: headers,
-			}
+			}).WithContext(req.Context())
 			interceptedReq.URL.RawQuery = queryString
 			interceptedReq, err = querierInterceptor(interceptedReq, userContext)
 			if err != nil {
@@ -113,10 +114,9 @@ func (q *Querier) GetQuerierAPIVersion(userContextIn ...UserContext) (string, er
 			req.Header = headers
 		}
 
-		client := &http.Client{}
-		resp, err := client.Do(req)
+		resp, err := querierHTTPClient.Do(req)
 		return resp, nil, err
-	}, len(QuerierHosts), nil)
+	}, len(QuerierHosts), nil, 0, userContext)
 
 	if err != nil {
 		return "", err
@@ -150,19 +150,70 @@ func GetNewQuerierInstanceOrThrowError(rIDToCore string) (*Querier, error) {
 	return &Querier{RIDToCore: rIDToCore}, nil
 }
 
-func initQuerier(hosts []QuerierHost, APIKey string, interceptor func(*http.Request, UserContext) (*http.Request, error), disableCache bool) {
+func initQuerier(hosts []QuerierHost, config ConnectionInfo) error {
 	if !querierInitCalled {
+		client, err := makeQuerierHTTPClient(config)
+		if err != nil {
+			return err
+		}
 		querierInitCalled = true
 		QuerierHosts = hosts
-		if APIKey != "" {
+		if config.APIKey != "" {
+			APIKey := config.APIKey
 			QuerierAPIKey = &APIKey
 		}
 		querierAPIVersion = ""
 		querierLastTriedIndex = 0
-		querierInterceptor = interceptor
+		querierInterceptor = config.NetworkInterceptor
 		querierGlobalCacheTag = GetCurrTimeInMS()
-		querierDisableCache = disableCache
+		querierDisableCache = config.DisableCoreCallCache
+		querierHTTPClient = client
+		querierHostsHealth = make([]querierHostHealth, len(hosts))
+		querierHealthConfig = normaliseCoreHostHealthConfig(config.HostHealth)
+		initSharedCoreCallCache(config.SharedCoreCallCache)
 	}
+	return nil
+}
+
+// makeQuerierHTTPClient creates the client that is shared by all requests to the core, so that
+// connections to the core are pooled and kept alive across requests.
+func makeQuerierHTTPClient(config ConnectionInfo) (*http.Client, error) {
+	client := &http.Client{}
+	if config.HTTPClient != nil {
+		// we copy the client so that the user's instance is not modified below
+		*client = *config.HTTPClient
+	}
+	if config.Transport != nil {
+		client.Transport = config.Transport
+	}
+
+	transportSettingsProvided := config.MaxIdleConns != 0 || config.MaxIdleConnsPerHost != 0 || config.MaxConnsPerHost != 0 || config.IdleConnTimeoutSec != 0 || config.TLSConfig != nil
+	if client.Transport == nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		if config.MaxIdleConns != 0 {
+			transport.MaxIdleConns = config.MaxIdleConns
+		}
+		if config.MaxIdleConnsPerHost != 0 {
+			transport.MaxIdleConnsPerHost = config.MaxIdleConnsPerHost
+		}
+		if config.MaxConnsPerHost != 0 {
+			transport.MaxConnsPerHost = config.MaxConnsPerHost
+		}
+		if config.IdleConnTimeoutSec != 0 {
+			transport.IdleConnTimeout = time.Duration(config.IdleConnTimeoutSec) * time.Second
+		}
+		if config.TLSConfig != nil {
+			transport.TLSClientConfig = config.TLSConfig.Clone()
+		}
+		client.Transport = transport
+	} else if transportSettingsProvided {
+		return nil, errors.New("connection pool and TLS settings cannot be used along with a custom Transport. Please configure them on your transport instead")
+	}
+
+	if config.RequestTimeoutMS != 0 {
+		client.Timeout = time.Duration(config.RequestTimeoutMS) * time.Millisecond
+	}
+	return client, nil
 }
 
 func (q *Querier) SendPostRequest(path string, data map[string]interface{}, userContext UserContext) (map[string]interface{}, error) {
@@ -171,7 +222,9 @@ func (q *Querier) SendPostRequest(path string, data map[string]interface{}, user
 	if err != nil {
 		return nil, err
 	}
-	resp, _, err := q.sendRequestHelper(nP, func(url string) (*http.Response, []byte, error) {
+	// this is done after the write so that responses fetched while it was in progress are not reused
+	defer invalidateSharedCoreCallCache(nP)
+	resp, _, err := q.sendRequestHelper(nP, "POST", func(url string) (*http.Response, []byte, error) {
 		if data == nil {
 			data = map[string]interface{}{}
 		}
@@ -179,7 +232,7 @@ func (q *Querier) SendPostRequest(path string, data map[string]interface{}, user
 		if err != nil {
 			return nil, nil, err
 		}
-		req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+		req, err := http.NewRequestWithContext(getContextFromUserContext(userContext), "POST", url, bytes.NewBuffer(jsonData))
 		if err != nil {
 			return nil, nil, err
 		}
@@ -205,10 +258,9 @@ func (q *Querier) SendPostRequest(path string, data map[string]interface{}, user
 			}
 		}
 
-		client := &http.Client{}
-		resp, err := client.Do(req)
+		resp, err := querierHTTPClient.Do(req)
 		return resp, nil, err
-	}, len(QuerierHosts), nil)
+	}, len(QuerierHosts), nil, 0, userContext)
 	return resp, err
 }
 
@@ -218,12 +270,14 @@ func (q *Querier) SendDeleteRequest(path string, data map[string]interface{}, pa
 	if err != nil {
 		return nil, err
 	}
-	resp, _, err := q.sendRequestHelper(nP, func(url string) (*http.Response, []byte, error) {
+	// this is done after the write so that responses fetched while it was in progress are not reused
+	defer invalidateSharedCoreCallCache(nP)
+	resp, _, err := q.sendRequestHelper(nP, "DELETE", func(url string) (*http.Response, []byte, error) {
 		jsonData, err := json.Marshal(data)
 		if err != nil {
 			return nil, nil, err
 		}
-		req, err := http.NewRequest("DELETE", url, bytes.NewBuffer(jsonData))
+		req, err := http.NewRequestWithContext(getContextFromUserContext(userContext), "DELETE", url, bytes.NewBuffer(jsonData))
 		if err != nil {
 			return nil, nil, err
 		}
@@ -256,10 +310,9 @@ func (q *Querier) SendDeleteRequest(path string, data map[string]interface{}, pa
 			}
 		}
 
-		client := &http.Client{}
-		resp, err := client.Do(req)
+		resp, err := querierHTTPClient.Do(req)
 		return resp, nil, err
-	}, len(QuerierHosts), nil)
+	}, len(QuerierHosts), nil, 0, userContext)
 	return resp, err
 }
 
@@ -268,8 +321,8 @@ func (q *Querier) SendGetRequest(path string, params map[string]string, userCont
 	if err != nil {
 		return nil, err
 	}
-	resp, _, err := q.sendRequestHelper(nP, func(url string) (*http.Response, []byte, error) {
-		req, err := http.NewRequest("GET", url, nil)
+	resp, _, err := q.sendRequestHelper(nP, "GET", func(url string) (*http.Response, []byte, error) {
+		req, err := http.NewRequestWithContext(getContextFromUserContext(userContext), "GET", url, nil)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -353,6 +406,13 @@ func (q *Querier) SendGetRequest(path string, params map[string]string, userCont
 			}
 		}
 
+		sharedCache, sharedCacheKey, sharedCacheTTLMS, useSharedCache := getSharedCoreCallCache(nP, uniqueKey)
+		if useSharedCache {
+			if body, ok := sharedCache.Get(sharedCacheKey); ok {
+				return nil, body, nil
+			}
+		}
+
 		if querierInterceptor != nil {
 			req, err = querierInterceptor(req, userContext)
 			if err != nil {
@@ -360,32 +420,39 @@ func (q *Querier) SendGetRequest(path string, params map[string]string, userCont
 			}
 		}
 
-		client := &http.Client{}
-		response, err := client.Do(req)
+		response, err := querierHTTPClient.Do(req)
 		if err != nil {
 			return nil, nil, err
 		}
 
-		if response.StatusCode == 200 && !querierDisableCache && userContext != nil {
+		useRequestCache := !querierDisableCache && userContext != nil
+		if response.StatusCode == 200 && (useRequestCache || useSharedCache) {
 			defer response.Body.Close()
 			body, err := ioutil.ReadAll(response.Body)
 			if err != nil {
 				return nil, nil, err
 			}
-			defaultContext, ok := (*userContext)["_default"].(map[string]interface{})
-			if !ok {
-				defaultContext = make(map[string]interface{})
-			}
 
-			coreCallCache, ok := defaultContext["coreCallCache"].(map[string]interface{})
-			if !ok {
-				coreCallCache = make(map[string]interface{})
+			if useRequestCache {
+				defaultContext, ok := (*userContext)["_default"].(map[string]interface{})
+				if !ok {
+					defaultContext = make(map[string]interface{})
+				}
+
+				coreCallCache, ok := defaultContext["coreCallCache"].(map[string]interface{})
+				if !ok {
+					coreCallCache = make(map[string]interface{})
+				}
+				coreCallCache[uniqueKey] = body
+				defaultContext["coreCallCache"] = coreCallCache
+				defaultContext["globalCacheTag"] = querierGlobalCacheTag
+
+				(*userContext)["_default"] = defaultContext
 			}
-			coreCallCache[uniqueKey] = body
-			defaultContext["coreCallCache"] = coreCallCache
-			defaultContext["globalCacheTag"] = querierGlobalCacheTag
 
-			(*userContext)["_default"] = defaultContext
+			if useSharedCache {
+				sharedCache.Set(sharedCacheKey, body, sharedCacheTTLMS)
+			}
 
 			// we send the cached body here because we cannot do ioutil.ReadAll(response.Body)
 			// once again on the body.
@@ -393,7 +460,7 @@ func (q *Querier) SendGetRequest(path string, params map[string]string, userCont
 		}
 
 		return response, nil, nil
-	}, len(QuerierHosts), nil)
+	}, len(QuerierHosts), nil, 0, userContext)
 	return resp, err
 }
 
@@ -403,8 +470,8 @@ func (q *Querier) SendGetRequestWithResponseHeaders(path string, params map[stri
 		return nil, nil, err
 	}
 
-	return q.sendRequestHelper(nP, func(url string) (*http.Response, []byte, error) {
-		req, err := http.NewRequest("GET", url, nil)
+	return q.sendRequestHelper(nP, "GET", func(url string) (*http.Response, []byte, error) {
+		req, err := http.NewRequestWithContext(getContextFromUserContext(userContext), "GET", url, nil)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -435,10 +502,9 @@ func (q *Querier) SendGetRequestWithResponseHeaders(path string, params map[stri
 			}
 		}
 
-		client := &http.Client{}
-		resp, err := client.Do(req)
+		resp, err := querierHTTPClient.Do(req)
 		return resp, nil, err
-	}, len(QuerierHosts), nil)
+	}, len(QuerierHosts), nil, 0, userContext)
 }
 
 func (q *Querier) SendPutRequest(path string, data map[string]interface{}, userContext UserContext) (map[string]interface{}, error) {
@@ -447,12 +513,14 @@ func (q *Querier) SendPutRequest(path string, data map[string]interface{}, userC
 	if err != nil {
 		return nil, err
 	}
-	resp, _, err := q.sendRequestHelper(nP, func(url string) (*http.Response, []byte, error) {
+	// this is done after the write so that responses fetched while it was in progress are not reused
+	defer invalidateSharedCoreCallCache(nP)
+	resp, _, err := q.sendRequestHelper(nP, "PUT", func(url string) (*http.Response, []byte, error) {
 		jsonData, err := json.Marshal(data)
 		if err != nil {
 			return nil, nil, err
 		}
-		req, err := http.NewRequest("PUT", url, bytes.NewBuffer(jsonData))
+		req, err := http.NewRequestWithContext(getContextFromUserContext(userContext), "PUT", url, bytes.NewBuffer(jsonData))
 		if err != nil {
 			return nil, nil, err
 		}
@@ -478,10 +546,9 @@ func (q *Querier) SendPutRequest(path string, data map[string]interface{}, userC
 			}
 		}
 
-		client := &http.Client{}
-		resp, err := client.Do(req)
+		resp, err := querierHTTPClient.Do(req)
 		return resp, nil, err
-	}, len(QuerierHosts), nil)
+	}, len(QuerierHosts), nil, 0, userContext)
 	return resp, err
 }
 
@@ -537,14 +604,21 @@ func GetAllCoreUrlsForPath(path string) []string {
 	return result
 }
 
-func (q *Querier) sendRequestHelper(path NormalisedURLPath, httpRequest httpRequestFunction, numberOfTries int, retryInfoMap *map[string]int) (map[string]interface{}, http.Header, error) {
+func (q *Querier) sendRequestHelper(path NormalisedURLPath, method string, httpRequest httpRequestFunction, numberOfTries int, retryInfoMap *map[string]int, getRetriesMade int, userContext UserContext) (map[string]interface{}, http.Header, error) {
+	ctx := getContextFromUserContext(userContext)
 	if numberOfTries == 0 {
 		return nil, nil, errors.New("no SuperTokens core available to query")
 	}
 
+	// there is no point in trying (or retrying) a request that the caller has already given up on
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
 	querierHostLock.Lock()
-	currentDomain := QuerierHosts[querierLastTriedIndex].Domain.GetAsStringDangerous()
-	currentBasePath := QuerierHosts[querierLastTriedIndex].BasePath.GetAsStringDangerous()
+	hostIndex := pickQuerierHostIndex()
+	currentDomain := QuerierHosts[hostIndex].Domain.GetAsStringDangerous()
+	currentBasePath := QuerierHosts[hostIndex].BasePath.GetAsStringDangerous()
 	url := currentDomain + currentBasePath + path.GetAsStringDangerous()
 
 	maxRetries := 5
@@ -561,19 +635,64 @@ func (q *Querier) sendRequestHelper(path NormalisedURLPath, httpRequest httpRequ
 	if !ok {
 		_retryInfoMap[url] = maxRetries
 	}
-
-	querierLastTriedIndex = (querierLastTriedIndex + 1) % len(QuerierHosts)
 	querierHostLock.Unlock()
 
+	// GET requests are idempotent, so we can safely retry them on another core if they fail
+	canRetryGET := method == "GET" && getRetriesMade < querierHealthConfig.maxGETRetries
+	retryGET := func() (map[string]interface{}, http.Header, error) {
+		err := waitBeforeRetry(ctx, getRetriesMade)
+		if err != nil {
+			return nil, nil, err
+		}
+		return q.sendRequestHelper(path, method, httpRequest, numberOfTries, &_retryInfoMap, getRetriesMade+1, userContext)
+	}
+
+	spanAttributes := map[string]interface{}{
+		AttributeCorePath:   path.GetAsStringDangerous(),
+		AttributeCoreHost:   currentDomain,
+		AttributeHTTPMethod: method,
+	}
+	span, endSpan := StartSpan(userContext, SpanNameCoreRequest, spanAttributes)
+	startTime := time.Now()
 	resp, cachedBody, err := httpRequest(url)
+	if err != nil {
+		span.RecordError(err)
+	} else if resp != nil {
+		span.SetAttributes(map[string]interface{}{AttributeHTTPStatusCode: resp.StatusCode})
+		spanAttributes[AttributeHTTPStatusCode] = resp.StatusCode
+	}
+	if resp != nil || err != nil {
+		// responses served from the cache are not counted as core requests
+		recordDurationSince(getContextFromUserContext(userContext), MetricCoreRequestDuration, startTime, spanAttributes)
+	}
+	endSpan()
+
+	logFields := append(LogFieldsFromUserContext(userContext),
+		LogFieldCorePath, path.GetAsStringDangerous(),
+		LogFieldCoreHost, currentDomain,
+		LogFieldMethod, method,
+		LogFieldLatencyMS, time.Since(startTime).Milliseconds(),
+	)
 
 	if err != nil {
-		if strings.Contains(err.Error(), "connection refused") {
-			return q.sendRequestHelper(path, httpRequest, numberOfTries-1, &_retryInfoMap)
-		}
 		if cachedBody == nil && resp != nil {
 			resp.Body.Close()
 		}
+		logFields = append(logFields, LogFieldError, err.Error())
+		// if the context is done, the error is because of the caller and not the core
+		if ctx.Err() != nil {
+			LogDebug("querier: request to core cancelled", logFields...)
+			releaseQuerierHostProbe(hostIndex)
+		} else {
+			LogWarn("querier: request to core failed", logFields...)
+			reportQuerierHostFailure(hostIndex, time.Since(startTime), err.Error())
+			if strings.Contains(err.Error(), "connection refused") {
+				return q.sendRequestHelper(path, method, httpRequest, numberOfTries-1, &_retryInfoMap, getRetriesMade, userContext)
+			}
+			if canRetryGET {
+				return retryGET()
+			}
+		}
 		return nil, nil, err
 	}
 
@@ -585,9 +704,26 @@ func (q *Querier) sendRequestHelper(path NormalisedURLPath, httpRequest httpRequ
 		defer resp.Body.Close()
 		body, err = ioutil.ReadAll(resp.Body)
 		if err != nil {
+			if ctx.Err() != nil {
+				releaseQuerierHostProbe(hostIndex)
+			} else {
+				reportQuerierHostFailure(hostIndex, time.Since(startTime), err.Error())
+			}
 			return nil, nil, err
 		}
 	}
+	if resp == nil {
+		// the response was served from the cache, so the core was not queried
+		releaseQuerierHostProbe(hostIndex)
+		LogDebug("querier: response served from cache", logFields...)
+	} else {
+		LogDebug("querier: request to core completed", append(logFields, LogFieldStatusCode, resp.StatusCode)...)
+		if resp.StatusCode >= 500 {
+			reportQuerierHostFailure(hostIndex, time.Since(startTime), fmt.Sprintf("status code: %v", resp.StatusCode))
+		} else {
+			reportQuerierHostSuccess(hostIndex, time.Since(startTime))
+		}
+	}
 	if resp != nil && resp.StatusCode != 200 {
 		if resp.StatusCode == RateLimitStatusCode {
 			retriesLeft := _retryInfoMap[url]
@@ -598,12 +734,22 @@ func (q *Querier) sendRequestHelper(path NormalisedURLPath, httpRequest httpRequ
 				attemptsMade := maxRetries - retriesLeft
 				delay := 10 + (250 * attemptsMade)
 
-				time.Sleep(time.Millisecond * time.Duration(delay))
+				timer := time.NewTimer(time.Millisecond * time.Duration(delay))
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return nil, nil, ctx.Err()
+				case <-timer.C:
+				}
 
-				return q.sendRequestHelper(path, httpRequest, numberOfTries, &_retryInfoMap)
+				return q.sendRequestHelper(path, method, httpRequest, numberOfTries, &_retryInfoMap, getRetriesMade, userContext)
 			}
 		}
 
+		if resp.StatusCode >= 500 && canRetryGET {
+			return retryGET()
+		}
+
 		return nil, nil, fmt.Errorf("SuperTokens core threw an error for a request to path: '%s' with status code: %v and message: %s", path.GetAsStringDangerous(), resp.StatusCode, body)
 	}
 
@@ -0,0 +1,245 @@
+/* Copyright (c) 2021, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package supertokens
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+const (
+	CoreHostCircuitClosed   = "CLOSED"
+	CoreHostCircuitOpen     = "OPEN"
+	CoreHostCircuitHalfOpen = "HALF_OPEN"
+)
+
+const (
+	defaultHostFailureThreshold = 3
+	defaultHostCooldownMS       = 10000
+	defaultRetryBaseDelayMS     = 50
+	defaultRetryMaxDelayMS      = 1000
+)
+
+// QuerierHostState is a snapshot of the health of one of the configured cores.
+type QuerierHostState struct {
+	Host                QuerierHost
+	CircuitState        string
+	ConsecutiveFailures int
+	TotalRequests       uint64
+	TotalFailures       uint64
+	// EjectedUntil is the time (in ms since epoch) until which the core is skipped. It is 0 if
+	// the core is not ejected.
+	EjectedUntil    uint64
+	LastError       string
+	LastLatencyMS   uint64
+	LastFailureTime uint64
+}
+
+type querierHostHealth struct {
+	consecutiveFailures int
+	totalRequests       uint64
+	totalFailures       uint64
+	ejectedUntil        uint64
+	probeStartedAt      uint64
+	lastError           string
+	lastLatencyMS       uint64
+	lastFailureTime     uint64
+}
+
+type normalisedCoreHostHealthConfig struct {
+	disableCircuitBreaker bool
+	failureThreshold      int
+	cooldownMS            uint64
+	maxGETRetries         int
+	retryBaseDelayMS      uint64
+	retryMaxDelayMS       uint64
+}
+
+var (
+	querierHostsHealth  []querierHostHealth
+	querierHealthConfig = normaliseCoreHostHealthConfig(nil)
+)
+
+func normaliseCoreHostHealthConfig(config *CoreHostHealthConfig) normalisedCoreHostHealthConfig {
+	result := normalisedCoreHostHealthConfig{
+		failureThreshold: defaultHostFailureThreshold,
+		cooldownMS:       defaultHostCooldownMS,
+		retryBaseDelayMS: defaultRetryBaseDelayMS,
+		retryMaxDelayMS:  defaultRetryMaxDelayMS,
+	}
+	if config == nil {
+		return result
+	}
+	result.disableCircuitBreaker = config.DisableCircuitBreaker
+	if config.FailureThreshold > 0 {
+		result.failureThreshold = config.FailureThreshold
+	}
+	if config.CooldownMS > 0 {
+		result.cooldownMS = config.CooldownMS
+	}
+	if config.MaxGETRetries > 0 {
+		result.maxGETRetries = config.MaxGETRetries
+	}
+	if config.RetryBaseDelayMS > 0 {
+		result.retryBaseDelayMS = config.RetryBaseDelayMS
+	}
+	if config.RetryMaxDelayMS > 0 {
+		result.retryMaxDelayMS = config.RetryMaxDelayMS
+	}
+	return result
+}
+
+// pickQuerierHostIndex returns the index of the next core to query in a round robin fashion,
+// skipping the cores whose circuit is open. If all the cores are ejected, the one that is due
+// to be retried the earliest is returned, so that a request is never failed without trying.
+// This must be called with querierHostLock held.
+func pickQuerierHostIndex() int {
+	if len(querierHostsHealth) != len(QuerierHosts) {
+		querierHostsHealth = make([]querierHostHealth, len(QuerierHosts))
+	}
+
+	now := GetCurrTimeInMS()
+	fallbackIndex := -1
+	for i := 0; i < len(QuerierHosts); i++ {
+		index := (querierLastTriedIndex + i) % len(QuerierHosts)
+		health := &querierHostsHealth[index]
+
+		available := querierHealthConfig.disableCircuitBreaker || health.ejectedUntil == 0
+		probeInFlight := health.probeStartedAt != 0 && health.probeStartedAt+querierHealthConfig.cooldownMS > now
+		if !available && health.ejectedUntil <= now && !probeInFlight {
+			// the cooldown is over, so we let one request through to check if the core has recovered
+			health.probeStartedAt = now
+			available = true
+		}
+
+		if available {
+			querierLastTriedIndex = (index + 1) % len(QuerierHosts)
+			return index
+		}
+
+		if fallbackIndex == -1 || health.ejectedUntil < querierHostsHealth[fallbackIndex].ejectedUntil {
+			fallbackIndex = index
+		}
+	}
+
+	querierLastTriedIndex = (fallbackIndex + 1) % len(QuerierHosts)
+	return fallbackIndex
+}
+
+func reportQuerierHostSuccess(index int, latency time.Duration) {
+	querierHostLock.Lock()
+	defer querierHostLock.Unlock()
+	if index >= len(querierHostsHealth) {
+		return
+	}
+	health := &querierHostsHealth[index]
+	health.totalRequests++
+	health.consecutiveFailures = 0
+	health.ejectedUntil = 0
+	health.probeStartedAt = 0
+	health.lastLatencyMS = uint64(latency.Milliseconds())
+}
+
+func reportQuerierHostFailure(index int, latency time.Duration, reason string) {
+	querierHostLock.Lock()
+	defer querierHostLock.Unlock()
+	if index >= len(querierHostsHealth) {
+		return
+	}
+	now := GetCurrTimeInMS()
+	health := &querierHostsHealth[index]
+	health.totalRequests++
+	health.totalFailures++
+	health.consecutiveFailures++
+	health.lastError = reason
+	health.lastFailureTime = now
+	health.lastLatencyMS = uint64(latency.Milliseconds())
+
+	if !querierHealthConfig.disableCircuitBreaker && (health.probeStartedAt != 0 || health.consecutiveFailures >= querierHealthConfig.failureThreshold) {
+		health.ejectedUntil = now + querierHealthConfig.cooldownMS
+		LogWarn("querier: ejecting core after consecutive failures", LogFieldCoreHost, QuerierHosts[index].Domain.GetAsStringDangerous(), LogFieldError, reason, "cooldownMs", querierHealthConfig.cooldownMS)
+	}
+	health.probeStartedAt = 0
+}
+
+// releaseQuerierHostProbe is called when a request to the core ended without telling us anything
+// about its health (for example, because the caller cancelled it), so that another request can
+// check if the core has recovered.
+func releaseQuerierHostProbe(index int) {
+	querierHostLock.Lock()
+	defer querierHostLock.Unlock()
+	if index >= len(querierHostsHealth) {
+		return
+	}
+	querierHostsHealth[index].probeStartedAt = 0
+}
+
+// waitBeforeRetry sleeps for an exponentially increasing delay with full jitter. It returns
+// early with the context's error if the context is done while waiting.
+func waitBeforeRetry(ctx context.Context, retriesMade int) error {
+	maxDelay := querierHealthConfig.retryBaseDelayMS << uint(retriesMade)
+	if maxDelay > querierHealthConfig.retryMaxDelayMS || maxDelay < querierHealthConfig.retryBaseDelayMS {
+		maxDelay = querierHealthConfig.retryMaxDelayMS
+	}
+	delay := time.Duration(rand.Int63n(int64(maxDelay)+1)) * time.Millisecond
+
+	timer := time.NewTimer(delay)
+	select {
+	case <-ctx.Done():
+		timer.Stop()
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// GetQuerierHostStates returns the current health of each of the cores in the ConnectionURI.
+func GetQuerierHostStates() []QuerierHostState {
+	querierHostLock.Lock()
+	defer querierHostLock.Unlock()
+
+	now := GetCurrTimeInMS()
+	result := []QuerierHostState{}
+	for i, host := range QuerierHosts {
+		health := querierHostHealth{}
+		if i < len(querierHostsHealth) {
+			health = querierHostsHealth[i]
+		}
+
+		circuitState := CoreHostCircuitClosed
+		if health.ejectedUntil != 0 {
+			if health.ejectedUntil > now {
+				circuitState = CoreHostCircuitOpen
+			} else {
+				circuitState = CoreHostCircuitHalfOpen
+			}
+		}
+
+		result = append(result, QuerierHostState{
+			Host:                host,
+			CircuitState:        circuitState,
+			ConsecutiveFailures: health.consecutiveFailures,
+			TotalRequests:       health.totalRequests,
+			TotalFailures:       health.totalFailures,
+			EjectedUntil:        health.ejectedUntil,
+			LastError:           health.lastError,
+			LastLatencyMS:       health.lastLatencyMS,
+			LastFailureTime:     health.lastFailureTime,
+		})
+	}
+	return result
+}
@@ -0,0 +1,46 @@
+//go:build go1.21
+
+/* Copyright (c) 2021, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package supertokens
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlogLoggerForwardsLevelAndFields(t *testing.T) {
+	var buf bytes.Buffer
+	SetLogger(NewSlogLogger(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))))
+	defer SetLogger(nil)
+
+	LogDebug("not logged")
+	assert.Equal(t, 0, buf.Len())
+
+	LogWarn("core ejected", LogFieldCoreHost, "http://localhost:3567", LogFieldLatencyMS, 12)
+
+	var record map[string]interface{}
+	err := json.Unmarshal(buf.Bytes(), &record)
+	assert.NoError(t, err)
+	assert.Equal(t, "WARN", record["level"])
+	assert.Equal(t, "core ejected", record["msg"])
+	assert.Equal(t, "http://localhost:3567", record[LogFieldCoreHost])
+	assert.Equal(t, float64(12), record[LogFieldLatencyMS])
+}
@@ -16,6 +16,9 @@
 package supertokens
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"flag"
@@ -23,6 +26,7 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // This function is required to be here because calling multitenancy recipe from this module causes cyclic dependency
@@ -55,6 +59,12 @@ func supertokensInit(config TypeInput) error {
 	}
 
 	DebugEnabled = config.Debug
+	if config.Logger != nil {
+		SetLogger(config.Logger)
+	}
+	if config.Instrumentation != nil {
+		SetInstrumentation(config.Instrumentation)
+	}
 
 	LogDebugMessage("Started SuperTokens with debug logging (supertokens.Init called)")
 
@@ -98,7 +108,10 @@ func supertokensInit(config TypeInput) error {
 					BasePath: basePath,
 				})
 			}
-			initQuerier(hosts, config.Supertokens.APIKey, config.Supertokens.NetworkInterceptor, config.Supertokens.DisableCoreCallCache)
+			err = initQuerier(hosts, *config.Supertokens)
+			if err != nil {
+				return err
+			}
 			superTokens.SuperTokens = *config.Supertokens
 		} else {
 			return errors.New("please provide 'ConnectionURI' value. If you do not want to provide a connection URI, then set config.Supertokens to nil")
@@ -158,6 +171,7 @@ func (s *superTokens) middleware(theirHandler http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		dw := MakeDoneWriter(w)
 		userContext := MakeDefaultUserContextFromAPI(r)
+		setRequestIDInUserContext(userContext, r)
 		reqURL, err := NewNormalisedURLPath(r.URL.Path)
 		if err != nil {
 			err = s.errorHandler(err, r, dw, userContext)
@@ -258,7 +272,7 @@ func (s *superTokens) middleware(theirHandler http.Handler) http.Handler {
 				}
 			}
 
-			apiErr := finalMatchedRecipe.HandleAPIRequest(*id, tenantId, r, dw, theirHandler.ServeHTTP, path, method, userContext)
+			apiErr := handleAPIRequestWithTelemetry(finalMatchedRecipe, *id, tenantId, r, dw, theirHandler.ServeHTTP, path, method, userContext)
 			if apiErr != nil {
 				apiErr = s.errorHandler(apiErr, r, dw, userContext)
 				if apiErr != nil && !dw.IsDone() {
@@ -287,7 +301,7 @@ func (s *superTokens) middlewareHelperHandleWithoutRid(path NormalisedURLPath, m
 
 		if id != nil {
 			LogDebugMessage("middleware: Request being handled by recipe. ID is: " + *id)
-			err := recipeModule.HandleAPIRequest(*id, tenantId, r, dw, theirHandler.ServeHTTP, path, method, userContext)
+			err := handleAPIRequestWithTelemetry(recipeModule, *id, tenantId, r, dw, theirHandler.ServeHTTP, path, method, userContext)
 			if err != nil {
 				err = s.errorHandler(err, r, dw, userContext)
 				if err != nil && !dw.IsDone() {
@@ -459,6 +473,8 @@ func deleteUser(userId string) error {
 
 func ResetForTest() {
 	ResetQuerierForTest()
+	SetLogger(nil)
+	SetInstrumentation(nil)
 	resetPostInitCallbackForTest()
 	if superTokensInstance != nil {
 		for _, recipeModule := range superTokensInstance.RecipeModules {
@@ -495,3 +511,85 @@ func getRequestFromUserContext(userContext UserContext) *http.Request {
 	}
 	return requestObj
 }
+
+// getContextFromUserContext returns the context.Context that core calls made with this
+// userContext should be bound to. A context set explicitly via SetContextInUserContext takes
+// precedence over the context of the request (if any) stored in the userContext.
+func getContextFromUserContext(userContext UserContext) context.Context {
+	if userContext != nil {
+		defaultObj, ok := (*userContext)["_default"].(map[string]interface{})
+		if ok {
+			ctx, ok := defaultObj["context"].(context.Context)
+			if ok && ctx != nil {
+				return ctx
+			}
+		}
+	}
+
+	req := getRequestFromUserContext(userContext)
+	if req != nil {
+		return req.Context()
+	}
+	return context.Background()
+}
+
+const requestIdHeader = "X-Request-Id"
+
+// setRequestIDInUserContext uses the X-Request-Id header of the request (or a random id if it is
+// not present) to correlate the log messages for the request.
+func setRequestIDInUserContext(userContext UserContext, r *http.Request) {
+	requestId := r.Header.Get(requestIdHeader)
+	if requestId == "" {
+		randomBytes := make([]byte, 8)
+		_, err := rand.Read(randomBytes)
+		if err == nil {
+			requestId = hex.EncodeToString(randomBytes)
+		}
+	}
+	defaultObj, ok := (*userContext)["_default"].(map[string]interface{})
+	if ok && requestId != "" {
+		defaultObj["requestId"] = requestId
+	}
+}
+
+func setTenantIdInUserContext(userContext UserContext, tenantId string) {
+	defaultObj, ok := (*userContext)["_default"].(map[string]interface{})
+	if ok {
+		defaultObj["tenantId"] = tenantId
+	}
+}
+
+// handleAPIRequestWithTelemetry calls the recipe's API handler inside a span, and logs and
+// records the duration of the API.
+func handleAPIRequestWithTelemetry(recipeModule RecipeModule, id string, tenantId string, r *http.Request, dw DoneWriter, theirHandler http.HandlerFunc, path NormalisedURLPath, method string, userContext UserContext) error {
+	setTenantIdInUserContext(userContext, tenantId)
+	attributes := map[string]interface{}{
+		AttributeAPIID:      id,
+		AttributeRecipeID:   recipeModule.GetRecipeID(),
+		AttributeTenantID:   tenantId,
+		AttributeHTTPMethod: method,
+	}
+	span, endSpan := StartSpan(userContext, SpanNameAPI, attributes)
+	startTime := time.Now()
+
+	err := recipeModule.HandleAPIRequest(id, tenantId, r, dw, theirHandler, path, method, userContext)
+
+	if err != nil {
+		span.RecordError(err)
+	}
+	recordDurationSince(getContextFromUserContext(userContext), MetricAPIDuration, startTime, attributes)
+	endSpan()
+
+	fields := append(LogFieldsFromUserContext(userContext),
+		LogFieldRecipeID, recipeModule.GetRecipeID(),
+		LogFieldAPIID, id,
+		LogFieldMethod, method,
+		LogFieldLatencyMS, time.Since(startTime).Milliseconds(),
+	)
+	if err != nil {
+		LogInfo("middleware: API returned an error", append(fields, LogFieldError, err.Error())...)
+	} else {
+		LogInfo("middleware: API handled", fields...)
+	}
+	return err
+}
@@ -17,6 +17,7 @@ package supertokens
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -331,6 +332,30 @@ func SetRequestInUserContextIfNotDefined(userContext *map[string]interface{}, r
 	return &_userContext
 }
 
+// MakeUserContextFromContext creates a new userContext that carries ctx. All core calls made with
+// the returned userContext honour the deadline and cancellation of ctx.
+func MakeUserContextFromContext(ctx context.Context) UserContext {
+	return SetContextInUserContext(nil, ctx)
+}
+
+// SetContextInUserContext binds ctx to the (possibly nil) userContext so that core calls made
+// with it are aborted once ctx is done. The context of a request already present in the
+// userContext is overridden by ctx.
+func SetContextInUserContext(userContext UserContext, ctx context.Context) UserContext {
+	if userContext == nil {
+		userContext = &map[string]interface{}{}
+	}
+
+	defaultObj, ok := (*userContext)["_default"].(map[string]interface{})
+	if !ok {
+		defaultObj = map[string]interface{}{}
+	}
+	defaultObj["context"] = ctx
+	(*userContext)["_default"] = defaultObj
+
+	return userContext
+}
+
 func GetTopLevelDomainForSameSiteResolution(URL string) (string, error) {
 	urlObj, err := url.Parse(URL)
 	if err != nil {